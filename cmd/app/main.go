@@ -6,11 +6,16 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/folklinoff/fitness-app/cmd/app/processor"
 	docs "github.com/folklinoff/fitness-app/docs"
+	"github.com/folklinoff/fitness-app/internal/config"
 )
 
+// shutdownTimeout bounds the wait for the requests in flight on shutdown
+const shutdownTimeout = 10 * time.Second
+
 // @title Fitness App API
 // @version 1.0
 // @description This is a fitness app server.
@@ -30,18 +35,31 @@ import (
 // @in header
 // @name Authorization
 func main() {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	errors := make(chan error)
 	go func() {
-		errors <- processor.Run()
+		errors <- processor.Run(cfg)
 	}()
 	docs.SwaggerInfo.Host = os.Getenv("HOST")
-	stop := make(chan os.Signal)
+	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
 
 	select {
 	case err := <-errors:
 		log.Println(err)
 	case <-stop:
-		processor.Shutdown(context.Background())
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := processor.Shutdown(ctx); err != nil {
+			log.Println(err)
+		}
+		// Run returns once the workers are stopped and the storage is closed
+		if err := <-errors; err != nil {
+			log.Println(err)
+		}
 	}
 }
@@ -0,0 +1,43 @@
+package processor
+
+import (
+	"context"
+	"log"
+
+	"github.com/folklinoff/fitness-app/internal/config"
+	"github.com/folklinoff/fitness-app/internal/domain"
+	"github.com/folklinoff/fitness-app/internal/password"
+	"github.com/folklinoff/fitness-app/internal/storage"
+	"golang.org/x/xerrors"
+)
+
+// ensureAdmin creates the configured admin account unless it already exists.
+// The password of an existing admin is left untouched.
+func ensureAdmin(ctx context.Context, admins storage.AdminRepository, hasher *password.Hasher, cfg config.Admin) error {
+	if cfg.Name == "" {
+		return nil
+	}
+
+	_, err := admins.GetByName(ctx, cfg.Name)
+	if err == nil {
+		return nil
+	}
+	if !xerrors.Is(err, storage.ErrNotFound) {
+		return xerrors.Errorf("look up admin %q: %w", cfg.Name, err)
+	}
+
+	hash, err := hasher.Hash(cfg.Password)
+	if err != nil {
+		return xerrors.Errorf("hash admin password: %w", err)
+	}
+	err = admins.Create(ctx, &domain.Admin{Name: cfg.Name, Password: hash})
+	if xerrors.Is(err, storage.ErrAlreadyExists) {
+		// Another instance created it concurrently
+		return nil
+	}
+	if err != nil {
+		return xerrors.Errorf("create admin %q: %w", cfg.Name, err)
+	}
+	log.Printf("admin account %q created", cfg.Name)
+	return nil
+}
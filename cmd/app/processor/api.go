@@ -1,39 +1,105 @@
 package processor
 
 import (
+	"github.com/folklinoff/fitness-app/internal/audit"
+	"github.com/folklinoff/fitness-app/internal/config"
 	"github.com/folklinoff/fitness-app/internal/handler"
 	middleware "github.com/folklinoff/fitness-app/internal/middleware/auth"
+	"github.com/folklinoff/fitness-app/internal/password"
+	"github.com/folklinoff/fitness-app/internal/payment"
+	"github.com/folklinoff/fitness-app/internal/policy"
+	"github.com/folklinoff/fitness-app/internal/storage"
 	"github.com/gin-gonic/gin"
 	swaggerFiles "github.com/swaggo/files"
 	"github.com/swaggo/gin-swagger"
 )
 
-func api() *gin.Engine {
+func api(store storage.Storage, hasher *password.Hasher, tokens *middleware.TokenManager, dev *middleware.DevAuthenticator, booking config.Booking,
+	gateway payment.PaymentGateway, checkout config.Payments, publicURL string) *gin.Engine {
 	r := gin.Default()
+	auditLog := audit.New(store.Audit)
+	h := handler.New(store, hasher, tokens, policy.New(store.Trainings, auditLog), booking, gateway, checkout, publicURL)
+	guard := middleware.NewGuard(auditLog)
 
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	r.GET("/.well-known/jwks.json", h.JWKS)
 
 	// Public routes
-	r.POST("/login/:user_type", handler.Login)
-	r.POST("/register/:user_type", handler.Register)
-	r.GET("/trainings", handler.GetAllTrainings)
+	r.POST("/login/:user_type", h.Login)
+	r.POST("/register/:user_type", h.Register)
+	r.POST("/token/refresh", h.RefreshToken)
+	r.GET("/trainings", h.GetAllTrainings)
+	r.GET("/locations", h.GetLocations)
+	r.GET("/locations/:id", h.GetLocation)
+	r.GET("/calendar/:token", h.GetCalendarFeed)
+	r.GET("/plans", h.GetPlans)
+	r.POST("/payments/webhook", h.PaymentWebhook)
 
 	// Protected routes
+	userOnly := guard.RequireRole(middleware.UserTypeUser)
+	trainerOnly := guard.RequireRole(middleware.UserTypeTrainer)
+	trainerOrAdmin := guard.RequireRole(middleware.UserTypeTrainer, middleware.UserTypeAdmin)
+	adminOnly := guard.RequireRole(middleware.UserTypeAdmin)
+	userOrTrainer := guard.RequireRole(middleware.UserTypeUser, middleware.UserTypeTrainer)
+
 	protected := r.Group("/protected")
-	protected.Use(middleware.AuthenticationMiddleware())
+	protected.Use(middleware.AuthenticationMiddleware(tokens, dev))
 	{
-		protected.GET("/profile", handler.Profile)
-		protected.POST("/training", handler.CreateTraining)
-		protected.POST("/training/:id/register", handler.RegisterUserForTraining)
-		protected.GET("/training/:id", handler.GetTrainingByID)
-		protected.PUT("/training/:id", handler.UpdateTraining)
-		protected.DELETE("/training/:id", handler.DeleteTraining)
-		protected.GET("/user/:id", handler.GetUserProfile)
-		protected.PUT("/user/:id", handler.UpdateUserProfile)
-		protected.DELETE("/user/:id", handler.DeleteUserProfile)
-		protected.GET("/user/schedule", handler.GetUserSchedule)
-		protected.GET("/trainer/schedule", handler.GetTrainerSchedule)
-		protected.GET("/training/:id/users", handler.GetUsersByTrainingID)
+		protected.GET("/profile", h.Profile)
+		protected.POST("/logout", h.Logout)
+		protected.POST("/logout/all", h.LogoutAll)
+		protected.POST("/training", trainerOnly, h.CreateTraining)
+		protected.POST("/training/:id/register", userOnly, h.RegisterUserForTraining)
+		protected.DELETE("/training/:id/register", userOnly, h.CancelRegistration)
+		protected.GET("/training/:id/registration", userOnly, h.GetRegistration)
+		protected.POST("/training/:id/checkout", userOnly, h.CheckoutTraining)
+		protected.POST("/training/:id/no-show", trainerOrAdmin, h.ReportNoShow)
+		protected.POST("/training/:id/check-in-code", trainerOrAdmin, h.CreateCheckInCode)
+		protected.POST("/training/:id/check-in", userOnly, h.CheckIn)
+		protected.GET("/training/:id/attendance", trainerOrAdmin, h.GetTrainingAttendance)
+		protected.PUT("/training/:id/attendance/:user_id", trainerOrAdmin, h.MarkAttendance)
+		protected.DELETE("/training/:id/attendance/:user_id", trainerOrAdmin, h.UnmarkAttendance)
+		protected.GET("/training/:id", h.GetTrainingByID)
+		protected.PUT("/training/:id", trainerOrAdmin, h.UpdateTraining)
+		protected.DELETE("/training/:id", trainerOrAdmin, h.DeleteTraining)
+		protected.GET("/users/:id", h.GetUser)
+		protected.PUT("/users/:id", h.UpdateUser)
+		protected.DELETE("/users/:id", h.DeleteUser)
+		protected.GET("/users/:id/incidents", h.GetUserIncidents)
+		protected.GET("/users/:id/memberships", h.GetUserMemberships)
+		protected.POST("/users/:id/memberships", adminOnly, h.GrantMembership)
+		protected.GET("/users/:id/ledger", h.GetUserLedger)
+		protected.GET("/users/:id/payments", h.GetUserPayments)
+		protected.GET("/trainers/:id", h.GetTrainer)
+		protected.PUT("/trainers/:id", h.UpdateTrainer)
+		protected.DELETE("/trainers/:id", h.DeleteTrainer)
+		// Deprecated, the ID is ambiguous between users and trainers
+		protected.GET("/user/:id", h.GetUserProfile)
+		protected.PUT("/user/:id", h.UpdateUserProfile)
+		protected.DELETE("/user/:id", h.DeleteUserProfile)
+		protected.GET("/user/schedule", userOnly, h.GetUserSchedule)
+		protected.GET("/trainer/schedule", trainerOnly, h.GetTrainerSchedule)
+		protected.GET("/user/schedule.ics", userOnly, h.GetUserScheduleCalendar)
+		protected.GET("/trainer/schedule.ics", trainerOnly, h.GetTrainerScheduleCalendar)
+		protected.POST("/calendar/feed", userOrTrainer, h.CreateCalendarFeed)
+		protected.DELETE("/calendar/feed", userOrTrainer, h.DeleteCalendarFeed)
+		protected.GET("/training/:id/users", trainerOrAdmin, h.GetUsersByTrainingID)
+		protected.POST("/series", trainerOnly, h.CreateSeries)
+		protected.GET("/series/:id", h.GetSeries)
+		protected.DELETE("/series/:id", trainerOrAdmin, h.DeleteSeries)
+		protected.PUT("/series/:id/occurrences/:training_id", trainerOrAdmin, h.UpdateOccurrence)
+		protected.DELETE("/series/:id/occurrences/:training_id", trainerOrAdmin, h.DeleteOccurrence)
+		protected.GET("/audit", adminOnly, h.GetAuditEvents)
+		protected.POST("/locations", adminOnly, h.CreateLocation)
+		protected.PUT("/locations/:id", adminOnly, h.UpdateLocation)
+		protected.DELETE("/locations/:id", adminOnly, h.DeleteLocation)
+		protected.POST("/locations/:id/rooms", adminOnly, h.CreateRoom)
+		protected.PUT("/rooms/:id", adminOnly, h.UpdateRoom)
+		protected.DELETE("/rooms/:id", adminOnly, h.DeleteRoom)
+		protected.GET("/plans", adminOnly, h.GetAllPlans)
+		protected.POST("/plans", adminOnly, h.CreatePlan)
+		protected.PUT("/plans/:id", adminOnly, h.UpdatePlan)
+		protected.POST("/plans/:id/checkout", userOnly, h.CheckoutPlan)
 	}
 
 	return r
@@ -0,0 +1,31 @@
+package processor
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/folklinoff/fitness-app/internal/storage"
+)
+
+// recordNoShows records the no-shows of ended trainings right away and then
+// every interval until the context is done
+func recordNoShows(ctx context.Context, attendance storage.AttendanceRepository, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		n, err := attendance.RecordNoShows(ctx, time.Now())
+		if err != nil && ctx.Err() == nil {
+			log.Printf("record no-shows: %v", err)
+		} else if n > 0 {
+			log.Printf("%d no-shows recorded", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
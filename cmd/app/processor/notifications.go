@@ -0,0 +1,148 @@
+package processor
+
+import (
+	"context"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"github.com/folklinoff/fitness-app/internal/config"
+	"github.com/folklinoff/fitness-app/internal/domain"
+	"github.com/folklinoff/fitness-app/internal/notify"
+	"github.com/folklinoff/fitness-app/internal/storage"
+	"golang.org/x/xerrors"
+)
+
+const (
+	// notifyBatch is the number of notifications sent per run
+	notifyBatch = 100
+	// notifyAttempts is how often a notification is tried before it fails
+	notifyAttempts = 5
+	// notifyTimeout bounds sending one notification, so a stuck server does not
+	// hold up the batch
+	notifyTimeout = 30 * time.Second
+)
+
+// openNotifier creates the senders of the configured channels and returns a
+// function releasing them
+func openNotifier(cfg config.Notify) (*notify.Notifier, func() error, error) {
+	location, err := time.LoadLocation(cfg.TimeZone)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var logWriter io.Writer = log.Writer()
+	release := func() error { return nil }
+	if cfg.LogFile != "" && (cfg.Email == config.SenderLog || cfg.SMS == config.SenderLog) {
+		file, err := os.OpenFile(cfg.LogFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+		if err != nil {
+			return nil, nil, xerrors.Errorf("open notification log: %w", err)
+		}
+		logWriter, release = file, file.Close
+	}
+	logSender := notify.NewLog(logWriter)
+
+	var email, sms notify.Sender
+	switch cfg.Email {
+	case config.SenderLog:
+		email = logSender
+	case config.SenderSMTP:
+		if email, err = notify.NewSMTP(cfg.SMTPAddr, cfg.SMTPUsername, cfg.SMTPPassword, cfg.MailFrom); err != nil {
+			release()
+			return nil, nil, err
+		}
+	}
+	switch cfg.SMS {
+	case config.SenderLog:
+		sms = logSender
+	case config.SenderTwilio:
+		sms = notify.NewTwilio(cfg.TwilioAccountSID, cfg.TwilioAuthToken, cfg.SMSFrom)
+	}
+	return notify.NewNotifier(email, sms, location), release, nil
+}
+
+// sendNotifications queues the reminders of the trainings starting within lead
+// and sends the pending notifications right away and then every interval until
+// the context is done
+func sendNotifications(ctx context.Context, store storage.Storage, notifier *notify.Notifier, lead, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		now := time.Now()
+		n, err := store.Notifications.EnqueueReminders(ctx, now, now.Add(lead))
+		if err != nil && ctx.Err() == nil {
+			log.Printf("queue reminders: %v", err)
+		} else if n > 0 {
+			log.Printf("%d reminders queued", n)
+		}
+		deliverNotifications(ctx, store, notifier)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// deliverNotifications sends a batch of pending notifications. Notifications of
+// trainings which are over and of deleted users are skipped, failed ones are
+// retried on the next runs.
+func deliverNotifications(ctx context.Context, store storage.Storage, notifier *notify.Notifier) {
+	pending, err := store.Notifications.ListPending(ctx, notifyBatch)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("list pending notifications: %v", err)
+		}
+		return
+	}
+
+	for _, notification := range pending {
+		status, reason := deliver(ctx, store.Users, notifier, notification)
+		if status == domain.NotificationPending {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("send notification %d: %s", notification.ID, reason)
+			if notification.Attempts+1 < notifyAttempts {
+				err = store.Notifications.Retry(ctx, notification.ID, reason)
+			} else {
+				err = store.Notifications.Finish(ctx, notification.ID, domain.NotificationFailed, reason, time.Now())
+			}
+		} else {
+			err = store.Notifications.Finish(ctx, notification.ID, status, reason, time.Now())
+		}
+		if err != nil && ctx.Err() == nil {
+			log.Printf("record notification %d: %v", notification.ID, err)
+		}
+	}
+}
+
+// deliver sends the notification and returns its new status, it stays pending
+// when sending failed
+func deliver(ctx context.Context, users storage.UserRepository, notifier *notify.Notifier, notification domain.Notification) (domain.NotificationStatus, string) {
+	if !notification.EndTime.After(time.Now()) {
+		return domain.NotificationSkipped, "training is over"
+	}
+	user, err := users.GetByID(ctx, notification.UserID)
+	if xerrors.Is(err, storage.ErrNotFound) {
+		return domain.NotificationSkipped, "user deleted"
+	}
+	if err != nil {
+		return domain.NotificationPending, err.Error()
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, notifyTimeout)
+	defer cancel()
+	sent, err := notifier.Deliver(ctx, notification, *user)
+	switch {
+	case err != nil:
+		return domain.NotificationPending, err.Error()
+	case !sent:
+		return domain.NotificationSkipped, ""
+	default:
+		return domain.NotificationSent, ""
+	}
+}
@@ -0,0 +1,140 @@
+package processor
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/folklinoff/fitness-app/internal/domain"
+	"github.com/folklinoff/fitness-app/internal/notify"
+	"github.com/folklinoff/fitness-app/internal/storage"
+	"github.com/folklinoff/fitness-app/internal/storage/memory"
+	"golang.org/x/xerrors"
+)
+
+// finishedNotifications records the notifications which are no longer pending
+type finishedNotifications struct {
+	storage.NotificationRepository
+	status map[int]domain.NotificationStatus
+	reason map[int]string
+}
+
+func (r *finishedNotifications) Finish(ctx context.Context, id int, status domain.NotificationStatus, reason string, at time.Time) error {
+	r.status[id], r.reason[id] = status, reason
+	return r.NotificationRepository.Finish(ctx, id, status, reason, at)
+}
+
+// brokenWriter fails every write, as a log file on a full disk
+type brokenWriter struct{}
+
+func (brokenWriter) Write([]byte) (int, error) {
+	return 0, xerrors.New("no space left on device")
+}
+
+// newOutbox returns a store with a user and the notification about a training
+// queued on every channel, and the recorder of finished notifications
+func newOutbox(t *testing.T, start time.Time) (storage.Storage, *finishedNotifications) {
+	t.Helper()
+	ctx := context.Background()
+	store := memory.New(storage.Options{})
+	user := domain.User{Name: "alice", Password: "hash", Mail: "alice@example.com", Phone: "+100"}
+	if err := store.Users.Create(ctx, &user); err != nil {
+		t.Fatal(err)
+	}
+	training := domain.Training{ID: 1, Name: "Yoga", StartTime: start, EndTime: start.Add(time.Hour)}
+	if err := store.Notifications.Enqueue(ctx, []domain.Notification{training.Notification(domain.NotificationRegistered, user.ID, time.Now())}); err != nil {
+		t.Fatal(err)
+	}
+
+	finished := &finishedNotifications{
+		NotificationRepository: store.Notifications,
+		status:                 make(map[int]domain.NotificationStatus),
+		reason:                 make(map[int]string),
+	}
+	store.Notifications = finished
+	return store, finished
+}
+
+func pendingNotifications(t *testing.T, store storage.Storage) []domain.Notification {
+	t.Helper()
+	pending, err := store.Notifications.ListPending(context.Background(), notifyBatch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pending
+}
+
+func TestDeliverNotifications(t *testing.T) {
+	store, finished := newOutbox(t, time.Now().Add(24*time.Hour))
+	var out bytes.Buffer
+	sender := notify.NewLog(&out)
+	deliverNotifications(context.Background(), store, notify.NewNotifier(sender, sender, time.UTC))
+
+	if pending := pendingNotifications(t, store); len(pending) != 0 {
+		t.Errorf("%d notifications still pending", len(pending))
+	}
+	if len(finished.status) != len(domain.NotificationChannels) {
+		t.Fatalf("finished %v, want one notification per channel", finished.status)
+	}
+	for id, status := range finished.status {
+		if status != domain.NotificationSent {
+			t.Errorf("notification %d %s (%s), want sent", id, status, finished.reason[id])
+		}
+	}
+	for _, want := range []string{"To: alice@example.com", "Subject: Your seat in Yoga is confirmed", "To: +100"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("log lacks %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestDeliverNotificationsRetriesAndGivesUp(t *testing.T) {
+	store, finished := newOutbox(t, time.Now().Add(24*time.Hour))
+	// Emails fail while text messages are turned off
+	notifier := notify.NewNotifier(notify.NewLog(brokenWriter{}), nil, time.UTC)
+
+	for attempt := 1; attempt < notifyAttempts; attempt++ {
+		deliverNotifications(context.Background(), store, notifier)
+		pending := pendingNotifications(t, store)
+		if len(pending) != 1 {
+			t.Fatalf("attempt %d: %d notifications pending, want the email", attempt, len(pending))
+		}
+		if pending[0].Channel != domain.ChannelEmail || pending[0].Attempts != attempt {
+			t.Errorf("attempt %d: pending %s notification with %d attempts", attempt, pending[0].Channel, pending[0].Attempts)
+		}
+		if !strings.Contains(pending[0].Error, "no space left on device") {
+			t.Errorf("attempt %d: error %q, want the error of the sender", attempt, pending[0].Error)
+		}
+	}
+	email := pendingNotifications(t, store)[0].ID
+
+	deliverNotifications(context.Background(), store, notifier)
+	if pending := pendingNotifications(t, store); len(pending) != 0 {
+		t.Errorf("%d notifications pending after %d attempts", len(pending), notifyAttempts)
+	}
+	if finished.status[email] != domain.NotificationFailed || !strings.Contains(finished.reason[email], "no space left on device") {
+		t.Errorf("email %s (%s), want failed with the error of the sender", finished.status[email], finished.reason[email])
+	}
+	for id, status := range finished.status {
+		if id != email && status != domain.NotificationSkipped {
+			t.Errorf("text message %s, want skipped as the channel is off", status)
+		}
+	}
+}
+
+func TestDeliverNotificationsSkipsEndedTrainings(t *testing.T) {
+	store, finished := newOutbox(t, time.Now().Add(-2*time.Hour))
+	notifier := notify.NewNotifier(notify.NewLog(brokenWriter{}), notify.NewLog(brokenWriter{}), time.UTC)
+	deliverNotifications(context.Background(), store, notifier)
+
+	if len(finished.status) != len(domain.NotificationChannels) {
+		t.Fatalf("finished %v, want one notification per channel", finished.status)
+	}
+	for id, status := range finished.status {
+		if status != domain.NotificationSkipped || finished.reason[id] != "training is over" {
+			t.Errorf("notification %d %s (%s), want skipped as the training is over", id, status, finished.reason[id])
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package processor
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/folklinoff/fitness-app/internal/config"
+	"github.com/folklinoff/fitness-app/internal/payment"
+	"github.com/folklinoff/fitness-app/internal/storage"
+	"golang.org/x/xerrors"
+)
+
+// openGateway creates the configured payment gateway
+func openGateway(cfg config.Payments) (payment.PaymentGateway, error) {
+	switch cfg.Provider {
+	case config.PaymentsStripe:
+		return payment.NewStripe(cfg.SecretKey, cfg.WebhookSecret)
+	case config.PaymentsFake:
+		gateway, err := payment.NewFake(cfg.WebhookSecret)
+		if err != nil {
+			return nil, err
+		}
+		log.Println("WARNING: using the fake payment gateway, no money is moved")
+		return gateway, nil
+	default:
+		return nil, xerrors.Errorf("unknown payment provider %q", cfg.Provider)
+	}
+}
+
+// settlePayments releases the seats held longer than hold for unpaid
+// registrations and issues the refunds due right away and then every interval
+// until the context is done
+func settlePayments(ctx context.Context, payments storage.PaymentRepository, gateway payment.PaymentGateway, hold, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		n, err := payments.ReleaseHolds(ctx, time.Now().Add(-hold))
+		if err != nil && ctx.Err() == nil {
+			log.Printf("release payment holds: %v", err)
+		} else if n > 0 {
+			log.Printf("%d unpaid seats released", n)
+		}
+		refundPayments(ctx, payments, gateway)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// refundPayments refunds the payments due for refund, failed refunds are retried
+// on the next run. Refunds are recorded as started before they are sent, a
+// started refund is only sent again when the gateway has no refund of the
+// payment, so a refund whose result was lost is not issued twice.
+func refundPayments(ctx context.Context, payments storage.PaymentRepository, gateway payment.PaymentGateway) {
+	due, err := payments.ListRefundsDue(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("list refunds due: %v", err)
+		}
+		return
+	}
+	for _, p := range due {
+		if p.RefundStartedAt != nil {
+			refunded, err := gateway.Refunded(ctx, p.Reference)
+			if err != nil {
+				log.Printf("look up refund of payment %d: %v", p.ID, err)
+				continue
+			}
+			if refunded {
+				if err := payments.MarkRefunded(ctx, p.ID); err != nil {
+					log.Printf("mark payment %d refunded: %v", p.ID, err)
+				}
+				continue
+			}
+		} else if err := payments.StartRefund(ctx, p.ID); err != nil {
+			log.Printf("start refund of payment %d: %v", p.ID, err)
+			continue
+		}
+		if err := gateway.Refund(ctx, p.Reference, p.Amount); err != nil {
+			log.Printf("refund payment %d: %v", p.ID, err)
+			continue
+		}
+		if err := payments.MarkRefunded(ctx, p.ID); err != nil {
+			log.Printf("mark payment %d refunded: %v", p.ID, err)
+			continue
+		}
+		log.Printf("payment %d refunded", p.ID)
+	}
+}
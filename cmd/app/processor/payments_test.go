@@ -0,0 +1,57 @@
+//go:build fakepayments
+
+package processor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/folklinoff/fitness-app/internal/domain"
+	"github.com/folklinoff/fitness-app/internal/payment"
+	"github.com/folklinoff/fitness-app/internal/storage"
+	"github.com/folklinoff/fitness-app/internal/storage/memory"
+	"golang.org/x/xerrors"
+)
+
+// lostRefunds fails recording the first refunds, as if the database went away
+// after the gateway refunded the payment
+type lostRefunds struct {
+	storage.PaymentRepository
+	failures int
+}
+
+func (r *lostRefunds) MarkRefunded(ctx context.Context, id int) error {
+	if r.failures > 0 {
+		r.failures--
+		return xerrors.New("connection lost")
+	}
+	return r.PaymentRepository.MarkRefunded(ctx, id)
+}
+
+func TestRefundPaymentsOnce(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New(storage.Options{})
+	gateway, err := payment.NewFake("whsec")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := gateway.Capture(ctx, "pi_1"); err != nil {
+		t.Fatal(err)
+	}
+	due := domain.Payment{UserID: 1, Amount: 1500, Currency: "eur", Status: domain.PaymentRefundDue, Reference: "pi_1", CreatedAt: time.Now()}
+	if err := store.Payments.Create(ctx, &due); err != nil {
+		t.Fatal(err)
+	}
+
+	payments := &lostRefunds{PaymentRepository: store.Payments, failures: 1}
+	refundPayments(ctx, payments, gateway)
+	refundPayments(ctx, payments, gateway)
+
+	if captured, _ := gateway.Payment("pi_1"); captured.Refunded != due.Amount {
+		t.Errorf("refunded %d, want %d", captured.Refunded, due.Amount)
+	}
+	if got, err := store.Payments.GetByID(ctx, due.ID); err != nil || got.Status != domain.PaymentRefunded {
+		t.Errorf("payment after the refund: %+v, %v", got, err)
+	}
+}
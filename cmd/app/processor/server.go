@@ -3,23 +3,119 @@ package processor
 import (
 	"context"
 	"net/http"
+	"sync"
+
+	"github.com/folklinoff/fitness-app/internal/config"
+	middleware "github.com/folklinoff/fitness-app/internal/middleware/auth"
+	"github.com/folklinoff/fitness-app/internal/password"
+	"golang.org/x/xerrors"
+)
+
+var (
+	mu       sync.Mutex
+	running  *http.Server
+	stopping bool
 )
 
-var stop func(ctx context.Context) error
+// Run serves the API until Shutdown is called and returns once the workers are
+// stopped and the storage and the notifier are closed
+func Run(cfg config.Config) error {
+	hasher, err := password.NewHasher(cfg.Password)
+	if err != nil {
+		return err
+	}
 
-func Run() error {
-	handler := api()
+	keys, err := middleware.LoadKeySet(cfg.JWT)
+	if err != nil {
+		return err
+	}
 
-	server := http.Server{
-		Addr:    ":8000",
+	store, closeStorage, err := openStorage(context.Background(), cfg.Storage, cfg.Booking)
+	if err != nil {
+		return err
+	}
+	defer closeStorage()
+
+	if err := ensureAdmin(context.Background(), store.Admins, hasher, cfg.Admin); err != nil {
+		return err
+	}
+
+	gateway, err := openGateway(cfg.Payments)
+	if err != nil {
+		return err
+	}
+	notifier, closeNotifier, err := openNotifier(cfg.Notify)
+	if err != nil {
+		return err
+	}
+	defer closeNotifier()
+
+	// Stopped before the storage and the notifier are closed
+	ctx, cancel := context.WithCancel(context.Background())
+	var workers sync.WaitGroup
+	workers.Add(3)
+	go func() {
+		defer workers.Done()
+		recordNoShows(ctx, store.Attendance, cfg.Booking.NoShowInterval)
+	}()
+	go func() {
+		defer workers.Done()
+		settlePayments(ctx, store.Payments, gateway, cfg.Payments.Hold, cfg.Payments.Interval)
+	}()
+	go func() {
+		defer workers.Done()
+		sendNotifications(ctx, store, notifier, cfg.Notify.ReminderLead, cfg.Notify.Interval)
+	}()
+	defer func() {
+		cancel()
+		workers.Wait()
+	}()
+
+	tokens := middleware.NewTokenManager(keys, store.Sessions, middleware.TokenConfig{
+		TTL:        cfg.JWT.TTL,
+		RefreshTTL: cfg.JWT.RefreshTTL,
+		Issuer:     cfg.JWT.Issuer,
+		Audience:   cfg.JWT.Audience,
+	})
+
+	var dev *middleware.DevAuthenticator
+	if cfg.DevAuth {
+		if dev, err = middleware.NewDevAuthenticator(store); err != nil {
+			return err
+		}
+	}
+
+	handler := api(store, hasher, tokens, dev, cfg.Booking, gateway, cfg.Payments, cfg.PublicURL)
+
+	server := &http.Server{
+		Addr:    cfg.Addr,
 		Handler: handler,
 	}
 
-	stop = server.Shutdown
+	mu.Lock()
+	if stopping {
+		mu.Unlock()
+		return nil
+	}
+	running = server
+	mu.Unlock()
 
-	return server.ListenAndServe()
+	if err := server.ListenAndServe(); !xerrors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
 }
 
-func Shutdown(ctx context.Context) {
-	stop(ctx)
+// Shutdown stops the server gracefully, Run returns after it. When the server is
+// not started yet, Run returns before serving.
+func Shutdown(ctx context.Context) error {
+	mu.Lock()
+	stopping = true
+	server := running
+	mu.Unlock()
+
+	if server == nil {
+		return nil
+	}
+	return server.Shutdown(ctx)
 }
@@ -0,0 +1,33 @@
+package processor
+
+import (
+	"context"
+
+	"github.com/folklinoff/fitness-app/internal/config"
+	"github.com/folklinoff/fitness-app/internal/storage"
+	"github.com/folklinoff/fitness-app/internal/storage/memory"
+	"github.com/folklinoff/fitness-app/internal/storage/postgres"
+	"github.com/folklinoff/fitness-app/internal/storage/sqlite"
+)
+
+// openStorage creates the configured storage backend enforcing the booking rules
+// and returns a function releasing it
+func openStorage(ctx context.Context, cfg config.Storage, booking config.Booking) (storage.Storage, func() error, error) {
+	options := storage.Options{RegistrationBuffer: booking.RegistrationBuffer}
+	switch cfg.Driver {
+	case config.StoragePostgres:
+		db, err := postgres.Open(ctx, cfg.DSN)
+		if err != nil {
+			return storage.Storage{}, nil, err
+		}
+		return postgres.New(db, options), db.Close, nil
+	case config.StorageSQLite:
+		db, err := sqlite.Open(ctx, cfg.DSN)
+		if err != nil {
+			return storage.Storage{}, nil, err
+		}
+		return sqlite.New(db, options), db.Close, nil
+	default:
+		return memory.New(options), func() error { return nil }, nil
+	}
+}
@@ -0,0 +1,47 @@
+package audit
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/folklinoff/fitness-app/internal/domain"
+	"github.com/folklinoff/fitness-app/internal/storage"
+)
+
+// Log records requests denied by authorization checks
+type Log struct {
+	events storage.AuditRepository
+}
+
+// New creates a Log storing events in the repository
+func New(events storage.AuditRepository) *Log {
+	return &Log{events: events}
+}
+
+// Resource formats the audited resource of the given type and ID
+func Resource(resourceType string, id int) string {
+	return resourceType + ":" + strconv.Itoa(id)
+}
+
+// Violation records that the actor was denied the action on the resource. The
+// request is denied either way, so a failure to store the event is only logged.
+func (l *Log) Violation(ctx context.Context, actorType string, actorID int, action, resource, reason string) {
+	event := domain.AuditEvent{
+		At:        time.Now(),
+		ActorType: actorType,
+		ActorID:   actorID,
+		Action:    action,
+		Resource:  resource,
+		Reason:    reason,
+	}
+	target := action
+	if resource != "" {
+		target += " on " + resource
+	}
+	log.Printf("audit: %s %d denied %s: %s", actorType, actorID, target, reason)
+	if err := l.events.Record(ctx, &event); err != nil {
+		log.Printf("audit: record event: %v", err)
+	}
+}
@@ -0,0 +1,449 @@
+package config
+
+import (
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/folklinoff/fitness-app/internal/password"
+	"golang.org/x/xerrors"
+)
+
+const (
+	StorageMemory   = "memory"
+	StoragePostgres = "postgres"
+	StorageSQLite   = "sqlite"
+
+	PaymentsFake   = "fake"
+	PaymentsStripe = "stripe"
+
+	SenderLog    = "log"
+	SenderOff    = "off"
+	SenderSMTP   = "smtp"
+	SenderTwilio = "twilio"
+)
+
+// Config holds the server settings read from the environment
+type Config struct {
+	// Addr is the address the HTTP server listens on
+	Addr string
+	// PublicURL is the base URL clients reach the API at, links handed out by
+	// the API such as calendar feed URLs are built on it
+	PublicURL string
+	Storage   Storage
+	Password  password.Params
+	JWT       JWT
+	Admin     Admin
+	Booking   Booking
+	Payments  Payments
+	Notify    Notify
+	// DevAuth enables the Dev authorization scheme, which needs a binary built with -tags devauth
+	DevAuth bool
+}
+
+// JWT describes the keys access tokens are signed and verified with
+type JWT struct {
+	// Algorithm of the signing key: HS256, RS256, ES256 or EdDSA
+	Algorithm string
+	// KeyID is put into the kid header of issued tokens
+	KeyID string
+	// Secret is the HMAC secret, it takes precedence over KeyFile for HS256
+	Secret string
+	// KeyFile is the PEM private key file, or the secret file for HS256
+	KeyFile string
+	// RetiredKeys are no longer used for signing but still accepted
+	RetiredKeys []JWTKey
+	// TTL is the lifetime of issued access tokens
+	TTL time.Duration
+	// RefreshTTL is the lifetime of issued refresh tokens
+	RefreshTTL time.Duration
+	// Issuer is the iss claim of issued tokens
+	Issuer string
+	// Audience is the aud claim issued tokens are restricted to
+	Audience string
+}
+
+// JWTKey is a verification key, KeyFile is a public or private PEM key or an HMAC secret file
+type JWTKey struct {
+	KeyID     string
+	Algorithm string
+	KeyFile   string
+}
+
+// Admin is the admin account ensured at startup, it is skipped when Name is empty
+type Admin struct {
+	Name     string
+	Password string
+}
+
+// Booking holds the rules of registering for and attending trainings
+type Booking struct {
+	// CancellationCutoff is how long before the start of a training a seat can
+	// still be cancelled without it counting as a late cancellation
+	CancellationCutoff time.Duration
+	// SeriesHorizon is how far after its first occurrence a training series must
+	// end, all its occurrences are created with it
+	SeriesHorizon time.Duration
+	// RegistrationBuffer is the time a user needs between two trainings,
+	// registrations for trainings closer to or overlapping one the user is
+	// registered or waitlisted for are rejected
+	RegistrationBuffer time.Duration
+	// LateCancellationRefund refunds the credits of seats cancelled within the
+	// cancellation cutoff, which are otherwise forfeited
+	LateCancellationRefund bool
+	// CheckInOpens is how long before the start of a training users can check in
+	// to it, check-in closes at its end
+	CheckInOpens time.Duration
+	// CheckInCodeTTL is the lifetime of a check-in code
+	CheckInCodeTTL time.Duration
+	// NoShowInterval is how often ended trainings are checked for no-shows
+	NoShowInterval time.Duration
+}
+
+// Payments configures the payment gateway paid trainings and membership plans
+// are sold through
+type Payments struct {
+	// Provider is PaymentsStripe, or PaymentsFake in binaries built with the
+	// fakepayments tag
+	Provider string
+	// Currency of the prices, an ISO 4217 code
+	Currency string
+	// SecretKey is the API key of the provider
+	SecretKey string
+	// WebhookSecret verifies the signatures of the webhook requests of the provider
+	WebhookSecret string
+	// SuccessURL and CancelURL are where users are sent back to after checkout
+	SuccessURL string
+	CancelURL  string
+	// Hold is how long the seat of a registration pending payment is held
+	Hold time.Duration
+	// Interval is how often expired holds are released and due refunds issued
+	Interval time.Duration
+}
+
+// Notify configures the email and SMS notifications sent to users
+type Notify struct {
+	// Email is SenderLog, SenderSMTP or SenderOff
+	Email string
+	// SMTPAddr is the host:port of the SMTP server, SMTPUsername enables PLAIN authentication
+	SMTPAddr     string
+	SMTPUsername string
+	SMTPPassword string
+	MailFrom     string
+	// SMS is SenderLog, SenderTwilio or SenderOff
+	SMS              string
+	TwilioAccountSID string
+	TwilioAuthToken  string
+	SMSFrom          string
+	// LogFile is where the log sender appends messages, the standard log by default
+	LogFile string
+	// TimeZone is the IANA time zone times are shown in
+	TimeZone string
+	// ReminderLead is how long before the start of a training its users are reminded
+	ReminderLead time.Duration
+	// Interval is how often reminders are queued and pending notifications sent
+	Interval time.Duration
+}
+
+// Storage selects the backend the repositories are served from
+type Storage struct {
+	// Driver is one of StorageMemory, StoragePostgres or StorageSQLite
+	Driver string
+	// DSN is the connection string of the database backend, or the database
+	// file path for SQLite
+	DSN string
+}
+
+// Load reads the configuration from the environment:
+//
+//	ADDR                    listen address, ":8000" by default
+//	PUBLIC_URL              base URL clients reach the API at, "http://localhost:8000" by default
+//	STORAGE_DRIVER          memory (default), postgres or sqlite
+//	DATABASE_URL            connection string for the postgres driver, file path
+//	                        for the sqlite driver (fitness.db by default)
+//	PASSWORD_HASH           argon2id (default) or bcrypt
+//	ARGON2_MEMORY           argon2id memory cost in KiB
+//	ARGON2_TIME             argon2id number of passes
+//	ARGON2_THREADS          argon2id parallelism
+//	BCRYPT_COST             bcrypt cost factor
+//	PLAINTEXT_PASSWORDS     "true" accepts passwords stored in plaintext before hashing
+//	                        and rehashes them on login, only meant for migrating
+//	JWT_ALG                 signing algorithm: HS256 (default), RS256, ES256 or EdDSA
+//	JWT_KID                 key id of the signing key, "default" by default
+//	JWT_SECRET              HMAC secret for HS256
+//	JWT_KEY_FILE            PEM private key, or the HMAC secret file for HS256
+//	JWT_RETIRED_KEYS        comma separated kid:alg:file keys still accepted during rotation
+//	JWT_TTL                 access token lifetime, 1h by default
+//	REFRESH_TOKEN_TTL       refresh token lifetime, 720h by default
+//	JWT_ISSUER              iss claim of issued tokens, "fitness-app" by default
+//	JWT_AUDIENCE            aud claim of issued tokens, "fitness-app" by default
+//	ADMIN_NAME              name of the admin account created at startup if missing
+//	ADMIN_PASSWORD          password of that admin account, required with ADMIN_NAME
+//	CANCELLATION_CUTOFF     cancellations later than this before the start of a training
+//	                        are recorded as late, 2h by default
+//	SERIES_HORIZON          training series must end by COUNT or UNTIL within this long
+//	                        after their first occurrence, 8760h (a year) by default
+//	REGISTRATION_BUFFER     minimum time between two trainings a user is registered for,
+//	                        0 by default, which only rejects overlapping trainings
+//	LATE_CANCEL_REFUND      "true" refunds the credits of seats cancelled late
+//	CHECK_IN_OPENS          how long before the start of a training check-in opens, 15m by default
+//	CHECK_IN_CODE_TTL       lifetime of a check-in code, 10m by default
+//	NO_SHOW_INTERVAL        how often no-shows of ended trainings are recorded, 1m by default
+//	PAYMENT_PROVIDER        stripe, or fake which moves no money and needs a binary built
+//	                        with -tags fakepayments, required
+//	PAYMENT_CURRENCY        currency of the prices, "eur" by default
+//	STRIPE_SECRET_KEY       API key of the stripe provider
+//	PAYMENT_WEBHOOK_SECRET  signing secret of the provider webhooks, required
+//	PAYMENT_SUCCESS_URL     where users return to after paying
+//	PAYMENT_CANCEL_URL      where users return to after abandoning a checkout
+//	PAYMENT_HOLD            how long a seat is held for a registration pending payment, 30m by default
+//	PAYMENT_INTERVAL        how often expired holds are released and refunds issued, 1m by default
+//	EMAIL_SENDER            log (default), smtp or off
+//	SMTP_ADDR               host:port of the SMTP server, required for smtp
+//	SMTP_USERNAME           SMTP user, authentication is skipped when empty
+//	SMTP_PASSWORD           password of the SMTP user
+//	MAIL_FROM               sender address of emails, required for smtp
+//	SMS_SENDER              log (default), twilio or off
+//	TWILIO_ACCOUNT_SID      account of the twilio sender
+//	TWILIO_AUTH_TOKEN       auth token of that account
+//	SMS_FROM                sender phone number of text messages, required for twilio
+//	NOTIFY_LOG_FILE         file the log senders append to, the server log by default
+//	NOTIFY_TIME_ZONE        IANA time zone of the times in notifications, UTC by default
+//	REMINDER_LEAD           how long before the start of a training reminders are sent, 24h by default
+//	NOTIFY_INTERVAL         how often reminders are queued and notifications sent, 1m by default
+//	DEV_AUTH                "true" enables impersonation with "Authorization: Dev <type>:<id>"
+func Load() (Config, error) {
+	cfg := Config{
+		Addr:      getenv("ADDR", ":8000"),
+		PublicURL: strings.TrimSuffix(getenv("PUBLIC_URL", "http://localhost:8000"), "/"),
+		Storage: Storage{
+			Driver: getenv("STORAGE_DRIVER", StorageMemory),
+			DSN:    os.Getenv("DATABASE_URL"),
+		},
+		Password: password.DefaultParams,
+	}
+
+	if public, err := url.Parse(cfg.PublicURL); err != nil || (public.Scheme != "http" && public.Scheme != "https") || public.Host == "" {
+		return Config{}, xerrors.Errorf("PUBLIC_URL: %q is not an http or https URL", cfg.PublicURL)
+	}
+
+	cfg.Password.Algorithm = getenv("PASSWORD_HASH", cfg.Password.Algorithm)
+	memory, err := getenvInt("ARGON2_MEMORY", int(cfg.Password.Argon2Memory))
+	if err != nil {
+		return Config{}, err
+	}
+	passes, err := getenvInt("ARGON2_TIME", int(cfg.Password.Argon2Time))
+	if err != nil {
+		return Config{}, err
+	}
+	threads, err := getenvInt("ARGON2_THREADS", int(cfg.Password.Argon2Threads))
+	if err != nil {
+		return Config{}, err
+	}
+	if memory <= 0 || passes <= 0 || threads <= 0 || threads > 255 {
+		return Config{}, xerrors.New("argon2 costs are out of range")
+	}
+	cfg.Password.Argon2Memory = uint32(memory)
+	cfg.Password.Argon2Time = uint32(passes)
+	cfg.Password.Argon2Threads = uint8(threads)
+	if cfg.Password.BcryptCost, err = getenvInt("BCRYPT_COST", cfg.Password.BcryptCost); err != nil {
+		return Config{}, err
+	}
+	if cfg.Password.LegacyPlaintext, err = getenvBool("PLAINTEXT_PASSWORDS", false); err != nil {
+		return Config{}, err
+	}
+
+	cfg.JWT = JWT{
+		Algorithm: getenv("JWT_ALG", "HS256"),
+		KeyID:     getenv("JWT_KID", "default"),
+		Secret:    os.Getenv("JWT_SECRET"),
+		KeyFile:   os.Getenv("JWT_KEY_FILE"),
+		Issuer:    getenv("JWT_ISSUER", "fitness-app"),
+		Audience:  getenv("JWT_AUDIENCE", "fitness-app"),
+	}
+	if cfg.JWT.TTL, err = getenvDuration("JWT_TTL", time.Hour); err != nil {
+		return Config{}, err
+	}
+	if cfg.JWT.RefreshTTL, err = getenvDuration("REFRESH_TOKEN_TTL", 30*24*time.Hour); err != nil {
+		return Config{}, err
+	}
+	cfg.Admin = Admin{Name: os.Getenv("ADMIN_NAME"), Password: os.Getenv("ADMIN_PASSWORD")}
+	if cfg.Admin.Name != "" && cfg.Admin.Password == "" {
+		return Config{}, xerrors.New("ADMIN_PASSWORD is required with ADMIN_NAME")
+	}
+	if cfg.Booking.CancellationCutoff, err = getenvDuration("CANCELLATION_CUTOFF", 2*time.Hour); err != nil {
+		return Config{}, err
+	}
+	if cfg.Booking.SeriesHorizon, err = getenvDuration("SERIES_HORIZON", 365*24*time.Hour); err != nil {
+		return Config{}, err
+	}
+	if cfg.Booking.RegistrationBuffer, err = getenvDuration("REGISTRATION_BUFFER", 0); err != nil {
+		return Config{}, err
+	}
+	if cfg.Booking.LateCancellationRefund, err = getenvBool("LATE_CANCEL_REFUND", false); err != nil {
+		return Config{}, err
+	}
+	if cfg.Booking.CheckInOpens, err = getenvDuration("CHECK_IN_OPENS", 15*time.Minute); err != nil {
+		return Config{}, err
+	}
+	if cfg.Booking.CheckInCodeTTL, err = getenvDuration("CHECK_IN_CODE_TTL", 10*time.Minute); err != nil {
+		return Config{}, err
+	}
+	if cfg.Booking.NoShowInterval, err = getenvDuration("NO_SHOW_INTERVAL", time.Minute); err != nil {
+		return Config{}, err
+	}
+	if cfg.Booking.CheckInCodeTTL <= 0 || cfg.Booking.NoShowInterval <= 0 {
+		return Config{}, xerrors.New("CHECK_IN_CODE_TTL and NO_SHOW_INTERVAL must be positive")
+	}
+	cfg.Payments = Payments{
+		Provider:      os.Getenv("PAYMENT_PROVIDER"),
+		Currency:      strings.ToLower(getenv("PAYMENT_CURRENCY", "eur")),
+		SecretKey:     os.Getenv("STRIPE_SECRET_KEY"),
+		WebhookSecret: os.Getenv("PAYMENT_WEBHOOK_SECRET"),
+		SuccessURL:    getenv("PAYMENT_SUCCESS_URL", "http://localhost:8000/payments/success"),
+		CancelURL:     getenv("PAYMENT_CANCEL_URL", "http://localhost:8000/payments/cancel"),
+	}
+	if cfg.Payments.Hold, err = getenvDuration("PAYMENT_HOLD", 30*time.Minute); err != nil {
+		return Config{}, err
+	}
+	if cfg.Payments.Interval, err = getenvDuration("PAYMENT_INTERVAL", time.Minute); err != nil {
+		return Config{}, err
+	}
+	if cfg.Payments.Hold <= 0 || cfg.Payments.Interval <= 0 {
+		return Config{}, xerrors.New("PAYMENT_HOLD and PAYMENT_INTERVAL must be positive")
+	}
+	switch cfg.Payments.Provider {
+	case "":
+		return Config{}, xerrors.New("PAYMENT_PROVIDER is required, stripe or fake")
+	case PaymentsFake:
+	case PaymentsStripe:
+		if cfg.Payments.SecretKey == "" {
+			return Config{}, xerrors.New("STRIPE_SECRET_KEY is required for the stripe provider")
+		}
+	default:
+		return Config{}, xerrors.Errorf("unknown PAYMENT_PROVIDER %q", cfg.Payments.Provider)
+	}
+	// Webhooks are public, an empty secret would let anyone sign events
+	if cfg.Payments.WebhookSecret == "" {
+		return Config{}, xerrors.New("PAYMENT_WEBHOOK_SECRET is required")
+	}
+
+	cfg.Notify = Notify{
+		Email:            getenv("EMAIL_SENDER", SenderLog),
+		SMTPAddr:         os.Getenv("SMTP_ADDR"),
+		SMTPUsername:     os.Getenv("SMTP_USERNAME"),
+		SMTPPassword:     os.Getenv("SMTP_PASSWORD"),
+		MailFrom:         os.Getenv("MAIL_FROM"),
+		SMS:              getenv("SMS_SENDER", SenderLog),
+		TwilioAccountSID: os.Getenv("TWILIO_ACCOUNT_SID"),
+		TwilioAuthToken:  os.Getenv("TWILIO_AUTH_TOKEN"),
+		SMSFrom:          os.Getenv("SMS_FROM"),
+		LogFile:          os.Getenv("NOTIFY_LOG_FILE"),
+		TimeZone:         getenv("NOTIFY_TIME_ZONE", "UTC"),
+	}
+	if cfg.Notify.ReminderLead, err = getenvDuration("REMINDER_LEAD", 24*time.Hour); err != nil {
+		return Config{}, err
+	}
+	if cfg.Notify.Interval, err = getenvDuration("NOTIFY_INTERVAL", time.Minute); err != nil {
+		return Config{}, err
+	}
+	if cfg.Notify.ReminderLead <= 0 || cfg.Notify.Interval <= 0 {
+		return Config{}, xerrors.New("REMINDER_LEAD and NOTIFY_INTERVAL must be positive")
+	}
+	if _, err := time.LoadLocation(cfg.Notify.TimeZone); err != nil {
+		return Config{}, xerrors.Errorf("NOTIFY_TIME_ZONE: %w", err)
+	}
+	switch cfg.Notify.Email {
+	case SenderLog, SenderOff:
+	case SenderSMTP:
+		if cfg.Notify.SMTPAddr == "" || cfg.Notify.MailFrom == "" {
+			return Config{}, xerrors.New("SMTP_ADDR and MAIL_FROM are required for the smtp sender")
+		}
+	default:
+		return Config{}, xerrors.Errorf("unknown EMAIL_SENDER %q", cfg.Notify.Email)
+	}
+	switch cfg.Notify.SMS {
+	case SenderLog, SenderOff:
+	case SenderTwilio:
+		if cfg.Notify.TwilioAccountSID == "" || cfg.Notify.TwilioAuthToken == "" || cfg.Notify.SMSFrom == "" {
+			return Config{}, xerrors.New("TWILIO_ACCOUNT_SID, TWILIO_AUTH_TOKEN and SMS_FROM are required for the twilio sender")
+		}
+	default:
+		return Config{}, xerrors.Errorf("unknown SMS_SENDER %q", cfg.Notify.SMS)
+	}
+
+	if cfg.DevAuth, err = getenvBool("DEV_AUTH", false); err != nil {
+		return Config{}, err
+	}
+
+	if retired := os.Getenv("JWT_RETIRED_KEYS"); retired != "" {
+		for _, spec := range strings.Split(retired, ",") {
+			parts := strings.SplitN(strings.TrimSpace(spec), ":", 3)
+			if len(parts) != 3 {
+				return Config{}, xerrors.Errorf("JWT_RETIRED_KEYS: %q is not kid:alg:file", spec)
+			}
+			cfg.JWT.RetiredKeys = append(cfg.JWT.RetiredKeys, JWTKey{KeyID: parts[0], Algorithm: parts[1], KeyFile: parts[2]})
+		}
+	}
+
+	switch cfg.Storage.Driver {
+	case StorageMemory:
+	case StoragePostgres:
+		if cfg.Storage.DSN == "" {
+			return Config{}, xerrors.Errorf("DATABASE_URL is required for the %s storage", cfg.Storage.Driver)
+		}
+	case StorageSQLite:
+		if cfg.Storage.DSN == "" {
+			cfg.Storage.DSN = "fitness.db"
+		}
+	default:
+		return Config{}, xerrors.Errorf("unknown STORAGE_DRIVER %q", cfg.Storage.Driver)
+	}
+
+	return cfg, nil
+}
+
+func getenv(key, fallback string) string {
+	if value, ok := os.LookupEnv(key); ok && value != "" {
+		return value
+	}
+	return fallback
+}
+
+func getenvDuration(key string, fallback time.Duration) (time.Duration, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, xerrors.Errorf("%s: %w", key, err)
+	}
+	return d, nil
+}
+
+func getenvBool(key string, fallback bool) (bool, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, xerrors.Errorf("%s: %w", key, err)
+	}
+	return b, nil
+}
+
+func getenvInt(key string, fallback int) (int, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, xerrors.Errorf("%s: %w", key, err)
+	}
+	return n, nil
+}
@@ -0,0 +1,28 @@
+package domain
+
+import "time"
+
+// AttendanceMethod tells how the attendance of a user was taken
+type AttendanceMethod string
+
+const (
+	// AttendanceTrainer is attendance marked by the trainer or an admin
+	AttendanceTrainer AttendanceMethod = "trainer"
+	// AttendanceSelf is a check-in of the user with the code of the training
+	AttendanceSelf AttendanceMethod = "self"
+)
+
+// Attendance records that a user with a seat attended a training
+type Attendance struct {
+	TrainingID  int
+	UserID      int
+	Method      AttendanceMethod
+	CheckedInAt time.Time
+}
+
+// CheckInCode is the code users check in to a training with until it expires
+type CheckInCode struct {
+	TrainingID int
+	Code       string
+	ExpiresAt  time.Time
+}
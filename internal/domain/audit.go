@@ -0,0 +1,16 @@
+package domain
+
+import "time"
+
+// AuditEvent records a request that was denied by an authorization check
+type AuditEvent struct {
+	ID        int
+	At        time.Time
+	ActorType string
+	ActorID   int
+	// Action is the attempted action, such as "account.update" or a route
+	Action string
+	// Resource identifies the target as "<type>:<id>", it is empty for route checks
+	Resource string
+	Reason   string
+}
@@ -0,0 +1,12 @@
+package domain
+
+import "time"
+
+// CalendarFeed gives calendar apps access to the schedule of an account through
+// a secret URL. Only the hash of the token in the URL is stored.
+type CalendarFeed struct {
+	TokenHash   string
+	AccountType string
+	AccountID   int
+	CreatedAt   time.Time
+}
@@ -0,0 +1,23 @@
+package domain
+
+import "time"
+
+// IncidentKind is the kind of a broken registration commitment
+type IncidentKind string
+
+const (
+	// IncidentLateCancellation is a seat cancelled within the cancellation cutoff
+	IncidentLateCancellation IncidentKind = "late_cancellation"
+	// IncidentNoShow is a seat kept but not attended
+	IncidentNoShow IncidentKind = "no_show"
+)
+
+// Incident records a late cancellation or no-show of a user. It outlives the
+// training it refers to.
+type Incident struct {
+	ID         int
+	UserID     int
+	TrainingID int
+	Kind       IncidentKind
+	At         time.Time
+}
@@ -0,0 +1,31 @@
+package domain
+
+import "time"
+
+// Location is a gym or studio trainings take place at
+type Location struct {
+	ID      int
+	Name    string
+	Address string
+	// TimeZone is the IANA time zone the opening hours are given in
+	TimeZone string
+	// OpeningHours lists when the location is open, a location without opening
+	// hours is always open
+	OpeningHours []OpeningHours
+}
+
+// OpeningHours is a time span a location is open on a weekday, Opens and Closes
+// are minutes after midnight
+type OpeningHours struct {
+	Weekday time.Weekday
+	Opens   int
+	Closes  int
+}
+
+// Room is a room of a location, trainings in it cannot have more seats than its capacity
+type Room struct {
+	ID         int
+	LocationID int
+	Name       string
+	Capacity   int
+}
@@ -0,0 +1,74 @@
+package domain
+
+import "time"
+
+// PlanKind is the kind of a membership plan
+type PlanKind string
+
+const (
+	// PlanUnlimited covers every training while the membership is valid
+	PlanUnlimited PlanKind = "unlimited"
+	// PlanPass is a punch card of a number of sessions
+	PlanPass PlanKind = "pass"
+	// PlanDropIn is a single session credit
+	PlanDropIn PlanKind = "drop_in"
+)
+
+// Plan is a membership product. Sessions is the number of credits a membership
+// of the plan starts with, memberships are valid for ValidDays days. Inactive
+// plans are no longer sold, plans without a Price are only granted by admins.
+type Plan struct {
+	ID        int
+	Name      string
+	Kind      PlanKind
+	Sessions  int
+	ValidDays int
+	Price     int
+	Active    bool
+}
+
+// Membership is a plan granted to a user. It covers the trainings starting in
+// [StartsAt, ExpiresAt), Credits are the sessions left and are not used by
+// unlimited memberships.
+type Membership struct {
+	ID        int
+	UserID    int
+	PlanID    int
+	Kind      PlanKind
+	Credits   int
+	StartsAt  time.Time
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
+
+// Covers reports whether the membership can pay a registration of the given
+// cost for a training starting at the given time
+func (m Membership) Covers(start time.Time, cost int) bool {
+	if start.Before(m.StartsAt) || !start.Before(m.ExpiresAt) {
+		return false
+	}
+	return m.Kind == PlanUnlimited || m.Credits >= cost
+}
+
+// LedgerEntryKind is the kind of a change of the credits of a membership
+type LedgerEntryKind string
+
+const (
+	LedgerGrant   LedgerEntryKind = "grant"
+	LedgerConsume LedgerEntryKind = "consume"
+	LedgerRefund  LedgerEntryKind = "refund"
+)
+
+// LedgerEntry records a change of the credits of a membership. Amount is the
+// change, Balance the credits left after it. TrainingID is set for consumed and
+// refunded credits and outlives the training.
+type LedgerEntry struct {
+	ID           int
+	UserID       int
+	MembershipID int
+	TrainingID   *int
+	Kind         LedgerEntryKind
+	Amount       int
+	Balance      int
+	At           time.Time
+}
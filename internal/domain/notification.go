@@ -0,0 +1,76 @@
+package domain
+
+import "time"
+
+// NotificationKind is the event a user is notified of
+type NotificationKind string
+
+const (
+	NotificationRegistered NotificationKind = "registration_confirmed"
+	NotificationUpdated    NotificationKind = "training_updated"
+	NotificationCancelled  NotificationKind = "training_cancelled"
+	// NotificationPromoted is sent to users moved from the waitlist into a seat
+	NotificationPromoted NotificationKind = "waitlist_promoted"
+	NotificationReminder NotificationKind = "training_reminder"
+)
+
+// NotificationChannel is how a notification reaches the user
+type NotificationChannel string
+
+const (
+	ChannelEmail NotificationChannel = "email"
+	ChannelSMS   NotificationChannel = "sms"
+)
+
+// NotificationChannels are the channels every notification is queued on
+var NotificationChannels = []NotificationChannel{ChannelEmail, ChannelSMS}
+
+// NotificationStatus is the delivery state of a notification
+type NotificationStatus string
+
+const (
+	NotificationPending NotificationStatus = "pending"
+	NotificationSent    NotificationStatus = "sent"
+	// NotificationSkipped is a notification on a channel which is turned off or
+	// the user has no address for
+	NotificationSkipped NotificationStatus = "skipped"
+	// NotificationFailed is a notification which could not be sent after retries
+	NotificationFailed NotificationStatus = "failed"
+)
+
+// Notification is a message queued for a user about a training. The training
+// fields are a copy taken when it was queued, as cancelled trainings are gone by
+// the time it is sent. Error is the reason of the last failed attempt.
+type Notification struct {
+	ID           int
+	UserID       int
+	Channel      NotificationChannel
+	Kind         NotificationKind
+	TrainingID   int
+	TrainingName string
+	StartTime    time.Time
+	EndTime      time.Time
+	// Sequence is the one of the training, reminders are sent once per sequence
+	Sequence  int
+	Status    NotificationStatus
+	Attempts  int
+	Error     string
+	CreatedAt time.Time
+	SentAt    *time.Time
+}
+
+// Notification returns the pending notification of the event about the training
+// for the user, its channel is set when it is queued
+func (t Training) Notification(kind NotificationKind, userID int, at time.Time) Notification {
+	return Notification{
+		UserID:       userID,
+		Kind:         kind,
+		TrainingID:   t.ID,
+		TrainingName: t.Name,
+		StartTime:    t.StartTime,
+		EndTime:      t.EndTime,
+		Sequence:     t.Sequence,
+		Status:       NotificationPending,
+		CreatedAt:    at,
+	}
+}
@@ -0,0 +1,38 @@
+package domain
+
+import "time"
+
+// PaymentStatus is the state of a payment made through the payment gateway
+type PaymentStatus string
+
+const (
+	// PaymentPending is a checkout the user has not paid yet
+	PaymentPending PaymentStatus = "pending"
+	PaymentPaid    PaymentStatus = "paid"
+	// PaymentFailed is a checkout which expired or could not be captured
+	PaymentFailed PaymentStatus = "failed"
+	// PaymentRefundDue is a captured payment waiting to be refunded
+	PaymentRefundDue PaymentStatus = "refund_due"
+	PaymentRefunded  PaymentStatus = "refunded"
+)
+
+// Payment is the purchase of a seat in a paid training or of a membership plan,
+// exactly one of TrainingID and PlanID is set. Amount is in the minor unit of
+// the currency. SessionID is the checkout session at the gateway, Reference the
+// captured payment refunds are made from. RefundStartedAt is set once its refund
+// was sent to the gateway.
+type Payment struct {
+	ID         int
+	UserID     int
+	TrainingID *int
+	PlanID     *int
+	Amount     int
+	Currency   string
+	Status     PaymentStatus
+	SessionID  string
+	Reference  string
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+
+	RefundStartedAt *time.Time
+}
@@ -0,0 +1,30 @@
+package domain
+
+import "time"
+
+// RegistrationStatus tells whether a registered user has a seat in the training
+type RegistrationStatus string
+
+const (
+	RegistrationConfirmed  RegistrationStatus = "confirmed"
+	RegistrationWaitlisted RegistrationStatus = "waitlisted"
+	// RegistrationPending holds a seat in a paid training until the payment succeeds
+	RegistrationPending RegistrationStatus = "pending_payment"
+)
+
+// Registration is the registration of a user for a training
+type Registration struct {
+	TrainingID int
+	UserID     int
+	Status     RegistrationStatus
+	// Position is the 1-based place on the waitlist, 0 for confirmed registrations
+	Position int
+	// MembershipID is the membership which paid the Credits the registration
+	// cost, it is nil for free trainings
+	MembershipID *int
+	Credits      int
+	// PaymentID is the payment of a paid training, HeldAt the time the seat of
+	// a pending registration has been held since
+	PaymentID *int
+	HeldAt    time.Time
+}
@@ -0,0 +1,26 @@
+package domain
+
+import "time"
+
+// TrainingSeries is a recurring training. Its occurrences are stored as
+// trainings referencing the series, StartTime and EndTime are those of the first
+// occurrence.
+type TrainingSeries struct {
+	ID        int
+	Name      string
+	Type      string
+	Level     string
+	TrainerID int
+	Capacity  int
+	Credits   int
+	Price     int
+	RoomID    *int
+	StartTime time.Time
+	EndTime   time.Time
+	// TimeZone is the IANA time zone the rule is expanded in
+	TimeZone string
+	// RRule is the RFC 5545 recurrence rule without the RRULE: prefix
+	RRule string
+	// Exceptions are the start times of the occurrences excluded from the rule
+	Exceptions []time.Time
+}
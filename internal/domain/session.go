@@ -0,0 +1,19 @@
+package domain
+
+import "time"
+
+// RefreshToken is a single-use token exchanged for a new access token. Only the
+// hash of the token value is stored. Tokens rotated from the same login share a
+// family, which is revoked as a whole when a used token is presented again.
+type RefreshToken struct {
+	Hash        string
+	FamilyID    string
+	AccountType string
+	AccountID   int
+	IssuedAt    time.Time
+	ExpiresAt   time.Time
+	// UsedAt is zero while the token has not been exchanged
+	UsedAt time.Time
+	// RevokedAt is zero while the token has not been revoked
+	RevokedAt time.Time
+}
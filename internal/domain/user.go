@@ -5,29 +5,47 @@ import "time"
 type User struct {
 	ID                int    `json:"id"`
 	Name              string `json:"name"`
-	Password          string `json:"password"`
+	Password          string `json:"-"`
 	Mail              string `json:"mail"`
 	Phone             string `json:"phone"`
 	HealthDescription string `json:"health_description"`
-	Trainings         []int  `json:"trainings"`
 }
 
 type Trainer struct {
-	ID        int    `json:"id"`
-	Name      string `json:"name"`
-	Password  string `json:"password"`
-	Mail      string `json:"mail"`
-	Phone     string `json:"phone"`
-	Trainings []int  `json:"trainings"`
+	ID       int    `json:"id"`
+	Name     string `json:"name"`
+	Password string `json:"-"`
+	Mail     string `json:"mail"`
+	Phone    string `json:"phone"`
 }
 
+// Admin is an operator account allowed to manage all users, trainers and trainings
+type Admin struct {
+	ID       int    `json:"id"`
+	Name     string `json:"name"`
+	Password string `json:"-"`
+}
+
+// Training is a single training session. Occurrences of a series reference it by
+// SeriesID, RecurrenceID is the start time the series rule gives the occurrence
+// and stays the same when only this occurrence is moved. Credits is the cost of a
+// registration in membership credits, free trainings cost none. Paid trainings
+// have a Price in the minor unit of the currency, paid through the payment
+// gateway by users without a membership covering the credits.
 type Training struct {
-	ID        int       `json:"id"`
-	Name      string    `json:"name"`
-	Type      string    `json:"type"`
-	Level     string    `json:"level"`
-	TrainerID int       `json:"trainer_id"`
-	StartTime time.Time `json:"start_time" swaggertype:"string" example:"2024-06-08T15:04:05Z"`
-	EndTime   time.Time `json:"end_time" swaggertype:"string" example:"2024-06-08T16:04:05Z"`
-	Users     []int     `json:"users"`
+	ID           int        `json:"id"`
+	Name         string     `json:"name"`
+	Type         string     `json:"type"`
+	Level        string     `json:"level"`
+	TrainerID    int        `json:"trainer_id"`
+	Capacity     int        `json:"capacity"`
+	Credits      int        `json:"credits"`
+	Price        int        `json:"price"`
+	StartTime    time.Time  `json:"start_time" swaggertype:"string" example:"2024-06-08T15:04:05Z"`
+	EndTime      time.Time  `json:"end_time" swaggertype:"string" example:"2024-06-08T16:04:05Z"`
+	RoomID       *int       `json:"room_id,omitempty"`
+	SeriesID     *int       `json:"series_id,omitempty"`
+	RecurrenceID *time.Time `json:"recurrence_id,omitempty" swaggertype:"string"`
+	// Sequence is raised by the storage whenever the start or end time changes
+	Sequence int `json:"sequence"`
 }
@@ -0,0 +1,414 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/folklinoff/fitness-app/internal/audit"
+	middleware "github.com/folklinoff/fitness-app/internal/middleware/auth"
+	"github.com/folklinoff/fitness-app/internal/policy"
+	"github.com/folklinoff/fitness-app/internal/storage"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/xerrors"
+)
+
+// GetUser godoc
+// @Summary Get a user profile by ID
+// @Description Get a user profile by ID. Users can only see their own profile, trainers see limited fields of the users registered for their trainings and admins see everyone.
+// @Tags user
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {object} ResponseSuccess{data=UserView}
+// @Failure 400 {object} ResponseError
+// @Failure 403 {object} ResponseError
+// @Failure 404 {object} ResponseError
+// @Security BearerAuth
+// @Router /protected/users/{id} [get]
+func (h *Handler) GetUser(c *gin.Context) {
+	if id, ok := accountID(c, middleware.UserTypeUser); ok {
+		h.showAccount(c, middleware.MustPrincipal(c), middleware.UserTypeUser, id)
+	}
+}
+
+// GetTrainer godoc
+// @Summary Get a trainer profile by ID
+// @Description Get a trainer profile by ID. Trainer profiles are visible to everyone, but only the trainer and admins see the mail and phone.
+// @Tags trainer
+// @Produce json
+// @Param id path int true "Trainer ID"
+// @Success 200 {object} ResponseSuccess{data=TrainerView}
+// @Failure 400 {object} ResponseError
+// @Failure 404 {object} ResponseError
+// @Security BearerAuth
+// @Router /protected/trainers/{id} [get]
+func (h *Handler) GetTrainer(c *gin.Context) {
+	if id, ok := accountID(c, middleware.UserTypeTrainer); ok {
+		h.showAccount(c, middleware.MustPrincipal(c), middleware.UserTypeTrainer, id)
+	}
+}
+
+// UpdateUser godoc
+// @Summary Update a user profile by ID
+// @Description Update a user profile by ID, only the user and admins can update it
+// @Tags user
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param user body UpdateProfileRequest true "Updated profile data"
+// @Success 200 {object} ResponseSuccess{data=UserView}
+// @Failure 400 {object} ResponseError
+// @Failure 403 {object} ResponseError
+// @Failure 404 {object} ResponseError
+// @Failure 409 {object} ResponseError
+// @Security BearerAuth
+// @Router /protected/users/{id} [put]
+func (h *Handler) UpdateUser(c *gin.Context) {
+	id, ok := accountID(c, middleware.UserTypeUser)
+	if !ok {
+		return
+	}
+	var req UpdateProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ResponseError{Error: "Invalid data: " + err.Error()})
+		return
+	}
+	h.updateAccount(c, middleware.MustPrincipal(c), middleware.UserTypeUser, id, req)
+}
+
+// UpdateTrainer godoc
+// @Summary Update a trainer profile by ID
+// @Description Update a trainer profile by ID, only the trainer and admins can update it. health_description is ignored.
+// @Tags trainer
+// @Accept json
+// @Produce json
+// @Param id path int true "Trainer ID"
+// @Param trainer body UpdateProfileRequest true "Updated profile data"
+// @Success 200 {object} ResponseSuccess{data=TrainerView}
+// @Failure 400 {object} ResponseError
+// @Failure 403 {object} ResponseError
+// @Failure 404 {object} ResponseError
+// @Failure 409 {object} ResponseError
+// @Security BearerAuth
+// @Router /protected/trainers/{id} [put]
+func (h *Handler) UpdateTrainer(c *gin.Context) {
+	id, ok := accountID(c, middleware.UserTypeTrainer)
+	if !ok {
+		return
+	}
+	var req UpdateProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ResponseError{Error: "Invalid data: " + err.Error()})
+		return
+	}
+	h.updateAccount(c, middleware.MustPrincipal(c), middleware.UserTypeTrainer, id, req)
+}
+
+// DeleteUser godoc
+// @Summary Delete a user profile by ID
+// @Description Delete a user profile by ID, only the user and admins can delete it
+// @Tags user
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {object} ResponseSuccess
+// @Failure 400 {object} ResponseError
+// @Failure 403 {object} ResponseError
+// @Failure 404 {object} ResponseError
+// @Security BearerAuth
+// @Router /protected/users/{id} [delete]
+func (h *Handler) DeleteUser(c *gin.Context) {
+	if id, ok := accountID(c, middleware.UserTypeUser); ok {
+		h.deleteAccount(c, middleware.MustPrincipal(c), middleware.UserTypeUser, id)
+	}
+}
+
+// DeleteTrainer godoc
+// @Summary Delete a trainer profile by ID
+// @Description Delete a trainer profile by ID together with their trainings, only the trainer and admins can delete it
+// @Tags trainer
+// @Produce json
+// @Param id path int true "Trainer ID"
+// @Success 200 {object} ResponseSuccess
+// @Failure 400 {object} ResponseError
+// @Failure 403 {object} ResponseError
+// @Failure 404 {object} ResponseError
+// @Security BearerAuth
+// @Router /protected/trainers/{id} [delete]
+func (h *Handler) DeleteTrainer(c *gin.Context) {
+	if id, ok := accountID(c, middleware.UserTypeTrainer); ok {
+		h.deleteAccount(c, middleware.MustPrincipal(c), middleware.UserTypeTrainer, id)
+	}
+}
+
+// GetUserProfile godoc
+// @Summary Get a user or trainer profile by ID
+// @Description Deprecated: use /protected/users/{id} or /protected/trainers/{id}. The ID is resolved to the caller's own account first, then to a user and then to a trainer; the Link header names the resource it resolved to.
+// @Tags user
+// @Produce json
+// @Param id path int true "User or Trainer ID"
+// @Success 200 {object} ResponseSuccess{data=UserView}
+// @Failure 400 {object} ResponseError
+// @Failure 403 {object} ResponseError
+// @Failure 404 {object} ResponseError
+// @Security BearerAuth
+// @Deprecated
+// @Router /protected/user/{id} [get]
+func (h *Handler) GetUserProfile(c *gin.Context) {
+	principal := middleware.MustPrincipal(c)
+	accountType, id, ok := h.resolveLegacyAccount(c, principal)
+	if !ok {
+		return
+	}
+	h.showAccount(c, principal, accountType, id)
+}
+
+// UpdateUserProfile godoc
+// @Summary Update a user or trainer profile by ID
+// @Description Deprecated: use /protected/users/{id} or /protected/trainers/{id}. The ID is resolved like in GET /protected/user/{id}.
+// @Tags user
+// @Accept json
+// @Produce json
+// @Param id path int true "User or Trainer ID"
+// @Param user body UpdateProfileRequest true "Updated profile data"
+// @Success 200 {object} ResponseSuccess{data=UserView}
+// @Failure 400 {object} ResponseError
+// @Failure 403 {object} ResponseError
+// @Failure 404 {object} ResponseError
+// @Failure 409 {object} ResponseError
+// @Security BearerAuth
+// @Deprecated
+// @Router /protected/user/{id} [put]
+func (h *Handler) UpdateUserProfile(c *gin.Context) {
+	var req UpdateProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ResponseError{Error: "Invalid data: " + err.Error()})
+		return
+	}
+
+	principal := middleware.MustPrincipal(c)
+	accountType, id, ok := h.resolveLegacyAccount(c, principal)
+	if !ok {
+		return
+	}
+	h.updateAccount(c, principal, accountType, id, req)
+}
+
+// DeleteUserProfile godoc
+// @Summary Delete a user or trainer profile by ID
+// @Description Deprecated: use /protected/users/{id} or /protected/trainers/{id}. The ID is resolved like in GET /protected/user/{id}.
+// @Tags user
+// @Produce json
+// @Param id path int true "User or Trainer ID"
+// @Success 200 {object} ResponseSuccess
+// @Failure 400 {object} ResponseError
+// @Failure 403 {object} ResponseError
+// @Failure 404 {object} ResponseError
+// @Security BearerAuth
+// @Deprecated
+// @Router /protected/user/{id} [delete]
+func (h *Handler) DeleteUserProfile(c *gin.Context) {
+	principal := middleware.MustPrincipal(c)
+	accountType, id, ok := h.resolveLegacyAccount(c, principal)
+	if !ok {
+		return
+	}
+	h.deleteAccount(c, principal, accountType, id)
+}
+
+// accountLabel names the account type in responses
+func accountLabel(accountType string) string {
+	if accountType == middleware.UserTypeTrainer {
+		return "Trainer"
+	}
+	return "User"
+}
+
+// accountID parses the :id parameter and responds with 400 when it is invalid
+func accountID(c *gin.Context, accountType string) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ResponseError{Error: "Invalid " + accountType + " ID: " + err.Error()})
+		return 0, false
+	}
+	return id, true
+}
+
+// resolveLegacyAccount resolves the account of a deprecated /user/:id route and
+// points the client to the typed resource with the Deprecation and Link headers
+func (h *Handler) resolveLegacyAccount(c *gin.Context, principal middleware.Principal) (string, int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ResponseError{Error: "Invalid user or trainer ID: " + err.Error()})
+		return "", 0, false
+	}
+
+	c.Header("Deprecation", "true")
+	accountType, err := h.findAccount(c.Request.Context(), principal, id)
+	if xerrors.Is(err, storage.ErrNotFound) {
+		c.JSON(http.StatusNotFound, ResponseError{Error: "User or trainer not found with ID " + strconv.Itoa(id)})
+		return "", 0, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseError{Error: "Error retrieving profile"})
+		return "", 0, false
+	}
+
+	c.Header("Link", "</protected/"+accountType+"s/"+strconv.Itoa(id)+`>; rel="successor-version"`)
+	return accountType, id, true
+}
+
+// findAccount resolves the account a legacy /user/:id route refers to. Users and
+// trainers are numbered separately, so the caller's own ID means their own
+// account; any other ID is looked up among users first and then trainers.
+func (h *Handler) findAccount(ctx context.Context, principal middleware.Principal, id int) (string, error) {
+	if !principal.IsAdmin() && principal.ID == id {
+		return principal.Type, nil
+	}
+
+	_, err := h.users.GetByID(ctx, id)
+	if err == nil {
+		return middleware.UserTypeUser, nil
+	}
+	if !xerrors.Is(err, storage.ErrNotFound) {
+		return "", err
+	}
+
+	if _, err := h.trainers.GetByID(ctx, id); err != nil {
+		return "", err
+	}
+	return middleware.UserTypeTrainer, nil
+}
+
+func (h *Handler) showAccount(c *gin.Context, principal middleware.Principal, accountType string, id int) {
+	ctx := c.Request.Context()
+	notFound := accountLabel(accountType) + " not found with ID " + strconv.Itoa(id)
+
+	access, err := h.policy.ViewAccount(ctx, principal, accountType, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseError{Error: "Error retrieving profile"})
+		return
+	}
+	if access == policy.Denied {
+		h.deny(c, principal, policy.ActionViewAccount, audit.Resource(accountType, id), "Not allowed to view this profile")
+		return
+	}
+
+	if accountType == middleware.UserTypeUser {
+		user, err := h.users.GetByID(ctx, id)
+		if err != nil {
+			c.JSON(http.StatusNotFound, ResponseError{Error: notFound})
+			return
+		}
+		if access == policy.Limited {
+			c.JSON(http.StatusOK, ResponseSuccess{Message: "User found", Data: newTraineeView(*user)})
+			return
+		}
+		c.JSON(http.StatusOK, ResponseSuccess{Message: "User found", Data: newUserView(*user)})
+		return
+	}
+
+	trainer, err := h.trainers.GetByID(ctx, id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, ResponseError{Error: notFound})
+		return
+	}
+	if access == policy.Limited {
+		c.JSON(http.StatusOK, ResponseSuccess{Message: "Trainer found", Data: newPublicTrainerView(*trainer)})
+		return
+	}
+	c.JSON(http.StatusOK, ResponseSuccess{Message: "Trainer found", Data: newTrainerView(*trainer)})
+}
+
+func (h *Handler) updateAccount(c *gin.Context, principal middleware.Principal, accountType string, id int, req UpdateProfileRequest) {
+	ctx := c.Request.Context()
+	notFound := accountLabel(accountType) + " not found with ID " + strconv.Itoa(id)
+
+	if !h.policy.CanManageAccount(principal, accountType, id) {
+		h.deny(c, principal, policy.ActionUpdateAccount, audit.Resource(accountType, id), "Not allowed to update this profile")
+		return
+	}
+
+	var hash string
+	if req.Password != "" {
+		var err error
+		if hash, err = h.hasher.Hash(req.Password); err != nil {
+			c.JSON(http.StatusInternalServerError, ResponseError{Error: "Error updating profile"})
+			return
+		}
+	}
+
+	if accountType == middleware.UserTypeUser {
+		user, err := h.users.GetByID(ctx, id)
+		if err != nil {
+			c.JSON(http.StatusNotFound, ResponseError{Error: notFound})
+			return
+		}
+		user.Name = req.Name
+		user.Mail = req.Mail
+		user.Phone = req.Phone
+		user.HealthDescription = req.HealthDescription
+		if hash != "" {
+			user.Password = hash
+		}
+		err = h.users.Update(ctx, user)
+		if xerrors.Is(err, storage.ErrAlreadyExists) {
+			c.JSON(http.StatusConflict, ResponseError{Error: "User with this name already exists"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, ResponseError{Error: "Error updating user profile"})
+			return
+		}
+		c.JSON(http.StatusOK, ResponseSuccess{Message: "User profile updated successfully", Data: newUserView(*user)})
+		return
+	}
+
+	trainer, err := h.trainers.GetByID(ctx, id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, ResponseError{Error: notFound})
+		return
+	}
+	trainer.Name = req.Name
+	trainer.Mail = req.Mail
+	trainer.Phone = req.Phone
+	if hash != "" {
+		trainer.Password = hash
+	}
+	err = h.trainers.Update(ctx, trainer)
+	if xerrors.Is(err, storage.ErrAlreadyExists) {
+		c.JSON(http.StatusConflict, ResponseError{Error: "Trainer with this name already exists"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseError{Error: "Error updating trainer profile"})
+		return
+	}
+	c.JSON(http.StatusOK, ResponseSuccess{Message: "Trainer profile updated successfully", Data: newTrainerView(*trainer)})
+}
+
+func (h *Handler) deleteAccount(c *gin.Context, principal middleware.Principal, accountType string, id int) {
+	ctx := c.Request.Context()
+
+	if !h.policy.CanManageAccount(principal, accountType, id) {
+		h.deny(c, principal, policy.ActionDeleteAccount, audit.Resource(accountType, id), "Not allowed to delete this profile")
+		return
+	}
+
+	var err error
+	if accountType == middleware.UserTypeUser {
+		err = h.users.Delete(ctx, id)
+	} else {
+		err = h.trainers.Delete(ctx, id)
+	}
+	if xerrors.Is(err, storage.ErrNotFound) {
+		c.JSON(http.StatusNotFound, ResponseError{Error: accountLabel(accountType) + " not found with ID " + strconv.Itoa(id)})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseError{Error: "Error deleting profile"})
+		return
+	}
+
+	h.endSessions(c, middleware.Principal{ID: id, Type: accountType})
+	c.JSON(http.StatusOK, ResponseSuccess{Message: accountLabel(accountType) + " profile deleted successfully"})
+}
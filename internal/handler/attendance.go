@@ -0,0 +1,324 @@
+package handler
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/folklinoff/fitness-app/internal/audit"
+	"github.com/folklinoff/fitness-app/internal/domain"
+	middleware "github.com/folklinoff/fitness-app/internal/middleware/auth"
+	"github.com/folklinoff/fitness-app/internal/policy"
+	"github.com/folklinoff/fitness-app/internal/storage"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/xerrors"
+)
+
+const (
+	// checkInCodeDigits is the length of check-in codes, they are short enough to
+	// be typed and live only for minutes
+	checkInCodeDigits = 6
+	// checkInAttempts is how often a user may enter a code for a training, so
+	// codes cannot be guessed. The trainer can still mark their attendance.
+	checkInAttempts = 5
+)
+
+// CreateCheckInCode godoc
+// @Summary Create a check-in code for a training session
+// @Description Create the code users check in to the training with, replacing the previous one (only for the trainer of the training and admins). Codes can be created while check-in is open, from shortly before the start until the end of the training, and expire after a few minutes or at the end.
+// @Tags attendance
+// @Produce json
+// @Param training_id path int true "Training ID"
+// @Success 201 {object} ResponseSuccess{data=CheckInCodeView}
+// @Failure 400 {object} ResponseError
+// @Failure 403 {object} ResponseError
+// @Failure 404 {object} ResponseError
+// @Failure 409 {object} ResponseError
+// @Security BearerAuth
+// @Router /protected/training/{training_id}/check-in-code [post]
+func (h *Handler) CreateCheckInCode(c *gin.Context) {
+	training, ok := h.attendedTraining(c)
+	if !ok {
+		return
+	}
+	now := time.Now()
+	if !h.checkInOpen(training, now) {
+		c.JSON(http.StatusConflict, ResponseError{Error: "Check-in is not open for this training"})
+		return
+	}
+
+	n, err := rand.Int(rand.Reader, big.NewInt(1_000_000))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseError{Error: "Error generating check-in code"})
+		return
+	}
+	code := domain.CheckInCode{
+		TrainingID: training.ID,
+		Code:       fmt.Sprintf("%0*d", checkInCodeDigits, n),
+		ExpiresAt:  now.Add(h.booking.CheckInCodeTTL),
+	}
+	if code.ExpiresAt.After(training.EndTime) {
+		code.ExpiresAt = training.EndTime
+	}
+	if err := h.attendance.ReplaceCheckInCode(c.Request.Context(), &code); err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseError{Error: "Error storing check-in code"})
+		return
+	}
+
+	query := url.Values{"training_id": {strconv.Itoa(training.ID)}, "code": {code.Code}}
+	c.JSON(http.StatusCreated, ResponseSuccess{Message: "Check-in code created", Data: CheckInCodeView{
+		TrainingID: training.ID,
+		Code:       code.Code,
+		ExpiresAt:  code.ExpiresAt,
+		QRPayload:  "fitness-app://check-in?" + query.Encode(),
+	}})
+}
+
+// CheckIn godoc
+// @Summary Check in to a training session
+// @Description Check in with the code shown by the trainer (only for users with a seat in the training). Check-in is open from shortly before the start until the end of the training. After 5 attempts check-in is locked for the user and only the trainer can mark their attendance.
+// @Tags attendance
+// @Accept json
+// @Produce json
+// @Param training_id path int true "Training ID"
+// @Param code body CheckInRequest true "Check-in code"
+// @Success 201 {object} ResponseSuccess{data=AttendanceView}
+// @Failure 400 {object} ResponseError
+// @Failure 403 {object} ResponseError
+// @Failure 404 {object} ResponseError
+// @Failure 409 {object} ResponseError
+// @Failure 429 {object} ResponseError
+// @Security BearerAuth
+// @Router /protected/training/{training_id}/check-in [post]
+func (h *Handler) CheckIn(c *gin.Context) {
+	principal := middleware.MustPrincipal(c)
+	trainingID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ResponseError{Error: "Invalid training ID: " + err.Error()})
+		return
+	}
+
+	var req CheckInRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ResponseError{Error: "Invalid data: " + err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	training, err := h.trainings.GetByID(ctx, trainingID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, ResponseError{Error: "Training not found with ID " + strconv.Itoa(trainingID)})
+		return
+	}
+	now := time.Now()
+	if !h.checkInOpen(training, now) {
+		c.JSON(http.StatusConflict, ResponseError{Error: "Check-in is not open for this training"})
+		return
+	}
+
+	code, err := h.attendance.GetCheckInCode(ctx, trainingID)
+	if err != nil && !xerrors.Is(err, storage.ErrNotFound) {
+		c.JSON(http.StatusInternalServerError, ResponseError{Error: "Error checking in"})
+		return
+	}
+	if code == nil || !now.Before(code.ExpiresAt) {
+		c.JSON(http.StatusForbidden, ResponseError{Error: "Invalid or expired check-in code"})
+		return
+	}
+
+	// Counted before comparing, so concurrent guesses cannot exceed the limit
+	attempts, err := h.attendance.AddCheckInAttempt(ctx, trainingID, principal.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseError{Error: "Error checking in"})
+		return
+	}
+	if attempts > checkInAttempts {
+		c.JSON(http.StatusTooManyRequests, ResponseError{Error: "Too many check-in attempts, ask the trainer to mark your attendance"})
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(code.Code), []byte(req.Code)) != 1 {
+		c.JSON(http.StatusForbidden, ResponseError{Error: "Invalid or expired check-in code"})
+		return
+	}
+
+	attendance := domain.Attendance{TrainingID: trainingID, UserID: principal.ID, Method: domain.AttendanceSelf, CheckedInAt: now}
+	if !h.markAttendance(c, &attendance) {
+		return
+	}
+	c.JSON(http.StatusCreated, ResponseSuccess{Message: "Checked in", Data: newAttendanceView(attendance)})
+}
+
+// MarkAttendance godoc
+// @Summary Mark the attendance of a user
+// @Description Mark that a user with a seat attended the training, removing a no-show recorded for it (only for the trainer of the training and admins). Attendance can be taken from the time check-in opens.
+// @Tags attendance
+// @Produce json
+// @Param training_id path int true "Training ID"
+// @Param user_id path int true "User ID"
+// @Success 200 {object} ResponseSuccess{data=AttendanceView}
+// @Failure 400 {object} ResponseError
+// @Failure 403 {object} ResponseError
+// @Failure 404 {object} ResponseError
+// @Failure 409 {object} ResponseError
+// @Security BearerAuth
+// @Router /protected/training/{training_id}/attendance/{user_id} [put]
+func (h *Handler) MarkAttendance(c *gin.Context) {
+	training, ok := h.attendedTraining(c)
+	if !ok {
+		return
+	}
+	userID, err := strconv.Atoi(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ResponseError{Error: "Invalid user ID: " + err.Error()})
+		return
+	}
+	now := time.Now()
+	if now.Before(training.StartTime.Add(-h.booking.CheckInOpens)) {
+		c.JSON(http.StatusConflict, ResponseError{Error: "Check-in is not open for this training"})
+		return
+	}
+
+	// Marking is idempotent, a user who already checked in keeps their check-in
+	if existing, err := h.attendance.Get(c.Request.Context(), training.ID, userID); err == nil {
+		c.JSON(http.StatusOK, ResponseSuccess{Message: "Attendance already marked", Data: newAttendanceView(*existing)})
+		return
+	}
+
+	attendance := domain.Attendance{TrainingID: training.ID, UserID: userID, Method: domain.AttendanceTrainer, CheckedInAt: now}
+	if !h.markAttendance(c, &attendance) {
+		return
+	}
+	c.JSON(http.StatusOK, ResponseSuccess{Message: "Attendance marked", Data: newAttendanceView(attendance)})
+}
+
+// UnmarkAttendance godoc
+// @Summary Remove the attendance of a user
+// @Description Remove the attendance taken for a user by mistake (only for the trainer of the training and admins). For a training that has ended the user is recorded as a no-show.
+// @Tags attendance
+// @Produce json
+// @Param training_id path int true "Training ID"
+// @Param user_id path int true "User ID"
+// @Success 200 {object} ResponseSuccess
+// @Failure 400 {object} ResponseError
+// @Failure 403 {object} ResponseError
+// @Failure 404 {object} ResponseError
+// @Security BearerAuth
+// @Router /protected/training/{training_id}/attendance/{user_id} [delete]
+func (h *Handler) UnmarkAttendance(c *gin.Context) {
+	training, ok := h.attendedTraining(c)
+	if !ok {
+		return
+	}
+	userID, err := strconv.Atoi(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ResponseError{Error: "Invalid user ID: " + err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	err = h.attendance.Unmark(ctx, training.ID, userID)
+	if xerrors.Is(err, storage.ErrNotFound) {
+		c.JSON(http.StatusNotFound, ResponseError{Error: "No attendance taken for this user"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseError{Error: "Error removing attendance"})
+		return
+	}
+
+	// No-shows of ended trainings may already be recorded, so the user is not
+	// left to the next run
+	if time.Now().Before(training.EndTime) {
+		c.JSON(http.StatusOK, ResponseSuccess{Message: "Attendance removed"})
+		return
+	}
+	incident := domain.Incident{UserID: userID, TrainingID: training.ID, Kind: domain.IncidentNoShow, At: training.EndTime}
+	if err := h.incidents.Record(ctx, &incident); err != nil && !xerrors.Is(err, storage.ErrAlreadyExists) {
+		log.Printf("record no-show of user %d for training %d: %v", userID, training.ID, err)
+	}
+	c.JSON(http.StatusOK, ResponseSuccess{Message: "Attendance removed, the no-show was recorded"})
+}
+
+// GetTrainingAttendance godoc
+// @Summary Get the attendance of a training session
+// @Description Get the users who attended the training and the users recorded as no-shows, which happens automatically once the training ends (only for the trainer of the training and admins)
+// @Tags attendance
+// @Produce json
+// @Param training_id path int true "Training ID"
+// @Success 200 {object} ResponseSuccess{data=TrainingAttendanceView}
+// @Failure 400 {object} ResponseError
+// @Failure 403 {object} ResponseError
+// @Failure 404 {object} ResponseError
+// @Security BearerAuth
+// @Router /protected/training/{training_id}/attendance [get]
+func (h *Handler) GetTrainingAttendance(c *gin.Context) {
+	training, ok := h.attendedTraining(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	attendance, err := h.attendance.ListByTraining(ctx, training.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseError{Error: "Error retrieving attendance"})
+		return
+	}
+	incidents, err := h.incidents.ListByTraining(ctx, training.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseError{Error: "Error retrieving attendance"})
+		return
+	}
+
+	c.JSON(http.StatusOK, ResponseSuccess{Message: "Attendance retrieved", Data: newTrainingAttendanceView(training.ID, attendance, incidents)})
+}
+
+// attendedTraining returns the training of the request if the principal may
+// take its attendance, otherwise it responds with an error
+func (h *Handler) attendedTraining(c *gin.Context) (*domain.Training, bool) {
+	principal := middleware.MustPrincipal(c)
+	trainingID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ResponseError{Error: "Invalid training ID: " + err.Error()})
+		return nil, false
+	}
+
+	training, err := h.trainings.GetByID(c.Request.Context(), trainingID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, ResponseError{Error: "Training not found with ID " + strconv.Itoa(trainingID)})
+		return nil, false
+	}
+	if !h.policy.CanManageTraining(principal, training) {
+		h.deny(c, principal, policy.ActionTakeAttendance, audit.Resource("training", trainingID), "Not allowed to take attendance for this training")
+		return nil, false
+	}
+	return training, true
+}
+
+// markAttendance stores the attendance, otherwise it responds with an error
+func (h *Handler) markAttendance(c *gin.Context, attendance *domain.Attendance) bool {
+	err := h.attendance.Mark(c.Request.Context(), attendance)
+	if xerrors.Is(err, storage.ErrNotFound) {
+		c.JSON(http.StatusNotFound, ResponseError{Error: "User has no seat in this training"})
+		return false
+	}
+	if xerrors.Is(err, storage.ErrAlreadyExists) {
+		c.JSON(http.StatusConflict, ResponseError{Error: "Already checked in"})
+		return false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseError{Error: "Error storing attendance"})
+		return false
+	}
+	return true
+}
+
+// checkInOpen reports whether users can check in to the training at the given time
+func (h *Handler) checkInOpen(training *domain.Training, now time.Time) bool {
+	return !now.Before(training.StartTime.Add(-h.booking.CheckInOpens)) && now.Before(training.EndTime)
+}
@@ -0,0 +1,60 @@
+package handler_test
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/folklinoff/fitness-app/internal/config"
+	"github.com/folklinoff/fitness-app/internal/domain"
+	middleware "github.com/folklinoff/fitness-app/internal/middleware/auth"
+)
+
+func TestCheckInLocksAfterAttempts(t *testing.T) {
+	ctx := context.Background()
+	s := newServer(t, nil, config.Payments{})
+	start := time.Now().Add(-time.Minute)
+	training := s.createTraining(t, domain.Training{Name: "Yoga", Capacity: 5, StartTime: start, EndTime: start.Add(time.Hour)})
+	code := domain.CheckInCode{TrainingID: training.ID, Code: "123456", ExpiresAt: time.Now().Add(10 * time.Minute)}
+	if err := s.store.Attendance.ReplaceCheckInCode(ctx, &code); err != nil {
+		t.Fatal(err)
+	}
+	checkIn := func(userID int, code string) int {
+		t.Helper()
+		token := s.login(t, middleware.Principal{ID: userID, Type: middleware.UserTypeUser}).AccessToken
+		path := "/protected/training/" + strconv.Itoa(training.ID) + "/check-in"
+		return s.do(http.MethodPost, path, token, `{"code":"`+code+`"}`, nil).Code
+	}
+	seat := func(name string) int {
+		t.Helper()
+		userID := s.createUser(t, name)
+		if _, err := s.store.Trainings.RegisterUser(ctx, training.ID, userID); err != nil {
+			t.Fatal(err)
+		}
+		return userID
+	}
+
+	guesser := seat("mallory")
+	for i := 1; i <= 5; i++ {
+		if got := checkIn(guesser, "00000"+strconv.Itoa(i)); got != http.StatusForbidden {
+			t.Fatalf("wrong code %d: got %d, want 403", i, got)
+		}
+	}
+	if got := checkIn(guesser, code.Code); got != http.StatusTooManyRequests {
+		t.Errorf("right code after the attempts: got %d, want 429", got)
+	}
+	if _, err := s.store.Attendance.Get(ctx, training.ID, guesser); err == nil {
+		t.Error("locked user checked in")
+	}
+
+	// Others keep their own attempts
+	typo := seat("alice")
+	if got := checkIn(typo, "123465"); got != http.StatusForbidden {
+		t.Errorf("wrong code: got %d, want 403", got)
+	}
+	if got := checkIn(typo, code.Code); got != http.StatusCreated {
+		t.Errorf("right code after a typo: got %d, want 201", got)
+	}
+}
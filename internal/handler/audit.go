@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	middleware "github.com/folklinoff/fitness-app/internal/middleware/auth"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultAuditLimit = 50
+	maxAuditLimit     = 500
+)
+
+// GetAuditEvents godoc
+// @Summary List denied requests
+// @Description List the most recent requests denied by authorization checks, newest first (only for admins)
+// @Tags audit
+// @Produce json
+// @Param limit query int false "Maximum number of events (default 50, at most 500)"
+// @Success 200 {object} ResponseSuccess{data=[]AuditEventView}
+// @Failure 400 {object} ResponseError
+// @Failure 403 {object} ResponseError
+// @Security BearerAuth
+// @Router /protected/audit [get]
+func (h *Handler) GetAuditEvents(c *gin.Context) {
+	limit := defaultAuditLimit
+	if raw := c.Query("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, ResponseError{Error: "Invalid limit"})
+			return
+		}
+		limit = min(n, maxAuditLimit)
+	}
+
+	events, err := h.audit.List(c.Request.Context(), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseError{Error: "Error retrieving audit events"})
+		return
+	}
+
+	c.JSON(http.StatusOK, ResponseSuccess{Message: "Audit events retrieved", Data: newAuditEventViews(events)})
+}
+
+// deny responds with 403 Forbidden and records the violation in the audit log
+func (h *Handler) deny(c *gin.Context, principal middleware.Principal, action, resource, message string) {
+	h.policy.Deny(c.Request.Context(), principal, action, resource, message)
+	c.JSON(http.StatusForbidden, ResponseError{Error: message})
+}
@@ -0,0 +1,260 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/folklinoff/fitness-app/internal/domain"
+	"github.com/folklinoff/fitness-app/internal/ical"
+	middleware "github.com/folklinoff/fitness-app/internal/middleware/auth"
+	"github.com/folklinoff/fitness-app/internal/storage"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/xerrors"
+)
+
+const (
+	// calendarUIDDomain makes the UIDs of training events globally unique
+	calendarUIDDomain = "fitness-app"
+	// feedRefreshInterval is how often calendar apps are asked to poll a feed
+	feedRefreshInterval = time.Hour
+)
+
+// GetUserScheduleCalendar godoc
+// @Summary Export the schedule of the current user as iCalendar
+// @Description Get the trainings the current user has a seat in as a text/calendar file (only for users). Each training is an event with the UID training-{id}@fitness-app, its SEQUENCE is raised whenever the times of the training change.
+// @Tags user
+// @Produce text/calendar
+// @Success 200 {string} string "iCalendar file"
+// @Failure 403 {object} ResponseError
+// @Security BearerAuth
+// @Router /protected/user/schedule.ics [get]
+func (h *Handler) GetUserScheduleCalendar(c *gin.Context) {
+	h.exportSchedule(c, middleware.MustPrincipal(c))
+}
+
+// GetTrainerScheduleCalendar godoc
+// @Summary Export the schedule of the current trainer as iCalendar
+// @Description Get the trainings of the current trainer as a text/calendar file (only for trainers). Each training is an event with the UID training-{id}@fitness-app, its SEQUENCE is raised whenever the times of the training change.
+// @Tags trainer
+// @Produce text/calendar
+// @Success 200 {string} string "iCalendar file"
+// @Failure 403 {object} ResponseError
+// @Security BearerAuth
+// @Router /protected/trainer/schedule.ics [get]
+func (h *Handler) GetTrainerScheduleCalendar(c *gin.Context) {
+	h.exportSchedule(c, middleware.MustPrincipal(c))
+}
+
+// CreateCalendarFeed godoc
+// @Summary Create a calendar feed URL for the current account
+// @Description Create a secret URL calendar apps can subscribe to for the schedule of the current user or trainer. The URL is shown only once; creating a new one revokes the previous URL.
+// @Tags calendar
+// @Produce json
+// @Success 201 {object} ResponseSuccess{data=CalendarFeedView}
+// @Failure 403 {object} ResponseError
+// @Security BearerAuth
+// @Router /protected/calendar/feed [post]
+func (h *Handler) CreateCalendarFeed(c *gin.Context) {
+	principal := middleware.MustPrincipal(c)
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseError{Error: "Error generating feed token"})
+		return
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+
+	feed := domain.CalendarFeed{
+		TokenHash:   hashFeedToken(token),
+		AccountType: principal.Type,
+		AccountID:   principal.ID,
+		CreatedAt:   time.Now(),
+	}
+	if err := h.calendars.Replace(c.Request.Context(), &feed); err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseError{Error: "Error creating calendar feed"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, ResponseSuccess{Message: "Calendar feed created", Data: CalendarFeedView{URL: h.feedURL(token)}})
+}
+
+// DeleteCalendarFeed godoc
+// @Summary Revoke the calendar feed URL of the current account
+// @Description Revoke the calendar feed URL of the current user or trainer
+// @Tags calendar
+// @Produce json
+// @Success 200 {object} ResponseSuccess
+// @Failure 403 {object} ResponseError
+// @Failure 404 {object} ResponseError
+// @Security BearerAuth
+// @Router /protected/calendar/feed [delete]
+func (h *Handler) DeleteCalendarFeed(c *gin.Context) {
+	principal := middleware.MustPrincipal(c)
+
+	err := h.calendars.Delete(c.Request.Context(), principal.Type, principal.ID)
+	if xerrors.Is(err, storage.ErrNotFound) {
+		c.JSON(http.StatusNotFound, ResponseError{Error: "No calendar feed to revoke"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseError{Error: "Error revoking calendar feed"})
+		return
+	}
+	c.JSON(http.StatusOK, ResponseSuccess{Message: "Calendar feed revoked"})
+}
+
+// GetCalendarFeed godoc
+// @Summary Get a calendar feed
+// @Description Get the schedule behind a calendar feed URL as a text/calendar file, the token in the URL authorizes the request
+// @Tags calendar
+// @Produce text/calendar
+// @Param token path string true "Feed token, optionally followed by .ics"
+// @Success 200 {string} string "iCalendar file"
+// @Failure 404 {object} ResponseError
+// @Router /calendar/{token} [get]
+func (h *Handler) GetCalendarFeed(c *gin.Context) {
+	token := strings.TrimSuffix(c.Param("token"), ".ics")
+
+	ctx := c.Request.Context()
+	feed, err := h.calendars.GetByTokenHash(ctx, hashFeedToken(token))
+	if err != nil {
+		c.JSON(http.StatusNotFound, ResponseError{Error: "Calendar feed not found"})
+		return
+	}
+
+	cal, err := h.scheduleCalendar(ctx, middleware.Principal{Type: feed.AccountType, ID: feed.AccountID})
+	if xerrors.Is(err, storage.ErrNotFound) {
+		c.JSON(http.StatusNotFound, ResponseError{Error: "Calendar feed not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseError{Error: "Error retrieving schedule"})
+		return
+	}
+	cal.RefreshInterval = feedRefreshInterval
+	writeCalendar(c, cal, "")
+}
+
+func (h *Handler) exportSchedule(c *gin.Context, principal middleware.Principal) {
+	cal, err := h.scheduleCalendar(c.Request.Context(), principal)
+	if xerrors.Is(err, storage.ErrNotFound) {
+		c.JSON(http.StatusNotFound, ResponseError{Error: "Account not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseError{Error: "Error retrieving schedule"})
+		return
+	}
+	writeCalendar(c, cal, "schedule.ics")
+}
+
+// scheduleCalendar returns the calendar of the trainings a user has a seat in or
+// a trainer gives, ErrNotFound is returned if the account does not exist
+func (h *Handler) scheduleCalendar(ctx context.Context, principal middleware.Principal) (ical.Calendar, error) {
+	var cal ical.Calendar
+	var trainings []domain.Training
+	switch principal.Type {
+	case middleware.UserTypeUser:
+		user, err := h.users.GetByID(ctx, principal.ID)
+		if err != nil {
+			return cal, err
+		}
+		cal.Name = "Trainings of " + user.Name
+		if trainings, err = h.trainings.ListByUser(ctx, principal.ID); err != nil {
+			return cal, err
+		}
+	case middleware.UserTypeTrainer:
+		trainer, err := h.trainers.GetByID(ctx, principal.ID)
+		if err != nil {
+			return cal, err
+		}
+		cal.Name = "Trainings given by " + trainer.Name
+		if trainings, err = h.trainings.ListByTrainer(ctx, principal.ID); err != nil {
+			return cal, err
+		}
+	default:
+		return cal, storage.ErrNotFound
+	}
+
+	places := make(map[int]string)
+	now := time.Now()
+	for _, t := range trainings {
+		event := ical.Event{
+			UID:      "training-" + strconv.Itoa(t.ID) + "@" + calendarUIDDomain,
+			Sequence: t.Sequence,
+			Stamp:    now,
+			Start:    t.StartTime,
+			End:      t.EndTime,
+			Summary:  t.Name,
+		}
+		var details []string
+		if t.Type != "" {
+			details = append(details, "Type: "+t.Type)
+		}
+		if t.Level != "" {
+			details = append(details, "Level: "+t.Level)
+		}
+		event.Description = strings.Join(details, "\n")
+		if t.RoomID != nil {
+			place, ok := places[*t.RoomID]
+			if !ok {
+				place = h.roomPlace(ctx, *t.RoomID)
+				places[*t.RoomID] = place
+			}
+			event.Location = place
+		}
+		cal.Events = append(cal.Events, event)
+	}
+	return cal, nil
+}
+
+// roomPlace describes where the room is, it is empty if the room is gone
+func (h *Handler) roomPlace(ctx context.Context, roomID int) string {
+	room, err := h.rooms.GetByID(ctx, roomID)
+	if err != nil {
+		return ""
+	}
+	parts := []string{room.Name}
+	if location, err := h.locations.GetByID(ctx, room.LocationID); err == nil {
+		parts = append(parts, location.Name)
+		if location.Address != "" {
+			parts = append(parts, location.Address)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// writeCalendar responds with the calendar, a filename makes it a download
+func writeCalendar(c *gin.Context, cal ical.Calendar, filename string) {
+	var buf bytes.Buffer
+	if err := ical.Write(&buf, cal); err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseError{Error: "Error writing calendar"})
+		return
+	}
+	if filename != "" {
+		c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	}
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", buf.Bytes())
+}
+
+// feedURL returns the absolute URL of the feed with the token. It is built on
+// the configured public URL, the Host and X-Forwarded-* headers are up to the
+// client and must not end up in links.
+func (h *Handler) feedURL(token string) string {
+	return h.publicURL + "/calendar/" + token + ".ics"
+}
+
+// hashFeedToken returns the stored form of a feed token, the tokens are random
+// enough for a plain SHA-256 to be safe
+func hashFeedToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
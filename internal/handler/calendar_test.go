@@ -0,0 +1,37 @@
+package handler_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/folklinoff/fitness-app/internal/config"
+	"github.com/folklinoff/fitness-app/internal/handler"
+	middleware "github.com/folklinoff/fitness-app/internal/middleware/auth"
+)
+
+func TestCalendarFeedURLIgnoresRequestHost(t *testing.T) {
+	s := newServer(t, nil, config.Payments{})
+	userID := s.createUser(t, "alice")
+	token := s.login(t, middleware.Principal{ID: userID, Type: middleware.UserTypeUser}).AccessToken
+
+	header := http.Header{
+		"X-Forwarded-Proto": {"http"},
+		"X-Forwarded-Host":  {"evil.example"},
+	}
+	created := s.do(http.MethodPost, "/protected/calendar/feed", token, "", header)
+	if created.Code != http.StatusCreated {
+		t.Fatalf("create feed: got %d %s, want 201", created.Code, created.Body)
+	}
+	var feed handler.CalendarFeedView
+	decode(t, created, &feed)
+
+	const base = "https://fitness.example.com/calendar/"
+	if !strings.HasPrefix(feed.URL, base) || !strings.HasSuffix(feed.URL, ".ics") {
+		t.Fatalf("feed URL %q is not on the public URL %s", feed.URL, base)
+	}
+	rec := s.do(http.MethodGet, strings.TrimPrefix(feed.URL, "https://fitness.example.com"), "", "", nil)
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/calendar") {
+		t.Errorf("get feed: got %d %s, want a calendar", rec.Code, rec.Header().Get("Content-Type"))
+	}
+}
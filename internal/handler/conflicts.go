@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/folklinoff/fitness-app/internal/storage"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/xerrors"
+)
+
+var errInvalidInterval = xerrors.New("end_time must be after start_time")
+
+func validInterval(start, end time.Time) error {
+	if !end.After(start) {
+		return errInvalidInterval
+	}
+	return nil
+}
+
+// allowOverlap reads the allow_overlap query parameter. It writes the error
+// response itself and reports whether the request may go on.
+func allowOverlap(c *gin.Context) (bool, bool) {
+	allow, err := strconv.ParseBool(c.DefaultQuery("allow_overlap", "false"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ResponseError{Error: "Invalid allow_overlap: " + err.Error()})
+		return false, false
+	}
+	return allow, true
+}
+
+// respondOverlap writes the 409 response for an OverlapError of the storage and
+// reports whether err is one
+func respondOverlap(c *gin.Context, err error) bool {
+	var overlap *storage.OverlapError
+	if !xerrors.As(err, &overlap) {
+		return false
+	}
+
+	var message string
+	switch {
+	case len(overlap.TrainerTrainingIDs) > 0 && len(overlap.RoomTrainingIDs) > 0:
+		message = "Training overlaps other trainings of the trainer and in its room, set allow_overlap to book it anyway"
+	case len(overlap.TrainerTrainingIDs) > 0:
+		message = "Training overlaps other trainings of the trainer, set allow_overlap to book it anyway"
+	default:
+		message = "Training overlaps other trainings in its room, set allow_overlap to book it anyway"
+	}
+	conflicts := overlap.TrainerTrainingIDs
+	for _, id := range overlap.RoomTrainingIDs {
+		if !containsID(conflicts, id) {
+			conflicts = append(conflicts, id)
+		}
+	}
+	c.JSON(http.StatusConflict, ResponseConflict{Error: message, ConflictingTrainingIDs: conflicts})
+	return true
+}
+
+func containsID(ids []int, id int) bool {
+	for _, i := range ids {
+		if i == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,682 @@
+package handler
+
+import (
+	"strings"
+	"time"
+
+	"github.com/folklinoff/fitness-app/internal/domain"
+	middleware "github.com/folklinoff/fitness-app/internal/middleware/auth"
+)
+
+// LoginRequest holds the credentials of a user or trainer
+type LoginRequest struct {
+	Name     string `json:"name" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+// RefreshRequest holds the refresh token exchanged for new tokens
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// LogoutRequest optionally names the refresh token of the session to end
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// RegisterRequest holds the data of a new account, health_description is only
+// stored for users
+type RegisterRequest struct {
+	Name              string `json:"name" binding:"required"`
+	Password          string `json:"password" binding:"required"`
+	Mail              string `json:"mail"`
+	Phone             string `json:"phone"`
+	HealthDescription string `json:"health_description"`
+}
+
+// UpdateProfileRequest holds the new profile data, an empty password keeps the current one
+type UpdateProfileRequest struct {
+	Name              string `json:"name" binding:"required"`
+	Password          string `json:"password"`
+	Mail              string `json:"mail"`
+	Phone             string `json:"phone"`
+	HealthDescription string `json:"health_description"`
+}
+
+// TrainingRequest holds the data of a created or updated training, a capacity
+// of 0 means unlimited seats or, in a room, as many seats as the room has.
+// Credits is the cost of a registration in membership credits, price the card
+// price in the minor unit of the currency paid when no membership covers it.
+type TrainingRequest struct {
+	Name      string    `json:"name" binding:"required"`
+	Type      string    `json:"type"`
+	Level     string    `json:"level"`
+	Capacity  int       `json:"capacity" binding:"min=0"`
+	Credits   int       `json:"credits" binding:"min=0"`
+	Price     int       `json:"price" binding:"min=0"`
+	RoomID    *int      `json:"room_id"`
+	StartTime time.Time `json:"start_time" swaggertype:"string" example:"2024-06-08T15:04:05Z"`
+	EndTime   time.Time `json:"end_time" swaggertype:"string" example:"2024-06-08T16:04:05Z"`
+}
+
+// SeriesRequest holds the data of a created training series, start_time and
+// end_time are those of its first occurrence
+type SeriesRequest struct {
+	Name       string      `json:"name" binding:"required"`
+	Type       string      `json:"type"`
+	Level      string      `json:"level"`
+	Capacity   int         `json:"capacity" binding:"min=0"`
+	Credits    int         `json:"credits" binding:"min=0"`
+	Price      int         `json:"price" binding:"min=0"`
+	RoomID     *int        `json:"room_id"`
+	StartTime  time.Time   `json:"start_time" swaggertype:"string" example:"2024-06-03T18:00:00+02:00"`
+	EndTime    time.Time   `json:"end_time" swaggertype:"string" example:"2024-06-03T19:00:00+02:00"`
+	TimeZone   string      `json:"time_zone" binding:"required" example:"Europe/Berlin"`
+	RRule      string      `json:"rrule" binding:"required" example:"FREQ=WEEKLY;BYDAY=MO,WE;COUNT=10"`
+	Exceptions []time.Time `json:"exceptions" swaggertype:"array,string"`
+}
+
+// LocationRequest holds the data of a created or updated location, a location
+// without opening hours is always open
+type LocationRequest struct {
+	Name         string                `json:"name" binding:"required"`
+	Address      string                `json:"address"`
+	TimeZone     string                `json:"time_zone" binding:"required" example:"Europe/Berlin"`
+	OpeningHours []OpeningHoursRequest `json:"opening_hours" binding:"dive"`
+}
+
+// OpeningHoursRequest is a time span a location is open on a weekday, closes
+// may be 24:00
+type OpeningHoursRequest struct {
+	Weekday string `json:"weekday" binding:"required" example:"monday"`
+	Opens   string `json:"opens" binding:"required" example:"06:00"`
+	Closes  string `json:"closes" binding:"required" example:"22:00"`
+}
+
+// RoomRequest holds the data of a created or updated room
+type RoomRequest struct {
+	Name     string `json:"name" binding:"required"`
+	Capacity int    `json:"capacity" binding:"required,min=1"`
+}
+
+// PlanRequest holds the data of a created or updated membership plan. Sessions
+// is the number of credits of pass plans, drop-in plans have one and unlimited
+// plans none. Plans are active unless active is false, plans without a price
+// are not sold online.
+type PlanRequest struct {
+	Name      string `json:"name" binding:"required"`
+	Kind      string `json:"kind" binding:"required,oneof=unlimited pass drop_in" enums:"unlimited,pass,drop_in"`
+	Sessions  int    `json:"sessions" binding:"min=0"`
+	ValidDays int    `json:"valid_days" binding:"required,min=1"`
+	Price     int    `json:"price" binding:"min=0"`
+	Active    *bool  `json:"active"`
+}
+
+// MembershipRequest grants a plan to a user, the membership starts now unless
+// starts_at is given
+type MembershipRequest struct {
+	PlanID   int        `json:"plan_id" binding:"required"`
+	StartsAt *time.Time `json:"starts_at" swaggertype:"string" example:"2024-06-01T00:00:00Z"`
+}
+
+// CalendarFeedView holds the secret URL of a calendar feed
+type CalendarFeedView struct {
+	URL string `json:"url" example:"https://fitness.example.com/calendar/3q2-7wX...ics"`
+}
+
+// PlanView is the representation of a membership plan
+type PlanView struct {
+	ID        int    `json:"id"`
+	Name      string `json:"name"`
+	Kind      string `json:"kind" enums:"unlimited,pass,drop_in"`
+	Sessions  int    `json:"sessions"`
+	ValidDays int    `json:"valid_days"`
+	Price     int    `json:"price"`
+	Active    bool   `json:"active"`
+}
+
+// MembershipView is a membership of a user, credits are the sessions left and
+// are not used by unlimited memberships
+type MembershipView struct {
+	ID        int       `json:"id"`
+	PlanID    int       `json:"plan_id"`
+	Kind      string    `json:"kind" enums:"unlimited,pass,drop_in"`
+	Credits   int       `json:"credits"`
+	StartsAt  time.Time `json:"starts_at" swaggertype:"string" example:"2024-06-01T00:00:00Z"`
+	ExpiresAt time.Time `json:"expires_at" swaggertype:"string" example:"2024-07-01T00:00:00Z"`
+}
+
+// LedgerEntryView is a change of the credits of a membership, balance is the
+// credits of the membership left after it
+type LedgerEntryView struct {
+	ID           int       `json:"id"`
+	MembershipID int       `json:"membership_id"`
+	TrainingID   *int      `json:"training_id,omitempty"`
+	Kind         string    `json:"kind" enums:"grant,consume,refund"`
+	Amount       int       `json:"amount"`
+	Balance      int       `json:"balance"`
+	At           time.Time `json:"at" swaggertype:"string" example:"2024-06-08T15:04:05Z"`
+}
+
+// LedgerView is the credit history of a user. Balance sums the credits of the
+// memberships which have not expired, unlimited is set while the user has an
+// unlimited membership which has not expired.
+type LedgerView struct {
+	Balance   int               `json:"balance"`
+	Unlimited bool              `json:"unlimited"`
+	Entries   []LedgerEntryView `json:"entries"`
+}
+
+// TokenResponse holds the tokens of a session, expires_in is the access token
+// lifetime in seconds
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type" example:"Bearer"`
+	ExpiresIn    int    `json:"expires_in"`
+}
+
+// UserView is the public representation of a user
+type UserView struct {
+	ID                int    `json:"id"`
+	Name              string `json:"name"`
+	Mail              string `json:"mail"`
+	Phone             string `json:"phone"`
+	HealthDescription string `json:"health_description"`
+}
+
+// TraineeView is the part of a user profile visible to the trainers of the user
+type TraineeView struct {
+	ID                int    `json:"id"`
+	Name              string `json:"name"`
+	HealthDescription string `json:"health_description"`
+}
+
+// TrainerView is the public representation of a trainer
+type TrainerView struct {
+	ID    int    `json:"id"`
+	Name  string `json:"name"`
+	Mail  string `json:"mail"`
+	Phone string `json:"phone"`
+}
+
+// PublicTrainerView is the part of a trainer profile visible to other accounts
+type PublicTrainerView struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// AdminView is the public representation of an admin
+type AdminView struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// RegistrationView is the registration of a user for a training, position is the
+// place on the waitlist and 0 for other registrations. Credits were paid from
+// the membership, which is omitted for free trainings. Seats pending payment are
+// held until payment_due, checkout is set when a checkout was just started.
+type RegistrationView struct {
+	TrainingID   int           `json:"training_id"`
+	UserID       int           `json:"user_id"`
+	Status       string        `json:"status" enums:"confirmed,waitlisted,pending_payment"`
+	Position     int           `json:"position"`
+	MembershipID *int          `json:"membership_id,omitempty"`
+	Credits      int           `json:"credits"`
+	PaymentID    *int          `json:"payment_id,omitempty"`
+	PaymentDue   *time.Time    `json:"payment_due,omitempty" swaggertype:"string"`
+	Checkout     *CheckoutView `json:"checkout,omitempty"`
+}
+
+// CheckoutView is a checkout session at the payment gateway, the user pays at url
+type CheckoutView struct {
+	PaymentID int       `json:"payment_id"`
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at" swaggertype:"string" example:"2024-06-08T15:04:05Z"`
+}
+
+// PaymentView is a payment of a user for a training seat or a membership plan,
+// amount is in the minor unit of the currency
+type PaymentView struct {
+	ID         int       `json:"id"`
+	TrainingID *int      `json:"training_id,omitempty"`
+	PlanID     *int      `json:"plan_id,omitempty"`
+	Amount     int       `json:"amount"`
+	Currency   string    `json:"currency"`
+	Status     string    `json:"status" enums:"pending,paid,failed,refund_due,refunded"`
+	CreatedAt  time.Time `json:"created_at" swaggertype:"string" example:"2024-06-08T15:04:05Z"`
+	UpdatedAt  time.Time `json:"updated_at" swaggertype:"string" example:"2024-06-08T15:04:05Z"`
+}
+
+// NoShowRequest names the user who did not attend the training
+type NoShowRequest struct {
+	UserID int `json:"user_id" binding:"required"`
+}
+
+// CheckInRequest is the check-in code a user got from the trainer
+type CheckInRequest struct {
+	Code string `json:"code" binding:"required" example:"042137"`
+}
+
+// CheckInCodeView is a check-in code of a training, qr_payload is the content of
+// the QR code users can scan instead of typing the code
+type CheckInCodeView struct {
+	TrainingID int       `json:"training_id"`
+	Code       string    `json:"code" example:"042137"`
+	ExpiresAt  time.Time `json:"expires_at" swaggertype:"string" example:"2024-06-08T15:14:05Z"`
+	QRPayload  string    `json:"qr_payload" example:"fitness-app://check-in?training_id=1&code=042137"`
+}
+
+// AttendanceView is the attendance of a user
+type AttendanceView struct {
+	TrainingID  int       `json:"training_id"`
+	UserID      int       `json:"user_id"`
+	Method      string    `json:"method" enums:"trainer,self"`
+	CheckedInAt time.Time `json:"checked_in_at" swaggertype:"string" example:"2024-06-08T15:04:05Z"`
+}
+
+// TrainingAttendanceView lists the users who attended a training and the users
+// with a recorded no-show
+type TrainingAttendanceView struct {
+	TrainingID    int              `json:"training_id"`
+	Attendance    []AttendanceView `json:"attendance"`
+	NoShowUserIDs []int            `json:"no_show_user_ids"`
+}
+
+// CancellationView is the result of cancelling a registration, late is set when
+// the seat was cancelled within the cancellation cutoff. Refunds of card payments
+// are made in the background once payment_refunded is set.
+type CancellationView struct {
+	TrainingID      int  `json:"training_id"`
+	Late            bool `json:"late"`
+	RefundedCredits int  `json:"refunded_credits"`
+	PaymentRefunded bool `json:"payment_refunded"`
+}
+
+// IncidentView is a late cancellation or no-show of a user
+type IncidentView struct {
+	ID         int       `json:"id"`
+	TrainingID int       `json:"training_id"`
+	Kind       string    `json:"kind" enums:"late_cancellation,no_show"`
+	At         time.Time `json:"at" swaggertype:"string" example:"2024-06-08T15:04:05Z"`
+}
+
+// IncidentsView lists the incidents of a user with their totals
+type IncidentsView struct {
+	LateCancellations int            `json:"late_cancellations"`
+	NoShows           int            `json:"no_shows"`
+	Incidents         []IncidentView `json:"incidents"`
+}
+
+// AuditEventView is the representation of an audit event
+type AuditEventView struct {
+	ID        int       `json:"id"`
+	At        time.Time `json:"at" swaggertype:"string" example:"2024-06-08T15:04:05Z"`
+	ActorType string    `json:"actor_type"`
+	ActorID   int       `json:"actor_id"`
+	Action    string    `json:"action"`
+	Resource  string    `json:"resource"`
+	Reason    string    `json:"reason"`
+}
+
+// TrainingView is the public representation of a training
+type TrainingView struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	Type      string    `json:"type"`
+	Level     string    `json:"level"`
+	TrainerID int       `json:"trainer_id"`
+	Capacity  int       `json:"capacity"`
+	Credits   int       `json:"credits"`
+	Price     int       `json:"price"`
+	RoomID    *int      `json:"room_id,omitempty"`
+	StartTime time.Time `json:"start_time" swaggertype:"string" example:"2024-06-08T15:04:05Z"`
+	EndTime   time.Time `json:"end_time" swaggertype:"string" example:"2024-06-08T16:04:05Z"`
+	// SeriesID and RecurrenceID are set for the occurrences of a training series
+	SeriesID     *int       `json:"series_id,omitempty"`
+	RecurrenceID *time.Time `json:"recurrence_id,omitempty" swaggertype:"string"`
+}
+
+// SeriesView is a training series with its occurrences
+type SeriesView struct {
+	ID          int            `json:"id"`
+	Name        string         `json:"name"`
+	Type        string         `json:"type"`
+	Level       string         `json:"level"`
+	TrainerID   int            `json:"trainer_id"`
+	Capacity    int            `json:"capacity"`
+	Credits     int            `json:"credits"`
+	Price       int            `json:"price"`
+	RoomID      *int           `json:"room_id,omitempty"`
+	StartTime   time.Time      `json:"start_time" swaggertype:"string" example:"2024-06-03T16:00:00Z"`
+	EndTime     time.Time      `json:"end_time" swaggertype:"string" example:"2024-06-03T17:00:00Z"`
+	TimeZone    string         `json:"time_zone" example:"Europe/Berlin"`
+	RRule       string         `json:"rrule" example:"FREQ=WEEKLY;COUNT=10;BYDAY=MO,WE"`
+	Exceptions  []time.Time    `json:"exceptions" swaggertype:"array,string"`
+	Occurrences []TrainingView `json:"occurrences"`
+}
+
+// LocationView is a location with its opening hours and rooms
+type LocationView struct {
+	ID           int                `json:"id"`
+	Name         string             `json:"name"`
+	Address      string             `json:"address"`
+	TimeZone     string             `json:"time_zone" example:"Europe/Berlin"`
+	OpeningHours []OpeningHoursView `json:"opening_hours"`
+	Rooms        []RoomView         `json:"rooms"`
+}
+
+// OpeningHoursView is a time span a location is open on a weekday
+type OpeningHoursView struct {
+	Weekday string `json:"weekday" example:"monday"`
+	Opens   string `json:"opens" example:"06:00"`
+	Closes  string `json:"closes" example:"22:00"`
+}
+
+// RoomView is the public representation of a room
+type RoomView struct {
+	ID         int    `json:"id"`
+	LocationID int    `json:"location_id"`
+	Name       string `json:"name"`
+	Capacity   int    `json:"capacity"`
+}
+
+func newTokenResponse(tokens middleware.Tokens) TokenResponse {
+	return TokenResponse{
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(time.Until(tokens.ExpiresAt).Round(time.Second).Seconds()),
+	}
+}
+
+func newUserView(user domain.User) UserView {
+	return UserView{
+		ID:                user.ID,
+		Name:              user.Name,
+		Mail:              user.Mail,
+		Phone:             user.Phone,
+		HealthDescription: user.HealthDescription,
+	}
+}
+
+func newUserViews(users []domain.User) []UserView {
+	views := make([]UserView, 0, len(users))
+	for _, user := range users {
+		views = append(views, newUserView(user))
+	}
+	return views
+}
+
+func newTraineeView(user domain.User) TraineeView {
+	return TraineeView{
+		ID:                user.ID,
+		Name:              user.Name,
+		HealthDescription: user.HealthDescription,
+	}
+}
+
+func newTraineeViews(users []domain.User) []TraineeView {
+	views := make([]TraineeView, 0, len(users))
+	for _, user := range users {
+		views = append(views, newTraineeView(user))
+	}
+	return views
+}
+
+func newTrainerView(trainer domain.Trainer) TrainerView {
+	return TrainerView{
+		ID:    trainer.ID,
+		Name:  trainer.Name,
+		Mail:  trainer.Mail,
+		Phone: trainer.Phone,
+	}
+}
+
+func newPublicTrainerView(trainer domain.Trainer) PublicTrainerView {
+	return PublicTrainerView{ID: trainer.ID, Name: trainer.Name}
+}
+
+func newAdminView(admin domain.Admin) AdminView {
+	return AdminView{ID: admin.ID, Name: admin.Name}
+}
+
+// newRegistrationView returns the view of the registration, seats pending
+// payment are held for hold
+func newRegistrationView(registration domain.Registration, hold time.Duration) RegistrationView {
+	view := RegistrationView{
+		TrainingID:   registration.TrainingID,
+		UserID:       registration.UserID,
+		Status:       string(registration.Status),
+		Position:     registration.Position,
+		MembershipID: registration.MembershipID,
+		Credits:      registration.Credits,
+		PaymentID:    registration.PaymentID,
+	}
+	if registration.Status == domain.RegistrationPending {
+		due := registration.HeldAt.Add(hold).UTC()
+		view.PaymentDue = &due
+	}
+	return view
+}
+
+func newPaymentViews(payments []domain.Payment) []PaymentView {
+	views := make([]PaymentView, 0, len(payments))
+	for _, payment := range payments {
+		views = append(views, PaymentView{
+			ID:         payment.ID,
+			TrainingID: payment.TrainingID,
+			PlanID:     payment.PlanID,
+			Amount:     payment.Amount,
+			Currency:   payment.Currency,
+			Status:     string(payment.Status),
+			CreatedAt:  payment.CreatedAt,
+			UpdatedAt:  payment.UpdatedAt,
+		})
+	}
+	return views
+}
+
+func newAttendanceView(attendance domain.Attendance) AttendanceView {
+	return AttendanceView{
+		TrainingID:  attendance.TrainingID,
+		UserID:      attendance.UserID,
+		Method:      string(attendance.Method),
+		CheckedInAt: attendance.CheckedInAt,
+	}
+}
+
+func newTrainingAttendanceView(trainingID int, attendance []domain.Attendance, incidents []domain.Incident) TrainingAttendanceView {
+	view := TrainingAttendanceView{
+		TrainingID:    trainingID,
+		Attendance:    make([]AttendanceView, 0, len(attendance)),
+		NoShowUserIDs: []int{},
+	}
+	for _, a := range attendance {
+		view.Attendance = append(view.Attendance, newAttendanceView(a))
+	}
+	for _, incident := range incidents {
+		if incident.Kind == domain.IncidentNoShow {
+			view.NoShowUserIDs = append(view.NoShowUserIDs, incident.UserID)
+		}
+	}
+	return view
+}
+
+func newPlanView(plan domain.Plan) PlanView {
+	return PlanView{
+		ID:        plan.ID,
+		Name:      plan.Name,
+		Kind:      string(plan.Kind),
+		Sessions:  plan.Sessions,
+		ValidDays: plan.ValidDays,
+		Price:     plan.Price,
+		Active:    plan.Active,
+	}
+}
+
+func newMembershipView(membership domain.Membership) MembershipView {
+	return MembershipView{
+		ID:        membership.ID,
+		PlanID:    membership.PlanID,
+		Kind:      string(membership.Kind),
+		Credits:   membership.Credits,
+		StartsAt:  membership.StartsAt,
+		ExpiresAt: membership.ExpiresAt,
+	}
+}
+
+func newLedgerView(memberships []domain.Membership, entries []domain.LedgerEntry, now time.Time) LedgerView {
+	view := LedgerView{Entries: make([]LedgerEntryView, 0, len(entries))}
+	for _, membership := range memberships {
+		if !now.Before(membership.ExpiresAt) {
+			continue
+		}
+		if membership.Kind == domain.PlanUnlimited {
+			view.Unlimited = true
+		}
+		view.Balance += membership.Credits
+	}
+	for _, entry := range entries {
+		view.Entries = append(view.Entries, LedgerEntryView{
+			ID:           entry.ID,
+			MembershipID: entry.MembershipID,
+			TrainingID:   entry.TrainingID,
+			Kind:         string(entry.Kind),
+			Amount:       entry.Amount,
+			Balance:      entry.Balance,
+			At:           entry.At,
+		})
+	}
+	return view
+}
+
+func newIncidentsView(incidents []domain.Incident) IncidentsView {
+	view := IncidentsView{Incidents: make([]IncidentView, 0, len(incidents))}
+	for _, incident := range incidents {
+		switch incident.Kind {
+		case domain.IncidentLateCancellation:
+			view.LateCancellations++
+		case domain.IncidentNoShow:
+			view.NoShows++
+		}
+		view.Incidents = append(view.Incidents, IncidentView{
+			ID:         incident.ID,
+			TrainingID: incident.TrainingID,
+			Kind:       string(incident.Kind),
+			At:         incident.At,
+		})
+	}
+	return view
+}
+
+func newAuditEventViews(events []domain.AuditEvent) []AuditEventView {
+	views := make([]AuditEventView, 0, len(events))
+	for _, event := range events {
+		views = append(views, AuditEventView{
+			ID:        event.ID,
+			At:        event.At,
+			ActorType: event.ActorType,
+			ActorID:   event.ActorID,
+			Action:    event.Action,
+			Resource:  event.Resource,
+			Reason:    event.Reason,
+		})
+	}
+	return views
+}
+
+func newTrainingView(training domain.Training) TrainingView {
+	return TrainingView{
+		ID:        training.ID,
+		Name:      training.Name,
+		Type:      training.Type,
+		Level:     training.Level,
+		TrainerID: training.TrainerID,
+		Capacity:  training.Capacity,
+		Credits:   training.Credits,
+		Price:     training.Price,
+		RoomID:    training.RoomID,
+		StartTime: training.StartTime,
+		EndTime:   training.EndTime,
+
+		SeriesID:     training.SeriesID,
+		RecurrenceID: training.RecurrenceID,
+	}
+}
+
+func newSeriesView(series domain.TrainingSeries, occurrences []domain.Training) SeriesView {
+	exceptions := series.Exceptions
+	if exceptions == nil {
+		exceptions = []time.Time{}
+	}
+	return SeriesView{
+		ID:          series.ID,
+		Name:        series.Name,
+		Type:        series.Type,
+		Level:       series.Level,
+		TrainerID:   series.TrainerID,
+		Capacity:    series.Capacity,
+		Credits:     series.Credits,
+		Price:       series.Price,
+		RoomID:      series.RoomID,
+		StartTime:   series.StartTime,
+		EndTime:     series.EndTime,
+		TimeZone:    series.TimeZone,
+		RRule:       series.RRule,
+		Exceptions:  exceptions,
+		Occurrences: newTrainingViews(occurrences),
+	}
+}
+
+func newLocationView(location domain.Location, rooms []domain.Room) LocationView {
+	view := LocationView{
+		ID:           location.ID,
+		Name:         location.Name,
+		Address:      location.Address,
+		TimeZone:     location.TimeZone,
+		OpeningHours: make([]OpeningHoursView, 0, len(location.OpeningHours)),
+		Rooms:        make([]RoomView, 0, len(rooms)),
+	}
+	for _, hours := range location.OpeningHours {
+		view.OpeningHours = append(view.OpeningHours, OpeningHoursView{
+			Weekday: strings.ToLower(hours.Weekday.String()),
+			Opens:   formatClock(hours.Opens),
+			Closes:  formatClock(hours.Closes),
+		})
+	}
+	for _, room := range rooms {
+		view.Rooms = append(view.Rooms, newRoomView(room))
+	}
+	return view
+}
+
+func newRoomView(room domain.Room) RoomView {
+	return RoomView{
+		ID:         room.ID,
+		LocationID: room.LocationID,
+		Name:       room.Name,
+		Capacity:   room.Capacity,
+	}
+}
+
+func newTrainingViews(trainings []domain.Training) []TrainingView {
+	views := make([]TrainingView, 0, len(trainings))
+	for _, training := range trainings {
+		views = append(views, newTrainingView(training))
+	}
+	return views
+}
+
+// apply copies the request into the training, keeping its ID and trainer
+func (r TrainingRequest) apply(training *domain.Training) {
+	training.Name = r.Name
+	training.Type = r.Type
+	training.Level = r.Level
+	training.Capacity = r.Capacity
+	training.Credits = r.Credits
+	training.Price = r.Price
+	training.RoomID = r.RoomID
+	training.StartTime = r.StartTime
+	training.EndTime = r.EndTime
+}
@@ -1,18 +1,77 @@
 package handler
 
 import (
+	"context"
+	"log"
 	"net/http"
 
+	"github.com/folklinoff/fitness-app/internal/config"
 	"github.com/folklinoff/fitness-app/internal/domain"
 	middleware "github.com/folklinoff/fitness-app/internal/middleware/auth"
+	"github.com/folklinoff/fitness-app/internal/password"
+	"github.com/folklinoff/fitness-app/internal/payment"
+	"github.com/folklinoff/fitness-app/internal/policy"
+	"github.com/folklinoff/fitness-app/internal/storage"
 	"github.com/gin-gonic/gin"
 	"golang.org/x/xerrors"
 )
 
-var users []domain.User
-var trainers []domain.Trainer
-var userID = 1
-var trainerID = 1
+// Handler serves the API endpoints on top of the given repositories
+type Handler struct {
+	users       storage.UserRepository
+	trainers    storage.TrainerRepository
+	admins      storage.AdminRepository
+	trainings   storage.TrainingRepository
+	series      storage.SeriesRepository
+	locations   storage.LocationRepository
+	rooms       storage.RoomRepository
+	audit       storage.AuditRepository
+	incidents   storage.IncidentRepository
+	attendance  storage.AttendanceRepository
+	plans       storage.PlanRepository
+	memberships storage.MembershipRepository
+	calendars   storage.CalendarFeedRepository
+	payments    storage.PaymentRepository
+	outbox      storage.NotificationRepository
+	gateway     payment.PaymentGateway
+	hasher      *password.Hasher
+	tokens      *middleware.TokenManager
+	policy      *policy.Policy
+	booking     config.Booking
+	checkout    config.Payments
+	publicURL   string
+}
+
+// New creates a Handler backed by the given storage, access to accounts and
+// trainings is decided by the policy and purchases are paid through the gateway.
+// Links handed out to clients are built on publicURL.
+func New(store storage.Storage, hasher *password.Hasher, tokens *middleware.TokenManager, pol *policy.Policy, booking config.Booking,
+	gateway payment.PaymentGateway, checkout config.Payments, publicURL string) *Handler {
+	return &Handler{
+		users:       store.Users,
+		trainers:    store.Trainers,
+		admins:      store.Admins,
+		trainings:   store.Trainings,
+		series:      store.Series,
+		locations:   store.Locations,
+		rooms:       store.Rooms,
+		audit:       store.Audit,
+		incidents:   store.Incidents,
+		attendance:  store.Attendance,
+		plans:       store.Plans,
+		memberships: store.Memberships,
+		calendars:   store.Calendars,
+		payments:    store.Payments,
+		outbox:      store.Notifications,
+		gateway:     gateway,
+		hasher:      hasher,
+		tokens:      tokens,
+		policy:      pol,
+		booking:     booking,
+		checkout:    checkout,
+		publicURL:   publicURL,
+	}
+}
 
 // ResponseSuccess defines the structure for a successful response
 type ResponseSuccess struct {
@@ -20,88 +79,134 @@ type ResponseSuccess struct {
 	Data    interface{} `json:"data"`
 }
 
+// ResponsePage defines the structure for a page of a listing, total counts the
+// items of the whole listing and next_cursor is empty on the last page
+type ResponsePage struct {
+	Message    string      `json:"message"`
+	Data       interface{} `json:"data"`
+	Total      int         `json:"total"`
+	NextCursor string      `json:"next_cursor"`
+}
+
 // ResponseError defines the structure for an error response
 type ResponseError struct {
 	Error string `json:"error"`
 }
 
+// ResponseConflict is the error response for a training overlapping others
+type ResponseConflict struct {
+	Error                  string `json:"error"`
+	ConflictingTrainingIDs []int  `json:"conflicting_training_ids"`
+}
+
 // Login godoc
 // @Summary Login a user or trainer
-// @Description Login a user or trainer based on user_type
+// @Description Login a user, trainer or admin based on user_type
 // @Tags auth
 // @Accept json
 // @Produce json
-// @Param user_type path string true "User Type (user or trainer)"
-// @Param credentials body domain.User true "User credentials"
-// @Success 200 {object} ResponseSuccess{data=string} "token"
+// @Param user_type path string true "User Type (user, trainer or admin)"
+// @Param credentials body LoginRequest true "User credentials"
+// @Success 200 {object} ResponseSuccess{data=TokenResponse} "access and refresh tokens"
 // @Failure 400 {object} ResponseError
 // @Failure 404 {object} ResponseError
 // @Failure 401 {object} ResponseError
 // @Router /login/{user_type} [post]
-func Login(c *gin.Context) {
+func (h *Handler) Login(c *gin.Context) {
 	userType := c.Param("user_type")
 
-	var credentials struct {
-		Name     string `json:"name"`
-		Password string `json:"password"`
-	}
+	var credentials LoginRequest
 
 	if err := c.ShouldBindJSON(&credentials); err != nil {
 		c.JSON(http.StatusBadRequest, ResponseError{Error: "Invalid data"})
 		return
 	}
 
-	if userType == "user" {
-		user, err := getUser(credentials.Name)
+	ctx := c.Request.Context()
+	account, err := h.findLoginAccount(ctx, userType, credentials.Name)
+	if xerrors.Is(err, errUnknownAccountType) {
+		c.JSON(http.StatusUnauthorized, ResponseError{Error: "Invalid credentials"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusNotFound, ResponseError{Error: "no " + userType + " in db"})
+		return
+	}
+	ok, rehash, err := h.hasher.Verify(account.password, credentials.Password)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseError{Error: "Error verifying credentials"})
+		return
+	}
+	if !ok {
+		c.JSON(http.StatusUnauthorized, ResponseError{Error: "Invalid credentials"})
+		return
+	}
+	if rehash {
+		h.rehashPassword(ctx, userType, account, credentials.Password)
+	}
+	tokens, err := h.tokens.IssueTokens(ctx, middleware.Principal{ID: account.id, Type: userType})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseError{Error: "Error generating token"})
+		return
+	}
+	c.JSON(http.StatusOK, ResponseSuccess{Message: "Login successful", Data: newTokenResponse(tokens)})
+}
+
+var errUnknownAccountType = xerrors.New("unknown account type")
+
+// loginAccount is what logging in needs of a user, trainer or admin
+type loginAccount struct {
+	id       int
+	password string
+	// setPassword stores a new password hash of the account
+	setPassword func(ctx context.Context, hash string) error
+}
+
+// findLoginAccount looks up the account of the given type by name
+func (h *Handler) findLoginAccount(ctx context.Context, userType, name string) (loginAccount, error) {
+	switch userType {
+	case middleware.UserTypeUser:
+		user, err := h.users.GetByName(ctx, name)
 		if err != nil {
-			c.JSON(http.StatusNotFound, ResponseError{Error: "no user in db"})
-			return
-		}
-		if user.Password == credentials.Password {
-			token, err := middleware.GenerateToken(uint(user.ID), userType)
-			if err != nil {
-				c.JSON(http.StatusInternalServerError, ResponseError{Error: "Error generating token"})
-				return
-			}
-			c.JSON(http.StatusOK, ResponseSuccess{Message: "Login successful", Data: token})
-			return
+			return loginAccount{}, err
 		}
-	} else if userType == "trainer" {
-		trainer, err := getTrainer(credentials.Name)
+		return loginAccount{id: user.ID, password: user.Password, setPassword: func(ctx context.Context, hash string) error {
+			user.Password = hash
+			return h.users.Update(ctx, user)
+		}}, nil
+	case middleware.UserTypeTrainer:
+		trainer, err := h.trainers.GetByName(ctx, name)
 		if err != nil {
-			c.JSON(http.StatusNotFound, ResponseError{Error: "no trainer in db"})
-			return
+			return loginAccount{}, err
 		}
-		if trainer.Password == credentials.Password {
-			token, err := middleware.GenerateToken(uint(trainer.ID), userType)
-			if err != nil {
-				c.JSON(http.StatusInternalServerError, ResponseError{Error: "Error generating token"})
-				return
-			}
-			c.JSON(http.StatusOK, ResponseSuccess{Message: "Login successful", Data: token})
-			return
+		return loginAccount{id: trainer.ID, password: trainer.Password, setPassword: func(ctx context.Context, hash string) error {
+			trainer.Password = hash
+			return h.trainers.Update(ctx, trainer)
+		}}, nil
+	case middleware.UserTypeAdmin:
+		admin, err := h.admins.GetByName(ctx, name)
+		if err != nil {
+			return loginAccount{}, err
 		}
+		return loginAccount{id: admin.ID, password: admin.Password, setPassword: func(ctx context.Context, hash string) error {
+			admin.Password = hash
+			return h.admins.Update(ctx, admin)
+		}}, nil
 	}
-
-	c.JSON(http.StatusUnauthorized, ResponseError{Error: "Invalid credentials"})
+	return loginAccount{}, errUnknownAccountType
 }
 
-func getUser(username string) (*domain.User, error) {
-	for _, user := range users {
-		if user.Name == username {
-			return &user, nil
-		}
+// rehashPassword upgrades the stored hash to the current parameters. A failure
+// only delays the upgrade to the next login, so it does not fail the request.
+func (h *Handler) rehashPassword(ctx context.Context, userType string, account loginAccount, plain string) {
+	hash, err := h.hasher.Hash(plain)
+	if err != nil {
+		log.Printf("rehash password of %s %d: %v", userType, account.id, err)
+		return
 	}
-	return nil, xerrors.Errorf("not found")
-}
-
-func getTrainer(username string) (*domain.Trainer, error) {
-	for _, trainer := range trainers {
-		if trainer.Name == username {
-			return &trainer, nil
-		}
+	if err := account.setPassword(ctx, hash); err != nil {
+		log.Printf("store rehashed password of %s %d: %v", userType, account.id, err)
 	}
-	return nil, xerrors.Errorf("not found")
 }
 
 // Register godoc
@@ -111,33 +216,67 @@ func getTrainer(username string) (*domain.Trainer, error) {
 // @Accept json
 // @Produce json
 // @Param user_type path string true "User Type (user or trainer)"
-// @Param user body domain.User true "User data"
+// @Param user body RegisterRequest true "User data"
 // @Success 201 {object} ResponseSuccess
 // @Failure 400 {object} ResponseError
+// @Failure 409 {object} ResponseError
 // @Router /register/{user_type} [post]
-func Register(c *gin.Context) {
+func (h *Handler) Register(c *gin.Context) {
 	userType := c.Param("user_type")
 
+	var req RegisterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ResponseError{Error: "Invalid data"})
+		return
+	}
+
 	if userType == "user" {
-		var user domain.User
-		if err := c.ShouldBindJSON(&user); err != nil {
-			c.JSON(http.StatusBadRequest, ResponseError{Error: "Invalid data"})
+		hash, err := h.hasher.Hash(req.Password)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, ResponseError{Error: "Error registering user"})
+			return
+		}
+		user := domain.User{
+			Name:              req.Name,
+			Password:          hash,
+			Mail:              req.Mail,
+			Phone:             req.Phone,
+			HealthDescription: req.HealthDescription,
+		}
+		err = h.users.Create(c.Request.Context(), &user)
+		if xerrors.Is(err, storage.ErrAlreadyExists) {
+			c.JSON(http.StatusConflict, ResponseError{Error: "User with this name already exists"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, ResponseError{Error: "Error registering user"})
 			return
 		}
-		user.ID = userID
-		userID++
-		users = append(users, user)
 		c.JSON(http.StatusCreated, ResponseSuccess{Message: "User registered successfully"})
 	} else if userType == "trainer" {
-		var trainer domain.Trainer
-		if err := c.ShouldBindJSON(&trainer); err != nil {
-			c.JSON(http.StatusBadRequest, ResponseError{Error: "Invalid data"})
+		hash, err := h.hasher.Hash(req.Password)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, ResponseError{Error: "Error registering trainer"})
+			return
+		}
+		trainer := domain.Trainer{
+			Name:     req.Name,
+			Password: hash,
+			Mail:     req.Mail,
+			Phone:    req.Phone,
+		}
+		err = h.trainers.Create(c.Request.Context(), &trainer)
+		if xerrors.Is(err, storage.ErrAlreadyExists) {
+			c.JSON(http.StatusConflict, ResponseError{Error: "Trainer with this name already exists"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, ResponseError{Error: "Error registering trainer"})
 			return
 		}
-		trainer.ID = trainerID
-		trainerID++
-		trainers = append(trainers, trainer)
 		c.JSON(http.StatusCreated, ResponseSuccess{Message: "Trainer registered successfully"})
+	} else {
+		c.JSON(http.StatusBadRequest, ResponseError{Error: "Only users and trainers can register"})
 	}
 }
 
@@ -146,32 +285,30 @@ func Register(c *gin.Context) {
 // @Description Get the profile of the currently authenticated user
 // @Tags user
 // @Produce json
-// @Success 200 {object} ResponseSuccess{data=domain.User}
+// @Success 200 {object} ResponseSuccess{data=UserView}
 // @Failure 401 {object} handler.ResponseError
 // @Security BearerAuth
 // @Router /protected/profile [get]
-func Profile(c *gin.Context) {
-	userID := c.MustGet("user_id").(int)
-	userType := c.MustGet("user_type").(string)
+func (h *Handler) Profile(c *gin.Context) {
+	principal := middleware.MustPrincipal(c)
 
 	var userProfile interface{}
 	var message string
 
-	if userType == "user" {
-		for _, user := range users {
-			if user.ID == userID {
-				userProfile = user
-				message = "User profile retrieved successfully"
-				break
-			}
+	if principal.IsUser() {
+		if user, err := h.users.GetByID(c.Request.Context(), principal.ID); err == nil {
+			userProfile = newUserView(*user)
+			message = "User profile retrieved successfully"
 		}
-	} else if userType == "trainer" {
-		for _, trainer := range trainers {
-			if trainer.ID == userID {
-				userProfile = trainer
-				message = "Trainer profile retrieved successfully"
-				break
-			}
+	} else if principal.IsTrainer() {
+		if trainer, err := h.trainers.GetByID(c.Request.Context(), principal.ID); err == nil {
+			userProfile = newTrainerView(*trainer)
+			message = "Trainer profile retrieved successfully"
+		}
+	} else if principal.IsAdmin() {
+		if admin, err := h.admins.GetByID(c.Request.Context(), principal.ID); err == nil {
+			userProfile = newAdminView(*admin)
+			message = "Admin profile retrieved successfully"
 		}
 	}
 
@@ -187,3 +324,14 @@ func Profile(c *gin.Context) {
 
 	c.JSON(http.StatusOK, response)
 }
+
+// JWKS godoc
+// @Summary Get the token verification keys
+// @Description Get the public keys access tokens are signed with as a JSON Web Key Set
+// @Tags auth
+// @Produce json
+// @Success 200 {object} middleware.JWKS
+// @Router /.well-known/jwks.json [get]
+func (h *Handler) JWKS(c *gin.Context) {
+	c.JSON(http.StatusOK, h.tokens.Keys().JWKS())
+}
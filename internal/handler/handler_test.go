@@ -0,0 +1,243 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/folklinoff/fitness-app/internal/audit"
+	"github.com/folklinoff/fitness-app/internal/config"
+	"github.com/folklinoff/fitness-app/internal/domain"
+	"github.com/folklinoff/fitness-app/internal/handler"
+	middleware "github.com/folklinoff/fitness-app/internal/middleware/auth"
+	"github.com/folklinoff/fitness-app/internal/password"
+	"github.com/folklinoff/fitness-app/internal/payment"
+	"github.com/folklinoff/fitness-app/internal/policy"
+	"github.com/folklinoff/fitness-app/internal/storage"
+	"github.com/folklinoff/fitness-app/internal/storage/memory"
+	"github.com/gin-gonic/gin"
+	jwt "github.com/golang-jwt/jwt/v4"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// server is the API on top of a memory store, with the routes the tests use
+type server struct {
+	store  storage.Storage
+	tokens *middleware.TokenManager
+	router *gin.Engine
+}
+
+func newServer(t *testing.T, gateway payment.PaymentGateway, checkout config.Payments) *server {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	store := memory.New(storage.Options{})
+	hasher, err := password.NewHasher(password.Params{Algorithm: password.Bcrypt, BcryptCost: bcrypt.MinCost})
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret := []byte("test-secret")
+	keys, err := middleware.NewKeySet(middleware.Key{ID: "test", Method: jwt.SigningMethodHS256, Sign: secret, Verify: secret})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokens := middleware.NewTokenManager(keys, store.Sessions, middleware.TokenConfig{
+		TTL:        time.Hour,
+		RefreshTTL: 24 * time.Hour,
+		Issuer:     "fitness-app",
+		Audience:   "fitness-app",
+	})
+	h := handler.New(store, hasher, tokens, policy.New(store.Trainings, audit.New(store.Audit)), config.Booking{}, gateway, checkout, "https://fitness.example.com")
+
+	r := gin.New()
+	r.POST("/login/:user_type", h.Login)
+	r.POST("/register/:user_type", h.Register)
+	r.POST("/token/refresh", h.RefreshToken)
+	r.POST("/payments/webhook", h.PaymentWebhook)
+	r.GET("/calendar/:token", h.GetCalendarFeed)
+	protected := r.Group("/protected", middleware.AuthenticationMiddleware(tokens, nil))
+	protected.GET("/profile", h.Profile)
+	protected.POST("/logout", h.Logout)
+	protected.POST("/logout/all", h.LogoutAll)
+	protected.POST("/training/:id/register", h.RegisterUserForTraining)
+	protected.GET("/training/:id/registration", h.GetRegistration)
+	protected.POST("/training/:id/checkout", h.CheckoutTraining)
+	protected.POST("/calendar/feed", h.CreateCalendarFeed)
+	protected.POST("/training/:id/check-in", h.CheckIn)
+
+	return &server{store: store, tokens: tokens, router: r}
+}
+
+// do sends the request with the bearer token, when given, and returns the response
+func (s *server) do(method, path, token, body string, header http.Header) *httptest.ResponseRecorder {
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	req := httptest.NewRequest(method, path, reader)
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, req)
+	return rec
+}
+
+// login starts a session of the account and returns its tokens
+func (s *server) login(t *testing.T, p middleware.Principal) middleware.Tokens {
+	t.Helper()
+	tokens, err := s.tokens.IssueTokens(context.Background(), p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tokens
+}
+
+func (s *server) createUser(t *testing.T, name string) int {
+	t.Helper()
+	user := domain.User{Name: name, Password: "hash"}
+	if err := s.store.Users.Create(context.Background(), &user); err != nil {
+		t.Fatal(err)
+	}
+	return user.ID
+}
+
+func (s *server) createTraining(t *testing.T, training domain.Training) domain.Training {
+	t.Helper()
+	ctx := context.Background()
+	trainer := domain.Trainer{Name: "trainer " + training.Name, Password: "hash"}
+	if err := s.store.Trainers.Create(ctx, &trainer); err != nil {
+		t.Fatal(err)
+	}
+	training.TrainerID = trainer.ID
+	if training.StartTime.IsZero() {
+		training.StartTime = time.Now().Add(48 * time.Hour).Truncate(time.Hour)
+		training.EndTime = training.StartTime.Add(time.Hour)
+	}
+	if err := s.store.Trainings.Create(ctx, &training, false); err != nil {
+		t.Fatal(err)
+	}
+	return training
+}
+
+// decode unmarshals the data of a successful response into data
+func decode(t *testing.T, rec *httptest.ResponseRecorder, data any) {
+	t.Helper()
+	response := struct {
+		Data any `json:"data"`
+	}{Data: data}
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("decode %s: %v", rec.Body, err)
+	}
+}
+
+// message returns the message of a successful response
+func message(t *testing.T, rec *httptest.ResponseRecorder) string {
+	t.Helper()
+	var response handler.ResponseSuccess
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("decode %s: %v", rec.Body, err)
+	}
+	return response.Message
+}
+
+func TestLogin(t *testing.T) {
+	s := newServer(t, nil, config.Payments{})
+	ctx := context.Background()
+	// Hashed with another cost than the server uses, so logging in rehashes them
+	old, err := password.NewHasher(password.Params{Algorithm: password.Bcrypt, BcryptCost: bcrypt.MinCost + 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash, err := old.Hash("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := domain.User{Name: "alice", Password: hash}
+	trainer := domain.Trainer{Name: "bob", Password: hash}
+	admin := domain.Admin{Name: "root", Password: hash}
+	if err := s.store.Users.Create(ctx, &user); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.store.Trainers.Create(ctx, &trainer); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.store.Admins.Create(ctx, &admin); err != nil {
+		t.Fatal(err)
+	}
+	stored := map[string]func(t *testing.T) string{
+		middleware.UserTypeUser: func(t *testing.T) string {
+			u, err := s.store.Users.GetByName(ctx, user.Name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return u.Password
+		},
+		middleware.UserTypeTrainer: func(t *testing.T) string {
+			tr, err := s.store.Trainers.GetByName(ctx, trainer.Name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return tr.Password
+		},
+		middleware.UserTypeAdmin: func(t *testing.T) string {
+			a, err := s.store.Admins.GetByName(ctx, admin.Name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return a.Password
+		},
+	}
+
+	tests := []struct {
+		userType string
+		name     string
+		id       int
+	}{
+		{userType: middleware.UserTypeUser, name: user.Name, id: user.ID},
+		{userType: middleware.UserTypeTrainer, name: trainer.Name, id: trainer.ID},
+		{userType: middleware.UserTypeAdmin, name: admin.Name, id: admin.ID},
+	}
+	for _, tt := range tests {
+		t.Run(tt.userType, func(t *testing.T) {
+			path := "/login/" + tt.userType
+			if rec := s.do(http.MethodPost, path, "", `{"name":"`+tt.name+`","password":"wrong"}`, nil); rec.Code != http.StatusUnauthorized {
+				t.Errorf("wrong password: got %d, want 401", rec.Code)
+			}
+			if rec := s.do(http.MethodPost, path, "", `{"name":"nobody","password":"secret"}`, nil); rec.Code != http.StatusNotFound {
+				t.Errorf("unknown name: got %d, want 404", rec.Code)
+			}
+
+			rec := s.do(http.MethodPost, path, "", `{"name":"`+tt.name+`","password":"secret"}`, nil)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("login: got %d %s, want 200", rec.Code, rec.Body)
+			}
+			var tokens handler.TokenResponse
+			decode(t, rec, &tokens)
+			claims, err := s.tokens.VerifyToken(ctx, tokens.AccessToken)
+			if err != nil {
+				t.Fatalf("verify access token: %v", err)
+			}
+			if want := (middleware.Principal{ID: tt.id, Type: tt.userType}); claims.Principal() != want {
+				t.Errorf("logged in as %+v, want %+v", claims.Principal(), want)
+			}
+
+			rehashed := stored[tt.userType](t)
+			if cost, err := bcrypt.Cost([]byte(rehashed)); err != nil || cost != bcrypt.MinCost {
+				t.Errorf("stored hash cost %d (%v), want it rehashed with cost %d", cost, err, bcrypt.MinCost)
+			}
+		})
+	}
+
+	if rec := s.do(http.MethodPost, "/login/owner", "", `{"name":"root","password":"secret"}`, nil); rec.Code != http.StatusUnauthorized {
+		t.Errorf("unknown account type: got %d, want 401", rec.Code)
+	}
+}
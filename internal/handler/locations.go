@@ -0,0 +1,466 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/folklinoff/fitness-app/internal/domain"
+	"github.com/folklinoff/fitness-app/internal/storage"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/xerrors"
+)
+
+const minutesPerDay = 24 * 60
+
+// CreateLocation godoc
+// @Summary Create a location
+// @Description Create a gym or studio with its opening hours (only for admins)
+// @Tags location
+// @Accept json
+// @Produce json
+// @Param location body LocationRequest true "Location data"
+// @Success 201 {object} ResponseSuccess{data=LocationView}
+// @Failure 400 {object} ResponseError
+// @Failure 403 {object} ResponseError
+// @Failure 409 {object} ResponseError
+// @Security BearerAuth
+// @Router /protected/locations [post]
+func (h *Handler) CreateLocation(c *gin.Context) {
+	var location domain.Location
+	if !bindLocation(c, &location) {
+		return
+	}
+
+	err := h.locations.Create(c.Request.Context(), &location)
+	if xerrors.Is(err, storage.ErrAlreadyExists) {
+		c.JSON(http.StatusConflict, ResponseError{Error: "Location name already taken"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseError{Error: "Error creating location"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, ResponseSuccess{Message: "Location created successfully", Data: newLocationView(location, nil)})
+}
+
+// GetLocations godoc
+// @Summary Get all locations
+// @Description Get all locations with their opening hours and rooms
+// @Tags location
+// @Produce json
+// @Success 200 {object} ResponseSuccess{data=[]LocationView}
+// @Router /locations [get]
+func (h *Handler) GetLocations(c *gin.Context) {
+	ctx := c.Request.Context()
+	locations, err := h.locations.List(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseError{Error: "Error retrieving locations"})
+		return
+	}
+
+	views := make([]LocationView, 0, len(locations))
+	for _, location := range locations {
+		rooms, err := h.rooms.ListByLocation(ctx, location.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, ResponseError{Error: "Error retrieving locations"})
+			return
+		}
+		views = append(views, newLocationView(location, rooms))
+	}
+	c.JSON(http.StatusOK, ResponseSuccess{Message: "All locations retrieved", Data: views})
+}
+
+// GetLocation godoc
+// @Summary Get a location by ID
+// @Description Get a location with its opening hours and rooms
+// @Tags location
+// @Produce json
+// @Param id path int true "Location ID"
+// @Success 200 {object} ResponseSuccess{data=LocationView}
+// @Failure 400 {object} ResponseError
+// @Failure 404 {object} ResponseError
+// @Router /locations/{id} [get]
+func (h *Handler) GetLocation(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ResponseError{Error: "Invalid location ID: " + err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	location, err := h.locations.GetByID(ctx, id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, ResponseError{Error: "Location not found with ID " + strconv.Itoa(id)})
+		return
+	}
+	rooms, err := h.rooms.ListByLocation(ctx, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseError{Error: "Error retrieving location"})
+		return
+	}
+
+	c.JSON(http.StatusOK, ResponseSuccess{Message: "Location found", Data: newLocationView(*location, rooms)})
+}
+
+// UpdateLocation godoc
+// @Summary Update a location by ID
+// @Description Update a location and replace its opening hours (only for admins). Trainings already booked outside the new opening hours are kept.
+// @Tags location
+// @Accept json
+// @Produce json
+// @Param id path int true "Location ID"
+// @Param location body LocationRequest true "Updated location data"
+// @Success 200 {object} ResponseSuccess{data=LocationView}
+// @Failure 400 {object} ResponseError
+// @Failure 403 {object} ResponseError
+// @Failure 404 {object} ResponseError
+// @Failure 409 {object} ResponseError
+// @Security BearerAuth
+// @Router /protected/locations/{id} [put]
+func (h *Handler) UpdateLocation(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ResponseError{Error: "Invalid location ID: " + err.Error()})
+		return
+	}
+
+	location := domain.Location{ID: id}
+	if !bindLocation(c, &location) {
+		return
+	}
+
+	ctx := c.Request.Context()
+	err = h.locations.Update(ctx, &location)
+	switch {
+	case xerrors.Is(err, storage.ErrNotFound):
+		c.JSON(http.StatusNotFound, ResponseError{Error: "Location not found with ID " + strconv.Itoa(id)})
+		return
+	case xerrors.Is(err, storage.ErrAlreadyExists):
+		c.JSON(http.StatusConflict, ResponseError{Error: "Location name already taken"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, ResponseError{Error: "Error updating location"})
+		return
+	}
+	rooms, err := h.rooms.ListByLocation(ctx, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseError{Error: "Error retrieving location"})
+		return
+	}
+
+	c.JSON(http.StatusOK, ResponseSuccess{Message: "Location updated successfully", Data: newLocationView(location, rooms)})
+}
+
+// DeleteLocation godoc
+// @Summary Delete a location by ID
+// @Description Delete a location with its rooms (only for admins), trainings in them are kept without a room
+// @Tags location
+// @Produce json
+// @Param id path int true "Location ID"
+// @Success 200 {object} ResponseSuccess
+// @Failure 400 {object} ResponseError
+// @Failure 403 {object} ResponseError
+// @Failure 404 {object} ResponseError
+// @Security BearerAuth
+// @Router /protected/locations/{id} [delete]
+func (h *Handler) DeleteLocation(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ResponseError{Error: "Invalid location ID: " + err.Error()})
+		return
+	}
+
+	err = h.locations.Delete(c.Request.Context(), id)
+	if xerrors.Is(err, storage.ErrNotFound) {
+		c.JSON(http.StatusNotFound, ResponseError{Error: "Location not found with ID " + strconv.Itoa(id)})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseError{Error: "Error deleting location"})
+		return
+	}
+	c.JSON(http.StatusOK, ResponseSuccess{Message: "Location deleted successfully"})
+}
+
+// CreateRoom godoc
+// @Summary Create a room of a location
+// @Description Create a room of a location (only for admins)
+// @Tags location
+// @Accept json
+// @Produce json
+// @Param id path int true "Location ID"
+// @Param room body RoomRequest true "Room data"
+// @Success 201 {object} ResponseSuccess{data=RoomView}
+// @Failure 400 {object} ResponseError
+// @Failure 403 {object} ResponseError
+// @Failure 404 {object} ResponseError
+// @Failure 409 {object} ResponseError
+// @Security BearerAuth
+// @Router /protected/locations/{id}/rooms [post]
+func (h *Handler) CreateRoom(c *gin.Context) {
+	locationID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ResponseError{Error: "Invalid location ID: " + err.Error()})
+		return
+	}
+
+	var req RoomRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ResponseError{Error: "Invalid data: " + err.Error()})
+		return
+	}
+
+	room := domain.Room{LocationID: locationID, Name: req.Name, Capacity: req.Capacity}
+	err = h.rooms.Create(c.Request.Context(), &room)
+	switch {
+	case xerrors.Is(err, storage.ErrNotFound):
+		c.JSON(http.StatusNotFound, ResponseError{Error: "Location not found with ID " + strconv.Itoa(locationID)})
+		return
+	case xerrors.Is(err, storage.ErrAlreadyExists):
+		c.JSON(http.StatusConflict, ResponseError{Error: "The location already has a room with this name"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, ResponseError{Error: "Error creating room"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, ResponseSuccess{Message: "Room created successfully", Data: newRoomView(room)})
+}
+
+// UpdateRoom godoc
+// @Summary Update a room by ID
+// @Description Update a room (only for admins). Trainings already booked with more seats than the new capacity are kept.
+// @Tags location
+// @Accept json
+// @Produce json
+// @Param id path int true "Room ID"
+// @Param room body RoomRequest true "Updated room data"
+// @Success 200 {object} ResponseSuccess{data=RoomView}
+// @Failure 400 {object} ResponseError
+// @Failure 403 {object} ResponseError
+// @Failure 404 {object} ResponseError
+// @Failure 409 {object} ResponseError
+// @Security BearerAuth
+// @Router /protected/rooms/{id} [put]
+func (h *Handler) UpdateRoom(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ResponseError{Error: "Invalid room ID: " + err.Error()})
+		return
+	}
+
+	var req RoomRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ResponseError{Error: "Invalid data: " + err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	room, err := h.rooms.GetByID(ctx, id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, ResponseError{Error: "Room not found with ID " + strconv.Itoa(id)})
+		return
+	}
+	room.Name, room.Capacity = req.Name, req.Capacity
+	err = h.rooms.Update(ctx, room)
+	switch {
+	case xerrors.Is(err, storage.ErrNotFound):
+		c.JSON(http.StatusNotFound, ResponseError{Error: "Room not found with ID " + strconv.Itoa(id)})
+		return
+	case xerrors.Is(err, storage.ErrAlreadyExists):
+		c.JSON(http.StatusConflict, ResponseError{Error: "The location already has a room with this name"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, ResponseError{Error: "Error updating room"})
+		return
+	}
+
+	c.JSON(http.StatusOK, ResponseSuccess{Message: "Room updated successfully", Data: newRoomView(*room)})
+}
+
+// DeleteRoom godoc
+// @Summary Delete a room by ID
+// @Description Delete a room (only for admins), trainings in it are kept without a room
+// @Tags location
+// @Produce json
+// @Param id path int true "Room ID"
+// @Success 200 {object} ResponseSuccess
+// @Failure 400 {object} ResponseError
+// @Failure 403 {object} ResponseError
+// @Failure 404 {object} ResponseError
+// @Security BearerAuth
+// @Router /protected/rooms/{id} [delete]
+func (h *Handler) DeleteRoom(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ResponseError{Error: "Invalid room ID: " + err.Error()})
+		return
+	}
+
+	err = h.rooms.Delete(c.Request.Context(), id)
+	if xerrors.Is(err, storage.ErrNotFound) {
+		c.JSON(http.StatusNotFound, ResponseError{Error: "Room not found with ID " + strconv.Itoa(id)})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseError{Error: "Error deleting room"})
+		return
+	}
+	c.JSON(http.StatusOK, ResponseSuccess{Message: "Room deleted successfully"})
+}
+
+// bindLocation reads the location request into location, it writes the error
+// response itself
+func bindLocation(c *gin.Context, location *domain.Location) bool {
+	var req LocationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ResponseError{Error: "Invalid data: " + err.Error()})
+		return false
+	}
+	loc, err := time.LoadLocation(req.TimeZone)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ResponseError{Error: "Invalid time zone: " + req.TimeZone})
+		return false
+	}
+
+	location.Name, location.Address, location.TimeZone = req.Name, req.Address, loc.String()
+	location.OpeningHours = nil
+	for _, r := range req.OpeningHours {
+		hours, err := r.parse()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ResponseError{Error: "Invalid opening hours: " + err.Error()})
+			return false
+		}
+		location.OpeningHours = append(location.OpeningHours, hours)
+	}
+	return true
+}
+
+// checkRoom validates the room a training is booked into and caps the capacity
+// to the room capacity, a capacity of 0 becomes the room capacity. It returns
+// the location of the room, or nil when no room is given, and writes the error
+// response itself.
+func (h *Handler) checkRoom(c *gin.Context, roomID *int, capacity *int) (*domain.Location, bool) {
+	if roomID == nil {
+		return nil, true
+	}
+
+	ctx := c.Request.Context()
+	room, err := h.rooms.GetByID(ctx, *roomID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, ResponseError{Error: "Room not found with ID " + strconv.Itoa(*roomID)})
+		return nil, false
+	}
+	if *capacity > room.Capacity {
+		c.JSON(http.StatusBadRequest, ResponseError{Error: "Capacity exceeds the capacity of room " + room.Name + " (" + strconv.Itoa(room.Capacity) + ")"})
+		return nil, false
+	}
+	if *capacity == 0 {
+		*capacity = room.Capacity
+	}
+
+	location, err := h.locations.GetByID(ctx, room.LocationID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseError{Error: "Error retrieving the location of room " + strconv.Itoa(room.ID)})
+		return nil, false
+	}
+	return location, true
+}
+
+// checkOpeningHours rejects trainings outside the opening hours of the location
+// with 400, it writes the error response itself
+func checkOpeningHours(c *gin.Context, location *domain.Location, trainings []domain.Training) bool {
+	if location == nil || len(location.OpeningHours) == 0 {
+		return true
+	}
+	loc, err := time.LoadLocation(location.TimeZone)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseError{Error: "Invalid time zone of location " + strconv.Itoa(location.ID)})
+		return false
+	}
+	for _, t := range trainings {
+		if !isOpen(location.OpeningHours, t.StartTime.In(loc), t.EndTime.In(loc)) {
+			c.JSON(http.StatusBadRequest, ResponseError{
+				Error: "Training at " + t.StartTime.In(loc).Format(time.RFC3339) + " is outside the opening hours of " + location.Name,
+			})
+			return false
+		}
+	}
+	return true
+}
+
+// isOpen reports whether the interval lies within one span of the opening hours,
+// start and end are in the time zone of the location
+func isOpen(hours []domain.OpeningHours, start, end time.Time) bool {
+	day := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, start.Location())
+	opens := start.Hour()*60 + start.Minute()
+	var closes int
+	switch next := day.AddDate(0, 0, 1); {
+	case end.Before(next):
+		closes = end.Hour()*60 + end.Minute()
+		if end.Second() > 0 || end.Nanosecond() > 0 {
+			closes++
+		}
+	case end.Equal(next):
+		closes = minutesPerDay
+	default:
+		return false
+	}
+
+	for _, h := range hours {
+		if h.Weekday == start.Weekday() && h.Opens <= opens && closes <= h.Closes {
+			return true
+		}
+	}
+	return false
+}
+
+func (r OpeningHoursRequest) parse() (domain.OpeningHours, error) {
+	var hours domain.OpeningHours
+	weekday, ok := weekdays[strings.ToLower(r.Weekday)]
+	if !ok {
+		return hours, xerrors.Errorf("unknown weekday %q", r.Weekday)
+	}
+	opens, err := parseClock(r.Opens)
+	if err != nil {
+		return hours, err
+	}
+	closes, err := parseClock(r.Closes)
+	if err != nil {
+		return hours, err
+	}
+	if closes <= opens {
+		return hours, xerrors.Errorf("%s closes at %s before it opens at %s", r.Weekday, r.Closes, r.Opens)
+	}
+	return domain.OpeningHours{Weekday: weekday, Opens: opens, Closes: closes}, nil
+}
+
+var weekdays = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+}
+
+// parseClock parses a time of day in the form 15:04 into minutes after midnight
+func parseClock(s string) (int, error) {
+	if s == "24:00" {
+		return minutesPerDay, nil
+	}
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, xerrors.Errorf("invalid time of day %q, expected HH:MM", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func formatClock(minutes int) string {
+	return fmt.Sprintf("%02d:%02d", minutes/60, minutes%60)
+}
@@ -0,0 +1,310 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/folklinoff/fitness-app/internal/audit"
+	"github.com/folklinoff/fitness-app/internal/domain"
+	middleware "github.com/folklinoff/fitness-app/internal/middleware/auth"
+	"github.com/folklinoff/fitness-app/internal/policy"
+	"github.com/folklinoff/fitness-app/internal/storage"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/xerrors"
+)
+
+// CreatePlan godoc
+// @Summary Create a membership plan
+// @Description Create a monthly unlimited plan, a punch card of a number of sessions or a drop-in credit (only for admins)
+// @Tags membership
+// @Accept json
+// @Produce json
+// @Param plan body PlanRequest true "Plan data"
+// @Success 201 {object} ResponseSuccess{data=PlanView}
+// @Failure 400 {object} ResponseError
+// @Failure 403 {object} ResponseError
+// @Failure 409 {object} ResponseError
+// @Security BearerAuth
+// @Router /protected/plans [post]
+func (h *Handler) CreatePlan(c *gin.Context) {
+	var plan domain.Plan
+	if !bindPlan(c, &plan) {
+		return
+	}
+
+	err := h.plans.Create(c.Request.Context(), &plan)
+	if xerrors.Is(err, storage.ErrAlreadyExists) {
+		c.JSON(http.StatusConflict, ResponseError{Error: "Plan name already taken"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseError{Error: "Error creating plan"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, ResponseSuccess{Message: "Plan created successfully", Data: newPlanView(plan)})
+}
+
+// GetPlans godoc
+// @Summary Get the membership plans on sale
+// @Description Get the active membership plans
+// @Tags membership
+// @Produce json
+// @Success 200 {object} ResponseSuccess{data=[]PlanView}
+// @Router /plans [get]
+func (h *Handler) GetPlans(c *gin.Context) {
+	h.listPlans(c, true)
+}
+
+// GetAllPlans godoc
+// @Summary Get all membership plans
+// @Description Get the membership plans including the inactive ones (only for admins)
+// @Tags membership
+// @Produce json
+// @Success 200 {object} ResponseSuccess{data=[]PlanView}
+// @Failure 403 {object} ResponseError
+// @Security BearerAuth
+// @Router /protected/plans [get]
+func (h *Handler) GetAllPlans(c *gin.Context) {
+	h.listPlans(c, false)
+}
+
+// UpdatePlan godoc
+// @Summary Update a membership plan by ID
+// @Description Update a membership plan or stop selling it by making it inactive (only for admins). Memberships already granted are kept as they are.
+// @Tags membership
+// @Accept json
+// @Produce json
+// @Param id path int true "Plan ID"
+// @Param plan body PlanRequest true "Updated plan data"
+// @Success 200 {object} ResponseSuccess{data=PlanView}
+// @Failure 400 {object} ResponseError
+// @Failure 403 {object} ResponseError
+// @Failure 404 {object} ResponseError
+// @Failure 409 {object} ResponseError
+// @Security BearerAuth
+// @Router /protected/plans/{id} [put]
+func (h *Handler) UpdatePlan(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ResponseError{Error: "Invalid plan ID: " + err.Error()})
+		return
+	}
+
+	plan := domain.Plan{ID: id}
+	if !bindPlan(c, &plan) {
+		return
+	}
+
+	err = h.plans.Update(c.Request.Context(), &plan)
+	switch {
+	case xerrors.Is(err, storage.ErrNotFound):
+		c.JSON(http.StatusNotFound, ResponseError{Error: "Plan not found with ID " + strconv.Itoa(id)})
+		return
+	case xerrors.Is(err, storage.ErrAlreadyExists):
+		c.JSON(http.StatusConflict, ResponseError{Error: "Plan name already taken"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, ResponseError{Error: "Error updating plan"})
+		return
+	}
+
+	c.JSON(http.StatusOK, ResponseSuccess{Message: "Plan updated successfully", Data: newPlanView(plan)})
+}
+
+// GrantMembership godoc
+// @Summary Grant a membership to a user
+// @Description Grant an active plan to a user (only for admins). The membership is valid for the days of the plan from its start and starts with the sessions of the plan as credits.
+// @Tags membership
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param membership body MembershipRequest true "Granted plan"
+// @Success 201 {object} ResponseSuccess{data=MembershipView}
+// @Failure 400 {object} ResponseError
+// @Failure 403 {object} ResponseError
+// @Failure 404 {object} ResponseError
+// @Failure 409 {object} ResponseError
+// @Security BearerAuth
+// @Router /protected/users/{id}/memberships [post]
+func (h *Handler) GrantMembership(c *gin.Context) {
+	userID, ok := accountID(c, middleware.UserTypeUser)
+	if !ok {
+		return
+	}
+
+	var req MembershipRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ResponseError{Error: "Invalid data: " + err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	plan, err := h.plans.GetByID(ctx, req.PlanID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, ResponseError{Error: "Plan not found with ID " + strconv.Itoa(req.PlanID)})
+		return
+	}
+	if !plan.Active {
+		c.JSON(http.StatusConflict, ResponseError{Error: "Plan is not active"})
+		return
+	}
+
+	membership := newMembership(*plan, userID, time.Now(), req.StartsAt)
+	err = h.memberships.Grant(ctx, &membership)
+	if xerrors.Is(err, storage.ErrNotFound) {
+		c.JSON(http.StatusNotFound, ResponseError{Error: "User not found with ID " + strconv.Itoa(userID)})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseError{Error: "Error granting membership"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, ResponseSuccess{Message: "Membership granted", Data: newMembershipView(membership)})
+}
+
+// GetUserMemberships godoc
+// @Summary Get the memberships of a user
+// @Description Get the memberships of a user with their credits left, visible to the user and admins
+// @Tags membership
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {object} ResponseSuccess{data=[]MembershipView}
+// @Failure 400 {object} ResponseError
+// @Failure 403 {object} ResponseError
+// @Security BearerAuth
+// @Router /protected/users/{id}/memberships [get]
+func (h *Handler) GetUserMemberships(c *gin.Context) {
+	userID, ok := h.membershipOwner(c)
+	if !ok {
+		return
+	}
+
+	memberships, err := h.memberships.ListByUser(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseError{Error: "Error retrieving memberships"})
+		return
+	}
+
+	views := make([]MembershipView, 0, len(memberships))
+	for _, membership := range memberships {
+		views = append(views, newMembershipView(membership))
+	}
+	c.JSON(http.StatusOK, ResponseSuccess{Message: "Memberships retrieved", Data: views})
+}
+
+// GetUserLedger godoc
+// @Summary Get the credit ledger of a user
+// @Description Get the balance of a user and the history of their credits: granted memberships, credits consumed by registrations and refunded by cancellations or deleted trainings. Visible to the user and admins.
+// @Tags membership
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {object} ResponseSuccess{data=LedgerView}
+// @Failure 400 {object} ResponseError
+// @Failure 403 {object} ResponseError
+// @Security BearerAuth
+// @Router /protected/users/{id}/ledger [get]
+func (h *Handler) GetUserLedger(c *gin.Context) {
+	userID, ok := h.membershipOwner(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	memberships, err := h.memberships.ListByUser(ctx, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseError{Error: "Error retrieving ledger"})
+		return
+	}
+	entries, err := h.memberships.Ledger(ctx, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseError{Error: "Error retrieving ledger"})
+		return
+	}
+
+	c.JSON(http.StatusOK, ResponseSuccess{Message: "Ledger retrieved", Data: newLedgerView(memberships, entries, time.Now())})
+}
+
+func (h *Handler) listPlans(c *gin.Context, activeOnly bool) {
+	plans, err := h.plans.List(c.Request.Context(), activeOnly)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseError{Error: "Error retrieving plans"})
+		return
+	}
+
+	views := make([]PlanView, 0, len(plans))
+	for _, plan := range plans {
+		views = append(views, newPlanView(plan))
+	}
+	c.JSON(http.StatusOK, ResponseSuccess{Message: "Plans retrieved", Data: views})
+}
+
+// membershipOwner returns the ID of the user whose memberships are requested if
+// the principal has full access to the account, otherwise it responds with an error
+func (h *Handler) membershipOwner(c *gin.Context) (int, bool) {
+	principal := middleware.MustPrincipal(c)
+	userID, ok := accountID(c, middleware.UserTypeUser)
+	if !ok {
+		return 0, false
+	}
+
+	access, err := h.policy.ViewAccount(c.Request.Context(), principal, middleware.UserTypeUser, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseError{Error: "Error retrieving memberships"})
+		return 0, false
+	}
+	if access != policy.Full {
+		h.deny(c, principal, policy.ActionViewAccount, audit.Resource(middleware.UserTypeUser, userID), "Not allowed to view the memberships of this user")
+		return 0, false
+	}
+	return userID, true
+}
+
+// newMembership returns the membership of the plan granted to the user at the
+// given time, starting then unless a start is given
+func newMembership(plan domain.Plan, userID int, now time.Time, startsAt *time.Time) domain.Membership {
+	start := now
+	if startsAt != nil {
+		start = startsAt.UTC()
+	}
+	return domain.Membership{
+		UserID:    userID,
+		PlanID:    plan.ID,
+		Kind:      plan.Kind,
+		Credits:   plan.Sessions,
+		StartsAt:  start,
+		ExpiresAt: start.AddDate(0, 0, plan.ValidDays),
+		CreatedAt: now,
+	}
+}
+
+// bindPlan reads the plan from the request body, otherwise it responds with an error
+func bindPlan(c *gin.Context, plan *domain.Plan) bool {
+	var req PlanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ResponseError{Error: "Invalid data: " + err.Error()})
+		return false
+	}
+
+	kind := domain.PlanKind(req.Kind)
+	switch {
+	case kind == domain.PlanUnlimited && req.Sessions != 0:
+		c.JSON(http.StatusBadRequest, ResponseError{Error: "Unlimited plans have no sessions"})
+		return false
+	case kind == domain.PlanPass && req.Sessions == 0:
+		c.JSON(http.StatusBadRequest, ResponseError{Error: "Pass plans need at least one session"})
+		return false
+	case kind == domain.PlanDropIn && req.Sessions > 1:
+		c.JSON(http.StatusBadRequest, ResponseError{Error: "Drop-in plans have a single session"})
+		return false
+	case kind == domain.PlanDropIn:
+		req.Sessions = 1
+	}
+
+	plan.Name, plan.Kind, plan.Sessions, plan.ValidDays = req.Name, kind, req.Sessions, req.ValidDays
+	plan.Price = req.Price
+	plan.Active = req.Active == nil || *req.Active
+	return true
+}
@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/folklinoff/fitness-app/internal/domain"
+)
+
+// notify queues the notification of the event about the training for the users.
+// Failing to queue it does not fail the request, which has already succeeded.
+func (h *Handler) notify(ctx context.Context, kind domain.NotificationKind, training domain.Training, userIDs ...int) {
+	if len(userIDs) == 0 {
+		return
+	}
+	now := time.Now()
+	notifications := make([]domain.Notification, 0, len(userIDs))
+	for _, userID := range userIDs {
+		notifications = append(notifications, training.Notification(kind, userID, now))
+	}
+	if err := h.outbox.Enqueue(ctx, notifications); err != nil {
+		log.Printf("queue %s notifications for training %d: %v", kind, training.ID, err)
+	}
+}
+
+// notifyRegistrants queues the notification for the users with a seat, a held
+// seat or a waitlist place in the training
+func (h *Handler) notifyRegistrants(ctx context.Context, kind domain.NotificationKind, training domain.Training) {
+	userIDs, err := h.trainings.ListRegistrants(ctx, training.ID)
+	if err != nil {
+		log.Printf("list registrants of training %d: %v", training.ID, err)
+		return
+	}
+	h.notify(ctx, kind, training, userIDs...)
+}
+
+// listRegistrants returns the registrants of the trainings by training ID, it
+// is called before the trainings are deleted as their registrations go with them
+func (h *Handler) listRegistrants(ctx context.Context, trainingIDs []int) (map[int][]int, error) {
+	registrants := make(map[int][]int, len(trainingIDs))
+	for _, id := range trainingIDs {
+		userIDs, err := h.trainings.ListRegistrants(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		registrants[id] = userIDs
+	}
+	return registrants, nil
+}
+
+// notifyCancelled queues the cancellation of the trainings for the registrants
+// listed before they were deleted
+func (h *Handler) notifyCancelled(ctx context.Context, trainings []domain.Training, registrants map[int][]int) {
+	for _, training := range trainings {
+		h.notify(ctx, domain.NotificationCancelled, training, registrants[training.ID]...)
+	}
+}
+
+// notifyUpdated queues the update notifications of the changed trainings whose
+// name or times differ from before, with the sequence the storage gave them
+func (h *Handler) notifyUpdated(ctx context.Context, before, changed []domain.Training) {
+	previous := make(map[int]domain.Training, len(before))
+	for _, training := range before {
+		previous[training.ID] = training
+	}
+	for _, training := range changed {
+		if old, ok := previous[training.ID]; ok && !changedForUsers(old, training) {
+			continue
+		}
+		if stored, err := h.trainings.GetByID(ctx, training.ID); err == nil {
+			training = *stored
+		}
+		h.notifyRegistrants(ctx, domain.NotificationUpdated, training)
+	}
+}
+
+// changedForUsers reports whether the update changed what the users of the
+// training are told about it: its name or times
+func changedForUsers(before, after domain.Training) bool {
+	return before.Name != after.Name || !before.StartTime.Equal(after.StartTime) || !before.EndTime.Equal(after.EndTime)
+}
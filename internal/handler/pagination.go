@@ -0,0 +1,145 @@
+package handler
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/folklinoff/fitness-app/internal/domain"
+	"github.com/folklinoff/fitness-app/internal/storage"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 500
+
+	// usersSort is the sort order of the cursors of user listings
+	usersSort = "id"
+)
+
+// cursor is the position after the last item of a page, it is handed out
+// base64-encoded and only valid for the sort order it was created for
+type cursor struct {
+	Sort      string    `json:"s"`
+	StartTime time.Time `json:"t,omitempty"`
+	Name      string    `json:"n,omitempty"`
+	ID        int       `json:"i"`
+}
+
+func (cur cursor) encode() string {
+	data, _ := json.Marshal(cur)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// bindPage reads the limit and cursor query parameters, a cursor of another sort
+// order is rejected. It writes the error response itself.
+func bindPage(c *gin.Context, sort string) (*cursor, int, bool) {
+	limit := defaultPageLimit
+	if raw := c.Query("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, ResponseError{Error: "Invalid limit"})
+			return nil, 0, false
+		}
+		limit = min(n, maxPageLimit)
+	}
+
+	raw := c.Query("cursor")
+	if raw == "" {
+		return nil, limit, true
+	}
+	var cur cursor
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err == nil {
+		err = json.Unmarshal(data, &cur)
+	}
+	if err != nil || cur.Sort != sort {
+		c.JSON(http.StatusBadRequest, ResponseError{Error: "Invalid cursor"})
+		return nil, 0, false
+	}
+	return &cur, limit, true
+}
+
+// bindTrainingFilter reads the filter, sort order and page of a training listing.
+// It writes the error response itself.
+func bindTrainingFilter(c *gin.Context) (storage.TrainingFilter, *storage.TrainingCursor, int, bool) {
+	filter := storage.TrainingFilter{
+		Type:   c.Query("type"),
+		Level:  c.Query("level"),
+		Search: c.Query("q"),
+		Sort:   storage.TrainingSort(c.DefaultQuery("sort", string(storage.SortByStartTime))),
+	}
+	switch filter.Sort {
+	case storage.SortByStartTime, storage.SortByStartTimeDesc, storage.SortByName, storage.SortByNameDesc:
+	default:
+		c.JSON(http.StatusBadRequest, ResponseError{Error: "Invalid sort, expected start_time, -start_time, name or -name"})
+		return filter, nil, 0, false
+	}
+
+	ids := []struct {
+		name  string
+		value *int
+	}{{"trainer_id", &filter.TrainerID}, {"location_id", &filter.LocationID}}
+	for _, id := range ids {
+		if raw := c.Query(id.name); raw != "" {
+			n, err := strconv.Atoi(raw)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, ResponseError{Error: "Invalid " + id.name + ": " + err.Error()})
+				return filter, nil, 0, false
+			}
+			*id.value = n
+		}
+	}
+	bounds := []struct {
+		name  string
+		value *time.Time
+	}{{"from", &filter.From}, {"to", &filter.To}}
+	for _, bound := range bounds {
+		if raw := c.Query(bound.name); raw != "" {
+			t, err := time.Parse(time.RFC3339, raw)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, ResponseError{Error: "Invalid " + bound.name + ", expected an RFC 3339 time"})
+				return filter, nil, 0, false
+			}
+			*bound.value = t
+		}
+	}
+	if raw := c.Query("has_free_spots"); raw != "" {
+		free, err := strconv.ParseBool(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ResponseError{Error: "Invalid has_free_spots: " + err.Error()})
+			return filter, nil, 0, false
+		}
+		filter.FreeSpots = free
+	}
+
+	cur, limit, ok := bindPage(c, string(filter.Sort))
+	if !ok {
+		return filter, nil, 0, false
+	}
+	var after *storage.TrainingCursor
+	if cur != nil {
+		after = &storage.TrainingCursor{StartTime: cur.StartTime, Name: cur.Name, ID: cur.ID}
+	}
+	return filter, after, limit, true
+}
+
+// respondTrainings writes a page of trainings with the cursor of the next one
+func respondTrainings(c *gin.Context, message string, sort storage.TrainingSort, page storage.Page[domain.Training]) {
+	var next string
+	if page.More {
+		last := page.Items[len(page.Items)-1]
+		cur := cursor{Sort: string(sort), ID: last.ID}
+		switch sort {
+		case storage.SortByName, storage.SortByNameDesc:
+			cur.Name = last.Name
+		default:
+			cur.StartTime = last.StartTime
+		}
+		next = cur.encode()
+	}
+	c.JSON(http.StatusOK, ResponsePage{Message: message, Data: newTrainingViews(page.Items), Total: page.Total, NextCursor: next})
+}
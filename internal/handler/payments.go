@@ -0,0 +1,338 @@
+package handler
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/folklinoff/fitness-app/internal/domain"
+	middleware "github.com/folklinoff/fitness-app/internal/middleware/auth"
+	"github.com/folklinoff/fitness-app/internal/payment"
+	"github.com/folklinoff/fitness-app/internal/storage"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/xerrors"
+)
+
+// maxWebhookSize bounds the body of webhook requests, gateway events are a few KB
+const maxWebhookSize = 1 << 16
+
+// CheckoutTraining godoc
+// @Summary Pay for a held seat
+// @Description Start a new checkout for the seat of the current user held pending payment (only for users), for example when the previous checkout was abandoned. The seat is confirmed once the payment succeeds before payment_due.
+// @Tags payment
+// @Produce json
+// @Param training_id path int true "Training ID"
+// @Success 201 {object} ResponseSuccess{data=CheckoutView}
+// @Failure 400 {object} ResponseError
+// @Failure 403 {object} ResponseError
+// @Failure 404 {object} ResponseError
+// @Failure 409 {object} ResponseError
+// @Failure 502 {object} ResponseError
+// @Security BearerAuth
+// @Router /protected/training/{training_id}/checkout [post]
+func (h *Handler) CheckoutTraining(c *gin.Context) {
+	principal := middleware.MustPrincipal(c)
+	trainingID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ResponseError{Error: "Invalid training ID: " + err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	training, err := h.trainings.GetByID(ctx, trainingID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, ResponseError{Error: "Training not found with ID " + strconv.Itoa(trainingID)})
+		return
+	}
+	registration, err := h.trainings.GetRegistration(ctx, trainingID, principal.ID)
+	if xerrors.Is(err, storage.ErrNotFound) {
+		c.JSON(http.StatusNotFound, ResponseError{Error: "User is not registered for this training"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseError{Error: "Error retrieving registration"})
+		return
+	}
+	if registration.Status != domain.RegistrationPending {
+		c.JSON(http.StatusConflict, ResponseError{Error: "No seat is held pending payment for this user"})
+		return
+	}
+	if !time.Now().Before(registration.HeldAt.Add(h.checkout.Hold)) {
+		c.JSON(http.StatusConflict, ResponseError{Error: "The seat is no longer held"})
+		return
+	}
+
+	checkout, err := h.checkoutTraining(ctx, *training, *registration)
+	if err != nil {
+		log.Printf("start checkout for training %d of user %d: %v", trainingID, principal.ID, err)
+		c.JSON(http.StatusBadGateway, ResponseError{Error: "Error starting the checkout"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, ResponseSuccess{Message: "Checkout started", Data: checkout})
+}
+
+// CheckoutPlan godoc
+// @Summary Buy a membership plan
+// @Description Start a checkout for an active plan with a price (only for users). The membership is granted from the time the payment succeeds.
+// @Tags payment
+// @Produce json
+// @Param id path int true "Plan ID"
+// @Success 201 {object} ResponseSuccess{data=CheckoutView}
+// @Failure 400 {object} ResponseError
+// @Failure 403 {object} ResponseError
+// @Failure 404 {object} ResponseError
+// @Failure 409 {object} ResponseError
+// @Failure 502 {object} ResponseError
+// @Security BearerAuth
+// @Router /protected/plans/{id}/checkout [post]
+func (h *Handler) CheckoutPlan(c *gin.Context) {
+	principal := middleware.MustPrincipal(c)
+	planID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ResponseError{Error: "Invalid plan ID: " + err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	plan, err := h.plans.GetByID(ctx, planID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, ResponseError{Error: "Plan not found with ID " + strconv.Itoa(planID)})
+		return
+	}
+	if !plan.Active || plan.Price == 0 {
+		c.JSON(http.StatusConflict, ResponseError{Error: "Plan is not sold online"})
+		return
+	}
+
+	purchase := domain.Payment{
+		UserID:    principal.ID,
+		PlanID:    &plan.ID,
+		Amount:    plan.Price,
+		Currency:  h.checkout.Currency,
+		Status:    domain.PaymentPending,
+		CreatedAt: time.Now(),
+	}
+	checkout, err := h.startCheckout(ctx, &purchase, plan.Name, purchase.CreatedAt.Add(h.checkout.Hold))
+	if err != nil {
+		log.Printf("start checkout for plan %d of user %d: %v", planID, principal.ID, err)
+		c.JSON(http.StatusBadGateway, ResponseError{Error: "Error starting the checkout"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, ResponseSuccess{Message: "Checkout started", Data: checkout})
+}
+
+// PaymentWebhook godoc
+// @Summary Receive a payment gateway event
+// @Description Apply a signed event of the payment gateway. Completed checkouts are captured and confirm the held seat or grant the membership, the authorization of payments whose seat is no longer held is cancelled without capturing it. Each event is applied once, redelivered events are acknowledged without effect.
+// @Tags payment
+// @Accept json
+// @Produce json
+// @Success 200 {object} ResponseSuccess
+// @Failure 400 {object} ResponseError
+// @Failure 502 {object} ResponseError
+// @Router /payments/webhook [post]
+func (h *Handler) PaymentWebhook(c *gin.Context) {
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxWebhookSize))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ResponseError{Error: "Invalid webhook body"})
+		return
+	}
+	event, err := h.gateway.VerifyWebhook(c.Request.Header, body)
+	if xerrors.Is(err, payment.ErrInvalidSignature) {
+		c.JSON(http.StatusBadRequest, ResponseError{Error: "Invalid webhook signature"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ResponseError{Error: "Invalid webhook event"})
+		return
+	}
+	if event.Type != payment.EventCheckoutCompleted && event.Type != payment.EventCheckoutFailed {
+		c.JSON(http.StatusOK, ResponseSuccess{Message: "Event ignored"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	purchase, err := h.payments.GetBySession(ctx, event.SessionID)
+	if xerrors.Is(err, storage.ErrNotFound) {
+		c.JSON(http.StatusOK, ResponseSuccess{Message: "Event ignored"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseError{Error: "Error processing event"})
+		return
+	}
+
+	if event.Type == payment.EventCheckoutFailed {
+		_, err = h.payments.Fail(ctx, event.ID, purchase.ID)
+	} else {
+		err = h.settle(ctx, *event, *purchase)
+	}
+	switch {
+	case xerrors.Is(err, storage.ErrAlreadyExists):
+		c.JSON(http.StatusOK, ResponseSuccess{Message: "Event already processed"})
+	case xerrors.Is(err, errCaptureFailed):
+		// Not recorded, so the gateway redelivers the event and the capture is retried
+		c.JSON(http.StatusBadGateway, ResponseError{Error: "Error capturing payment"})
+	case xerrors.Is(err, errCancelFailed):
+		c.JSON(http.StatusBadGateway, ResponseError{Error: "Error cancelling payment"})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, ResponseError{Error: "Error processing event"})
+	default:
+		c.JSON(http.StatusOK, ResponseSuccess{Message: "Event processed"})
+	}
+}
+
+// GetUserPayments godoc
+// @Summary Get the payments of a user
+// @Description Get the card payments of a user for training seats and membership plans, visible to the user and admins
+// @Tags payment
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {object} ResponseSuccess{data=[]PaymentView}
+// @Failure 400 {object} ResponseError
+// @Failure 403 {object} ResponseError
+// @Security BearerAuth
+// @Router /protected/users/{id}/payments [get]
+func (h *Handler) GetUserPayments(c *gin.Context) {
+	userID, ok := h.membershipOwner(c)
+	if !ok {
+		return
+	}
+
+	payments, err := h.payments.ListByUser(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseError{Error: "Error retrieving payments"})
+		return
+	}
+
+	c.JSON(http.StatusOK, ResponseSuccess{Message: "Payments retrieved", Data: newPaymentViews(payments)})
+}
+
+var (
+	errCaptureFailed = xerrors.New("capture failed")
+	errCancelFailed  = xerrors.New("cancel failed")
+)
+
+// settle captures the payment of a completed checkout and confirms the held seat
+// or grants the membership it paid for. Payments which can no longer be
+// delivered are not captured, their authorization is cancelled instead.
+func (h *Handler) settle(ctx context.Context, event payment.Event, purchase domain.Payment) error {
+	if purchase.Status != domain.PaymentPending && purchase.Status != domain.PaymentFailed {
+		return storage.ErrAlreadyExists
+	}
+
+	deliverable, err := h.deliverable(ctx, purchase)
+	if err != nil {
+		return err
+	}
+	if !deliverable {
+		if err := h.gateway.Cancel(ctx, event.Reference); err != nil {
+			log.Printf("cancel payment %d: %v", purchase.ID, err)
+			return errCancelFailed
+		}
+		if _, err := h.payments.Fail(ctx, event.ID, purchase.ID); err != nil {
+			return err
+		}
+		log.Printf("payment %d arrived after its seat was released, authorization cancelled", purchase.ID)
+		return nil
+	}
+
+	var membership *domain.Membership
+	if purchase.PlanID != nil {
+		plan, err := h.plans.GetByID(ctx, *purchase.PlanID)
+		if err != nil {
+			return err
+		}
+		granted := newMembership(*plan, purchase.UserID, time.Now(), nil)
+		membership = &granted
+	}
+
+	if err := h.gateway.Capture(ctx, event.Reference); err != nil {
+		log.Printf("capture payment %d: %v", purchase.ID, err)
+		return errCaptureFailed
+	}
+	settled, err := h.payments.Settle(ctx, event.ID, purchase.ID, event.Reference, membership)
+	if err != nil {
+		return err
+	}
+	switch {
+	case settled.Status == domain.PaymentRefundDue:
+		// The seat was released between the check and the capture
+		log.Printf("payment %d arrived after its seat was released, refund due", purchase.ID)
+	case settled.Status == domain.PaymentPaid && settled.TrainingID != nil:
+		if training, err := h.trainings.GetByID(ctx, *settled.TrainingID); err == nil {
+			h.notify(ctx, domain.NotificationRegistered, *training, settled.UserID)
+		}
+	}
+	return nil
+}
+
+// deliverable reports whether what the payment is for can still be delivered:
+// the seat is still held for the user or the user of the membership exists
+func (h *Handler) deliverable(ctx context.Context, purchase domain.Payment) (bool, error) {
+	if purchase.TrainingID == nil {
+		_, err := h.users.GetByID(ctx, purchase.UserID)
+		if xerrors.Is(err, storage.ErrNotFound) {
+			return false, nil
+		}
+		return err == nil, err
+	}
+
+	registration, err := h.trainings.GetRegistration(ctx, *purchase.TrainingID, purchase.UserID)
+	if xerrors.Is(err, storage.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return registration.Status == domain.RegistrationPending && time.Now().Before(registration.HeldAt.Add(h.checkout.Hold)), nil
+}
+
+// checkoutTraining starts the checkout for a seat held pending payment
+func (h *Handler) checkoutTraining(ctx context.Context, training domain.Training, registration domain.Registration) (*CheckoutView, error) {
+	purchase := domain.Payment{
+		UserID:     registration.UserID,
+		TrainingID: &training.ID,
+		Amount:     training.Price,
+		Currency:   h.checkout.Currency,
+		Status:     domain.PaymentPending,
+		CreatedAt:  time.Now(),
+	}
+	description := training.Name + ", " + training.StartTime.UTC().Format("2006-01-02 15:04 MST")
+	return h.startCheckout(ctx, &purchase, description, registration.HeldAt.Add(h.checkout.Hold))
+}
+
+// startCheckout records the pending payment and opens its checkout session at
+// the gateway, the session expires with the hold of what is paid for
+func (h *Handler) startCheckout(ctx context.Context, purchase *domain.Payment, description string, expiresAt time.Time) (*CheckoutView, error) {
+	if err := h.payments.Create(ctx, purchase); err != nil {
+		return nil, err
+	}
+	var email string
+	if user, err := h.users.GetByID(ctx, purchase.UserID); err == nil {
+		email = user.Mail
+	}
+
+	session, err := h.gateway.CreateCheckout(ctx, payment.Checkout{
+		Reference:   strconv.Itoa(purchase.ID),
+		Description: description,
+		Amount:      purchase.Amount,
+		Currency:    purchase.Currency,
+		Email:       email,
+		SuccessURL:  h.checkout.SuccessURL,
+		CancelURL:   h.checkout.CancelURL,
+		ExpiresAt:   expiresAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := h.payments.SetSession(ctx, purchase.ID, session.ID); err != nil {
+		return nil, err
+	}
+	return &CheckoutView{PaymentID: purchase.ID, URL: session.URL, ExpiresAt: session.ExpiresAt.UTC()}, nil
+}
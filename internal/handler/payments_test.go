@@ -0,0 +1,192 @@
+//go:build fakepayments
+
+package handler_test
+
+import (
+	"context"
+	"net/http"
+	"path"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/folklinoff/fitness-app/internal/config"
+	"github.com/folklinoff/fitness-app/internal/domain"
+	"github.com/folklinoff/fitness-app/internal/handler"
+	middleware "github.com/folklinoff/fitness-app/internal/middleware/auth"
+	"github.com/folklinoff/fitness-app/internal/payment"
+)
+
+const hold = 30 * time.Minute
+
+// paidSeat is a user holding a seat in a paid training with a checkout started
+type paidSeat struct {
+	s        *server
+	gateway  *payment.Fake
+	training domain.Training
+	userID   int
+	token    string
+	checkout handler.CheckoutView
+}
+
+func newPaidSeat(t *testing.T) *paidSeat {
+	t.Helper()
+	gateway, err := payment.NewFake("whsec_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := newServer(t, gateway, config.Payments{Currency: "eur", Hold: hold})
+	seat := &paidSeat{
+		s:        s,
+		gateway:  gateway,
+		training: s.createTraining(t, domain.Training{Name: "Spinning", Capacity: 1, Price: 1500}),
+		userID:   s.createUser(t, "alice"),
+	}
+	seat.token = s.login(t, middleware.Principal{ID: seat.userID, Type: middleware.UserTypeUser}).AccessToken
+
+	rec := s.do(http.MethodPost, seat.trainingPath("/register"), seat.token, "", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("register: %d %s", rec.Code, rec.Body)
+	}
+	var registration handler.RegistrationView
+	decode(t, rec, &registration)
+	if registration.Status != string(domain.RegistrationPending) || registration.Checkout == nil {
+		t.Fatalf("registration for a paid training: %+v", registration)
+	}
+	seat.checkout = *registration.Checkout
+	return seat
+}
+
+func (seat *paidSeat) trainingPath(suffix string) string {
+	return "/protected/training/" + strconv.Itoa(seat.training.ID) + suffix
+}
+
+// webhook sends the webhook request and returns the response message
+func (seat *paidSeat) webhook(t *testing.T, header http.Header, body []byte) string {
+	t.Helper()
+	rec := seat.s.do(http.MethodPost, "/payments/webhook", "", string(body), header)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("webhook: %d %s", rec.Code, rec.Body)
+	}
+	return message(t, rec)
+}
+
+// complete pays the checkout at the gateway and returns its webhook request
+func (seat *paidSeat) complete(t *testing.T) (http.Header, []byte) {
+	t.Helper()
+	header, body, err := seat.gateway.Complete(path.Base(seat.checkout.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return header, body
+}
+
+func (seat *paidSeat) status(t *testing.T) (domain.PaymentStatus, payment.FakeSession) {
+	t.Helper()
+	purchase, err := seat.s.store.Payments.GetByID(context.Background(), seat.checkout.PaymentID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	session, _ := seat.gateway.Session(path.Base(seat.checkout.URL))
+	return purchase.Status, session
+}
+
+func (seat *paidSeat) registrationStatus(t *testing.T) (int, string) {
+	t.Helper()
+	rec := seat.s.do(http.MethodGet, seat.trainingPath("/registration"), seat.token, "", nil)
+	var registration handler.RegistrationView
+	if rec.Code == http.StatusOK {
+		decode(t, rec, &registration)
+	}
+	return rec.Code, registration.Status
+}
+
+func TestPaymentConfirmsSeat(t *testing.T) {
+	seat := newPaidSeat(t)
+	header, body := seat.complete(t)
+
+	if message := seat.webhook(t, header, body); message != "Event processed" {
+		t.Errorf("webhook: %q", message)
+	}
+	status, session := seat.status(t)
+	if status != domain.PaymentPaid || !session.Captured || session.Cancelled {
+		t.Errorf("payment %s, captured %v, cancelled %v, want paid and captured", status, session.Captured, session.Cancelled)
+	}
+	if code, registration := seat.registrationStatus(t); registration != string(domain.RegistrationConfirmed) {
+		t.Errorf("registration after payment: %d %s", code, registration)
+	}
+}
+
+func TestPaymentDuplicateWebhook(t *testing.T) {
+	seat := newPaidSeat(t)
+	header, body := seat.complete(t)
+
+	seat.webhook(t, header, body)
+	if message := seat.webhook(t, header, body); message != "Event already processed" {
+		t.Errorf("redelivered webhook: %q", message)
+	}
+	if status, _ := seat.status(t); status != domain.PaymentPaid {
+		t.Errorf("payment after the redelivered webhook: %s", status)
+	}
+	if code, registration := seat.registrationStatus(t); registration != string(domain.RegistrationConfirmed) {
+		t.Errorf("registration after the redelivered webhook: %d %s", code, registration)
+	}
+}
+
+func TestPaymentInvalidSignature(t *testing.T) {
+	seat := newPaidSeat(t)
+	header, body := seat.complete(t)
+	header.Set(payment.FakeSignatureHeader, "00")
+
+	if rec := seat.s.do(http.MethodPost, "/payments/webhook", "", string(body), header); rec.Code != http.StatusBadRequest {
+		t.Errorf("forged webhook: %d %s", rec.Code, rec.Body)
+	}
+	if status, session := seat.status(t); status != domain.PaymentPending || session.Captured {
+		t.Errorf("payment after a forged webhook: %s, captured %v", status, session.Captured)
+	}
+}
+
+func TestPaymentAfterHoldReleased(t *testing.T) {
+	seat := newPaidSeat(t)
+	n, err := seat.s.store.Payments.ReleaseHolds(context.Background(), time.Now().Add(time.Minute))
+	if err != nil || n != 1 {
+		t.Fatalf("release holds: %d, %v", n, err)
+	}
+	header, body := seat.complete(t)
+
+	seat.webhook(t, header, body)
+	status, session := seat.status(t)
+	if status != domain.PaymentFailed || session.Captured || !session.Cancelled {
+		t.Errorf("late payment %s, captured %v, cancelled %v, want failed and cancelled", status, session.Captured, session.Cancelled)
+	}
+	if code, _ := seat.registrationStatus(t); code != http.StatusNotFound {
+		t.Errorf("registration after the late payment: %d", code)
+	}
+	if message := seat.webhook(t, header, body); message != "Event already processed" {
+		t.Errorf("redelivered late webhook: %q", message)
+	}
+}
+
+func TestCheckoutExpired(t *testing.T) {
+	seat := newPaidSeat(t)
+	header, body, err := seat.gateway.Expire(path.Base(seat.checkout.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	seat.webhook(t, header, body)
+	if status, _ := seat.status(t); status != domain.PaymentFailed {
+		t.Errorf("payment of the expired checkout: %s", status)
+	}
+	// The seat stays held, a new checkout can be started
+	rec := seat.s.do(http.MethodPost, seat.trainingPath("/checkout"), seat.token, "", nil)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("new checkout: %d %s", rec.Code, rec.Body)
+	}
+	decode(t, rec, &seat.checkout)
+	header, body = seat.complete(t)
+	seat.webhook(t, header, body)
+	if code, registration := seat.registrationStatus(t); registration != string(domain.RegistrationConfirmed) {
+		t.Errorf("registration after paying the new checkout: %d %s", code, registration)
+	}
+}
@@ -0,0 +1,190 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/folklinoff/fitness-app/internal/audit"
+	"github.com/folklinoff/fitness-app/internal/domain"
+	middleware "github.com/folklinoff/fitness-app/internal/middleware/auth"
+	"github.com/folklinoff/fitness-app/internal/policy"
+	"github.com/folklinoff/fitness-app/internal/storage"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/xerrors"
+)
+
+// CancelRegistration godoc
+// @Summary Cancel the registration for a training session
+// @Description Give up the seat or waitlist place of the current user (only for users). Seats cancelled within the cancellation cutoff before the start are recorded as late cancellations and their credits or card payment are only refunded if the late cancellation refund is enabled; trainings that have started cannot be cancelled.
+// @Tags training
+// @Produce json
+// @Param training_id path int true "Training ID"
+// @Success 200 {object} ResponseSuccess{data=CancellationView}
+// @Failure 400 {object} ResponseError
+// @Failure 403 {object} ResponseError
+// @Failure 404 {object} ResponseError
+// @Failure 409 {object} ResponseError
+// @Security BearerAuth
+// @Router /protected/training/{training_id}/register [delete]
+func (h *Handler) CancelRegistration(c *gin.Context) {
+	principal := middleware.MustPrincipal(c)
+	trainingID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ResponseError{Error: "Invalid training ID: " + err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	training, err := h.trainings.GetByID(ctx, trainingID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, ResponseError{Error: "Training not found with ID " + strconv.Itoa(trainingID)})
+		return
+	}
+	now := time.Now()
+	if !now.Before(training.StartTime) {
+		c.JSON(http.StatusConflict, ResponseError{Error: "Training has already started"})
+		return
+	}
+
+	withinCutoff := now.After(training.StartTime.Add(-h.booking.CancellationCutoff))
+	refundSeat := !withinCutoff || h.booking.LateCancellationRefund
+	registration, _, err := h.trainings.CancelRegistration(ctx, trainingID, principal.ID, refundSeat)
+	if xerrors.Is(err, storage.ErrNotFound) {
+		c.JSON(http.StatusNotFound, ResponseError{Error: "User is not registered for this training"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseError{Error: "Error cancelling registration"})
+		return
+	}
+
+	// Leaving the waitlist never blocks a seat and an unpaid seat was never
+	// confirmed, so neither is late
+	late := registration.Status == domain.RegistrationConfirmed && withinCutoff
+	if late {
+		incident := domain.Incident{UserID: principal.ID, TrainingID: trainingID, Kind: domain.IncidentLateCancellation, At: now}
+		if err := h.incidents.Record(ctx, &incident); err != nil && !xerrors.Is(err, storage.ErrAlreadyExists) {
+			log.Printf("record late cancellation of user %d for training %d: %v", principal.ID, trainingID, err)
+		}
+	}
+
+	message := "Registration cancelled"
+	if late {
+		message = "Registration cancelled, the late cancellation was recorded"
+	}
+	view := CancellationView{TrainingID: trainingID, Late: late}
+	if !late || refundSeat {
+		view.RefundedCredits = registration.Credits
+		view.PaymentRefunded = registration.Status == domain.RegistrationConfirmed && registration.PaymentID != nil
+	}
+	c.JSON(http.StatusOK, ResponseSuccess{Message: message, Data: view})
+}
+
+// ReportNoShow godoc
+// @Summary Report a no-show
+// @Description Record that a user with a seat and no attendance did not attend a training that has started (only for the trainer of the training and admins). No-shows are also recorded automatically once a training ends.
+// @Tags training
+// @Accept json
+// @Produce json
+// @Param training_id path int true "Training ID"
+// @Param user body NoShowRequest true "User who did not attend"
+// @Success 201 {object} ResponseSuccess
+// @Failure 400 {object} ResponseError
+// @Failure 403 {object} ResponseError
+// @Failure 404 {object} ResponseError
+// @Failure 409 {object} ResponseError
+// @Security BearerAuth
+// @Router /protected/training/{training_id}/no-show [post]
+func (h *Handler) ReportNoShow(c *gin.Context) {
+	principal := middleware.MustPrincipal(c)
+	trainingID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ResponseError{Error: "Invalid training ID: " + err.Error()})
+		return
+	}
+
+	var req NoShowRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ResponseError{Error: "Invalid data: " + err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	training, err := h.trainings.GetByID(ctx, trainingID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, ResponseError{Error: "Training not found with ID " + strconv.Itoa(trainingID)})
+		return
+	}
+	if !h.policy.CanManageTraining(principal, training) {
+		h.deny(c, principal, policy.ActionReportNoShow, audit.Resource("training", trainingID), "Not allowed to report no-shows for this training")
+		return
+	}
+	now := time.Now()
+	if now.Before(training.StartTime) {
+		c.JSON(http.StatusConflict, ResponseError{Error: "Training has not started yet"})
+		return
+	}
+
+	registration, err := h.trainings.GetRegistration(ctx, trainingID, req.UserID)
+	if err != nil || registration.Status != domain.RegistrationConfirmed {
+		c.JSON(http.StatusNotFound, ResponseError{Error: "User has no seat in this training"})
+		return
+	}
+	if _, err := h.attendance.Get(ctx, trainingID, req.UserID); err == nil {
+		c.JSON(http.StatusConflict, ResponseError{Error: "User attended this training"})
+		return
+	}
+
+	incident := domain.Incident{UserID: req.UserID, TrainingID: trainingID, Kind: domain.IncidentNoShow, At: now}
+	err = h.incidents.Record(ctx, &incident)
+	if xerrors.Is(err, storage.ErrAlreadyExists) {
+		c.JSON(http.StatusConflict, ResponseError{Error: "No-show already recorded"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseError{Error: "Error recording no-show"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, ResponseSuccess{Message: "No-show recorded"})
+}
+
+// GetUserIncidents godoc
+// @Summary Get the late cancellations and no-shows of a user
+// @Description Get the late cancellations and no-shows of a user, visible to the user, their trainers and admins. Users with a seat and no attendance are recorded as no-shows once the training ends.
+// @Tags user
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {object} ResponseSuccess{data=IncidentsView}
+// @Failure 400 {object} ResponseError
+// @Failure 403 {object} ResponseError
+// @Security BearerAuth
+// @Router /protected/users/{id}/incidents [get]
+func (h *Handler) GetUserIncidents(c *gin.Context) {
+	principal := middleware.MustPrincipal(c)
+	id, ok := accountID(c, middleware.UserTypeUser)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	access, err := h.policy.ViewAccount(ctx, principal, middleware.UserTypeUser, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseError{Error: "Error retrieving incidents"})
+		return
+	}
+	if access == policy.Denied {
+		h.deny(c, principal, policy.ActionViewAccount, audit.Resource(middleware.UserTypeUser, id), "Not allowed to view this profile")
+		return
+	}
+
+	incidents, err := h.incidents.ListByUser(ctx, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseError{Error: "Error retrieving incidents"})
+		return
+	}
+
+	c.JSON(http.StatusOK, ResponseSuccess{Message: "Incidents retrieved", Data: newIncidentsView(incidents)})
+}
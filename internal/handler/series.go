@@ -0,0 +1,632 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/folklinoff/fitness-app/internal/audit"
+	"github.com/folklinoff/fitness-app/internal/domain"
+	middleware "github.com/folklinoff/fitness-app/internal/middleware/auth"
+	"github.com/folklinoff/fitness-app/internal/policy"
+	"github.com/folklinoff/fitness-app/internal/recurrence"
+	"github.com/folklinoff/fitness-app/internal/storage"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/xerrors"
+)
+
+// Scopes of a change to an occurrence of a training series
+const (
+	scopeOccurrence = "this"
+	scopeFollowing  = "following"
+	scopeSeries     = "all"
+)
+
+// CreateSeries godoc
+// @Summary Create a recurring training series
+// @Description Create a training series from an RFC 5545 recurrence rule (only for trainers). The rule is expanded in the given time zone, so occurrences keep their wall-clock time across daylight saving changes. The rule must end by COUNT or UNTIL within the configured horizon after the first occurrence, as all occurrences are created with the series; open-ended rules are rejected, continue them with a new series. Supported rule parts are FREQ (DAILY, WEEKLY, MONTHLY, YEARLY), INTERVAL, COUNT, UNTIL, BYDAY, BYMONTHDAY and WKST. Occurrences in a room must lie within the opening hours of its location.
+// @Tags series
+// @Accept json
+// @Produce json
+// @Param series body SeriesRequest true "Series data"
+// @Param allow_overlap query bool false "Create the series even if occurrences overlap other sessions of the trainer or in the room"
+// @Success 201 {object} ResponseSuccess{data=SeriesView}
+// @Failure 400 {object} ResponseError
+// @Failure 403 {object} ResponseError
+// @Failure 404 {object} ResponseError
+// @Failure 409 {object} ResponseConflict
+// @Security BearerAuth
+// @Router /protected/series [post]
+func (h *Handler) CreateSeries(c *gin.Context) {
+	principal := middleware.MustPrincipal(c)
+
+	var req SeriesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ResponseError{Error: "Invalid data: " + err.Error()})
+		return
+	}
+	if err := validInterval(req.StartTime, req.EndTime); err != nil {
+		c.JSON(http.StatusBadRequest, ResponseError{Error: "Invalid data: " + err.Error()})
+		return
+	}
+	loc, err := time.LoadLocation(req.TimeZone)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ResponseError{Error: "Invalid time zone: " + req.TimeZone})
+		return
+	}
+	rule, err := recurrence.Parse(req.RRule, loc)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ResponseError{Error: "Invalid rrule: " + err.Error()})
+		return
+	}
+	location, ok := h.checkRoom(c, req.RoomID, &req.Capacity)
+	if !ok {
+		return
+	}
+
+	series := domain.TrainingSeries{
+		Name:      req.Name,
+		Type:      req.Type,
+		Level:     req.Level,
+		TrainerID: principal.ID,
+		Capacity:  req.Capacity,
+		Credits:   req.Credits,
+		Price:     req.Price,
+		RoomID:    req.RoomID,
+		StartTime: req.StartTime.UTC(),
+		EndTime:   req.EndTime.UTC(),
+		TimeZone:  loc.String(),
+		RRule:     rule.String(),
+	}
+	for _, t := range req.Exceptions {
+		series.Exceptions = append(series.Exceptions, t.UTC())
+	}
+
+	if !rule.Ends(req.StartTime.In(loc), req.StartTime.Add(h.booking.SeriesHorizon)) {
+		c.JSON(http.StatusBadRequest, ResponseError{Error: "The rule must end by COUNT or UNTIL within " + h.booking.SeriesHorizon.String() + " after the first occurrence"})
+		return
+	}
+	occurrences := h.expandSeries(&series, rule, loc)
+	if len(occurrences) == 0 {
+		c.JSON(http.StatusBadRequest, ResponseError{Error: "The series has no occurrences"})
+		return
+	}
+	if !checkOpeningHours(c, location, occurrences) {
+		return
+	}
+	allow, ok := allowOverlap(c)
+	if !ok {
+		return
+	}
+	err = h.series.Create(c.Request.Context(), &series, occurrences, allow)
+	if xerrors.Is(err, storage.ErrNotFound) {
+		c.JSON(http.StatusNotFound, ResponseError{Error: "Trainer not found"})
+		return
+	}
+	if respondOverlap(c, err) {
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseError{Error: "Error creating series"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, ResponseSuccess{Message: "Series created successfully", Data: newSeriesView(series, occurrences)})
+}
+
+// GetSeries godoc
+// @Summary Get a training series by ID
+// @Description Get a training series with its occurrences
+// @Tags series
+// @Produce json
+// @Param id path int true "Series ID"
+// @Success 200 {object} ResponseSuccess{data=SeriesView}
+// @Failure 400 {object} ResponseError
+// @Failure 404 {object} ResponseError
+// @Security BearerAuth
+// @Router /protected/series/{id} [get]
+func (h *Handler) GetSeries(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ResponseError{Error: "Invalid series ID: " + err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	series, err := h.series.GetByID(ctx, id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, ResponseError{Error: "Series not found with ID " + strconv.Itoa(id)})
+		return
+	}
+	occurrences, err := h.series.ListOccurrences(ctx, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseError{Error: "Error retrieving series"})
+		return
+	}
+
+	c.JSON(http.StatusOK, ResponseSuccess{Message: "Series found", Data: newSeriesView(*series, occurrences)})
+}
+
+// UpdateOccurrence godoc
+// @Summary Update an occurrence of a training series
+// @Description Update one occurrence of a series (scope "this"), it and the following ones ("following") or every occurrence which has not started yet ("all") (only for its trainer or an admin). Changes to several occurrences replace their individual changes and may move the time of day but not the date, the capacity cannot be lowered below the seats taken in an occurrence; "following" splits off a new series starting at the occurrence. Users registered for the occurrences are notified when their name or times change.
+// @Tags series
+// @Accept json
+// @Produce json
+// @Param id path int true "Series ID"
+// @Param training_id path int true "Training ID of the occurrence"
+// @Param scope query string false "Scope of the change" Enums(this, following, all) default(this)
+// @Param training body TrainingRequest true "Updated training data"
+// @Param allow_overlap query bool false "Apply the change even if occurrences overlap other sessions of the trainer or in the room"
+// @Success 200 {object} ResponseSuccess{data=SeriesView}
+// @Failure 400 {object} ResponseError
+// @Failure 403 {object} ResponseError
+// @Failure 404 {object} ResponseError
+// @Failure 409 {object} ResponseConflict
+// @Security BearerAuth
+// @Router /protected/series/{id}/occurrences/{training_id} [put]
+func (h *Handler) UpdateOccurrence(c *gin.Context) {
+	var req TrainingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ResponseError{Error: "Invalid data: " + err.Error()})
+		return
+	}
+	if err := validInterval(req.StartTime, req.EndTime); err != nil {
+		c.JSON(http.StatusBadRequest, ResponseError{Error: "Invalid data: " + err.Error()})
+		return
+	}
+
+	target, ok := h.loadOccurrence(c, policy.ActionUpdateSeries)
+	if !ok {
+		return
+	}
+	series, occurrence, loc := target.series, target.occurrence, target.loc
+	location, ok := h.checkRoom(c, req.RoomID, &req.Capacity)
+	if !ok {
+		return
+	}
+
+	if target.scope == scopeOccurrence {
+		req.apply(&occurrence)
+		if !checkOpeningHours(c, location, []domain.Training{occurrence}) {
+			return
+		}
+		allow, ok := allowOverlap(c)
+		if !ok {
+			return
+		}
+		h.saveSeries(c, series, target.occurrences, []domain.Training{occurrence}, allow, "Occurrence updated successfully")
+		return
+	}
+
+	from := recurrenceID(occurrence)
+	start := req.StartTime.In(loc)
+	if y, m, d := from.In(loc).Date(); start.Year() != y || start.Month() != m || start.Day() != d {
+		c.JSON(http.StatusBadRequest, ResponseError{Error: "Changes to several occurrences cannot move their date, update single occurrences instead"})
+		return
+	}
+	shift := recurrence.WallClockShift(from, req.StartTime, loc)
+	duration := req.EndTime.Sub(req.StartTime)
+
+	// Occurrences which have started are kept as they are
+	now := time.Now()
+	var changed []domain.Training
+	for _, o := range target.occurrences {
+		if target.scope == scopeFollowing && recurrenceID(o).Before(from) {
+			continue
+		}
+		if !o.StartTime.After(now) {
+			continue
+		}
+		rid := recurrence.ShiftWallClock(recurrenceID(o), shift, loc).UTC()
+		req.apply(&o)
+		o.StartTime, o.EndTime, o.RecurrenceID = rid, rid.Add(duration), &rid
+		changed = append(changed, o)
+	}
+	if !checkOpeningHours(c, location, changed) {
+		return
+	}
+	allow, ok := allowOverlap(c)
+	if !ok {
+		return
+	}
+
+	if target.scope == scopeSeries || from.Equal(series.StartTime) {
+		series.Name, series.Type, series.Level, series.Capacity = req.Name, req.Type, req.Level, req.Capacity
+		series.Credits, series.Price = req.Credits, req.Price
+		series.RoomID = req.RoomID
+		series.StartTime = recurrence.ShiftWallClock(series.StartTime, shift, loc).UTC()
+		series.EndTime = series.StartTime.Add(duration)
+		for i, t := range series.Exceptions {
+			series.Exceptions[i] = recurrence.ShiftWallClock(t, shift, loc).UTC()
+		}
+		if err := shiftRule(series, shift, loc); err != nil {
+			c.JSON(http.StatusInternalServerError, ResponseError{Error: "Error updating series"})
+			return
+		}
+		h.saveSeries(c, series, target.occurrences, changed, allow, "Series updated successfully")
+		return
+	}
+
+	next := domain.TrainingSeries{
+		Name:      req.Name,
+		Type:      req.Type,
+		Level:     req.Level,
+		TrainerID: series.TrainerID,
+		Capacity:  req.Capacity,
+		Credits:   req.Credits,
+		Price:     req.Price,
+		RoomID:    req.RoomID,
+		StartTime: recurrence.ShiftWallClock(from, shift, loc).UTC(),
+		TimeZone:  series.TimeZone,
+	}
+	next.EndTime = next.StartTime.Add(duration)
+	var kept []time.Time
+	for _, t := range series.Exceptions {
+		if t.Before(from) {
+			kept = append(kept, t)
+		} else {
+			next.Exceptions = append(next.Exceptions, recurrence.ShiftWallClock(t, shift, loc).UTC())
+		}
+	}
+	series.Exceptions = kept
+	if err := splitRule(series, &next, from, shift, loc); err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseError{Error: "Error updating series"})
+		return
+	}
+
+	// The moved occurrences are those of next, including the started ones
+	var moved []domain.Training
+	for _, o := range target.occurrences {
+		if recurrenceID(o).Before(from) {
+			continue
+		}
+		for _, ch := range changed {
+			if ch.ID == o.ID {
+				o = ch
+			}
+		}
+		moved = append(moved, o)
+	}
+
+	ctx := c.Request.Context()
+	err := h.series.Split(ctx, series, &next, moved, allow)
+	if xerrors.Is(err, storage.ErrCapacityBelowSeats) {
+		c.JSON(http.StatusConflict, ResponseError{Error: "Capacity is below the seats already taken, cancel registrations first"})
+		return
+	}
+	if respondOverlap(c, err) {
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseError{Error: "Error updating series"})
+		return
+	}
+	h.notifyUpdated(ctx, target.occurrences, changed)
+	h.respondSeries(c, next.ID, "Series split, the following occurrences were moved to series "+strconv.Itoa(next.ID))
+}
+
+// DeleteOccurrence godoc
+// @Summary Delete an occurrence of a training series
+// @Description Delete one occurrence of a series (scope "this"), it and the following ones ("following") or the whole series ("all") (only for its trainer or an admin). Deleted single occurrences become exceptions of the series; occurrences which have started are kept as standalone trainings when several are deleted. Users registered for the deleted occurrences are notified of the cancellation.
+// @Tags series
+// @Produce json
+// @Param id path int true "Series ID"
+// @Param training_id path int true "Training ID of the occurrence"
+// @Param scope query string false "Scope of the deletion" Enums(this, following, all) default(this)
+// @Success 200 {object} ResponseSuccess
+// @Failure 400 {object} ResponseError
+// @Failure 403 {object} ResponseError
+// @Failure 404 {object} ResponseError
+// @Security BearerAuth
+// @Router /protected/series/{id}/occurrences/{training_id} [delete]
+func (h *Handler) DeleteOccurrence(c *gin.Context) {
+	target, ok := h.loadOccurrence(c, policy.ActionDeleteSeries)
+	if !ok {
+		return
+	}
+	series, occurrence := target.series, target.occurrence
+	from := recurrenceID(occurrence)
+
+	ctx := c.Request.Context()
+	if target.scope == scopeOccurrence {
+		registrants, err := h.listRegistrants(ctx, []int{occurrence.ID})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, ResponseError{Error: "Error deleting occurrence"})
+			return
+		}
+		series.Exceptions = append(series.Exceptions, from)
+		if err := h.series.Update(ctx, series, nil, []int{occurrence.ID}, false); err != nil {
+			c.JSON(http.StatusInternalServerError, ResponseError{Error: "Error deleting occurrence"})
+			return
+		}
+		h.notifyCancelled(ctx, []domain.Training{occurrence}, registrants)
+		c.JSON(http.StatusOK, ResponseSuccess{Message: "Occurrence deleted successfully"})
+		return
+	}
+	if target.scope == scopeSeries || from.Equal(series.StartTime) {
+		h.deleteSeries(c, series.ID, target.occurrences)
+		return
+	}
+
+	var kept []time.Time
+	for _, t := range series.Exceptions {
+		if t.Before(from) {
+			kept = append(kept, t)
+		}
+	}
+	series.Exceptions = kept
+	if err := splitRule(series, nil, from, 0, target.loc); err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseError{Error: "Error deleting occurrences"})
+		return
+	}
+
+	detached, removed := partitionStarted(target.occurrences, from)
+	registrants, err := h.listRegistrants(ctx, removed)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseError{Error: "Error deleting occurrences"})
+		return
+	}
+	// The detached occurrences keep their times and cannot overlap anew
+	if err := h.series.Update(ctx, series, detached, removed, false); err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseError{Error: "Error deleting occurrences"})
+		return
+	}
+	h.notifyCancelled(ctx, target.occurrences, registrants)
+	c.JSON(http.StatusOK, ResponseSuccess{Message: "Occurrences deleted successfully"})
+}
+
+// DeleteSeries godoc
+// @Summary Delete a training series
+// @Description Delete a training series with its occurrences which have not started yet, the others are kept as standalone trainings (only for its trainer or an admin), the users registered for the deleted occurrences are notified of the cancellation
+// @Tags series
+// @Produce json
+// @Param id path int true "Series ID"
+// @Success 200 {object} ResponseSuccess
+// @Failure 400 {object} ResponseError
+// @Failure 403 {object} ResponseError
+// @Failure 404 {object} ResponseError
+// @Security BearerAuth
+// @Router /protected/series/{id} [delete]
+func (h *Handler) DeleteSeries(c *gin.Context) {
+	principal := middleware.MustPrincipal(c)
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ResponseError{Error: "Invalid series ID: " + err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	series, err := h.series.GetByID(ctx, id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, ResponseError{Error: "Series not found with ID " + strconv.Itoa(id)})
+		return
+	}
+	if !h.policy.CanManageSeries(principal, series) {
+		h.deny(c, principal, policy.ActionDeleteSeries, audit.Resource("series", id), "Not allowed to delete this series")
+		return
+	}
+	occurrences, err := h.series.ListOccurrences(ctx, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseError{Error: "Error deleting series"})
+		return
+	}
+	h.deleteSeries(c, id, occurrences)
+}
+
+func (h *Handler) deleteSeries(c *gin.Context, id int, occurrences []domain.Training) {
+	ctx := c.Request.Context()
+	_, removed := partitionStarted(occurrences, time.Time{})
+	registrants, err := h.listRegistrants(ctx, removed)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseError{Error: "Error deleting series"})
+		return
+	}
+	if err := h.series.Delete(ctx, id, removed); err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseError{Error: "Error deleting series"})
+		return
+	}
+	h.notifyCancelled(ctx, occurrences, registrants)
+	c.JSON(http.StatusOK, ResponseSuccess{Message: "Series deleted successfully"})
+}
+
+// occurrenceTarget is an occurrence of a series a change applies to
+type occurrenceTarget struct {
+	series      *domain.TrainingSeries
+	occurrences []domain.Training
+	occurrence  domain.Training
+	loc         *time.Location
+	scope       string
+}
+
+// loadOccurrence resolves the series and occurrence of the request and checks
+// that the principal may manage the series, it writes the error response itself
+func (h *Handler) loadOccurrence(c *gin.Context, action string) (occurrenceTarget, bool) {
+	principal := middleware.MustPrincipal(c)
+	var target occurrenceTarget
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ResponseError{Error: "Invalid series ID: " + err.Error()})
+		return target, false
+	}
+	trainingID, err := strconv.Atoi(c.Param("training_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ResponseError{Error: "Invalid training ID: " + err.Error()})
+		return target, false
+	}
+	switch target.scope = c.DefaultQuery("scope", scopeOccurrence); target.scope {
+	case scopeOccurrence, scopeFollowing, scopeSeries:
+	default:
+		c.JSON(http.StatusBadRequest, ResponseError{Error: "Invalid scope, expected this, following or all"})
+		return target, false
+	}
+
+	ctx := c.Request.Context()
+	if target.series, err = h.series.GetByID(ctx, id); err != nil {
+		c.JSON(http.StatusNotFound, ResponseError{Error: "Series not found with ID " + strconv.Itoa(id)})
+		return target, false
+	}
+	if !h.policy.CanManageSeries(principal, target.series) {
+		h.deny(c, principal, action, audit.Resource("series", id), "Not allowed to change this series")
+		return target, false
+	}
+	if target.loc, err = time.LoadLocation(target.series.TimeZone); err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseError{Error: "Invalid time zone of series " + strconv.Itoa(id)})
+		return target, false
+	}
+	if target.occurrences, err = h.series.ListOccurrences(ctx, id); err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseError{Error: "Error retrieving series"})
+		return target, false
+	}
+
+	for _, o := range target.occurrences {
+		if o.ID == trainingID {
+			target.occurrence = o
+			return target, true
+		}
+	}
+	c.JSON(http.StatusNotFound, ResponseError{Error: "Training " + strconv.Itoa(trainingID) + " is not an occurrence of series " + strconv.Itoa(id)})
+	return target, false
+}
+
+// saveSeries stores the series with the changed occurrences, notifies the
+// registrants of those whose name or times changed from before and responds
+// with the series
+func (h *Handler) saveSeries(c *gin.Context, series *domain.TrainingSeries, before, changed []domain.Training, allowOverlap bool, message string) {
+	err := h.series.Update(c.Request.Context(), series, changed, nil, allowOverlap)
+	if xerrors.Is(err, storage.ErrCapacityBelowSeats) {
+		c.JSON(http.StatusConflict, ResponseError{Error: "Capacity is below the seats already taken, cancel registrations first"})
+		return
+	}
+	if respondOverlap(c, err) {
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseError{Error: "Error updating series"})
+		return
+	}
+	h.notifyUpdated(c.Request.Context(), before, changed)
+	h.respondSeries(c, series.ID, message)
+}
+
+func (h *Handler) respondSeries(c *gin.Context, id int, message string) {
+	ctx := c.Request.Context()
+	series, err := h.series.GetByID(ctx, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseError{Error: "Error retrieving series"})
+		return
+	}
+	occurrences, err := h.series.ListOccurrences(ctx, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseError{Error: "Error retrieving series"})
+		return
+	}
+	c.JSON(http.StatusOK, ResponseSuccess{Message: message, Data: newSeriesView(*series, occurrences)})
+}
+
+// expandSeries returns the occurrences of the series up to the series horizon,
+// leaving out its exceptions. CreateSeries only accepts rules ending before it.
+func (h *Handler) expandSeries(series *domain.TrainingSeries, rule *recurrence.Rule, loc *time.Location) []domain.Training {
+	start := series.StartTime.In(loc)
+	duration := series.EndTime.Sub(series.StartTime)
+
+	var occurrences []domain.Training
+	for _, t := range rule.Occurrences(start, start.Add(h.booking.SeriesHorizon)) {
+		if isException(series.Exceptions, t) {
+			continue
+		}
+		rid := t.UTC()
+		occurrences = append(occurrences, domain.Training{
+			Name:         series.Name,
+			Type:         series.Type,
+			Level:        series.Level,
+			TrainerID:    series.TrainerID,
+			Capacity:     series.Capacity,
+			Credits:      series.Credits,
+			Price:        series.Price,
+			RoomID:       series.RoomID,
+			StartTime:    rid,
+			EndTime:      rid.Add(duration),
+			RecurrenceID: &rid,
+		})
+	}
+	return occurrences
+}
+
+// splitRule ends the rule of the series before the occurrence at from. When
+// next is given it gets the rest of the rule, moved by shift.
+func splitRule(series, next *domain.TrainingSeries, from time.Time, shift time.Duration, loc *time.Location) error {
+	rule, err := recurrence.Parse(series.RRule, loc)
+	if err != nil {
+		return xerrors.Errorf("parse rule of series %d: %w", series.ID, err)
+	}
+	rest := *rule
+
+	if rule.Count > 0 {
+		// COUNT includes the exceptions, so the occurrences before from are counted by the rule
+		before := len(rule.Occurrences(series.StartTime.In(loc), from.Add(-time.Second)))
+		rule.Count, rest.Count = before, rule.Count-before
+	} else {
+		rule.Until = from.Add(-time.Second)
+		if !rest.Until.IsZero() {
+			rest.Until = recurrence.ShiftWallClock(rest.Until, shift, loc)
+		}
+	}
+
+	series.RRule = rule.String()
+	if next != nil {
+		next.RRule = rest.String()
+	}
+	return nil
+}
+
+// shiftRule moves the UNTIL of the series rule along with its occurrences
+func shiftRule(series *domain.TrainingSeries, shift time.Duration, loc *time.Location) error {
+	rule, err := recurrence.Parse(series.RRule, loc)
+	if err != nil {
+		return xerrors.Errorf("parse rule of series %d: %w", series.ID, err)
+	}
+	if !rule.Until.IsZero() {
+		rule.Until = recurrence.ShiftWallClock(rule.Until, shift, loc)
+		series.RRule = rule.String()
+	}
+	return nil
+}
+
+// partitionStarted splits the occurrences from the given recurrence ID on into
+// those which have started, detached from their series, and the IDs of the others
+func partitionStarted(occurrences []domain.Training, from time.Time) ([]domain.Training, []int) {
+	now := time.Now()
+	var started []domain.Training
+	var others []int
+	for _, o := range occurrences {
+		if recurrenceID(o).Before(from) {
+			continue
+		}
+		if o.StartTime.After(now) {
+			others = append(others, o.ID)
+			continue
+		}
+		o.SeriesID, o.RecurrenceID = nil, nil
+		started = append(started, o)
+	}
+	return started, others
+}
+
+// recurrenceID returns the start time the series rule gives the occurrence
+func recurrenceID(training domain.Training) time.Time {
+	if training.RecurrenceID != nil {
+		return *training.RecurrenceID
+	}
+	return training.StartTime
+}
+
+func isException(exceptions []time.Time, t time.Time) bool {
+	for _, e := range exceptions {
+		if e.Equal(t) {
+			return true
+		}
+	}
+	return false
+}
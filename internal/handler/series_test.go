@@ -0,0 +1,140 @@
+package handler
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/folklinoff/fitness-app/internal/domain"
+)
+
+func TestSplitRule(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	utc := func(value string) time.Time {
+		parsed, err := time.Parse("2006-01-02 15:04", value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return parsed
+	}
+
+	tests := []struct {
+		name     string
+		rule     string
+		loc      *time.Location
+		start    time.Time
+		from     time.Time
+		shift    time.Duration
+		want     string
+		wantNext string
+	}{
+		{
+			name:     "count is divided at the occurrence",
+			rule:     "FREQ=DAILY;COUNT=5",
+			start:    utc("2024-01-01 10:00"),
+			from:     utc("2024-01-03 10:00"),
+			want:     "FREQ=DAILY;COUNT=2",
+			wantNext: "FREQ=DAILY;COUNT=3",
+		},
+		{
+			name:     "count of a weekly rule by day",
+			rule:     "FREQ=WEEKLY;BYDAY=MO,WE;COUNT=6",
+			start:    utc("2024-01-01 10:00"),
+			from:     utc("2024-01-10 10:00"),
+			want:     "FREQ=WEEKLY;COUNT=3;BYDAY=MO,WE",
+			wantNext: "FREQ=WEEKLY;COUNT=3;BYDAY=MO,WE",
+		},
+		{
+			name:     "count is not changed by the shift",
+			rule:     "FREQ=MONTHLY;BYDAY=-1FR;COUNT=4",
+			start:    utc("2024-01-26 18:00"),
+			from:     utc("2024-03-29 18:00"),
+			shift:    time.Hour,
+			want:     "FREQ=MONTHLY;COUNT=2;BYDAY=-1FR",
+			wantNext: "FREQ=MONTHLY;COUNT=2;BYDAY=-1FR",
+		},
+		{
+			name:     "until ends before the occurrence and moves with the shift",
+			rule:     "FREQ=DAILY;UNTIL=20240110T100000Z",
+			start:    utc("2024-01-01 10:00"),
+			from:     utc("2024-01-05 10:00"),
+			shift:    time.Hour,
+			want:     "FREQ=DAILY;UNTIL=20240105T095959Z",
+			wantNext: "FREQ=DAILY;UNTIL=20240110T110000Z",
+		},
+		{
+			name:     "unbounded rule",
+			rule:     "FREQ=WEEKLY",
+			start:    utc("2024-01-01 10:00"),
+			from:     utc("2024-02-05 10:00"),
+			shift:    -time.Hour,
+			want:     "FREQ=WEEKLY;UNTIL=20240205T095959Z",
+			wantNext: "FREQ=WEEKLY",
+		},
+		{
+			name:     "until moves in wall-clock time across daylight saving time",
+			rule:     "FREQ=DAILY;UNTIL=20240330T170000Z",
+			loc:      berlin,
+			start:    utc("2024-03-17 17:00"),
+			from:     utc("2024-03-24 17:00"),
+			shift:    24 * time.Hour,
+			want:     "FREQ=DAILY;UNTIL=20240324T165959Z",
+			wantNext: "FREQ=DAILY;UNTIL=20240331T160000Z",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loc := tt.loc
+			if loc == nil {
+				loc = time.UTC
+			}
+			series := domain.TrainingSeries{ID: 1, RRule: tt.rule, StartTime: tt.start}
+			var next domain.TrainingSeries
+			if err := splitRule(&series, &next, tt.from, tt.shift, loc); err != nil {
+				t.Fatal(err)
+			}
+			if series.RRule != tt.want || next.RRule != tt.wantNext {
+				t.Errorf("split into %s and %s, want %s and %s", series.RRule, next.RRule, tt.want, tt.wantNext)
+			}
+
+			// Without the following series only the rule is ended
+			series = domain.TrainingSeries{ID: 1, RRule: tt.rule, StartTime: tt.start}
+			if err := splitRule(&series, nil, tt.from, tt.shift, loc); err != nil {
+				t.Fatal(err)
+			}
+			if series.RRule != tt.want {
+				t.Errorf("ended as %s, want %s", series.RRule, tt.want)
+			}
+		})
+	}
+
+	series := domain.TrainingSeries{ID: 1, RRule: "FREQ=SECONDLY"}
+	if err := splitRule(&series, nil, time.Now(), 0, time.UTC); err == nil {
+		t.Error("split an invalid rule")
+	}
+}
+
+func TestShiftRule(t *testing.T) {
+	tests := []struct {
+		rule  string
+		shift time.Duration
+		want  string
+	}{
+		{"FREQ=DAILY;UNTIL=20240110T100000Z", -time.Hour, "FREQ=DAILY;UNTIL=20240110T090000Z"},
+		{"FREQ=DAILY;UNTIL=20240110T100000Z", 24 * time.Hour, "FREQ=DAILY;UNTIL=20240111T100000Z"},
+		{"FREQ=DAILY;COUNT=5", time.Hour, "FREQ=DAILY;COUNT=5"},
+		{"FREQ=DAILY", time.Hour, "FREQ=DAILY"},
+	}
+	for _, tt := range tests {
+		series := domain.TrainingSeries{ID: 1, RRule: tt.rule}
+		if err := shiftRule(&series, tt.shift, time.UTC); err != nil {
+			t.Fatal(err)
+		}
+		if series.RRule != tt.want {
+			t.Errorf("shift %s by %v: got %s, want %s", tt.rule, tt.shift, series.RRule, tt.want)
+		}
+	}
+}
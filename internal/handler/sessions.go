@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	middleware "github.com/folklinoff/fitness-app/internal/middleware/auth"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/xerrors"
+)
+
+// RefreshToken godoc
+// @Summary Refresh the access token
+// @Description Exchange a refresh token for a new access and refresh token. A refresh token can be used once, reusing it revokes the whole session.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param token body RefreshRequest true "Refresh token"
+// @Success 200 {object} ResponseSuccess{data=TokenResponse}
+// @Failure 400 {object} ResponseError
+// @Failure 401 {object} ResponseError
+// @Router /token/refresh [post]
+func (h *Handler) RefreshToken(c *gin.Context) {
+	var req RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ResponseError{Error: "Invalid data"})
+		return
+	}
+
+	tokens, err := h.tokens.Refresh(c.Request.Context(), req.RefreshToken)
+	if xerrors.Is(err, middleware.ErrInvalidRefreshToken) {
+		c.JSON(http.StatusUnauthorized, ResponseError{Error: "Invalid refresh token"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseError{Error: "Error refreshing token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, ResponseSuccess{Message: "Token refreshed", Data: newTokenResponse(tokens)})
+}
+
+// Logout godoc
+// @Summary Log out of the current session
+// @Description Revoke the access token of the request and, when given, the session of the refresh token
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param token body LogoutRequest false "Refresh token of the session"
+// @Success 200 {object} ResponseSuccess
+// @Failure 400 {object} ResponseError
+// @Security BearerAuth
+// @Router /protected/logout [post]
+func (h *Handler) Logout(c *gin.Context) {
+	var req LogoutRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, ResponseError{Error: "Invalid data"})
+			return
+		}
+	}
+
+	claims, _ := middleware.ClaimsFrom(c)
+	if err := h.tokens.Logout(c.Request.Context(), claims, req.RefreshToken); err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseError{Error: "Error logging out"})
+		return
+	}
+
+	c.JSON(http.StatusOK, ResponseSuccess{Message: "Logged out"})
+}
+
+// LogoutAll godoc
+// @Summary Log out of all sessions
+// @Description Revoke every refresh token of the account and all access tokens issued to it so far
+// @Tags auth
+// @Produce json
+// @Success 200 {object} ResponseSuccess
+// @Security BearerAuth
+// @Router /protected/logout/all [post]
+func (h *Handler) LogoutAll(c *gin.Context) {
+	principal := middleware.MustPrincipal(c)
+	ctx := c.Request.Context()
+	if err := h.tokens.LogoutAll(ctx, principal); err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseError{Error: "Error logging out"})
+		return
+	}
+	// Tokens issued in the second of the revocation outlive it, so the token
+	// of the request is revoked by its ID as well
+	claims, _ := middleware.ClaimsFrom(c)
+	if err := h.tokens.Logout(ctx, claims, ""); err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseError{Error: "Error logging out"})
+		return
+	}
+
+	c.JSON(http.StatusOK, ResponseSuccess{Message: "Logged out of all sessions"})
+}
+
+// endSessions revokes the sessions of a deleted account. The account is gone
+// already, so a failure is only logged.
+func (h *Handler) endSessions(c *gin.Context, p middleware.Principal) {
+	if err := h.tokens.LogoutAll(c.Request.Context(), p); err != nil {
+		log.Printf("revoke sessions of %s %d: %v", p.Type, p.ID, err)
+	}
+}
@@ -1,208 +1,294 @@
 package handler
 
 import (
+	"log"
 	"net/http"
-	"sort"
 	"strconv"
+	"time"
 
+	"github.com/folklinoff/fitness-app/internal/audit"
 	"github.com/folklinoff/fitness-app/internal/domain"
+	middleware "github.com/folklinoff/fitness-app/internal/middleware/auth"
+	"github.com/folklinoff/fitness-app/internal/policy"
+	"github.com/folklinoff/fitness-app/internal/storage"
 	"github.com/gin-gonic/gin"
+	"golang.org/x/xerrors"
 )
 
-var trainings []domain.Training
-var trainingID = 1
-
 // CreateTraining godoc
 // @Summary Create a new training session
-// @Description Create a new training session (only for trainers)
+// @Description Create a new training session (only for trainers). A session in a room must lie within the opening hours of its location and cannot have more seats than the room. Sessions overlapping other sessions of the trainer or in the room are rejected unless allow_overlap is set.
 // @Tags training
 // @Accept json
 // @Produce json
-// @Param training body domain.Training true "Training data"
-// @Success 201 {object} ResponseSuccess{data=domain.Training}
+// @Param training body TrainingRequest true "Training data"
+// @Param allow_overlap query bool false "Book the session even if it overlaps other sessions of the trainer or in the room"
+// @Success 201 {object} ResponseSuccess{data=TrainingView}
 // @Failure 400 {object} ResponseError
+// @Failure 403 {object} ResponseError
+// @Failure 404 {object} ResponseError
+// @Failure 409 {object} ResponseConflict
 // @Security BearerAuth
 // @Router /protected/training [post]
-func CreateTraining(c *gin.Context) {
-	trainerID := c.MustGet("user_id").(float64)
+func (h *Handler) CreateTraining(c *gin.Context) {
+	principal := middleware.MustPrincipal(c)
 
-	var training domain.Training
-	if err := c.ShouldBindJSON(&training); err != nil {
+	var req TrainingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, ResponseError{Error: "Invalid data: " + err.Error()})
 		return
 	}
 
-	training.ID = trainingID
-	training.TrainerID = int(trainerID)
-	trainingID++
-	trainings = append(trainings, training)
+	if err := validInterval(req.StartTime, req.EndTime); err != nil {
+		c.JSON(http.StatusBadRequest, ResponseError{Error: "Invalid data: " + err.Error()})
+		return
+	}
 
-	for i, trainer := range trainers {
-		if trainer.ID == int(trainerID) {
-			trainers[i].Trainings = append(trainers[i].Trainings, training.ID)
-			break
-		}
+	location, ok := h.checkRoom(c, req.RoomID, &req.Capacity)
+	if !ok {
+		return
+	}
+	training := domain.Training{TrainerID: principal.ID}
+	req.apply(&training)
+	if !checkOpeningHours(c, location, []domain.Training{training}) {
+		return
+	}
+	allow, ok := allowOverlap(c)
+	if !ok {
+		return
+	}
+	err := h.trainings.Create(c.Request.Context(), &training, allow)
+	if xerrors.Is(err, storage.ErrNotFound) {
+		c.JSON(http.StatusNotFound, ResponseError{Error: "Trainer not found"})
+		return
+	}
+	if respondOverlap(c, err) {
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseError{Error: "Error creating training"})
+		return
 	}
 
-	c.JSON(http.StatusCreated, ResponseSuccess{Message: "Training created successfully", Data: training})
+	c.JSON(http.StatusCreated, ResponseSuccess{Message: "Training created successfully", Data: newTrainingView(training)})
 }
 
 // RegisterUserForTraining godoc
 // @Summary Register a user for a training session
-// @Description Register a user for a specific training session
+// @Description Register a user for a specific training session which has not started yet (only for users). When the training is full the user is put on its waitlist and gets a seat automatically once one is freed. Registrations for sessions overlapping, or closer than the registration buffer to, a session the user has a seat, a held seat or a waitlist place in are rejected, and waitlisted users are passed over for seats in sessions conflicting with their seats. Sessions with a credit cost are paid from a membership of the user valid at their start, unlimited memberships first and then the one expiring first. Priced sessions no membership covers are paid by card: the seat is held pending payment and a checkout is started, the registration is confirmed once the payment succeeds and dropped when it is not paid in time. Users are notified when they get a confirmed seat, including through the waitlist.
 // @Tags training
 // @Accept json
 // @Produce json
 // @Param training_id path int true "Training ID"
-// @Success 200 {object} ResponseSuccess
+// @Success 200 {object} ResponseSuccess{data=RegistrationView}
 // @Failure 400 {object} ResponseError
+// @Failure 403 {object} ResponseError
+// @Failure 402 {object} ResponseError
 // @Failure 404 {object} ResponseError
+// @Failure 409 {object} ResponseConflict
 // @Security BearerAuth
 // @Router /protected/training/{training_id}/register [post]
-func RegisterUserForTraining(c *gin.Context) {
-	userID := c.MustGet("user_id").(float64)
+func (h *Handler) RegisterUserForTraining(c *gin.Context) {
+	principal := middleware.MustPrincipal(c)
 	trainingID, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, ResponseError{Error: "Invalid training ID: " + err.Error()})
 		return
 	}
 
-	for i, training := range trainings {
-		if training.ID == trainingID {
-			for _, user := range training.Users {
-				if user == int(userID) {
-					c.JSON(http.StatusConflict, ResponseError{Error: "User already registered for this training"})
-					return
-				}
-			}
-			trainings[i].Users = append(trainings[i].Users, int(userID))
-
-			for j, user := range users {
-				if user.ID == int(userID) {
-					users[j].Trainings = append(users[j].Trainings, trainingID)
-					break
-				}
-			}
+	ctx := c.Request.Context()
+	training, err := h.trainings.GetByID(ctx, trainingID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, ResponseError{Error: "Training not found with ID " + strconv.Itoa(trainingID)})
+		return
+	}
+	if !time.Now().Before(training.StartTime) {
+		c.JSON(http.StatusConflict, ResponseError{Error: "Training has already started"})
+		return
+	}
+	registration, err := h.trainings.RegisterUser(ctx, trainingID, principal.ID)
+	var conflict *storage.ScheduleConflictError
+	switch {
+	case xerrors.Is(err, storage.ErrNotFound):
+		c.JSON(http.StatusNotFound, ResponseError{Error: "Training not found with ID " + strconv.Itoa(trainingID)})
+		return
+	case xerrors.Is(err, storage.ErrAlreadyExists):
+		c.JSON(http.StatusConflict, ResponseError{Error: "User already registered for this training"})
+		return
+	case xerrors.As(err, &conflict):
+		c.JSON(http.StatusConflict, ResponseConflict{
+			Error:                  "Training overlaps or is too close to trainings the user is registered or waitlisted for",
+			ConflictingTrainingIDs: conflict.TrainingIDs,
+		})
+		return
+	case xerrors.Is(err, storage.ErrNoCredits):
+		c.JSON(http.StatusPaymentRequired, ResponseError{Error: "No membership with enough credits for this training"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, ResponseError{Error: "Error registering user for training"})
+		return
+	}
 
-			c.JSON(http.StatusOK, ResponseSuccess{Message: "User registered for training"})
+	view := newRegistrationView(*registration, h.checkout.Hold)
+	switch registration.Status {
+	case domain.RegistrationWaitlisted:
+		c.JSON(http.StatusOK, ResponseSuccess{Message: "Training is full, user added to the waitlist", Data: view})
+	case domain.RegistrationPending:
+		// The seat stays held if the checkout cannot be started, it is retried
+		// through the checkout endpoint
+		checkout, err := h.checkoutTraining(ctx, *training, *registration)
+		if err != nil {
+			log.Printf("start checkout for training %d of user %d: %v", trainingID, principal.ID, err)
+			c.JSON(http.StatusOK, ResponseSuccess{Message: "Seat held pending payment, starting the checkout failed", Data: view})
 			return
 		}
+		view.Checkout = checkout
+		c.JSON(http.StatusOK, ResponseSuccess{Message: "Seat held pending payment", Data: view})
+	default:
+		h.notify(ctx, domain.NotificationRegistered, *training, principal.ID)
+		c.JSON(http.StatusOK, ResponseSuccess{Message: "User registered for training", Data: view})
 	}
-
-	c.JSON(http.StatusNotFound, ResponseError{Error: "Training not found with ID " + strconv.Itoa(trainingID)})
 }
 
-// GetTrainingByID godoc
-// @Summary Get a training session by ID
-// @Description Get a training session by ID
+// GetRegistration godoc
+// @Summary Get the registration of the current user for a training
+// @Description Get whether the current user has a seat in the training or the position on its waitlist (only for users)
 // @Tags training
 // @Produce json
-// @Param id path int true "Training ID"
-// @Success 200 {object} ResponseSuccess{data=domain.Training}
+// @Param training_id path int true "Training ID"
+// @Success 200 {object} ResponseSuccess{data=RegistrationView}
 // @Failure 400 {object} ResponseError
+// @Failure 403 {object} ResponseError
 // @Failure 404 {object} ResponseError
 // @Security BearerAuth
-// @Router /protected/training/{id} [get]
-func GetTrainingByID(c *gin.Context) {
-	id, err := strconv.Atoi(c.Param("id"))
+// @Router /protected/training/{training_id}/registration [get]
+func (h *Handler) GetRegistration(c *gin.Context) {
+	principal := middleware.MustPrincipal(c)
+	trainingID, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, ResponseError{Error: "Invalid training ID: " + err.Error()})
 		return
 	}
 
-	for _, training := range trainings {
-		if training.ID == id {
-			c.JSON(http.StatusOK, ResponseSuccess{Message: "Training found", Data: training})
-			return
-		}
+	registration, err := h.trainings.GetRegistration(c.Request.Context(), trainingID, principal.ID)
+	if xerrors.Is(err, storage.ErrNotFound) {
+		c.JSON(http.StatusNotFound, ResponseError{Error: "User is not registered for this training"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseError{Error: "Error retrieving registration"})
+		return
 	}
 
-	c.JSON(http.StatusNotFound, ResponseError{Error: "Training not found with ID " + strconv.Itoa(id)})
+	c.JSON(http.StatusOK, ResponseSuccess{Message: "Registration found", Data: newRegistrationView(*registration, h.checkout.Hold)})
 }
 
-// GetUserProfile godoc
-// @Summary Get a user or trainer profile by ID
-// @Description Get a user or trainer profile by ID
-// @Tags user
+// GetTrainingByID godoc
+// @Summary Get a training session by ID
+// @Description Get a training session by ID
+// @Tags training
 // @Produce json
-// @Param id path int true "User or Trainer ID"
-// @Success 200 {object} ResponseSuccess{data=domain.User}
+// @Param id path int true "Training ID"
+// @Success 200 {object} ResponseSuccess{data=TrainingView}
 // @Failure 400 {object} ResponseError
 // @Failure 404 {object} ResponseError
 // @Security BearerAuth
-// @Router /protected/user/{id} [get]
-func GetUserProfile(c *gin.Context) {
+// @Router /protected/training/{id} [get]
+func (h *Handler) GetTrainingByID(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, ResponseError{Error: "Invalid user or trainer ID: " + err.Error()})
+		c.JSON(http.StatusBadRequest, ResponseError{Error: "Invalid training ID: " + err.Error()})
 		return
 	}
 
-	for _, user := range users {
-		if user.ID == id {
-			c.JSON(http.StatusOK, ResponseSuccess{Message: "User found", Data: user})
-			return
-		}
-	}
-
-	for _, trainer := range trainers {
-		if trainer.ID == id {
-			c.JSON(http.StatusOK, ResponseSuccess{Message: "Trainer found", Data: trainer})
-			return
-		}
+	training, err := h.trainings.GetByID(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, ResponseError{Error: "Training not found with ID " + strconv.Itoa(id)})
+		return
 	}
 
-	c.JSON(http.StatusNotFound, ResponseError{Error: "User or trainer not found with ID " + strconv.Itoa(id)})
+	c.JSON(http.StatusOK, ResponseSuccess{Message: "Training found", Data: newTrainingView(*training)})
 }
 
 // UpdateTraining godoc
 // @Summary Update a training session by ID
-// @Description Update a training session by ID (only for trainers)
+// @Description Update a training session by ID (only for its trainer or an admin). A session in a room must lie within the opening hours of its location and cannot have more seats than the room. Sessions overlapping other sessions of the trainer or in the room are rejected unless allow_overlap is set. The capacity cannot be lowered below the confirmed and held seats, which stay as they are. Users registered for the session are notified when its name or times change.
 // @Tags training
 // @Accept json
 // @Produce json
 // @Param id path int true "Training ID"
-// @Param training body domain.Training true "Updated training data"
-// @Success 200 {object} ResponseSuccess{data=domain.Training}
+// @Param training body TrainingRequest true "Updated training data"
+// @Param allow_overlap query bool false "Book the session even if it overlaps other sessions of the trainer or in the room"
+// @Success 200 {object} ResponseSuccess{data=TrainingView}
 // @Failure 400 {object} ResponseError
 // @Failure 403 {object} ResponseError
 // @Failure 404 {object} ResponseError
+// @Failure 409 {object} ResponseConflict
 // @Security BearerAuth
 // @Router /protected/training/{id} [put]
-func UpdateTraining(c *gin.Context) {
-	trainerID := c.MustGet("user_id").(float64)
+func (h *Handler) UpdateTraining(c *gin.Context) {
+	principal := middleware.MustPrincipal(c)
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, ResponseError{Error: "Invalid training ID: " + err.Error()})
 		return
 	}
 
-	var updatedTraining domain.Training
-	if err := c.ShouldBindJSON(&updatedTraining); err != nil {
+	var req TrainingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, ResponseError{Error: "Invalid data: " + err.Error()})
 		return
 	}
 
-	for i, training := range trainings {
-		if training.ID == id {
-			if training.TrainerID != int(trainerID) {
-				c.JSON(http.StatusForbidden, ResponseError{Error: "Not allowed to update this training"})
-				return
-			}
-			updatedTraining.ID = training.ID
-			updatedTraining.TrainerID = training.TrainerID
-			trainings[i] = updatedTraining
-			c.JSON(http.StatusOK, ResponseSuccess{Message: "Training updated successfully", Data: updatedTraining})
-			return
-		}
+	if err := validInterval(req.StartTime, req.EndTime); err != nil {
+		c.JSON(http.StatusBadRequest, ResponseError{Error: "Invalid data: " + err.Error()})
+		return
 	}
 
-	c.JSON(http.StatusNotFound, ResponseError{Error: "Training not found with ID " + strconv.Itoa(id)})
+	training, err := h.trainings.GetByID(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, ResponseError{Error: "Training not found with ID " + strconv.Itoa(id)})
+		return
+	}
+	if !h.policy.CanManageTraining(principal, training) {
+		h.deny(c, principal, policy.ActionUpdateTraining, audit.Resource("training", id), "Not allowed to update this training")
+		return
+	}
+
+	location, ok := h.checkRoom(c, req.RoomID, &req.Capacity)
+	if !ok {
+		return
+	}
+	before := *training
+	req.apply(training)
+	if !checkOpeningHours(c, location, []domain.Training{*training}) {
+		return
+	}
+	allow, ok := allowOverlap(c)
+	if !ok {
+		return
+	}
+	err = h.trainings.Update(c.Request.Context(), training, allow)
+	if xerrors.Is(err, storage.ErrCapacityBelowSeats) {
+		c.JSON(http.StatusConflict, ResponseError{Error: "Capacity is below the seats already taken, cancel registrations first"})
+		return
+	}
+	if respondOverlap(c, err) {
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseError{Error: "Error updating training"})
+		return
+	}
+	if changedForUsers(before, *training) {
+		h.notifyRegistrants(c.Request.Context(), domain.NotificationUpdated, *training)
+	}
+	c.JSON(http.StatusOK, ResponseSuccess{Message: "Training updated successfully", Data: newTrainingView(*training)})
 }
 
 // DeleteTraining godoc
 // @Summary Delete a training session by ID
-// @Description Delete a training session by ID (only for trainers)
+// @Description Delete a training session by ID (only for its trainer or an admin), the users registered for it are notified of the cancellation
 // @Tags training
 // @Produce json
 // @Param id path int true "Training ID"
@@ -212,226 +298,203 @@ func UpdateTraining(c *gin.Context) {
 // @Failure 404 {object} ResponseError
 // @Security BearerAuth
 // @Router /protected/training/{id} [delete]
-func DeleteTraining(c *gin.Context) {
-	trainerID := c.MustGet("user_id").(float64)
+func (h *Handler) DeleteTraining(c *gin.Context) {
+	principal := middleware.MustPrincipal(c)
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, ResponseError{Error: "Invalid training ID: " + err.Error()})
 		return
 	}
 
-	for i, training := range trainings {
-		if training.ID == id {
-			if training.TrainerID != int(trainerID) {
-				c.JSON(http.StatusForbidden, ResponseError{Error: "Not allowed to delete this training"})
-				return
-			}
-			trainings = append(trainings[:i], trainings[i+1:]...)
-			for j, trainer := range trainers {
-				if trainer.ID == int(trainerID) {
-					for k, tid := range trainer.Trainings {
-						if tid == id {
-							trainers[j].Trainings = append(trainers[j].Trainings[:k], trainers[j].Trainings[k+1:]...)
-							break
-						}
-					}
-					break
-				}
-			}
-			c.JSON(http.StatusOK, ResponseSuccess{Message: "Training deleted successfully"})
-			return
-		}
-	}
-
-	c.JSON(http.StatusNotFound, ResponseError{Error: "Training not found with ID " + strconv.Itoa(id)})
-}
-
-// UpdateUserProfile godoc
-// @Summary Update a user or trainer profile by ID
-// @Description Update a user or trainer profile by ID
-// @Tags user
-// @Accept json
-// @Produce json
-// @Param id path int true "User or Trainer ID"
-// @Param user body domain.User true "Updated user data"
-// @Success 200 {object} ResponseSuccess{data=domain.User}
-// @Failure 400 {object} ResponseError
-// @Failure 404 {object} ResponseError
-// @Security BearerAuth
-// @Router /protected/user/{id} [put]
-func UpdateUserProfile(c *gin.Context) {
-	id, err := strconv.Atoi(c.Param("id"))
+	training, err := h.trainings.GetByID(c.Request.Context(), id)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, ResponseError{Error: "Invalid user or trainer ID: " + err.Error()})
+		c.JSON(http.StatusNotFound, ResponseError{Error: "Training not found with ID " + strconv.Itoa(id)})
 		return
 	}
-
-	var updatedUser domain.User
-	if err := c.ShouldBindJSON(&updatedUser); err != nil {
-		c.JSON(http.StatusBadRequest, ResponseError{Error: "Invalid data: " + err.Error()})
-		return
-	}
-
-	for i, user := range users {
-		if user.ID == id {
-			updatedUser.ID = user.ID
-			users[i] = updatedUser
-			c.JSON(http.StatusOK, ResponseSuccess{Message: "User profile updated successfully", Data: updatedUser})
-			return
-		}
-	}
-
-	var updatedTrainer domain.Trainer
-	if err := c.ShouldBindJSON(&updatedTrainer); err != nil {
-		c.JSON(http.StatusBadRequest, ResponseError{Error: "Invalid data: " + err.Error()})
+	if !h.policy.CanManageTraining(principal, training) {
+		h.deny(c, principal, policy.ActionDeleteTraining, audit.Resource("training", id), "Not allowed to delete this training")
 		return
 	}
 
-	for i, trainer := range trainers {
-		if trainer.ID == id {
-			updatedTrainer.ID = trainer.ID
-			trainers[i] = updatedTrainer
-			c.JSON(http.StatusOK, ResponseSuccess{Message: "Trainer profile updated successfully", Data: updatedTrainer})
-			return
-		}
-	}
-
-	c.JSON(http.StatusNotFound, ResponseError{Error: "User or trainer not found with ID " + strconv.Itoa(id)})
-}
-
-// DeleteUserProfile godoc
-// @Summary Delete a user or trainer profile by ID
-// @Description Delete a user or trainer profile by ID
-// @Tags user
-// @Produce json
-// @Param id path int true "User or Trainer ID"
-// @Success 200 {object} ResponseSuccess
-// @Failure 400 {object} ResponseError
-// @Failure 404 {object} ResponseError
-// @Security BearerAuth
-// @Router /protected/user/{id} [delete]
-func DeleteUserProfile(c *gin.Context) {
-	id, err := strconv.Atoi(c.Param("id"))
+	// Listed first, the registrations are gone with the training
+	registrants, err := h.trainings.ListRegistrants(c.Request.Context(), id)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, ResponseError{Error: "Invalid user or trainer ID: " + err.Error()})
+		c.JSON(http.StatusInternalServerError, ResponseError{Error: "Error deleting training"})
 		return
 	}
-
-	for i, user := range users {
-		if user.ID == id {
-			users = append(users[:i], users[i+1:]...)
-			c.JSON(http.StatusOK, ResponseSuccess{Message: "User profile deleted successfully"})
-			return
-		}
-	}
-
-	for i, trainer := range trainers {
-		if trainer.ID == id {
-			trainers = append(trainers[:i], trainers[i+1:]...)
-			c.JSON(http.StatusOK, ResponseSuccess{Message: "Trainer profile deleted successfully"})
-			return
-		}
+	if err := h.trainings.Delete(c.Request.Context(), id); err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseError{Error: "Error deleting training"})
+		return
 	}
-
-	c.JSON(http.StatusNotFound, ResponseError{Error: "User or trainer not found with ID " + strconv.Itoa(id)})
+	h.notify(c.Request.Context(), domain.NotificationCancelled, *training, registrants...)
+	c.JSON(http.StatusOK, ResponseSuccess{Message: "Training deleted successfully"})
 }
 
 // GetUserSchedule godoc
 // @Summary Get the schedule for the current user
-// @Description Get the schedule for the current user
+// @Description Get the trainings the current user has a seat in (only for users), filtered, sorted and paginated like GET /trainings
 // @Tags user
 // @Produce json
-// @Success 200 {object} ResponseSuccess{data=[]domain.Training}
+// @Param type query string false "Only trainings of this type"
+// @Param level query string false "Only trainings of this level"
+// @Param trainer_id query int false "Only trainings of this trainer"
+// @Param location_id query int false "Only trainings in the rooms of this location"
+// @Param from query string false "Only trainings starting at or after this RFC 3339 time"
+// @Param to query string false "Only trainings starting before this RFC 3339 time"
+// @Param has_free_spots query bool false "Only trainings with unlimited capacity or free seats"
+// @Param q query string false "Only trainings whose name contains this text, ignoring case"
+// @Param sort query string false "Sort order" Enums(start_time, -start_time, name, -name) default(start_time)
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param limit query int false "Maximum number of trainings (default 50, at most 500)"
+// @Success 200 {object} ResponsePage{data=[]TrainingView}
+// @Failure 400 {object} ResponseError
+// @Failure 403 {object} ResponseError
 // @Security BearerAuth
 // @Router /protected/user/schedule [get]
-func GetUserSchedule(c *gin.Context) {
-	userID := c.MustGet("user_id").(float64)
-	var userTrainings []domain.Training
-
-	for _, training := range trainings {
-		for _, user := range training.Users {
-			if user == int(userID) {
-				userTrainings = append(userTrainings, training)
-				break
-			}
-		}
+func (h *Handler) GetUserSchedule(c *gin.Context) {
+	principal := middleware.MustPrincipal(c)
+	filter, after, limit, ok := bindTrainingFilter(c)
+	if !ok {
+		return
 	}
 
-	sort.Slice(userTrainings, func(i, j int) bool {
-		return userTrainings[i].StartTime.Before(userTrainings[j].StartTime)
-	})
+	filter.UserID = principal.ID
+	page, err := h.trainings.Search(c.Request.Context(), filter, after, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseError{Error: "Error retrieving user schedule"})
+		return
+	}
 
-	c.JSON(http.StatusOK, ResponseSuccess{Message: "User schedule retrieved", Data: userTrainings})
+	respondTrainings(c, "User schedule retrieved", filter.Sort, page)
 }
 
 // GetTrainerSchedule godoc
 // @Summary Get the schedule for the current trainer
-// @Description Get the schedule for the current trainer
+// @Description Get the trainings of the current trainer (only for trainers), filtered, sorted and paginated like GET /trainings; trainer_id is ignored
 // @Tags trainer
 // @Produce json
-// @Success 200 {object} ResponseSuccess{data=[]domain.Training}
+// @Param type query string false "Only trainings of this type"
+// @Param level query string false "Only trainings of this level"
+// @Param trainer_id query int false "Only trainings of this trainer"
+// @Param location_id query int false "Only trainings in the rooms of this location"
+// @Param from query string false "Only trainings starting at or after this RFC 3339 time"
+// @Param to query string false "Only trainings starting before this RFC 3339 time"
+// @Param has_free_spots query bool false "Only trainings with unlimited capacity or free seats"
+// @Param q query string false "Only trainings whose name contains this text, ignoring case"
+// @Param sort query string false "Sort order" Enums(start_time, -start_time, name, -name) default(start_time)
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param limit query int false "Maximum number of trainings (default 50, at most 500)"
+// @Success 200 {object} ResponsePage{data=[]TrainingView}
+// @Failure 400 {object} ResponseError
+// @Failure 403 {object} ResponseError
 // @Security BearerAuth
 // @Router /protected/trainer/schedule [get]
-func GetTrainerSchedule(c *gin.Context) {
-	trainerID := c.MustGet("user_id").(float64)
-	var trainerTrainings []domain.Training
-
-	for _, training := range trainings {
-		if training.TrainerID == int(trainerID) {
-			trainerTrainings = append(trainerTrainings, training)
-		}
+func (h *Handler) GetTrainerSchedule(c *gin.Context) {
+	principal := middleware.MustPrincipal(c)
+	filter, after, limit, ok := bindTrainingFilter(c)
+	if !ok {
+		return
 	}
 
-	sort.Slice(trainerTrainings, func(i, j int) bool {
-		return trainerTrainings[i].StartTime.Before(trainerTrainings[j].StartTime)
-	})
+	filter.TrainerID = principal.ID
+	page, err := h.trainings.Search(c.Request.Context(), filter, after, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseError{Error: "Error retrieving trainer schedule"})
+		return
+	}
 
-	c.JSON(http.StatusOK, ResponseSuccess{Message: "Trainer schedule retrieved", Data: trainerTrainings})
+	respondTrainings(c, "Trainer schedule retrieved", filter.Sort, page)
 }
 
 // GetAllTrainings godoc
 // @Summary Get all available trainings
-// @Description Get all available trainings
+// @Description Get the trainings matching all given filters, one page at a time. Pass the next_cursor of a page as cursor, with the same filters and sort order, to get the following page; next_cursor is empty on the last page and total counts all matching trainings.
 // @Tags training
 // @Produce json
-// @Success 200 {object} ResponseSuccess{data=[]domain.Training}
+// @Param type query string false "Only trainings of this type"
+// @Param level query string false "Only trainings of this level"
+// @Param trainer_id query int false "Only trainings of this trainer"
+// @Param location_id query int false "Only trainings in the rooms of this location"
+// @Param from query string false "Only trainings starting at or after this RFC 3339 time"
+// @Param to query string false "Only trainings starting before this RFC 3339 time"
+// @Param has_free_spots query bool false "Only trainings with unlimited capacity or free seats"
+// @Param q query string false "Only trainings whose name contains this text, ignoring case"
+// @Param sort query string false "Sort order" Enums(start_time, -start_time, name, -name) default(start_time)
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param limit query int false "Maximum number of trainings (default 50, at most 500)"
+// @Success 200 {object} ResponsePage{data=[]TrainingView}
+// @Failure 400 {object} ResponseError
 // @Router /trainings [get]
-func GetAllTrainings(c *gin.Context) {
-	c.JSON(http.StatusOK, ResponseSuccess{Message: "All trainings retrieved", Data: trainings})
+func (h *Handler) GetAllTrainings(c *gin.Context) {
+	filter, after, limit, ok := bindTrainingFilter(c)
+	if !ok {
+		return
+	}
+
+	page, err := h.trainings.Search(c.Request.Context(), filter, after, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseError{Error: "Error retrieving trainings"})
+		return
+	}
+
+	respondTrainings(c, "All trainings retrieved", filter.Sort, page)
 }
 
 // GetUsersByTrainingID godoc
 // @Summary Get all users by training ID
-// @Description Get all users by training ID
+// @Description Get the users with a seat in the training ordered by ID, one page at a time (only for the trainer of the training and admins). Trainers only see the name and health description of their trainees.
 // @Tags training
 // @Produce json
 // @Param training_id path int true "Training ID"
-// @Success 200 {object} ResponseSuccess{data=[]domain.User}
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param limit query int false "Maximum number of users (default 50, at most 500)"
+// @Success 200 {object} ResponsePage{data=[]UserView}
 // @Failure 400 {object} ResponseError
+// @Failure 403 {object} ResponseError
 // @Failure 404 {object} ResponseError
 // @Security BearerAuth
 // @Router /protected/training/{training_id}/users [get]
-func GetUsersByTrainingID(c *gin.Context) {
+func (h *Handler) GetUsersByTrainingID(c *gin.Context) {
+	principal := middleware.MustPrincipal(c)
 	trainingID, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, ResponseError{Error: "Invalid training ID: " + err.Error()})
 		return
 	}
 
-	var trainingUsers []domain.User
-	for _, training := range trainings {
-		if training.ID == trainingID {
-			for _, userID := range training.Users {
-				for _, user := range users {
-					if user.ID == userID {
-						trainingUsers = append(trainingUsers, user)
-					}
-				}
-			}
-			c.JSON(http.StatusOK, ResponseSuccess{Message: "Users found for training", Data: trainingUsers})
-			return
-		}
+	training, err := h.trainings.GetByID(c.Request.Context(), trainingID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, ResponseError{Error: "Training not found with ID " + strconv.Itoa(trainingID)})
+		return
+	}
+	access := h.policy.ViewTrainees(principal, training)
+	if access == policy.Denied {
+		h.deny(c, principal, policy.ActionViewTrainees, audit.Resource("training", trainingID), "Not allowed to view the users of this training")
+		return
 	}
 
-	c.JSON(http.StatusNotFound, ResponseError{Error: "Training not found with ID " + strconv.Itoa(trainingID)})
+	cur, limit, ok := bindPage(c, usersSort)
+	if !ok {
+		return
+	}
+	var afterID int
+	if cur != nil {
+		afterID = cur.ID
+	}
+
+	page, err := h.users.ListByTraining(c.Request.Context(), trainingID, afterID, limit)
+	if err != nil {
+		c.JSON(http.StatusNotFound, ResponseError{Error: "Training not found with ID " + strconv.Itoa(trainingID)})
+		return
+	}
+
+	var next string
+	if page.More {
+		next = cursor{Sort: usersSort, ID: page.Items[len(page.Items)-1].ID}.encode()
+	}
+	response := ResponsePage{Message: "Users found for training", Data: newUserViews(page.Items), Total: page.Total, NextCursor: next}
+	if access == policy.Limited {
+		response.Data = newTraineeViews(page.Items)
+	}
+	c.JSON(http.StatusOK, response)
 }
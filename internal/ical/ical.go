@@ -0,0 +1,121 @@
+// Package ical writes RFC 5545 iCalendar files.
+//
+// Only what schedules need is supported: a VCALENDAR with timed VEVENTs whose
+// times are written in UTC.
+package ical
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ProductID is the PRODID of written calendars
+const ProductID = "-//fitness-app//schedule//EN"
+
+// maxLineOctets is the length content lines are folded at
+const maxLineOctets = 75
+
+// Calendar is a VCALENDAR
+type Calendar struct {
+	// Name is shown by calendar apps as the name of a subscribed calendar
+	Name string
+	// RefreshInterval tells calendar apps how often to poll a subscribed
+	// calendar, it is left out when zero
+	RefreshInterval time.Duration
+	Events          []Event
+}
+
+// Event is a VEVENT
+type Event struct {
+	// UID identifies the event across versions of the calendar
+	UID string
+	// Sequence is the revision of the event, it is raised when its times change
+	Sequence    int
+	Stamp       time.Time
+	Start       time.Time
+	End         time.Time
+	Summary     string
+	Description string
+	Location    string
+}
+
+// Write writes the calendar to w
+func Write(w io.Writer, cal Calendar) error {
+	bw := bufio.NewWriter(w)
+	line := func(name, value string) {
+		writeLine(bw, name+":"+value)
+	}
+
+	line("BEGIN", "VCALENDAR")
+	line("VERSION", "2.0")
+	line("PRODID", ProductID)
+	line("CALSCALE", "GREGORIAN")
+	line("METHOD", "PUBLISH")
+	if cal.Name != "" {
+		line("X-WR-CALNAME", escape(cal.Name))
+	}
+	if cal.RefreshInterval > 0 {
+		line("REFRESH-INTERVAL;VALUE=DURATION", duration(cal.RefreshInterval))
+		line("X-PUBLISHED-TTL", duration(cal.RefreshInterval))
+	}
+	for _, e := range cal.Events {
+		line("BEGIN", "VEVENT")
+		line("UID", escape(e.UID))
+		line("SEQUENCE", strconv.Itoa(e.Sequence))
+		line("DTSTAMP", dateTime(e.Stamp))
+		line("DTSTART", dateTime(e.Start))
+		line("DTEND", dateTime(e.End))
+		line("SUMMARY", escape(e.Summary))
+		if e.Description != "" {
+			line("DESCRIPTION", escape(e.Description))
+		}
+		if e.Location != "" {
+			line("LOCATION", escape(e.Location))
+		}
+		line("END", "VEVENT")
+	}
+	line("END", "VCALENDAR")
+	return bw.Flush()
+}
+
+// writeLine writes a content line folded into lines of at most 75 octets,
+// continuation lines start with a space
+func writeLine(w *bufio.Writer, s string) {
+	limit := maxLineOctets
+	for len(s) > limit {
+		cut := limit
+		// Do not split multi-octet characters
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		w.WriteString(s[:cut])
+		w.WriteString("\r\n ")
+		s = s[cut:]
+		// The leading space counts towards the length of continuation lines
+		limit = maxLineOctets - 1
+	}
+	w.WriteString(s)
+	w.WriteString("\r\n")
+}
+
+// textEscaper escapes TEXT values. Line breaks become \n, a bare CR as well
+// since calendar apps would take it for the end of the content line.
+var textEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`)
+
+// escape escapes a TEXT value
+func escape(s string) string {
+	return textEscaper.Replace(s)
+}
+
+func dateTime(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+// duration formats d as a DURATION value of whole seconds
+func duration(d time.Duration) string {
+	return "PT" + strconv.FormatInt(int64(d/time.Second), 10) + "S"
+}
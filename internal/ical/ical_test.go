@@ -0,0 +1,169 @@
+package ical
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+func TestWriteGolden(t *testing.T) {
+	stamp := time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC)
+	berlin := time.FixedZone("CET", 3600)
+	cal := Calendar{
+		Name:            "Schedule of Zoë",
+		RefreshInterval: time.Hour,
+		Events: []Event{
+			{
+				UID:         "training-1@fitness-app",
+				Sequence:    0,
+				Stamp:       stamp,
+				Start:       time.Date(2024, 3, 4, 10, 0, 0, 0, berlin),
+				End:         time.Date(2024, 3, 4, 11, 0, 0, 0, berlin),
+				Summary:     "Yoga; stretching, breathing",
+				Description: "Type: yoga\nLevel: beginner\r\nBring a mat\\towel\rand water",
+				Location:    "Hall, Studio, Straße 1",
+			},
+			{
+				UID:      "training-2@fitness-app",
+				Sequence: 3,
+				Stamp:    stamp,
+				Start:    time.Date(2024, 3, 5, 18, 0, 0, 0, time.UTC),
+				End:      time.Date(2024, 3, 5, 19, 30, 0, 0, time.UTC),
+				Summary:  strings.Repeat("Кроссфит ", 12) + "🏋️ " + strings.Repeat("x", 80),
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, cal); err != nil {
+		t.Fatal(err)
+	}
+	golden := filepath.Join("testdata", "schedule.ics")
+	if *update {
+		if err := os.WriteFile(golden, buf.Bytes(), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), want) {
+		t.Errorf("calendar differs from %s, run the tests with -update to accept it:\n%s", golden, buf.Bytes())
+	}
+}
+
+func TestWriteLine(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want string
+	}{
+		{
+			name: "short",
+			line: "SUMMARY:Yoga",
+			want: "SUMMARY:Yoga\r\n",
+		},
+		{
+			name: "exactly 75 octets",
+			line: strings.Repeat("a", 75),
+			want: strings.Repeat("a", 75) + "\r\n",
+		},
+		{
+			name: "76 octets",
+			line: strings.Repeat("a", 76),
+			want: strings.Repeat("a", 75) + "\r\n a\r\n",
+		},
+		{
+			name: "continuation lines count the leading space",
+			line: strings.Repeat("a", 75+74+1),
+			want: strings.Repeat("a", 75) + "\r\n " + strings.Repeat("a", 74) + "\r\n a\r\n",
+		},
+		{
+			name: "two octet rune across the fold",
+			line: strings.Repeat("a", 74) + "ë" + "b",
+			want: strings.Repeat("a", 74) + "\r\n ëb\r\n",
+		},
+		{
+			name: "four octet rune across the fold",
+			line: strings.Repeat("a", 73) + "🏋" + "b",
+			want: strings.Repeat("a", 73) + "\r\n 🏋b\r\n",
+		},
+		{
+			name: "rune ending at the fold",
+			line: strings.Repeat("a", 73) + "ë" + "b",
+			want: strings.Repeat("a", 73) + "ë\r\n b\r\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w := bufio.NewWriter(&buf)
+			writeLine(w, tt.line)
+			if err := w.Flush(); err != nil {
+				t.Fatal(err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("got  %q\nwant %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteLineKeepsRunes(t *testing.T) {
+	line := "SUMMARY:" + strings.Repeat("Zoë Кроссфит 🏋️ ", 20)
+	var buf bytes.Buffer
+	w := bufio.NewWriter(&buf)
+	writeLine(w, line)
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	folded := strings.Split(strings.TrimSuffix(buf.String(), "\r\n"), "\r\n")
+	var unfolded strings.Builder
+	for i, l := range folded {
+		if len(l) > maxLineOctets {
+			t.Errorf("line %d has %d octets", i, len(l))
+		}
+		if !utf8.ValidString(l) {
+			t.Errorf("line %d splits a rune: %q", i, l)
+		}
+		if i > 0 {
+			l = strings.TrimPrefix(l, " ")
+		}
+		unfolded.WriteString(l)
+	}
+	if unfolded.String() != line {
+		t.Errorf("unfolded line differs:\ngot  %q\nwant %q", unfolded.String(), line)
+	}
+}
+
+func TestEscape(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: "plain", want: "plain"},
+		{in: `back\slash`, want: `back\\slash`},
+		{in: "a;b,c", want: `a\;b\,c`},
+		{in: "one\ntwo", want: `one\ntwo`},
+		{in: "one\r\ntwo", want: `one\ntwo`},
+		{in: "one\rtwo", want: `one\ntwo`},
+		{in: "\r\n\r\n", want: `\n\n`},
+		{in: "\n\r", want: `\n\n`},
+		{in: `\n`, want: `\\n`},
+	}
+	for _, tt := range tests {
+		if got := escape(tt.in); got != tt.want {
+			t.Errorf("escape(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -1,36 +1,228 @@
 package middleware
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
+	"strconv"
 	"time"
 
+	"github.com/folklinoff/fitness-app/internal/domain"
+	"github.com/folklinoff/fitness-app/internal/storage"
 	jwt "github.com/golang-jwt/jwt/v4"
+	"golang.org/x/xerrors"
 )
 
-var secretKey = []byte("secretpassword")
+// Issue times carry microseconds, so revoking an account also revokes the tokens
+// issued earlier within the same second. The storage keeps revocation times to
+// the microsecond as well.
+func init() {
+	jwt.TimePrecision = time.Microsecond
+}
 
-// GenerateToken generates a JWT token with the user ID as part of the claims
-func GenerateToken(userID uint, userType string) (string, error) {
-	claims := jwt.MapClaims{}
-	claims["user_id"] = userID
-	claims["exp"] = time.Now().Add(time.Hour * 1).Unix() // Token valid for 1 hour
-	claims["type"] = userType
+// ErrInvalidRefreshToken is returned for unknown, expired, used or revoked refresh tokens
+var ErrInvalidRefreshToken = xerrors.New("invalid refresh token")
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(secretKey)
+// Claims are the claims of an access token
+type Claims struct {
+	UserID   int    `json:"user_id"`
+	UserType string `json:"user_type"`
+	jwt.RegisteredClaims
 }
 
-// VerifyToken verifies a token JWT validate
-func VerifyToken(tokenString string) (jwt.MapClaims, error) {
-	// Parse the token
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		// Check the signing method
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("Invalid signing method")
-		}
+// Principal returns the account the token was issued to
+func (c *Claims) Principal() Principal {
+	return Principal{ID: c.UserID, Type: c.UserType}
+}
+
+// Tokens are the access and refresh tokens of a session
+type Tokens struct {
+	AccessToken  string
+	RefreshToken string
+	// ExpiresAt is the expiry of the access token
+	ExpiresAt time.Time
+}
+
+// TokenManager issues and verifies JWT access tokens with the keys of a KeySet
+// and manages the refresh tokens of sessions
+type TokenManager struct {
+	keys       *KeySet
+	sessions   storage.SessionRepository
+	ttl        time.Duration
+	refreshTTL time.Duration
+	issuer     string
+	audience   string
+}
+
+// TokenConfig configures the tokens issued by a TokenManager
+type TokenConfig struct {
+	// TTL is the lifetime of access tokens
+	TTL time.Duration
+	// RefreshTTL is the lifetime of refresh tokens
+	RefreshTTL time.Duration
+	Issuer     string
+	Audience   string
+}
+
+// NewTokenManager creates a TokenManager storing sessions in the repository
+func NewTokenManager(keys *KeySet, sessions storage.SessionRepository, cfg TokenConfig) *TokenManager {
+	return &TokenManager{
+		keys:       keys,
+		sessions:   sessions,
+		ttl:        cfg.TTL,
+		refreshTTL: cfg.RefreshTTL,
+		issuer:     cfg.Issuer,
+		audience:   cfg.Audience,
+	}
+}
+
+// Keys returns the key set tokens are signed and verified with
+func (m *TokenManager) Keys() *KeySet {
+	return m.keys
+}
+
+// GenerateToken generates a JWT access token for the principal
+func (m *TokenManager) GenerateToken(p Principal) (string, error) {
+	token, _, err := m.generateToken(p, time.Now())
+	return token, err
+}
+
+func (m *TokenManager) generateToken(p Principal, now time.Time) (string, time.Time, error) {
+	jti, err := newTokenID()
+	if err != nil {
+		return "", time.Time{}, err
+	}
 
-		return secretKey, nil
+	claims := Claims{
+		UserID:   p.ID,
+		UserType: p.Type,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    m.issuer,
+			Subject:   p.Type + ":" + strconv.Itoa(p.ID),
+			Audience:  jwt.ClaimStrings{m.audience},
+			ExpiresAt: jwt.NewNumericDate(now.Add(m.ttl)),
+			NotBefore: jwt.NewNumericDate(now),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ID:        jti,
+		},
+	}
+
+	key := m.keys.signing
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.ID
+	signed, err := token.SignedString(key.Sign)
+	return signed, claims.ExpiresAt.Time, err
+}
+
+// IssueTokens starts a new session of the principal
+func (m *TokenManager) IssueTokens(ctx context.Context, p Principal) (Tokens, error) {
+	familyID, err := newTokenID()
+	if err != nil {
+		return Tokens{}, err
+	}
+	return m.issueTokens(ctx, p, familyID)
+}
+
+func (m *TokenManager) issueTokens(ctx context.Context, p Principal, familyID string) (Tokens, error) {
+	now := time.Now()
+	access, expiresAt, err := m.generateToken(p, now)
+	if err != nil {
+		return Tokens{}, err
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return Tokens{}, xerrors.Errorf("generate refresh token: %w", err)
+	}
+	refresh := base64.RawURLEncoding.EncodeToString(secret)
+
+	err = m.sessions.CreateRefreshToken(ctx, &domain.RefreshToken{
+		Hash:        hashRefreshToken(refresh),
+		FamilyID:    familyID,
+		AccountType: p.Type,
+		AccountID:   p.ID,
+		IssuedAt:    now,
+		ExpiresAt:   now.Add(m.refreshTTL),
 	})
+	if err != nil {
+		return Tokens{}, xerrors.Errorf("store refresh token: %w", err)
+	}
+
+	return Tokens{AccessToken: access, RefreshToken: refresh, ExpiresAt: expiresAt}, nil
+}
+
+// Refresh exchanges a refresh token for new access and refresh tokens. Each
+// refresh token is accepted once; presenting a used one again means it leaked,
+// so the whole session is revoked.
+func (m *TokenManager) Refresh(ctx context.Context, refreshToken string) (Tokens, error) {
+	now := time.Now()
+	hash := hashRefreshToken(refreshToken)
+
+	token, err := m.sessions.GetRefreshToken(ctx, hash)
+	if xerrors.Is(err, storage.ErrNotFound) {
+		return Tokens{}, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return Tokens{}, err
+	}
+	if !token.RevokedAt.IsZero() || !token.ExpiresAt.After(now) {
+		return Tokens{}, ErrInvalidRefreshToken
+	}
+
+	err = m.sessions.UseRefreshToken(ctx, hash, now)
+	if xerrors.Is(err, storage.ErrNotFound) {
+		// Already used, either earlier or by a concurrent request
+		if err := m.sessions.RevokeRefreshTokenFamily(ctx, token.FamilyID, now); err != nil {
+			return Tokens{}, err
+		}
+		return Tokens{}, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return Tokens{}, err
+	}
+
+	return m.issueTokens(ctx, Principal{ID: token.AccountID, Type: token.AccountType}, token.FamilyID)
+}
+
+// Logout revokes the access token and, when given, the session of the refresh token
+func (m *TokenManager) Logout(ctx context.Context, claims *Claims, refreshToken string) error {
+	now := time.Now()
+	if claims != nil {
+		if err := m.sessions.RevokeAccessToken(ctx, claims.ID, claims.ExpiresAt.Time); err != nil {
+			return err
+		}
+	}
+	if refreshToken == "" {
+		return nil
+	}
+
+	token, err := m.sessions.GetRefreshToken(ctx, hashRefreshToken(refreshToken))
+	if xerrors.Is(err, storage.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	// A principal may only end its own sessions
+	if claims != nil && (token.AccountID != claims.UserID || token.AccountType != claims.UserType) {
+		return nil
+	}
+	return m.sessions.RevokeRefreshTokenFamily(ctx, token.FamilyID, now)
+}
+
+// LogoutAll revokes every session of the principal and all access tokens issued to it so far
+func (m *TokenManager) LogoutAll(ctx context.Context, p Principal) error {
+	return m.sessions.RevokeAccount(ctx, p.Type, p.ID, time.Now())
+}
+
+// VerifyToken verifies the token signature, lifetime, issuer and audience, and
+// that it has not been revoked
+func (m *TokenManager) VerifyToken(ctx context.Context, tokenString string) (*Claims, error) {
+	// Parse the token, the key is picked by its kid header
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, m.keys.lookup)
 
 	// Check for errors
 	if err != nil {
@@ -38,9 +230,39 @@ func VerifyToken(tokenString string) (jwt.MapClaims, error) {
 	}
 
 	// Validate the token
-	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		return claims, nil
+	claims, ok := token.Claims.(*Claims)
+	if !ok || !token.Valid {
+		return nil, fmt.Errorf("Invalid token")
+	}
+	if !claims.VerifyIssuer(m.issuer, true) || !claims.VerifyAudience(m.audience, true) {
+		return nil, fmt.Errorf("Invalid token issuer or audience")
+	}
+	if claims.UserID <= 0 || !isUserType(claims.UserType) {
+		return nil, fmt.Errorf("Invalid token subject")
 	}
 
-	return nil, fmt.Errorf("Invalid token")
+	revoked, err := m.sessions.IsAccessTokenRevoked(ctx, claims.ID, claims.UserType, claims.UserID, claims.IssuedAt.Time)
+	if err != nil {
+		return nil, xerrors.Errorf("check token revocation: %w", err)
+	}
+	if revoked {
+		return nil, fmt.Errorf("Token has been revoked")
+	}
+
+	return claims, nil
+}
+
+// hashRefreshToken returns the stored form of a refresh token, the tokens are
+// random enough for a plain SHA-256 to be safe
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func newTokenID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", xerrors.Errorf("generate token id: %w", err)
+	}
+	return hex.EncodeToString(b), nil
 }
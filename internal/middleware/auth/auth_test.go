@@ -0,0 +1,293 @@
+package middleware_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	middleware "github.com/folklinoff/fitness-app/internal/middleware/auth"
+	"github.com/folklinoff/fitness-app/internal/storage"
+	"github.com/folklinoff/fitness-app/internal/storage/memory"
+	jwt "github.com/golang-jwt/jwt/v4"
+	"golang.org/x/xerrors"
+)
+
+var secret = []byte("0123456789abcdef0123456789abcdef")
+
+func newTokenManager(t *testing.T) *middleware.TokenManager {
+	t.Helper()
+	keys, err := middleware.NewKeySet(middleware.Key{ID: "test", Method: jwt.SigningMethodHS256, Sign: secret, Verify: secret})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := memory.New(storage.Options{})
+	return middleware.NewTokenManager(keys, store.Sessions, middleware.TokenConfig{
+		TTL:        time.Hour,
+		RefreshTTL: 24 * time.Hour,
+		Issuer:     "fitness-app",
+		Audience:   "fitness-app",
+	})
+}
+
+func issue(t *testing.T, tokens *middleware.TokenManager, p middleware.Principal) middleware.Tokens {
+	t.Helper()
+	issued, err := tokens.IssueTokens(context.Background(), p)
+	if err != nil {
+		t.Fatalf("issue tokens: %v", err)
+	}
+	return issued
+}
+
+func TestRefreshRotatesTokens(t *testing.T) {
+	ctx := context.Background()
+	tokens := newTokenManager(t)
+	alice := middleware.Principal{ID: 1, Type: "user"}
+	first := issue(t, tokens, alice)
+
+	second, err := tokens.Refresh(ctx, first.RefreshToken)
+	if err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	if second.RefreshToken == first.RefreshToken || second.AccessToken == first.AccessToken {
+		t.Error("refresh returned the presented tokens")
+	}
+	claims, err := tokens.VerifyToken(ctx, second.AccessToken)
+	if err != nil {
+		t.Fatalf("verify refreshed access token: %v", err)
+	}
+	if claims.Principal() != alice {
+		t.Errorf("refreshed token of %+v, want %+v", claims.Principal(), alice)
+	}
+	third, err := tokens.Refresh(ctx, second.RefreshToken)
+	if err != nil {
+		t.Fatalf("refresh the rotated token: %v", err)
+	}
+
+	// Presenting a used token again revokes the whole session
+	if _, err := tokens.Refresh(ctx, first.RefreshToken); !xerrors.Is(err, middleware.ErrInvalidRefreshToken) {
+		t.Fatalf("reuse refresh token: got %v, want ErrInvalidRefreshToken", err)
+	}
+	if _, err := tokens.Refresh(ctx, third.RefreshToken); !xerrors.Is(err, middleware.ErrInvalidRefreshToken) {
+		t.Errorf("refresh after reuse: got %v, want ErrInvalidRefreshToken", err)
+	}
+
+	// Other sessions are kept
+	other := issue(t, tokens, alice)
+	if _, err := tokens.Refresh(ctx, other.RefreshToken); err != nil {
+		t.Errorf("refresh another session: %v", err)
+	}
+	if _, err := tokens.Refresh(ctx, "unknown"); !xerrors.Is(err, middleware.ErrInvalidRefreshToken) {
+		t.Errorf("refresh unknown token: got %v, want ErrInvalidRefreshToken", err)
+	}
+}
+
+func TestLogoutDeniesTokens(t *testing.T) {
+	ctx := context.Background()
+	tokens := newTokenManager(t)
+	alice := middleware.Principal{ID: 1, Type: "user"}
+	bob := middleware.Principal{ID: 2, Type: "user"}
+	session := issue(t, tokens, alice)
+	other := issue(t, tokens, alice)
+	bobs := issue(t, tokens, bob)
+
+	claims, err := tokens.VerifyToken(ctx, session.AccessToken)
+	if err != nil {
+		t.Fatalf("verify access token: %v", err)
+	}
+	// A principal cannot end the sessions of others
+	if err := tokens.Logout(ctx, claims, bobs.RefreshToken); err != nil {
+		t.Fatalf("logout with the refresh token of another account: %v", err)
+	}
+	if _, err := tokens.Refresh(ctx, bobs.RefreshToken); err != nil {
+		t.Errorf("refresh the session of another account after logout: %v", err)
+	}
+
+	if err := tokens.Logout(ctx, claims, session.RefreshToken); err != nil {
+		t.Fatalf("logout: %v", err)
+	}
+	if _, err := tokens.VerifyToken(ctx, session.AccessToken); err == nil {
+		t.Error("access token is valid after logout")
+	}
+	if _, err := tokens.Refresh(ctx, session.RefreshToken); !xerrors.Is(err, middleware.ErrInvalidRefreshToken) {
+		t.Errorf("refresh after logout: got %v, want ErrInvalidRefreshToken", err)
+	}
+	if _, err := tokens.VerifyToken(ctx, other.AccessToken); err != nil {
+		t.Errorf("access token of another session after logout: %v", err)
+	}
+}
+
+func TestLogoutAllRevokesSessions(t *testing.T) {
+	ctx := context.Background()
+	tokens := newTokenManager(t)
+	alice := middleware.Principal{ID: 1, Type: "user"}
+	// The same ID with another account type is another account
+	trainer := middleware.Principal{ID: 1, Type: "trainer"}
+	sessions := []middleware.Tokens{issue(t, tokens, alice), issue(t, tokens, alice)}
+	kept := issue(t, tokens, trainer)
+
+	if err := tokens.LogoutAll(ctx, alice); err != nil {
+		t.Fatalf("logout all: %v", err)
+	}
+	for i, session := range sessions {
+		if _, err := tokens.VerifyToken(ctx, session.AccessToken); err == nil {
+			t.Errorf("access token of session %d is valid after logging out all sessions", i)
+		}
+		if _, err := tokens.Refresh(ctx, session.RefreshToken); !xerrors.Is(err, middleware.ErrInvalidRefreshToken) {
+			t.Errorf("refresh session %d: got %v, want ErrInvalidRefreshToken", i, err)
+		}
+	}
+	if _, err := tokens.VerifyToken(ctx, kept.AccessToken); err != nil {
+		t.Errorf("access token of another account: %v", err)
+	}
+	if _, err := tokens.Refresh(ctx, kept.RefreshToken); err != nil {
+		t.Errorf("refresh session of another account: %v", err)
+	}
+}
+
+func TestLogoutAllRevokesTokensOfTheSameSecond(t *testing.T) {
+	ctx := context.Background()
+	tokens := newTokenManager(t)
+	alice := middleware.Principal{ID: 1, Type: "user"}
+
+	// Issued and revoked within one second
+	for time.Now().Nanosecond() > int(900*time.Millisecond) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	issued, err := tokens.IssueTokens(ctx, alice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tokens.LogoutAll(ctx, alice); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tokens.VerifyToken(ctx, issued.AccessToken); err == nil {
+		t.Error("token issued in the second of the logout is still valid")
+	}
+
+	// Issue times have microseconds, so this one follows the logout
+	time.Sleep(time.Millisecond)
+	again, err := tokens.IssueTokens(ctx, alice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tokens.VerifyToken(ctx, again.AccessToken); err != nil {
+		t.Errorf("token issued after the logout: %v", err)
+	}
+}
+
+func TestVerifyTokenClaims(t *testing.T) {
+	tokens := newTokenManager(t)
+	now := time.Now()
+	valid := func() middleware.Claims {
+		return middleware.Claims{
+			UserID:   1,
+			UserType: middleware.UserTypeUser,
+			RegisteredClaims: jwt.RegisteredClaims{
+				Issuer:    "fitness-app",
+				Subject:   "user:1",
+				Audience:  jwt.ClaimStrings{"fitness-app"},
+				ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour)),
+				NotBefore: jwt.NewNumericDate(now),
+				IssuedAt:  jwt.NewNumericDate(now),
+				ID:        "jti",
+			},
+		}
+	}
+	sign := func(method jwt.SigningMethod, kid interface{}, key interface{}, claims middleware.Claims) string {
+		t.Helper()
+		token := jwt.NewWithClaims(method, claims)
+		if kid != nil {
+			token.Header["kid"] = kid
+		}
+		signed, err := token.SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signed
+	}
+
+	tests := []struct {
+		name    string
+		token   func() string
+		wantErr bool
+	}{
+		{name: "valid", token: func() string {
+			return sign(jwt.SigningMethodHS256, "test", secret, valid())
+		}},
+		{name: "expired", wantErr: true, token: func() string {
+			claims := valid()
+			claims.ExpiresAt = jwt.NewNumericDate(now.Add(-time.Minute))
+			return sign(jwt.SigningMethodHS256, "test", secret, claims)
+		}},
+		{name: "not valid yet", wantErr: true, token: func() string {
+			claims := valid()
+			claims.NotBefore = jwt.NewNumericDate(now.Add(time.Minute))
+			return sign(jwt.SigningMethodHS256, "test", secret, claims)
+		}},
+		{name: "other issuer", wantErr: true, token: func() string {
+			claims := valid()
+			claims.Issuer = "other"
+			return sign(jwt.SigningMethodHS256, "test", secret, claims)
+		}},
+		{name: "missing issuer", wantErr: true, token: func() string {
+			claims := valid()
+			claims.Issuer = ""
+			return sign(jwt.SigningMethodHS256, "test", secret, claims)
+		}},
+		{name: "other audience", wantErr: true, token: func() string {
+			claims := valid()
+			claims.Audience = jwt.ClaimStrings{"other"}
+			return sign(jwt.SigningMethodHS256, "test", secret, claims)
+		}},
+		{name: "missing audience", wantErr: true, token: func() string {
+			claims := valid()
+			claims.Audience = nil
+			return sign(jwt.SigningMethodHS256, "test", secret, claims)
+		}},
+		{name: "other HMAC algorithm", wantErr: true, token: func() string {
+			return sign(jwt.SigningMethodHS384, "test", secret, valid())
+		}},
+		{name: "alg none", wantErr: true, token: func() string {
+			return sign(jwt.SigningMethodNone, "test", jwt.UnsafeAllowNoneSignatureType, valid())
+		}},
+		{name: "other secret", wantErr: true, token: func() string {
+			return sign(jwt.SigningMethodHS256, "test", []byte("another secret of at least 32 bytes"), valid())
+		}},
+		{name: "unknown kid", wantErr: true, token: func() string {
+			return sign(jwt.SigningMethodHS256, "other", secret, valid())
+		}},
+		{name: "missing kid", wantErr: true, token: func() string {
+			return sign(jwt.SigningMethodHS256, nil, secret, valid())
+		}},
+		{name: "kid of another type", wantErr: true, token: func() string {
+			return sign(jwt.SigningMethodHS256, 1, secret, valid())
+		}},
+		{name: "unknown account type", wantErr: true, token: func() string {
+			claims := valid()
+			claims.UserType = "owner"
+			return sign(jwt.SigningMethodHS256, "test", secret, claims)
+		}},
+		{name: "missing user id", wantErr: true, token: func() string {
+			claims := valid()
+			claims.UserID = 0
+			return sign(jwt.SigningMethodHS256, "test", secret, claims)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := tokens.VerifyToken(context.Background(), tt.token())
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("got claims %+v, want an error", claims)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := (middleware.Principal{ID: 1, Type: middleware.UserTypeUser}); claims.Principal() != want {
+				t.Errorf("principal %+v, want %+v", claims.Principal(), want)
+			}
+		})
+	}
+}
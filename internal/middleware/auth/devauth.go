@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/folklinoff/fitness-app/internal/storage"
+	"golang.org/x/xerrors"
+)
+
+// DevScheme is the Authorization scheme of the development authenticator:
+//
+//	Authorization: Dev user:1
+//	Authorization: Dev trainer:3
+//	Authorization: Dev admin:1
+const DevScheme = "Dev"
+
+// ErrDevAuthUnavailable is returned when the development authenticator is
+// requested from a binary built without the devauth tag
+var ErrDevAuthUnavailable = xerrors.New("development authentication is not compiled in, build with -tags devauth")
+
+// DevAuthenticator lets developers act as any existing account without a token.
+// It is only available in binaries built with the devauth tag and must be
+// enabled explicitly, so production builds always reject the Dev scheme.
+type DevAuthenticator struct {
+	users    storage.UserRepository
+	trainers storage.TrainerRepository
+	admins   storage.AdminRepository
+}
+
+// NewDevAuthenticator creates the development authenticator impersonating
+// accounts of the storage
+func NewDevAuthenticator(store storage.Storage) (*DevAuthenticator, error) {
+	if !devAuthCompiled {
+		return nil, ErrDevAuthUnavailable
+	}
+	log.Println("WARNING: development authentication is enabled, any account can be impersonated")
+	return &DevAuthenticator{users: store.Users, trainers: store.Trainers, admins: store.Admins}, nil
+}
+
+// Authenticate resolves the "<type>:<id>" credentials to an existing principal
+func (a *DevAuthenticator) Authenticate(ctx context.Context, credentials string) (Principal, error) {
+	userType, rawID, ok := strings.Cut(credentials, ":")
+	if !ok {
+		return Principal{}, xerrors.New("dev credentials must be <type>:<id>")
+	}
+	id, err := strconv.Atoi(rawID)
+	if err != nil {
+		return Principal{}, xerrors.Errorf("invalid dev account id: %w", err)
+	}
+
+	switch userType {
+	case UserTypeUser:
+		_, err = a.users.GetByID(ctx, id)
+	case UserTypeTrainer:
+		_, err = a.trainers.GetByID(ctx, id)
+	case UserTypeAdmin:
+		_, err = a.admins.GetByID(ctx, id)
+	default:
+		return Principal{}, xerrors.Errorf("unknown account type %q", userType)
+	}
+	if err != nil {
+		return Principal{}, xerrors.Errorf("dev account %s: %w", credentials, err)
+	}
+
+	return Principal{ID: id, Type: userType}, nil
+}
@@ -0,0 +1,5 @@
+//go:build !devauth
+
+package middleware
+
+const devAuthCompiled = false
@@ -0,0 +1,210 @@
+package middleware
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"log"
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/folklinoff/fitness-app/internal/config"
+	jwt "github.com/golang-jwt/jwt/v4"
+	"golang.org/x/xerrors"
+)
+
+// Key is a JWT signing or verification key identified by its kid
+type Key struct {
+	ID     string
+	Method jwt.SigningMethod
+	// Sign is the private key or HMAC secret, nil for keys which only verify
+	Sign interface{}
+	// Verify is the public key or HMAC secret
+	Verify interface{}
+}
+
+// KeySet holds the active signing key and every key tokens are still accepted
+// with. Retired keys stay in the set until the tokens they signed have expired.
+type KeySet struct {
+	signing *Key
+	keys    map[string]*Key
+}
+
+// NewKeySet builds a key set signing with the first key
+func NewKeySet(signing Key, retired ...Key) (*KeySet, error) {
+	if signing.Sign == nil {
+		return nil, xerrors.Errorf("key %q cannot sign tokens", signing.ID)
+	}
+
+	ks := &KeySet{keys: make(map[string]*Key)}
+	for _, key := range append([]Key{signing}, retired...) {
+		key := key
+		if key.ID == "" {
+			return nil, xerrors.New("key id must not be empty")
+		}
+		if _, ok := ks.keys[key.ID]; ok {
+			return nil, xerrors.Errorf("duplicate key id %q", key.ID)
+		}
+		ks.keys[key.ID] = &key
+	}
+	ks.signing = ks.keys[signing.ID]
+	return ks, nil
+}
+
+// LoadKeySet reads the keys described by the configuration. Without a configured
+// key a random HS256 secret is generated, so tokens do not survive a restart.
+func LoadKeySet(cfg config.JWT) (*KeySet, error) {
+	var signing Key
+	if cfg.Secret == "" && cfg.KeyFile == "" {
+		log.Println("JWT_SECRET and JWT_KEY_FILE are not set, signing tokens with an ephemeral key")
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, xerrors.Errorf("generate jwt secret: %w", err)
+		}
+		signing = Key{ID: cfg.KeyID, Method: jwt.SigningMethodHS256, Sign: secret, Verify: secret}
+	} else {
+		var err error
+		signing, err = loadKey(cfg.KeyID, cfg.Algorithm, cfg.Secret, cfg.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var retired []Key
+	for _, spec := range cfg.RetiredKeys {
+		key, err := loadKey(spec.KeyID, spec.Algorithm, "", spec.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		retired = append(retired, key)
+	}
+
+	return NewKeySet(signing, retired...)
+}
+
+// loadKey reads an HMAC secret, or a private or public PEM key for asymmetric algorithms
+func loadKey(kid, alg, secret, path string) (Key, error) {
+	method := jwt.GetSigningMethod(alg)
+	if method == nil {
+		return Key{}, xerrors.Errorf("key %q: unsupported algorithm %q", kid, alg)
+	}
+	key := Key{ID: kid, Method: method}
+
+	if _, ok := method.(*jwt.SigningMethodHMAC); ok {
+		if secret == "" {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return Key{}, xerrors.Errorf("key %q: %w", kid, err)
+			}
+			secret = strings.TrimSpace(string(data))
+		}
+		if len(secret) < 32 {
+			return Key{}, xerrors.Errorf("key %q: HMAC secret must be at least 32 bytes", kid)
+		}
+		key.Sign, key.Verify = []byte(secret), []byte(secret)
+		return key, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Key{}, xerrors.Errorf("key %q: %w", kid, err)
+	}
+
+	switch method.(type) {
+	case *jwt.SigningMethodRSA:
+		if private, err := jwt.ParseRSAPrivateKeyFromPEM(data); err == nil {
+			key.Sign, key.Verify = private, &private.PublicKey
+		} else if key.Verify, err = jwt.ParseRSAPublicKeyFromPEM(data); err != nil {
+			return Key{}, xerrors.Errorf("key %q: %w", kid, err)
+		}
+	case *jwt.SigningMethodECDSA:
+		if private, err := jwt.ParseECPrivateKeyFromPEM(data); err == nil {
+			key.Sign, key.Verify = private, &private.PublicKey
+		} else if key.Verify, err = jwt.ParseECPublicKeyFromPEM(data); err != nil {
+			return Key{}, xerrors.Errorf("key %q: %w", kid, err)
+		}
+	case *jwt.SigningMethodEd25519:
+		if private, err := jwt.ParseEdPrivateKeyFromPEM(data); err == nil {
+			key.Sign, key.Verify = private, private.(ed25519.PrivateKey).Public()
+		} else if key.Verify, err = jwt.ParseEdPublicKeyFromPEM(data); err != nil {
+			return Key{}, xerrors.Errorf("key %q: %w", kid, err)
+		}
+	default:
+		return Key{}, xerrors.Errorf("key %q: unsupported algorithm %q", kid, alg)
+	}
+	return key, nil
+}
+
+// lookup returns the verification key for the token header
+func (ks *KeySet) lookup(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := ks.keys[kid]
+	if !ok {
+		return nil, xerrors.Errorf("unknown key id %q", kid)
+	}
+	// The algorithm is bound to the key, so a token cannot pick a weaker one
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, xerrors.Errorf("unexpected signing method %s", token.Method.Alg())
+	}
+	return key.Verify, nil
+}
+
+// JWK is a public key in the JSON Web Key format (RFC 7517)
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Algorithm string `json:"alg"`
+	Use       string `json:"use"`
+	Curve     string `json:"crv,omitempty"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+	X         string `json:"x,omitempty"`
+	Y         string `json:"y,omitempty"`
+}
+
+// JWKS is a JSON Web Key Set
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public keys of the set. HMAC secrets are never published,
+// so other services can only verify tokens signed with asymmetric keys.
+func (ks *KeySet) JWKS() JWKS {
+	set := JWKS{Keys: []JWK{}}
+	for _, key := range ks.keys {
+		if jwk, ok := publicJWK(key); ok {
+			set.Keys = append(set.Keys, jwk)
+		}
+	}
+	sort.Slice(set.Keys, func(i, j int) bool {
+		return set.Keys[i].KeyID < set.Keys[j].KeyID
+	})
+	return set
+}
+
+func publicJWK(key *Key) (JWK, bool) {
+	jwk := JWK{KeyID: key.ID, Algorithm: key.Method.Alg(), Use: "sig"}
+	encode := base64.RawURLEncoding.EncodeToString
+
+	switch public := key.Verify.(type) {
+	case *rsa.PublicKey:
+		jwk.KeyType = "RSA"
+		jwk.N = encode(public.N.Bytes())
+		jwk.E = encode(big.NewInt(int64(public.E)).Bytes())
+	case *ecdsa.PublicKey:
+		size := (public.Curve.Params().BitSize + 7) / 8
+		jwk.KeyType = "EC"
+		jwk.Curve = public.Curve.Params().Name
+		jwk.X = encode(public.X.FillBytes(make([]byte, size)))
+		jwk.Y = encode(public.Y.FillBytes(make([]byte, size)))
+	case ed25519.PublicKey:
+		jwk.KeyType = "OKP"
+		jwk.Curve = "Ed25519"
+		jwk.X = encode(public)
+	}
+	return jwk, jwk.KeyType != ""
+}
@@ -0,0 +1,330 @@
+package middleware_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/folklinoff/fitness-app/internal/config"
+	middleware "github.com/folklinoff/fitness-app/internal/middleware/auth"
+	"github.com/folklinoff/fitness-app/internal/storage"
+	"github.com/folklinoff/fitness-app/internal/storage/memory"
+	jwt "github.com/golang-jwt/jwt/v4"
+)
+
+// testKeys are generated once, RSA keys take a while
+var testKeys = struct {
+	rsa     *rsa.PrivateKey
+	ecdsa   *ecdsa.PrivateKey
+	ed25519 ed25519.PrivateKey
+}{
+	rsa: func() *rsa.PrivateKey {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			panic(err)
+		}
+		return key
+	}(),
+	ecdsa: func() *ecdsa.PrivateKey {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			panic(err)
+		}
+		return key
+	}(),
+	ed25519: func() ed25519.PrivateKey {
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			panic(err)
+		}
+		return key
+	}(),
+}
+
+// writePEM writes the private key, or its public key when public is set, to a
+// file and returns its path
+func writePEM(t *testing.T, key crypto.Signer, public bool) string {
+	t.Helper()
+	block := &pem.Block{Type: "PRIVATE KEY"}
+	var err error
+	if public {
+		block.Type = "PUBLIC KEY"
+		block.Bytes, err = x509.MarshalPKIXPublicKey(key.Public())
+	} else {
+		block.Bytes, err = x509.MarshalPKCS8PrivateKey(key)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return writeFile(t, string(pem.EncodeToMemory(block)))
+}
+
+func writeFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "key")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func managerFor(keys *middleware.KeySet) *middleware.TokenManager {
+	store := memory.New(storage.Options{})
+	return middleware.NewTokenManager(keys, store.Sessions, middleware.TokenConfig{
+		TTL:        time.Hour,
+		RefreshTTL: 24 * time.Hour,
+		Issuer:     "fitness-app",
+		Audience:   "fitness-app",
+	})
+}
+
+// header returns the unverified header of the token
+func header(t *testing.T, token string) map[string]interface{} {
+	t.Helper()
+	parsed, _, err := new(jwt.Parser).ParseUnverified(token, &middleware.Claims{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed.Header
+}
+
+func TestLoadKeySet(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     func(t *testing.T) config.JWT
+		alg     string
+		wantErr bool
+	}{
+		{
+			name: "HS256 secret",
+			cfg: func(t *testing.T) config.JWT {
+				return config.JWT{Algorithm: "HS256", KeyID: "hmac", Secret: string(secret)}
+			},
+			alg: "HS256",
+		},
+		{
+			name: "HS256 secret file",
+			cfg: func(t *testing.T) config.JWT {
+				return config.JWT{Algorithm: "HS256", KeyID: "hmac", KeyFile: writeFile(t, string(secret)+"\n")}
+			},
+			alg: "HS256",
+		},
+		{
+			name: "ephemeral HS256 secret",
+			cfg: func(t *testing.T) config.JWT {
+				return config.JWT{Algorithm: "RS256", KeyID: "default"}
+			},
+			alg: "HS256",
+		},
+		{
+			name: "RS256",
+			cfg: func(t *testing.T) config.JWT {
+				return config.JWT{Algorithm: "RS256", KeyID: "rsa", KeyFile: writePEM(t, testKeys.rsa, false)}
+			},
+			alg: "RS256",
+		},
+		{
+			name: "ES256",
+			cfg: func(t *testing.T) config.JWT {
+				return config.JWT{Algorithm: "ES256", KeyID: "ec", KeyFile: writePEM(t, testKeys.ecdsa, false)}
+			},
+			alg: "ES256",
+		},
+		{
+			name: "EdDSA",
+			cfg: func(t *testing.T) config.JWT {
+				return config.JWT{Algorithm: "EdDSA", KeyID: "ed", KeyFile: writePEM(t, testKeys.ed25519, false)}
+			},
+			alg: "EdDSA",
+		},
+		{
+			name: "retired public keys",
+			cfg: func(t *testing.T) config.JWT {
+				return config.JWT{Algorithm: "EdDSA", KeyID: "ed", KeyFile: writePEM(t, testKeys.ed25519, false),
+					RetiredKeys: []config.JWTKey{
+						{KeyID: "rsa", Algorithm: "RS256", KeyFile: writePEM(t, testKeys.rsa, true)},
+						{KeyID: "ec", Algorithm: "ES256", KeyFile: writePEM(t, testKeys.ecdsa, true)},
+						{KeyID: "hmac", Algorithm: "HS256", KeyFile: writeFile(t, string(secret))},
+					}}
+			},
+			alg: "EdDSA",
+		},
+		{
+			name: "short HMAC secret",
+			cfg: func(t *testing.T) config.JWT {
+				return config.JWT{Algorithm: "HS256", KeyID: "hmac", Secret: "short"}
+			},
+			wantErr: true,
+		},
+		{
+			name: "unknown algorithm",
+			cfg: func(t *testing.T) config.JWT {
+				return config.JWT{Algorithm: "XS256", KeyID: "x", Secret: string(secret)}
+			},
+			wantErr: true,
+		},
+		{
+			name: "missing key file",
+			cfg: func(t *testing.T) config.JWT {
+				return config.JWT{Algorithm: "RS256", KeyID: "rsa", KeyFile: filepath.Join(t.TempDir(), "missing")}
+			},
+			wantErr: true,
+		},
+		{
+			name: "key of another algorithm",
+			cfg: func(t *testing.T) config.JWT {
+				return config.JWT{Algorithm: "RS256", KeyID: "rsa", KeyFile: writePEM(t, testKeys.ecdsa, false)}
+			},
+			wantErr: true,
+		},
+		{
+			name: "public signing key",
+			cfg: func(t *testing.T) config.JWT {
+				return config.JWT{Algorithm: "ES256", KeyID: "ec", KeyFile: writePEM(t, testKeys.ecdsa, true)}
+			},
+			wantErr: true,
+		},
+		{
+			name: "duplicate key id",
+			cfg: func(t *testing.T) config.JWT {
+				return config.JWT{Algorithm: "HS256", KeyID: "hmac", Secret: string(secret),
+					RetiredKeys: []config.JWTKey{{KeyID: "hmac", Algorithm: "ES256", KeyFile: writePEM(t, testKeys.ecdsa, true)}}}
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.cfg(t)
+			keys, err := middleware.LoadKeySet(cfg)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("got no error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			tokens := managerFor(keys)
+			token, err := tokens.GenerateToken(middleware.Principal{ID: 1, Type: middleware.UserTypeUser})
+			if err != nil {
+				t.Fatalf("sign: %v", err)
+			}
+			if h := header(t, token); h["alg"] != tt.alg || h["kid"] != cfg.KeyID {
+				t.Errorf("header alg=%v kid=%v, want alg=%s kid=%s", h["alg"], h["kid"], tt.alg, cfg.KeyID)
+			}
+			if _, err := tokens.VerifyToken(context.Background(), token); err != nil {
+				t.Errorf("verify: %v", err)
+			}
+		})
+	}
+}
+
+func TestKeySetSelectsKeyByID(t *testing.T) {
+	rsaKey := middleware.Key{ID: "rsa", Method: jwt.SigningMethodRS256, Sign: testKeys.rsa, Verify: &testKeys.rsa.PublicKey}
+	ecKey := middleware.Key{ID: "ec", Method: jwt.SigningMethodES256, Sign: testKeys.ecdsa, Verify: &testKeys.ecdsa.PublicKey}
+	newSet := func(signing middleware.Key, retired ...middleware.Key) *middleware.TokenManager {
+		t.Helper()
+		keys, err := middleware.NewKeySet(signing, retired...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return managerFor(keys)
+	}
+	old := newSet(rsaKey)
+	rotated := newSet(ecKey, middleware.Key{ID: rsaKey.ID, Method: rsaKey.Method, Verify: rsaKey.Verify})
+	// Holds a key of another algorithm under the kid of the retired key
+	confused := newSet(ecKey, middleware.Key{ID: rsaKey.ID, Method: jwt.SigningMethodES256, Verify: &testKeys.ecdsa.PublicKey})
+	dropped := newSet(ecKey)
+
+	p := middleware.Principal{ID: 1, Type: middleware.UserTypeUser}
+	oldToken, err := old.GenerateToken(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newToken, err := rotated.GenerateToken(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		tokens  *middleware.TokenManager
+		token   string
+		wantErr bool
+	}{
+		{name: "signing key", tokens: rotated, token: newToken},
+		{name: "retired key", tokens: rotated, token: oldToken},
+		{name: "key dropped from the set", tokens: dropped, token: oldToken, wantErr: true},
+		{name: "new key unknown to the old set", tokens: old, token: newToken, wantErr: true},
+		{name: "kid bound to another algorithm", tokens: confused, token: oldToken, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.tokens.VerifyToken(context.Background(), tt.token)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("verify: got %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	keys, err := middleware.NewKeySet(
+		middleware.Key{ID: "hmac", Method: jwt.SigningMethodHS256, Sign: secret, Verify: secret},
+		middleware.Key{ID: "rsa", Method: jwt.SigningMethodRS256, Verify: &testKeys.rsa.PublicKey},
+		middleware.Key{ID: "ec", Method: jwt.SigningMethodES256, Sign: testKeys.ecdsa, Verify: &testKeys.ecdsa.PublicKey},
+		middleware.Key{ID: "ed", Method: jwt.SigningMethodEdDSA, Verify: testKeys.ed25519.Public()},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encode := base64.RawURLEncoding.EncodeToString
+	ec := testKeys.ecdsa.PublicKey
+	want := []middleware.JWK{
+		{KeyType: "EC", KeyID: "ec", Algorithm: "ES256", Use: "sig", Curve: "P-256",
+			X: encode(ec.X.FillBytes(make([]byte, 32))), Y: encode(ec.Y.FillBytes(make([]byte, 32)))},
+		{KeyType: "OKP", KeyID: "ed", Algorithm: "EdDSA", Use: "sig", Curve: "Ed25519",
+			X: encode(testKeys.ed25519.Public().(ed25519.PublicKey))},
+		{KeyType: "RSA", KeyID: "rsa", Algorithm: "RS256", Use: "sig",
+			N: encode(testKeys.rsa.N.Bytes()), E: "AQAB"},
+	}
+
+	got := keys.JWKS().Keys
+	if len(got) != len(want) {
+		t.Fatalf("got %d keys, want %d without the HMAC secret: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("key %d:\ngot  %+v\nwant %+v", i, got[i], want[i])
+		}
+	}
+
+	// The published RSA modulus and exponent verify tokens of the key
+	n, err := base64.RawURLEncoding.DecodeString(got[2].N)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := base64.RawURLEncoding.DecodeString(got[2].E)
+	if err != nil {
+		t.Fatal(err)
+	}
+	public := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	if !public.Equal(&testKeys.rsa.PublicKey) {
+		t.Error("published RSA key differs from the verification key")
+	}
+}
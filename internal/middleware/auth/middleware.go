@@ -7,23 +7,12 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-// AuthenticationMiddleware checks if the user has a valid JWT token
-func AuthenticationMiddleware() gin.HandlerFunc {
+// AuthenticationMiddleware checks if the user has a valid JWT token. When dev is
+// not nil, requests using the Dev scheme are authenticated by it instead.
+func AuthenticationMiddleware(tokens *TokenManager, dev *DevAuthenticator) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		tokenString := c.GetHeader("Authorization")
 
-		if tokenString == "USER_TOKEN" {
-			c.Set("user_id", 1)
-			c.Set("user_type", "user")
-			c.Next()
-		}
-
-		if tokenString == "TRAINER_TOKEN" {
-			c.Set("user_id", 1)
-			c.Set("user_type", "trainer")
-			c.Next()
-		}
-
 		if tokenString == "" {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Missing authentication token"})
 			c.Abort()
@@ -32,7 +21,25 @@ func AuthenticationMiddleware() gin.HandlerFunc {
 
 		// The token should be prefixed with "Bearer "
 		tokenParts := strings.Split(tokenString, " ")
-		if len(tokenParts) != 2 || tokenParts[0] != "Bearer" {
+		if len(tokenParts) != 2 {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authentication token"})
+			c.Abort()
+			return
+		}
+
+		if tokenParts[0] == DevScheme && dev != nil {
+			principal, err := dev.Authenticate(c.Request.Context(), tokenParts[1])
+			if err != nil {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authentication token"})
+				c.Abort()
+				return
+			}
+			SetPrincipal(c, principal)
+			c.Next()
+			return
+		}
+
+		if tokenParts[0] != "Bearer" {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authentication token"})
 			c.Abort()
 			return
@@ -40,15 +47,15 @@ func AuthenticationMiddleware() gin.HandlerFunc {
 
 		tokenString = tokenParts[1]
 
-		claims, err := VerifyToken(tokenString)
+		claims, err := tokens.VerifyToken(c.Request.Context(), tokenString)
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authentication token"})
 			c.Abort()
 			return
 		}
 
-		c.Set("user_id", claims["user_id"])
-		c.Set("user_type", claims["user_type"])
+		SetPrincipal(c, claims.Principal())
+		c.Set(claimsKey, claims)
 		c.Next()
 	}
 }
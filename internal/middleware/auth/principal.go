@@ -0,0 +1,92 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// Account types a principal can have, the type is also the role of the principal
+const (
+	UserTypeUser    = "user"
+	UserTypeTrainer = "trainer"
+	UserTypeAdmin   = "admin"
+)
+
+const (
+	principalKey = "principal"
+	claimsKey    = "claims"
+)
+
+// Principal is the authenticated account a request is made on behalf of
+type Principal struct {
+	ID   int
+	Type string
+}
+
+func isUserType(userType string) bool {
+	switch userType {
+	case UserTypeUser, UserTypeTrainer, UserTypeAdmin:
+		return true
+	}
+	return false
+}
+
+// IsUser reports whether the principal is a user account
+func (p Principal) IsUser() bool {
+	return p.Type == UserTypeUser
+}
+
+// IsTrainer reports whether the principal is a trainer account
+func (p Principal) IsTrainer() bool {
+	return p.Type == UserTypeTrainer
+}
+
+// IsAdmin reports whether the principal is an admin account
+func (p Principal) IsAdmin() bool {
+	return p.Type == UserTypeAdmin
+}
+
+// HasRole reports whether the principal has one of the roles
+func (p Principal) HasRole(roles ...string) bool {
+	for _, role := range roles {
+		if p.Type == role {
+			return true
+		}
+	}
+	return false
+}
+
+// SetPrincipal stores the authenticated principal in the gin context
+func SetPrincipal(c *gin.Context, p Principal) {
+	c.Set(principalKey, p)
+}
+
+// PrincipalFrom returns the principal stored by the authentication middleware
+func PrincipalFrom(c *gin.Context) (Principal, bool) {
+	value, ok := c.Get(principalKey)
+	if !ok {
+		return Principal{}, false
+	}
+	p, ok := value.(Principal)
+	return p, ok
+}
+
+// MustPrincipal returns the authenticated principal and panics when the route is
+// not behind AuthenticationMiddleware
+func MustPrincipal(c *gin.Context) Principal {
+	p, ok := PrincipalFrom(c)
+	if !ok {
+		panic("middleware: no authenticated principal in context")
+	}
+	return p
+}
+
+// ClaimsFrom returns the claims of the access token the request was authenticated
+// with, it is empty for other authentication schemes
+func ClaimsFrom(c *gin.Context) (*Claims, bool) {
+	value, ok := c.Get(claimsKey)
+	if !ok {
+		return nil, false
+	}
+	claims, ok := value.(*Claims)
+	return claims, ok
+}
@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/folklinoff/fitness-app/internal/audit"
+	"github.com/gin-gonic/gin"
+)
+
+// Guard builds middlewares restricting routes to roles, denied requests are
+// recorded in the audit log
+type Guard struct {
+	audit *audit.Log
+}
+
+// NewGuard creates a Guard auditing to the log
+func NewGuard(log *audit.Log) *Guard {
+	return &Guard{audit: log}
+}
+
+// RequireRole lets the request through only for principals with one of the
+// roles and responds with 403 Forbidden otherwise. It must run after
+// AuthenticationMiddleware.
+func (g *Guard) RequireRole(roles ...string) gin.HandlerFunc {
+	required := strings.Join(roles, " or ")
+	return func(c *gin.Context) {
+		principal, ok := PrincipalFrom(c)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Missing authentication token"})
+			c.Abort()
+			return
+		}
+
+		if !principal.HasRole(roles...) {
+			g.audit.Violation(c.Request.Context(), principal.Type, principal.ID,
+				c.Request.Method+" "+c.FullPath(), "", "requires the "+required+" role")
+			Forbid(c, "Forbidden: requires the "+required+" role")
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// Forbid aborts the request with 403 Forbidden, so that every denied request
+// gets the same response shape
+func Forbid(c *gin.Context, message string) {
+	c.JSON(http.StatusForbidden, gin.H{"error": message})
+	c.Abort()
+}
@@ -0,0 +1,35 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Log writes messages to a file or the server log instead of sending them, for
+// local development. It can be shared by both channels.
+type Log struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewLog returns a sender writing to w
+func NewLog(w io.Writer) *Log {
+	return &Log{w: w}
+}
+
+func (l *Log) Send(ctx context.Context, message Message) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	_, err := fmt.Fprintf(l.w, "--- %s\nTo: %s\n", time.Now().UTC().Format(time.RFC3339), message.To)
+	if err == nil && message.Subject != "" {
+		_, err = fmt.Fprintf(l.w, "Subject: %s\n", message.Subject)
+	}
+	if err == nil {
+		_, err = fmt.Fprintf(l.w, "\n%s\n\n", message.Body)
+	}
+	return err
+}
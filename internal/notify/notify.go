@@ -0,0 +1,89 @@
+// Package notify renders the notifications queued for users into email and text
+// messages and sends them through pluggable senders.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"text/template"
+	"time"
+
+	"github.com/folklinoff/fitness-app/internal/domain"
+	"golang.org/x/xerrors"
+)
+
+// Message is an email or a text message, text messages have no subject
+type Message struct {
+	// To is an email address or a phone number
+	To      string
+	Subject string
+	Body    string
+}
+
+// Sender delivers messages on one channel
+type Sender interface {
+	Send(ctx context.Context, message Message) error
+}
+
+// Notifier sends notifications by email and text message, a nil sender turns
+// its channel off
+type Notifier struct {
+	email    Sender
+	sms      Sender
+	location *time.Location
+}
+
+// NewNotifier returns a notifier showing times in the given location
+func NewNotifier(email, sms Sender, location *time.Location) *Notifier {
+	return &Notifier{email: email, sms: sms, location: location}
+}
+
+// Deliver sends the notification to the user on its channel. It returns false
+// without sending when the channel is off or the user has no address on it.
+func (n *Notifier) Deliver(ctx context.Context, notification domain.Notification, user domain.User) (bool, error) {
+	tmpl, ok := templates[notification.Kind]
+	if !ok {
+		return false, xerrors.Errorf("no template for %s notifications", notification.Kind)
+	}
+	data := messageData{
+		Name:     user.Name,
+		Training: notification.TrainingName,
+		Start:    notification.StartTime.In(n.location).Format("Mon, 2 Jan 2006 15:04 MST"),
+		End:      notification.EndTime.In(n.location).Format("15:04"),
+	}
+
+	switch notification.Channel {
+	case domain.ChannelEmail:
+		if n.email == nil || user.Mail == "" {
+			return false, nil
+		}
+		subject, err := render(tmpl.subject, data)
+		if err != nil {
+			return false, err
+		}
+		body, err := render(tmpl.body, data)
+		if err != nil {
+			return false, err
+		}
+		return true, n.email.Send(ctx, Message{To: user.Mail, Subject: subject, Body: body})
+	case domain.ChannelSMS:
+		if n.sms == nil || user.Phone == "" {
+			return false, nil
+		}
+		text, err := render(tmpl.sms, data)
+		if err != nil {
+			return false, err
+		}
+		return true, n.sms.Send(ctx, Message{To: user.Phone, Body: text})
+	default:
+		return false, xerrors.Errorf("unknown notification channel %q", notification.Channel)
+	}
+}
+
+func render(tmpl *template.Template, data messageData) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", xerrors.Errorf("render %s: %w", tmpl.Name(), err)
+	}
+	return buf.String(), nil
+}
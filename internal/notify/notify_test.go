@@ -0,0 +1,147 @@
+package notify
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/folklinoff/fitness-app/internal/domain"
+)
+
+// recorder keeps the messages it is asked to send
+type recorder struct {
+	messages []Message
+}
+
+func (r *recorder) Send(ctx context.Context, message Message) error {
+	r.messages = append(r.messages, message)
+	return nil
+}
+
+var kinds = []domain.NotificationKind{
+	domain.NotificationRegistered,
+	domain.NotificationUpdated,
+	domain.NotificationCancelled,
+	domain.NotificationPromoted,
+	domain.NotificationReminder,
+}
+
+func TestTemplatesCoverKinds(t *testing.T) {
+	if len(templates) != len(kinds) {
+		t.Errorf("%d templates for %d kinds of notifications", len(templates), len(kinds))
+	}
+	for _, kind := range kinds {
+		if _, ok := templates[kind]; !ok {
+			t.Errorf("no template for %s", kind)
+		}
+	}
+}
+
+func TestDeliverRendersTemplates(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2024, 3, 4, 17, 0, 0, 0, time.UTC)
+	user := domain.User{Name: "Zoë", Mail: "zoe@example.com", Phone: "+100"}
+	tests := []struct {
+		kind    domain.NotificationKind
+		subject string
+		body    string
+		sms     string
+	}{
+		{
+			kind:    domain.NotificationRegistered,
+			subject: "Your seat in Yoga & <Pilates> is confirmed",
+			body:    "Hi Zoë,\n\nyour seat in Yoga & <Pilates> on Mon, 4 Mar 2024 18:00 CET to 19:30 is confirmed.",
+			sms:     "Your seat in Yoga & <Pilates> on Mon, 4 Mar 2024 18:00 CET is confirmed.",
+		},
+		{
+			kind:    domain.NotificationUpdated,
+			subject: "Yoga & <Pilates> was changed",
+			body:    "It is now Yoga & <Pilates> on Mon, 4 Mar 2024 18:00 CET to 19:30.",
+			sms:     "A training you registered for was changed: Yoga & <Pilates> on Mon, 4 Mar 2024 18:00 CET to 19:30.",
+		},
+		{
+			kind:    domain.NotificationCancelled,
+			subject: "Yoga & <Pilates> is cancelled",
+			body:    "Yoga & <Pilates> on Mon, 4 Mar 2024 18:00 CET is cancelled.",
+			sms:     "Yoga & <Pilates> on Mon, 4 Mar 2024 18:00 CET is cancelled, any credits or payment are refunded.",
+		},
+		{
+			kind:    domain.NotificationPromoted,
+			subject: "You got a seat in Yoga & <Pilates>",
+			body:    "a seat in Yoga & <Pilates> on Mon, 4 Mar 2024 18:00 CET to 19:30 became free",
+			sms:     "You got a seat in Yoga & <Pilates> on Mon, 4 Mar 2024 18:00 CET. Paid seats are held until you pay in the app.",
+		},
+		{
+			kind:    domain.NotificationReminder,
+			subject: "Reminder: Yoga & <Pilates> on Mon, 4 Mar 2024 18:00 CET",
+			body:    "this is a reminder of your seat in Yoga & <Pilates> on Mon, 4 Mar 2024 18:00 CET to 19:30.",
+			sms:     "Reminder: Yoga & <Pilates> on Mon, 4 Mar 2024 18:00 CET.",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.kind), func(t *testing.T) {
+			email, sms := &recorder{}, &recorder{}
+			notifier := NewNotifier(email, sms, berlin)
+			notification := domain.Notification{
+				Kind:         tt.kind,
+				TrainingName: "Yoga & <Pilates>",
+				StartTime:    start,
+				EndTime:      start.Add(90 * time.Minute),
+			}
+
+			for _, channel := range domain.NotificationChannels {
+				notification.Channel = channel
+				sent, err := notifier.Deliver(context.Background(), notification, user)
+				if err != nil || !sent {
+					t.Fatalf("deliver %s: sent=%v err=%v", channel, sent, err)
+				}
+			}
+
+			if len(email.messages) != 1 || len(sms.messages) != 1 {
+				t.Fatalf("sent %d emails and %d text messages, want one each", len(email.messages), len(sms.messages))
+			}
+			mail := email.messages[0]
+			if mail.To != user.Mail || mail.Subject != tt.subject || !strings.Contains(mail.Body, tt.body) {
+				t.Errorf("email to %s\nsubject %q\nbody %q\nwant subject %q and body with %q", mail.To, mail.Subject, mail.Body, tt.subject, tt.body)
+			}
+			text := sms.messages[0]
+			if text.To != user.Phone || text.Subject != "" || text.Body != tt.sms {
+				t.Errorf("text message to %s %q %q, want %q", text.To, text.Subject, text.Body, tt.sms)
+			}
+		})
+	}
+}
+
+func TestDeliverSkips(t *testing.T) {
+	notification := domain.Notification{Kind: domain.NotificationReminder, TrainingName: "Yoga", StartTime: time.Now(), EndTime: time.Now()}
+	tests := []struct {
+		name    string
+		channel domain.NotificationChannel
+		email   Sender
+		user    domain.User
+	}{
+		{name: "email turned off", channel: domain.ChannelEmail, user: domain.User{Mail: "zoe@example.com"}},
+		{name: "no email address", channel: domain.ChannelEmail, email: &recorder{}, user: domain.User{Phone: "+100"}},
+		{name: "sms turned off", channel: domain.ChannelSMS, email: &recorder{}, user: domain.User{Phone: "+100"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			notification.Channel = tt.channel
+			sent, err := NewNotifier(tt.email, nil, time.UTC).Deliver(context.Background(), notification, tt.user)
+			if err != nil || sent {
+				t.Errorf("got sent=%v err=%v, want it skipped", sent, err)
+			}
+		})
+	}
+
+	notification.Kind = "unknown"
+	notification.Channel = domain.ChannelEmail
+	if _, err := NewNotifier(&recorder{}, nil, time.UTC).Deliver(context.Background(), notification, domain.User{Mail: "zoe@example.com"}); err == nil {
+		t.Error("unknown kind: got no error")
+	}
+}
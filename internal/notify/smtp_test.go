@@ -0,0 +1,188 @@
+package notify
+
+import (
+	"context"
+	"net"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+// smtpServer is a minimal SMTP server accepting one message, without STARTTLS
+// and authentication
+type smtpServer struct {
+	addr string
+	// commands are the commands received, data the message of DATA
+	commands chan string
+	data     chan string
+}
+
+func newSMTPServer(t *testing.T) *smtpServer {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	s := &smtpServer{addr: listener.Addr().String(), commands: make(chan string, 16), data: make(chan string, 1)}
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		tc := textproto.NewConn(conn)
+		tc.PrintfLine("220 localhost ESMTP")
+		for {
+			line, err := tc.ReadLine()
+			if err != nil {
+				return
+			}
+			s.commands <- line
+			switch verb := strings.ToUpper(strings.Fields(line)[0]); verb {
+			case "EHLO":
+				tc.PrintfLine("250-localhost")
+				tc.PrintfLine("250 8BITMIME")
+			case "DATA":
+				tc.PrintfLine("354 go ahead")
+				data, err := tc.ReadDotBytes()
+				if err != nil {
+					return
+				}
+				s.data <- string(data)
+				tc.PrintfLine("250 queued")
+			case "QUIT":
+				tc.PrintfLine("221 bye")
+				return
+			default:
+				tc.PrintfLine("250 ok")
+			}
+		}
+	}()
+	return s
+}
+
+// silentServer accepts connections and never answers
+func silentServer(t *testing.T) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	conns := make(chan net.Conn, 16)
+	t.Cleanup(func() {
+		listener.Close()
+		close(conns)
+		for conn := range conns {
+			conn.Close()
+		}
+	})
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conns <- conn
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func TestSMTPSend(t *testing.T) {
+	server := newSMTPServer(t)
+	sender, err := NewSMTP(server.addr, "", "", "Fitness <noreply@example.com>")
+	if err != nil {
+		t.Fatal(err)
+	}
+	message := Message{To: "Zoë <zoe@example.com>", Subject: "Your seat in Yoga is confirmed ✓", Body: "Hi Zoë,\n\nsee you there!\n"}
+	if err := sender.Send(context.Background(), message); err != nil {
+		t.Fatal(err)
+	}
+
+	var commands []string
+	for len(server.commands) > 0 {
+		commands = append(commands, <-server.commands)
+	}
+	joined := strings.Join(commands, "\n")
+	for _, want := range []string{"MAIL FROM:<noreply@example.com>", "RCPT TO:<zoe@example.com>", "QUIT"} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("commands lack %q:\n%s", want, joined)
+		}
+	}
+
+	// Reading the dot encoded data turns line endings into \n
+	data := <-server.data
+	for _, want := range []string{
+		"From: \"Fitness\" <noreply@example.com>\n",
+		"To: =?utf-8?q?Zo=C3=AB?= <zoe@example.com>\n",
+		"Subject: =?utf-8?q?Your_seat_in_Yoga_is_confirmed_=E2=9C=93?=\n",
+		"Content-Type: text/plain; charset=UTF-8\n",
+		"\n\nHi Zoë,\n\nsee you there!\n",
+	} {
+		if !strings.Contains(data, want) {
+			t.Errorf("message lacks %q:\n%s", want, data)
+		}
+	}
+}
+
+func TestSMTPSendRejectsHeaderInjection(t *testing.T) {
+	sender, err := NewSMTP(silentServer(t), "", "", "noreply@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = sender.Send(context.Background(), Message{To: "zoe@example.com\r\nBcc: all@example.com", Body: "hi"})
+	if err == nil {
+		t.Error("recipient with a line break: got no error")
+	}
+}
+
+func TestSMTPSendObservesContext(t *testing.T) {
+	tests := []struct {
+		name string
+		ctx  func() (context.Context, context.CancelFunc)
+		want error
+	}{
+		{
+			name: "deadline",
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithTimeout(context.Background(), 100*time.Millisecond)
+			},
+			want: context.DeadlineExceeded,
+		},
+		{
+			name: "cancelled",
+			ctx: func() (context.Context, context.CancelFunc) {
+				ctx, cancel := context.WithCancel(context.Background())
+				time.AfterFunc(100*time.Millisecond, cancel)
+				return ctx, cancel
+			},
+			want: context.Canceled,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sender, err := NewSMTP(silentServer(t), "", "", "noreply@example.com")
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctx, cancel := tt.ctx()
+			defer cancel()
+
+			done := make(chan error, 1)
+			go func() { done <- sender.Send(ctx, Message{To: "zoe@example.com", Body: "hi"}) }()
+			select {
+			case err := <-done:
+				if !xerrors.Is(err, tt.want) {
+					t.Errorf("got %v, want %v", err, tt.want)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("send to a silent server did not return")
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package notify
+
+import (
+	"text/template"
+
+	"github.com/folklinoff/fitness-app/internal/domain"
+)
+
+// messageData is what the templates are rendered with, Start holds the date
+// and End only the time of day
+type messageData struct {
+	Name     string
+	Training string
+	Start    string
+	End      string
+}
+
+// messageTemplate is the email subject and body and the text message of a kind
+// of notification
+type messageTemplate struct {
+	subject *template.Template
+	body    *template.Template
+	sms     *template.Template
+}
+
+var templates = map[domain.NotificationKind]messageTemplate{
+	domain.NotificationRegistered: parse(domain.NotificationRegistered,
+		`Your seat in {{.Training}} is confirmed`,
+		`Hi {{.Name}},
+
+your seat in {{.Training}} on {{.Start}} to {{.End}} is confirmed.
+
+See you there!
+`,
+		`Your seat in {{.Training}} on {{.Start}} is confirmed.`,
+	),
+	domain.NotificationUpdated: parse(domain.NotificationUpdated,
+		`{{.Training}} was changed`,
+		`Hi {{.Name}},
+
+a training you registered for was changed. It is now {{.Training}} on {{.Start}} to {{.End}}.
+
+If it no longer suits you, you can cancel your registration in the app.
+`,
+		`A training you registered for was changed: {{.Training}} on {{.Start}} to {{.End}}.`,
+	),
+	domain.NotificationCancelled: parse(domain.NotificationCancelled,
+		`{{.Training}} is cancelled`,
+		`Hi {{.Name}},
+
+{{.Training}} on {{.Start}} is cancelled. Any credits or payment of your registration are refunded.
+
+We are sorry for the inconvenience.
+`,
+		`{{.Training}} on {{.Start}} is cancelled, any credits or payment are refunded.`,
+	),
+	domain.NotificationPromoted: parse(domain.NotificationPromoted,
+		`You got a seat in {{.Training}}`,
+		`Hi {{.Name}},
+
+a seat in {{.Training}} on {{.Start}} to {{.End}} became free and it is yours now.
+
+If the training has a price and no membership of yours covers it, the seat is held for a short time until you pay for it in the app.
+`,
+		`You got a seat in {{.Training}} on {{.Start}}. Paid seats are held until you pay in the app.`,
+	),
+	domain.NotificationReminder: parse(domain.NotificationReminder,
+		`Reminder: {{.Training}} on {{.Start}}`,
+		`Hi {{.Name}},
+
+this is a reminder of your seat in {{.Training}} on {{.Start}} to {{.End}}.
+
+If you cannot make it, please cancel your registration so someone on the waitlist gets the seat.
+`,
+		`Reminder: {{.Training}} on {{.Start}}.`,
+	),
+}
+
+func parse(kind domain.NotificationKind, subject, body, sms string) messageTemplate {
+	return messageTemplate{
+		subject: template.Must(template.New(string(kind) + " subject").Parse(subject)),
+		body:    template.Must(template.New(string(kind) + " body").Parse(body)),
+		sms:     template.Must(template.New(string(kind) + " sms").Parse(sms)),
+	}
+}
@@ -0,0 +1,63 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+const twilioAPI = "https://api.twilio.com/2010-04-01"
+
+// Twilio sends text messages through the Twilio Messaging API
+type Twilio struct {
+	accountSID string
+	authToken  string
+	from       string
+	client     *http.Client
+}
+
+// NewTwilio returns a sender using the account credentials and sending from the
+// given phone number
+func NewTwilio(accountSID, authToken, from string) *Twilio {
+	return &Twilio{
+		accountSID: accountSID,
+		authToken:  authToken,
+		from:       from,
+		client:     &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (t *Twilio) Send(ctx context.Context, message Message) error {
+	form := url.Values{}
+	form.Set("To", message.To)
+	form.Set("From", t.from)
+	form.Set("Body", message.Body)
+
+	endpoint := twilioAPI + "/Accounts/" + url.PathEscape(t.accountSID) + "/Messages.json"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return xerrors.Errorf("twilio: %w", err)
+	}
+	req.SetBasicAuth(t.accountSID, t.authToken)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return xerrors.Errorf("twilio: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		var failure struct {
+			Message string `json:"message"`
+		}
+		json.NewDecoder(resp.Body).Decode(&failure)
+		return xerrors.Errorf("twilio: %s: %s", resp.Status, failure.Message)
+	}
+	return nil
+}
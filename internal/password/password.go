@@ -0,0 +1,186 @@
+// Package password hashes and verifies account passwords.
+//
+// Hashes are self-describing strings which carry the algorithm and its cost
+// parameters, so the configured costs can be raised at any time: hashes made
+// with other parameters keep verifying and are reported as needing a rehash.
+//
+//	argon2id  $argon2id$v=19$m=65536,t=3,p=2$<salt>$<key>
+//	bcrypt    $2a$12$<salt and key>
+//
+// Values without a known prefix are rejected as malformed unless legacy
+// plaintext passwords are accepted while migrating.
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/xerrors"
+)
+
+const (
+	Argon2id = "argon2id"
+	Bcrypt   = "bcrypt"
+)
+
+const (
+	saltLength = 16
+	keyLength  = 32
+)
+
+// ErrMalformedHash is returned when a stored hash cannot be parsed
+var ErrMalformedHash = xerrors.New("malformed password hash")
+
+// Params selects the algorithm new hashes are made with and its costs
+type Params struct {
+	// Algorithm is Argon2id or Bcrypt
+	Algorithm string
+	// Argon2Memory is the argon2id memory cost in KiB
+	Argon2Memory uint32
+	// Argon2Time is the number of argon2id passes
+	Argon2Time uint32
+	// Argon2Threads is the argon2id parallelism
+	Argon2Threads uint8
+	// BcryptCost is the bcrypt cost factor
+	BcryptCost int
+	// LegacyPlaintext accepts stored values without a known prefix as plaintext
+	// passwords, which are rehashed on login. Enable it only while migrating
+	// accounts created before passwords were hashed.
+	LegacyPlaintext bool
+}
+
+// DefaultParams follow the OWASP recommendations for argon2id
+var DefaultParams = Params{
+	Algorithm:     Argon2id,
+	Argon2Memory:  64 * 1024,
+	Argon2Time:    3,
+	Argon2Threads: 2,
+	BcryptCost:    12,
+}
+
+// Hasher hashes passwords with the configured parameters
+type Hasher struct {
+	params Params
+}
+
+// NewHasher validates the parameters and returns a Hasher using them
+func NewHasher(params Params) (*Hasher, error) {
+	switch params.Algorithm {
+	case Argon2id:
+		if params.Argon2Memory == 0 || params.Argon2Time == 0 || params.Argon2Threads == 0 {
+			return nil, xerrors.New("argon2id costs must be positive")
+		}
+	case Bcrypt:
+		if params.BcryptCost < bcrypt.MinCost || params.BcryptCost > bcrypt.MaxCost {
+			return nil, xerrors.Errorf("bcrypt cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+		}
+	default:
+		return nil, xerrors.Errorf("unknown password hashing algorithm %q", params.Algorithm)
+	}
+	return &Hasher{params: params}, nil
+}
+
+// Hash returns the encoded hash of the password
+func (h *Hasher) Hash(password string) (string, error) {
+	if h.params.Algorithm == Bcrypt {
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), h.params.BcryptCost)
+		if err != nil {
+			return "", xerrors.Errorf("bcrypt: %w", err)
+		}
+		return string(hash), nil
+	}
+
+	salt := make([]byte, saltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", xerrors.Errorf("generate salt: %w", err)
+	}
+	key := argon2.IDKey([]byte(password), salt, h.params.Argon2Time, h.params.Argon2Memory, h.params.Argon2Threads, keyLength)
+	return fmt.Sprintf("$%s$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		Argon2id, argon2.Version, h.params.Argon2Memory, h.params.Argon2Time, h.params.Argon2Threads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// Verify reports whether the password matches the encoded hash. When it matches,
+// rehash tells whether the hash was made with other parameters than the current
+// ones and should be replaced with a fresh Hash of the password.
+func (h *Hasher) Verify(encoded, password string) (ok, rehash bool, err error) {
+	switch {
+	case strings.HasPrefix(encoded, "$"+Argon2id+"$"):
+		return h.verifyArgon2id(encoded, password)
+	case strings.HasPrefix(encoded, "$2a$"), strings.HasPrefix(encoded, "$2b$"), strings.HasPrefix(encoded, "$2y$"):
+		return h.verifyBcrypt(encoded, password)
+	case strings.HasPrefix(encoded, "$"), !h.params.LegacyPlaintext:
+		// Hashes of unknown algorithms must never be compared as plaintext
+		return false, false, ErrMalformedHash
+	default:
+		// Legacy plaintext password stored before hashing was introduced
+		ok = subtle.ConstantTimeCompare([]byte(encoded), []byte(password)) == 1
+		return ok, ok, nil
+	}
+}
+
+func (h *Hasher) verifyArgon2id(encoded, password string) (bool, bool, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 {
+		return false, false, ErrMalformedHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return false, false, ErrMalformedHash
+	}
+	if version != argon2.Version {
+		return false, false, xerrors.Errorf("unsupported argon2 version %d", version)
+	}
+
+	var memory, time uint32
+	var threads uint8
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &time, &threads); err != nil {
+		return false, false, ErrMalformedHash
+	}
+	// argon2 panics on zero parallelism or key length
+	if memory == 0 || time == 0 || threads == 0 {
+		return false, false, ErrMalformedHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return false, false, ErrMalformedHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(salt) == 0 || len(key) == 0 {
+		return false, false, ErrMalformedHash
+	}
+
+	candidate := argon2.IDKey([]byte(password), salt, time, memory, threads, uint32(len(key)))
+	if subtle.ConstantTimeCompare(key, candidate) != 1 {
+		return false, false, nil
+	}
+
+	rehash := h.params.Algorithm != Argon2id ||
+		memory != h.params.Argon2Memory || time != h.params.Argon2Time || threads != h.params.Argon2Threads ||
+		len(salt) != saltLength || len(key) != keyLength
+	return true, rehash, nil
+}
+
+func (h *Hasher) verifyBcrypt(encoded, password string) (bool, bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+	if xerrors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, false, nil
+	}
+	if err != nil {
+		return false, false, ErrMalformedHash
+	}
+
+	cost, err := bcrypt.Cost([]byte(encoded))
+	if err != nil {
+		return false, false, ErrMalformedHash
+	}
+	return true, h.params.Algorithm != Bcrypt || cost != h.params.BcryptCost, nil
+}
@@ -0,0 +1,154 @@
+package password
+
+import (
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/xerrors"
+)
+
+// Cheap costs keep the tests fast
+var (
+	argon2Params = Params{Algorithm: Argon2id, Argon2Memory: 64, Argon2Time: 1, Argon2Threads: 1}
+	bcryptParams = Params{Algorithm: Bcrypt, BcryptCost: bcrypt.MinCost}
+)
+
+func newHasher(t *testing.T, params Params) *Hasher {
+	t.Helper()
+	h, err := NewHasher(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return h
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, params := range []Params{argon2Params, bcryptParams} {
+		t.Run(params.Algorithm, func(t *testing.T) {
+			h := newHasher(t, params)
+			hash, err := h.Hash("correct horse")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if hash == "correct horse" {
+				t.Fatal("hash is the plaintext password")
+			}
+
+			ok, rehash, err := h.Verify(hash, "correct horse")
+			if err != nil || !ok || rehash {
+				t.Errorf("verify the password: got ok=%v rehash=%v err=%v, want ok", ok, rehash, err)
+			}
+			ok, rehash, err = h.Verify(hash, "battery staple")
+			if err != nil || ok || rehash {
+				t.Errorf("verify a wrong password: got ok=%v rehash=%v err=%v, want no match", ok, rehash, err)
+			}
+
+			again, err := h.Hash("correct horse")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if again == hash {
+				t.Error("hashes of the same password are equal, salt is not random")
+			}
+		})
+	}
+}
+
+func TestRehash(t *testing.T) {
+	stronger := argon2Params
+	stronger.Argon2Time++
+	moreThreads := argon2Params
+	moreThreads.Argon2Threads++
+	costlier := bcryptParams
+	costlier.BcryptCost++
+
+	tests := []struct {
+		name   string
+		hashed Params
+		params Params
+		rehash bool
+	}{
+		{name: "same argon2id params", hashed: argon2Params, params: argon2Params},
+		{name: "same bcrypt cost", hashed: bcryptParams, params: bcryptParams},
+		{name: "argon2id passes raised", hashed: argon2Params, params: stronger, rehash: true},
+		{name: "argon2id threads raised", hashed: argon2Params, params: moreThreads, rehash: true},
+		{name: "bcrypt cost raised", hashed: bcryptParams, params: costlier, rehash: true},
+		{name: "bcrypt to argon2id", hashed: bcryptParams, params: argon2Params, rehash: true},
+		{name: "argon2id to bcrypt", hashed: argon2Params, params: bcryptParams, rehash: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hash, err := newHasher(t, tt.hashed).Hash("secret")
+			if err != nil {
+				t.Fatal(err)
+			}
+			ok, rehash, err := newHasher(t, tt.params).Verify(hash, "secret")
+			if err != nil || !ok {
+				t.Fatalf("verify: got ok=%v err=%v, want ok", ok, err)
+			}
+			if rehash != tt.rehash {
+				t.Errorf("rehash = %v, want %v", rehash, tt.rehash)
+			}
+		})
+	}
+}
+
+func TestMalformedHash(t *testing.T) {
+	// A salt and key of the lengths Hash uses
+	const salt, key = "c2FsdHNhbHRzYWx0c2FsdA", "a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2U"
+	tests := []struct {
+		name    string
+		encoded string
+	}{
+		{name: "zero parallelism", encoded: "$argon2id$v=19$m=64,t=1,p=0$" + salt + "$" + key},
+		{name: "zero passes", encoded: "$argon2id$v=19$m=64,t=0,p=1$" + salt + "$" + key},
+		{name: "zero memory", encoded: "$argon2id$v=19$m=0,t=1,p=1$" + salt + "$" + key},
+		{name: "empty key", encoded: "$argon2id$v=19$m=64,t=1,p=1$" + salt + "$"},
+		{name: "empty salt", encoded: "$argon2id$v=19$m=64,t=1,p=1$$" + key},
+		{name: "bad base64", encoded: "$argon2id$v=19$m=64,t=1,p=1$" + salt + "$not base64!"},
+		{name: "missing params", encoded: "$argon2id$v=19$" + salt + "$" + key},
+		{name: "bad params", encoded: "$argon2id$v=19$m=x,t=1,p=1$" + salt + "$" + key},
+		{name: "threads overflow", encoded: "$argon2id$v=19$m=64,t=1,p=256$" + salt + "$" + key},
+		{name: "truncated bcrypt", encoded: "$2a$04$short"},
+		{name: "unknown algorithm", encoded: "$scrypt$ln=15,r=8,p=1$c2FsdA$a2V5"},
+		{name: "unknown prefix", encoded: "$"},
+		{name: "plaintext", encoded: "secret"},
+		{name: "empty", encoded: ""},
+	}
+	h := newHasher(t, argon2Params)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, _, err := h.Verify(tt.encoded, "secret")
+			if !xerrors.Is(err, ErrMalformedHash) {
+				t.Errorf("got ok=%v err=%v, want ErrMalformedHash", ok, err)
+			}
+			if ok {
+				t.Error("malformed hash matched")
+			}
+		})
+	}
+
+	if _, _, err := h.Verify("$argon2id$v=16$m=64,t=1,p=1$"+salt+"$"+key, "secret"); err == nil {
+		t.Error("unsupported argon2 version: got no error")
+	}
+}
+
+func TestLegacyPlaintext(t *testing.T) {
+	params := argon2Params
+	params.LegacyPlaintext = true
+	h := newHasher(t, params)
+
+	ok, rehash, err := h.Verify("secret", "secret")
+	if err != nil || !ok || !rehash {
+		t.Errorf("matching plaintext: got ok=%v rehash=%v err=%v, want a match to rehash", ok, rehash, err)
+	}
+	ok, _, err = h.Verify("secret", "other")
+	if err != nil || ok {
+		t.Errorf("wrong plaintext: got ok=%v err=%v, want no match", ok, err)
+	}
+	// Values shaped like hashes are never compared as plaintext
+	ok, _, err = h.Verify("$scrypt$secret", "$scrypt$secret")
+	if !xerrors.Is(err, ErrMalformedHash) || ok {
+		t.Errorf("unknown hash equal to the password: got ok=%v err=%v, want ErrMalformedHash", ok, err)
+	}
+}
@@ -0,0 +1,234 @@
+package payment
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"golang.org/x/xerrors"
+)
+
+// FakeSignatureHeader holds the hex HMAC-SHA256 of the body of fake webhook requests
+const FakeSignatureHeader = "Fake-Signature"
+
+// Fake is an in-process gateway for tests and local development which moves no
+// money. Checkouts are paid with Complete or abandoned with Expire, which return
+// the signed webhook request the provider would send. Its webhook events are
+// JSON objects with id, type, session_id and reference fields.
+type Fake struct {
+	secret []byte
+
+	mu       sync.Mutex
+	n        int
+	sessions map[string]*FakeSession
+	// captures maps the reference of a completed checkout to its session
+	captures map[string]*FakeSession
+	// FailCaptures makes captures fail, as they do for declined payments
+	FailCaptures bool
+}
+
+// FakeSession is a checkout session of the fake gateway
+type FakeSession struct {
+	Session
+	Checkout  Checkout
+	Reference string
+	Captured  bool
+	Cancelled bool
+	Refunded  int
+}
+
+// ErrFakeUnavailable is returned when the fake gateway is requested from a
+// binary built without the fakepayments tag
+var ErrFakeUnavailable = xerrors.New("fake payment gateway is not compiled in, build with -tags fakepayments")
+
+// NewFake returns a fake gateway signing its webhook requests with secret. It is
+// only available in binaries built with the fakepayments tag, so production
+// builds never accept its webhook requests.
+func NewFake(secret string) (*Fake, error) {
+	if !fakeCompiled {
+		return nil, ErrFakeUnavailable
+	}
+	if secret == "" {
+		return nil, ErrNoWebhookSecret
+	}
+	return &Fake{
+		secret:   []byte(secret),
+		sessions: make(map[string]*FakeSession),
+		captures: make(map[string]*FakeSession),
+	}, nil
+}
+
+func (f *Fake) CreateCheckout(ctx context.Context, checkout Checkout) (*Session, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.n++
+	id := "cs_fake_" + strconv.Itoa(f.n)
+	session := &FakeSession{
+		Session:  Session{ID: id, URL: "https://checkout.fake.invalid/" + id, ExpiresAt: checkout.ExpiresAt},
+		Checkout: checkout,
+	}
+	f.sessions[id] = session
+	return &session.Session, nil
+}
+
+// Capture collects the payment of a completed checkout, capturing it again is a
+// no-op like a retried Stripe request. Checkouts completed with hand-made webhook
+// requests are captured on first use of their reference.
+func (f *Fake) Capture(ctx context.Context, reference string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.FailCaptures {
+		return xerrors.New("fake capture declined")
+	}
+	session, ok := f.captures[reference]
+	if !ok {
+		session = &FakeSession{Reference: reference}
+		f.captures[reference] = session
+	}
+	if session.Cancelled {
+		return xerrors.Errorf("fake payment %s was cancelled", reference)
+	}
+	session.Captured = true
+	return nil
+}
+
+// Cancel releases the authorization of a completed checkout, cancelling it again
+// is a no-op. Captured payments cannot be cancelled.
+func (f *Fake) Cancel(ctx context.Context, reference string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	session, ok := f.captures[reference]
+	if !ok {
+		session = &FakeSession{Reference: reference}
+		f.captures[reference] = session
+	}
+	if session.Captured {
+		return xerrors.Errorf("fake payment %s already captured", reference)
+	}
+	session.Cancelled = true
+	return nil
+}
+
+func (f *Fake) Refund(ctx context.Context, reference string, amount int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	session, ok := f.captures[reference]
+	if !ok || !session.Captured {
+		return xerrors.Errorf("fake payment %s not captured", reference)
+	}
+	session.Refunded += amount
+	return nil
+}
+
+func (f *Fake) Refunded(ctx context.Context, reference string) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	session, ok := f.captures[reference]
+	return ok && session.Refunded > 0, nil
+}
+
+func (f *Fake) VerifyWebhook(header http.Header, body []byte) (*Event, error) {
+	signature, err := hex.DecodeString(header.Get(FakeSignatureHeader))
+	if err != nil || !hmac.Equal(signature, f.sign(body)) {
+		return nil, ErrInvalidSignature
+	}
+
+	var event fakeEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		return nil, xerrors.Errorf("decode fake event: %w", err)
+	}
+	return &Event{ID: event.ID, Type: EventType(event.Type), SessionID: event.SessionID, Reference: event.Reference}, nil
+}
+
+// Complete pays the checkout session and returns the webhook request reporting it
+func (f *Fake) Complete(sessionID string) (http.Header, []byte, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	session, ok := f.sessions[sessionID]
+	if !ok {
+		return nil, nil, xerrors.Errorf("unknown fake session %s", sessionID)
+	}
+	if session.Reference == "" {
+		session.Reference = "pi_fake_" + sessionID
+		f.captures[session.Reference] = session
+	}
+	return f.event(EventCheckoutCompleted, session)
+}
+
+// Expire abandons the checkout session and returns the webhook request reporting it
+func (f *Fake) Expire(sessionID string) (http.Header, []byte, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	session, ok := f.sessions[sessionID]
+	if !ok {
+		return nil, nil, xerrors.Errorf("unknown fake session %s", sessionID)
+	}
+	return f.event(EventCheckoutFailed, session)
+}
+
+// Session returns a copy of the checkout session
+func (f *Fake) Session(id string) (FakeSession, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	session, ok := f.sessions[id]
+	if !ok {
+		return FakeSession{}, false
+	}
+	return *session, true
+}
+
+// Payment returns a copy of the payment with the reference, once it was captured,
+// cancelled or completed
+func (f *Fake) Payment(reference string) (FakeSession, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	session, ok := f.captures[reference]
+	if !ok {
+		return FakeSession{}, false
+	}
+	return *session, true
+}
+
+type fakeEvent struct {
+	ID        string `json:"id"`
+	Type      string `json:"type"`
+	SessionID string `json:"session_id"`
+	Reference string `json:"reference,omitempty"`
+}
+
+// event returns the signed webhook request of an event about the session, mu must be held
+func (f *Fake) event(eventType EventType, session *FakeSession) (http.Header, []byte, error) {
+	f.n++
+	body, err := json.Marshal(fakeEvent{
+		ID:        "evt_fake_" + strconv.Itoa(f.n),
+		Type:      string(eventType),
+		SessionID: session.ID,
+		Reference: session.Reference,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	header := http.Header{}
+	header.Set(FakeSignatureHeader, hex.EncodeToString(f.sign(body)))
+	return header, body, nil
+}
+
+func (f *Fake) sign(body []byte) []byte {
+	mac := hmac.New(sha256.New, f.secret)
+	mac.Write(body)
+	return mac.Sum(nil)
+}
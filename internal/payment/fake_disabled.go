@@ -0,0 +1,5 @@
+//go:build !fakepayments
+
+package payment
+
+const fakeCompiled = false
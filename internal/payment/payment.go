@@ -0,0 +1,81 @@
+// Package payment abstracts the payment provider membership plans and paid
+// trainings are sold through.
+//
+// A purchase starts with a checkout session the user pays at the provider. The
+// payment is only authorized there: once the provider reports the completed
+// checkout through a webhook, the application captures it, or cancels the
+// authorization when the purchase can no longer be delivered.
+package payment
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+// ErrInvalidSignature is returned for webhook requests which are not signed by the provider
+var ErrInvalidSignature = xerrors.New("invalid webhook signature")
+
+// ErrNoWebhookSecret is returned when a gateway is created without a webhook
+// secret, with which anyone could sign webhook requests
+var ErrNoWebhookSecret = xerrors.New("webhook secret is empty")
+
+// PaymentGateway is a payment provider
+type PaymentGateway interface {
+	// CreateCheckout starts a checkout session for the purchase
+	CreateCheckout(ctx context.Context, checkout Checkout) (*Session, error)
+	// Capture collects the authorized payment with the given reference
+	Capture(ctx context.Context, reference string) error
+	// Cancel releases the authorized payment with the given reference without
+	// collecting it
+	Cancel(ctx context.Context, reference string) error
+	// Refund gives amount of the captured payment back to the customer
+	Refund(ctx context.Context, reference string, amount int) error
+	// Refunded reports whether a refund of the captured payment was issued
+	Refunded(ctx context.Context, reference string) (bool, error)
+	// VerifyWebhook checks the signature of a webhook request and returns its event
+	VerifyWebhook(header http.Header, body []byte) (*Event, error)
+}
+
+// Checkout describes a purchase. Amount is in the minor unit of the currency.
+type Checkout struct {
+	// Reference identifies the payment in this application
+	Reference   string
+	Description string
+	Amount      int
+	Currency    string
+	Email       string
+	// SuccessURL and CancelURL are where the customer is sent back to
+	SuccessURL string
+	CancelURL  string
+	ExpiresAt  time.Time
+}
+
+// Session is a checkout session the customer pays at URL
+type Session struct {
+	ID        string
+	URL       string
+	ExpiresAt time.Time
+}
+
+// EventType is the kind of a webhook event
+type EventType string
+
+const (
+	// EventCheckoutCompleted reports an authorized payment
+	EventCheckoutCompleted EventType = "checkout.completed"
+	// EventCheckoutFailed reports a checkout which expired or whose payment failed
+	EventCheckoutFailed EventType = "checkout.failed"
+)
+
+// Event is a webhook event about a checkout session. Events of other types are
+// returned with the type of the provider. Reference is the payment to capture
+// of a completed checkout.
+type Event struct {
+	ID        string
+	Type      EventType
+	SessionID string
+	Reference string
+}
@@ -0,0 +1,219 @@
+package payment
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+const (
+	stripeAPI = "https://api.stripe.com/v1"
+	// stripeTolerance is the maximum age of a webhook signature, older ones
+	// are rejected as replays
+	stripeTolerance = 5 * time.Minute
+	// stripeMinExpiry is the shortest lifetime Stripe accepts for a checkout session
+	stripeMinExpiry = 31 * time.Minute
+)
+
+// Stripe is the Stripe payment provider. Checkout sessions only authorize the
+// payment, which is captured separately.
+type Stripe struct {
+	key           string
+	webhookSecret []byte
+	client        *http.Client
+}
+
+// NewStripe returns a Stripe gateway using the secret API key and the signing
+// secret of the webhook endpoint
+func NewStripe(key, webhookSecret string) (*Stripe, error) {
+	if webhookSecret == "" {
+		return nil, ErrNoWebhookSecret
+	}
+	return &Stripe{
+		key:           key,
+		webhookSecret: []byte(webhookSecret),
+		client:        &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+func (s *Stripe) CreateCheckout(ctx context.Context, checkout Checkout) (*Session, error) {
+	expiresAt := checkout.ExpiresAt
+	if min := time.Now().Add(stripeMinExpiry); expiresAt.Before(min) {
+		expiresAt = min
+	}
+
+	form := url.Values{}
+	form.Set("mode", "payment")
+	form.Set("client_reference_id", checkout.Reference)
+	form.Set("success_url", checkout.SuccessURL)
+	form.Set("cancel_url", checkout.CancelURL)
+	form.Set("expires_at", strconv.FormatInt(expiresAt.Unix(), 10))
+	form.Set("line_items[0][quantity]", "1")
+	form.Set("line_items[0][price_data][currency]", checkout.Currency)
+	form.Set("line_items[0][price_data][unit_amount]", strconv.Itoa(checkout.Amount))
+	form.Set("line_items[0][price_data][product_data][name]", checkout.Description)
+	form.Set("payment_intent_data[capture_method]", "manual")
+	form.Set("metadata[reference]", checkout.Reference)
+	if checkout.Email != "" {
+		form.Set("customer_email", checkout.Email)
+	}
+
+	var session struct {
+		ID        string `json:"id"`
+		URL       string `json:"url"`
+		ExpiresAt int64  `json:"expires_at"`
+	}
+	if err := s.post(ctx, "/checkout/sessions", form, "checkout-"+checkout.Reference, &session); err != nil {
+		return nil, err
+	}
+	return &Session{ID: session.ID, URL: session.URL, ExpiresAt: time.Unix(session.ExpiresAt, 0)}, nil
+}
+
+func (s *Stripe) Capture(ctx context.Context, reference string) error {
+	return s.post(ctx, "/payment_intents/"+url.PathEscape(reference)+"/capture", url.Values{}, "capture-"+reference, nil)
+}
+
+func (s *Stripe) Cancel(ctx context.Context, reference string) error {
+	return s.post(ctx, "/payment_intents/"+url.PathEscape(reference)+"/cancel", url.Values{}, "cancel-"+reference, nil)
+}
+
+func (s *Stripe) Refund(ctx context.Context, reference string, amount int) error {
+	form := url.Values{}
+	form.Set("payment_intent", reference)
+	form.Set("amount", strconv.Itoa(amount))
+	return s.post(ctx, "/refunds", form, "refund-"+reference, nil)
+}
+
+// Refunded lists the refunds of the payment intent, refunds which failed or were
+// cancelled do not count
+func (s *Stripe) Refunded(ctx context.Context, reference string) (bool, error) {
+	query := url.Values{}
+	query.Set("payment_intent", reference)
+	var refunds struct {
+		Data []struct {
+			Status string `json:"status"`
+		} `json:"data"`
+	}
+	if err := s.do(ctx, http.MethodGet, "/refunds?"+query.Encode(), nil, "", &refunds); err != nil {
+		return false, err
+	}
+	for _, refund := range refunds.Data {
+		if refund.Status != "failed" && refund.Status != "canceled" {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// VerifyWebhook checks the Stripe-Signature header, which holds the timestamp
+// and HMAC-SHA256 signatures of "timestamp.body" keyed by the webhook secret
+func (s *Stripe) VerifyWebhook(header http.Header, body []byte) (*Event, error) {
+	var timestamp string
+	var signatures []string
+	for _, part := range strings.Split(header.Get("Stripe-Signature"), ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || len(signatures) == 0 {
+		return nil, ErrInvalidSignature
+	}
+	if age := time.Since(time.Unix(unix, 0)); age > stripeTolerance || age < -stripeTolerance {
+		return nil, ErrInvalidSignature
+	}
+
+	mac := hmac.New(sha256.New, s.webhookSecret)
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	expected := mac.Sum(nil)
+	valid := false
+	for _, signature := range signatures {
+		if decoded, err := hex.DecodeString(signature); err == nil && hmac.Equal(decoded, expected) {
+			valid = true
+		}
+	}
+	if !valid {
+		return nil, ErrInvalidSignature
+	}
+
+	var payload struct {
+		ID   string `json:"id"`
+		Type string `json:"type"`
+		Data struct {
+			Object struct {
+				ID            string `json:"id"`
+				PaymentIntent string `json:"payment_intent"`
+			} `json:"object"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, xerrors.Errorf("decode stripe event: %w", err)
+	}
+
+	event := Event{ID: payload.ID, Type: EventType(payload.Type), SessionID: payload.Data.Object.ID}
+	switch payload.Type {
+	case "checkout.session.completed":
+		event.Type = EventCheckoutCompleted
+		event.Reference = payload.Data.Object.PaymentIntent
+	case "checkout.session.expired", "checkout.session.async_payment_failed":
+		event.Type = EventCheckoutFailed
+	}
+	return &event, nil
+}
+
+// post calls the Stripe API. The idempotency key makes retried requests return
+// the result of the first one instead of repeating it.
+func (s *Stripe) post(ctx context.Context, path string, form url.Values, idempotencyKey string, out any) error {
+	return s.do(ctx, http.MethodPost, path, form, idempotencyKey, out)
+}
+
+// do sends a request to the Stripe API, the form and idempotency key are only
+// sent with POST requests
+func (s *Stripe) do(ctx context.Context, method, path string, form url.Values, idempotencyKey string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, method, stripeAPI+path, strings.NewReader(form.Encode()))
+	if err != nil {
+		return xerrors.Errorf("stripe %s: %w", path, err)
+	}
+	req.Header.Set("Authorization", "Bearer "+s.key)
+	if method == http.MethodPost {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("Idempotency-Key", idempotencyKey)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return xerrors.Errorf("stripe %s: %w", path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		var failure struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		json.NewDecoder(resp.Body).Decode(&failure)
+		return xerrors.Errorf("stripe %s: %s: %s", path, resp.Status, failure.Error.Message)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return xerrors.Errorf("stripe %s: decode response: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package policy
+
+import (
+	"context"
+
+	"github.com/folklinoff/fitness-app/internal/audit"
+	"github.com/folklinoff/fitness-app/internal/domain"
+	middleware "github.com/folklinoff/fitness-app/internal/middleware/auth"
+	"github.com/folklinoff/fitness-app/internal/storage"
+)
+
+// Access is the level of access a principal has to a resource
+type Access int
+
+const (
+	Denied Access = iota
+	// Limited access only exposes the fields the principal needs
+	Limited
+	Full
+)
+
+// Actions checked by the policy, they name the action in the audit log
+const (
+	ActionViewAccount    = "account.view"
+	ActionUpdateAccount  = "account.update"
+	ActionDeleteAccount  = "account.delete"
+	ActionUpdateTraining = "training.update"
+	ActionDeleteTraining = "training.delete"
+	ActionViewTrainees   = "training.trainees"
+	ActionReportNoShow   = "training.no_show"
+	ActionTakeAttendance = "training.attendance"
+	ActionUpdateSeries   = "series.update"
+	ActionDeleteSeries   = "series.delete"
+)
+
+// Policy decides which accounts and trainings a principal may see and change:
+//
+//   - principals have full access to their own account, admins to every account
+//   - trainer accounts are public, except for their contact details
+//   - trainers see limited fields of the users registered for their trainings
+//   - trainings and training series are managed by their trainer and by admins
+type Policy struct {
+	trainings storage.TrainingRepository
+	audit     *audit.Log
+}
+
+// New creates a Policy, violations are recorded in the audit log
+func New(trainings storage.TrainingRepository, log *audit.Log) *Policy {
+	return &Policy{trainings: trainings, audit: log}
+}
+
+func isOwner(p middleware.Principal, accountType string, id int) bool {
+	return p.Type == accountType && p.ID == id
+}
+
+// ViewAccount returns the access of the principal to the user or trainer account
+func (pol *Policy) ViewAccount(ctx context.Context, p middleware.Principal, accountType string, id int) (Access, error) {
+	if p.IsAdmin() || isOwner(p, accountType, id) {
+		return Full, nil
+	}
+	if accountType == middleware.UserTypeTrainer {
+		return Limited, nil
+	}
+	if p.IsTrainer() && accountType == middleware.UserTypeUser {
+		trainee, err := pol.isTrainee(ctx, p.ID, id)
+		if err != nil {
+			return Denied, err
+		}
+		if trainee {
+			return Limited, nil
+		}
+	}
+	return Denied, nil
+}
+
+// isTrainee reports whether the user is registered for a training of the trainer
+func (pol *Policy) isTrainee(ctx context.Context, trainerID, userID int) (bool, error) {
+	trainings, err := pol.trainings.ListByUser(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	for _, training := range trainings {
+		if training.TrainerID == trainerID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// CanManageAccount reports whether the principal may update or delete the account
+func (pol *Policy) CanManageAccount(p middleware.Principal, accountType string, id int) bool {
+	return p.IsAdmin() || isOwner(p, accountType, id)
+}
+
+// CanManageTraining reports whether the principal may update or delete the training
+func (pol *Policy) CanManageTraining(p middleware.Principal, training *domain.Training) bool {
+	return p.IsAdmin() || isOwner(p, middleware.UserTypeTrainer, training.TrainerID)
+}
+
+// CanManageSeries reports whether the principal may change or delete the training series
+func (pol *Policy) CanManageSeries(p middleware.Principal, series *domain.TrainingSeries) bool {
+	return p.IsAdmin() || isOwner(p, middleware.UserTypeTrainer, series.TrainerID)
+}
+
+// ViewTrainees returns the access of the principal to the users registered for the training
+func (pol *Policy) ViewTrainees(p middleware.Principal, training *domain.Training) Access {
+	if p.IsAdmin() {
+		return Full
+	}
+	if isOwner(p, middleware.UserTypeTrainer, training.TrainerID) {
+		return Limited
+	}
+	return Denied
+}
+
+// Deny records that the principal was denied the action on the resource
+func (pol *Policy) Deny(ctx context.Context, p middleware.Principal, action, resource, reason string) {
+	pol.audit.Violation(ctx, p.Type, p.ID, action, resource, reason)
+}
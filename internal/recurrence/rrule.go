@@ -0,0 +1,419 @@
+// Package recurrence parses and expands RFC 5545 recurrence rules.
+//
+// The supported subset covers the rules trainings use: FREQ (DAILY, WEEKLY,
+// MONTHLY, YEARLY), INTERVAL, COUNT, UNTIL, BYDAY, BYMONTHDAY and WKST.
+// Occurrences are expanded in wall-clock time of the rule's location, so a
+// weekly 18:00 class stays at 18:00 across daylight saving changes.
+package recurrence
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+// MaxOccurrences limits the number of occurrences a rule expands to
+const MaxOccurrences = 1000
+
+// Frequency is the FREQ of a rule
+type Frequency string
+
+const (
+	Daily   Frequency = "DAILY"
+	Weekly  Frequency = "WEEKLY"
+	Monthly Frequency = "MONTHLY"
+	Yearly  Frequency = "YEARLY"
+)
+
+// WeekdayNum is a BYDAY entry, N selects the Nth weekday of the month counted
+// from the end when negative, 0 selects every such weekday
+type WeekdayNum struct {
+	N       int
+	Weekday time.Weekday
+}
+
+// Rule is a parsed recurrence rule
+type Rule struct {
+	Freq     Frequency
+	Interval int
+	// Count and Until bound the rule, an unbounded rule has neither
+	Count      int
+	Until      time.Time
+	ByDay      []WeekdayNum
+	ByMonthDay []int
+	WeekStart  time.Weekday
+}
+
+var weekdays = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+func weekdayName(d time.Weekday) string {
+	return strings.ToUpper(d.String()[:2])
+}
+
+// Parse parses the rule, with or without the RRULE: prefix. A floating UNTIL is
+// interpreted in loc.
+func Parse(s string, loc *time.Location) (*Rule, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "RRULE:")
+	rule := Rule{Interval: 1, WeekStart: time.Monday}
+	seen := make(map[string]bool)
+
+	for _, part := range strings.Split(s, ";") {
+		name, value, ok := strings.Cut(part, "=")
+		if !ok || value == "" {
+			return nil, xerrors.Errorf("invalid rule part %q", part)
+		}
+		name = strings.ToUpper(name)
+		if seen[name] {
+			return nil, xerrors.Errorf("duplicate rule part %s", name)
+		}
+		seen[name] = true
+
+		var err error
+		switch name {
+		case "FREQ":
+			rule.Freq = Frequency(strings.ToUpper(value))
+			switch rule.Freq {
+			case Daily, Weekly, Monthly, Yearly:
+			default:
+				err = xerrors.Errorf("unsupported frequency %s", value)
+			}
+		case "INTERVAL":
+			rule.Interval, err = positive(value)
+		case "COUNT":
+			rule.Count, err = positive(value)
+		case "UNTIL":
+			rule.Until, err = parseUntil(value, loc)
+		case "BYDAY":
+			rule.ByDay, err = parseByDay(value)
+		case "BYMONTHDAY":
+			rule.ByMonthDay, err = parseByMonthDay(value)
+		case "WKST":
+			var ok bool
+			if rule.WeekStart, ok = weekdays[strings.ToUpper(value)]; !ok {
+				err = xerrors.Errorf("invalid WKST %s", value)
+			}
+		default:
+			err = xerrors.Errorf("unsupported rule part %s", name)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if rule.Freq == "" {
+		return nil, xerrors.New("FREQ is required")
+	}
+	if rule.Count > 0 && !rule.Until.IsZero() {
+		return nil, xerrors.New("COUNT and UNTIL cannot be combined")
+	}
+	if len(rule.ByMonthDay) > 0 && (rule.Freq == Weekly || rule.Freq == Yearly) {
+		return nil, xerrors.Errorf("BYMONTHDAY is not supported with FREQ=%s", rule.Freq)
+	}
+	if len(rule.ByDay) > 0 && rule.Freq == Yearly {
+		return nil, xerrors.New("BYDAY is not supported with FREQ=YEARLY")
+	}
+	for _, day := range rule.ByDay {
+		if day.N != 0 && (rule.Freq != Monthly || len(rule.ByMonthDay) > 0) {
+			return nil, xerrors.New("numbered BYDAY is only supported with FREQ=MONTHLY")
+		}
+	}
+	return &rule, nil
+}
+
+func positive(value string) (int, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		return 0, xerrors.Errorf("invalid number %s", value)
+	}
+	return n, nil
+}
+
+func parseUntil(value string, loc *time.Location) (time.Time, error) {
+	if t, err := time.Parse("20060102T150405Z", value); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("20060102T150405", value, loc); err == nil {
+		return t, nil
+	}
+	// A date includes the whole day
+	if t, err := time.ParseInLocation("20060102", value, loc); err == nil {
+		return t.AddDate(0, 0, 1).Add(-time.Second), nil
+	}
+	return time.Time{}, xerrors.Errorf("invalid UNTIL %s", value)
+}
+
+func parseByDay(value string) ([]WeekdayNum, error) {
+	var days []WeekdayNum
+	for _, item := range strings.Split(value, ",") {
+		item = strings.ToUpper(item)
+		if len(item) < 2 {
+			return nil, xerrors.Errorf("invalid BYDAY %s", item)
+		}
+		weekday, ok := weekdays[item[len(item)-2:]]
+		if !ok {
+			return nil, xerrors.Errorf("invalid BYDAY %s", item)
+		}
+		day := WeekdayNum{Weekday: weekday}
+		if prefix := item[:len(item)-2]; prefix != "" {
+			n, err := strconv.Atoi(prefix)
+			if err != nil || n == 0 || n < -5 || n > 5 {
+				return nil, xerrors.Errorf("invalid BYDAY %s", item)
+			}
+			day.N = n
+		}
+		days = append(days, day)
+	}
+	return days, nil
+}
+
+func parseByMonthDay(value string) ([]int, error) {
+	var days []int
+	for _, item := range strings.Split(value, ",") {
+		n, err := strconv.Atoi(item)
+		if err != nil || n == 0 || n < -31 || n > 31 {
+			return nil, xerrors.Errorf("invalid BYMONTHDAY %s", item)
+		}
+		days = append(days, n)
+	}
+	return days, nil
+}
+
+// String formats the rule without the RRULE: prefix, UNTIL is written in UTC
+func (r *Rule) String() string {
+	parts := []string{"FREQ=" + string(r.Freq)}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if !r.Until.IsZero() {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, 0, len(r.ByDay))
+		for _, day := range r.ByDay {
+			prefix := ""
+			if day.N != 0 {
+				prefix = strconv.Itoa(day.N)
+			}
+			days = append(days, prefix+weekdayName(day.Weekday))
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		days := make([]string, 0, len(r.ByMonthDay))
+		for _, day := range r.ByMonthDay {
+			days = append(days, strconv.Itoa(day))
+		}
+		parts = append(parts, "BYMONTHDAY="+strings.Join(days, ","))
+	}
+	if r.WeekStart != time.Monday {
+		parts = append(parts, "WKST="+weekdayName(r.WeekStart))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Bounded reports whether the rule ends by COUNT or UNTIL
+func (r *Rule) Bounded() bool {
+	return r.Count > 0 || !r.Until.IsZero()
+}
+
+// Ends reports whether every occurrence of the rule starting at start is not
+// after limit. Unbounded rules and rules with MaxOccurrences or more occurrences
+// under UNTIL never end in this sense, as their expansion is cut off.
+func (r *Rule) Ends(start, limit time.Time) bool {
+	within := len(r.Occurrences(start, limit))
+	switch {
+	case r.Count > 0:
+		return within == r.Count
+	case !r.Until.IsZero():
+		return within < MaxOccurrences && within == len(r.Occurrences(start, r.Until))
+	}
+	return false
+}
+
+// Occurrences returns the start times of the occurrences of the rule starting at
+// start which are not after limit, in start's location. As in RFC 5545 start is
+// always the first occurrence. At most MaxOccurrences are returned.
+func (r *Rule) Occurrences(start, limit time.Time) []time.Time {
+	end := limit
+	if !r.Until.IsZero() && r.Until.Before(end) {
+		end = r.Until
+	}
+	if start.After(end) {
+		return nil
+	}
+
+	loc := start.Location()
+	hour, minute, second := start.Clock()
+	occurrences := []time.Time{start}
+	done := func() bool {
+		return len(occurrences) >= MaxOccurrences || (r.Count > 0 && len(occurrences) >= r.Count)
+	}
+
+	for period := 0; !done(); period++ {
+		days, first := r.period(start, period)
+		if civil(first, loc, 0, 0, 0).After(end) {
+			break
+		}
+		for _, day := range days {
+			t := civil(day, loc, hour, minute, second)
+			if !t.After(start) {
+				continue
+			}
+			if t.After(end) || done() {
+				return occurrences
+			}
+			occurrences = append(occurrences, t)
+		}
+	}
+	return occurrences
+}
+
+// period returns the sorted candidate days of the nth period of the rule and the
+// first day of the period. Days are dates at midnight UTC.
+func (r *Rule) period(start time.Time, n int) ([]time.Time, time.Time) {
+	year, month, day := start.Date()
+	step := n * r.Interval
+
+	switch r.Freq {
+	case Daily:
+		d := date(year, month, day+step)
+		if r.matchesWeekday(d) && r.matchesMonthDay(d) {
+			return []time.Time{d}, d
+		}
+		return nil, d
+	case Weekly:
+		offset := (int(start.Weekday()) - int(r.WeekStart) + 7) % 7
+		first := date(year, month, day-offset+7*step)
+		var days []time.Time
+		for i := 0; i < 7; i++ {
+			d := first.AddDate(0, 0, i)
+			if len(r.ByDay) > 0 && r.matchesWeekday(d) || len(r.ByDay) == 0 && d.Weekday() == start.Weekday() {
+				days = append(days, d)
+			}
+		}
+		return days, first
+	case Monthly:
+		first := date(year, month+time.Month(step), 1)
+		return r.monthDays(first, day), first
+	default:
+		first := date(year+step, 1, 1)
+		d := date(year+step, month, day)
+		if d.Month() != month {
+			// There is no such date in this year, e.g. February 29
+			return nil, first
+		}
+		return []time.Time{d}, first
+	}
+}
+
+// monthDays returns the candidate days of the month starting at first
+func (r *Rule) monthDays(first time.Time, startDay int) []time.Time {
+	length := first.AddDate(0, 1, -1).Day()
+	var days []time.Time
+
+	switch {
+	case len(r.ByMonthDay) > 0:
+		for _, n := range r.ByMonthDay {
+			if n < 0 {
+				n = length + n + 1
+			}
+			if n >= 1 && n <= length {
+				d := first.AddDate(0, 0, n-1)
+				if r.matchesWeekday(d) {
+					days = append(days, d)
+				}
+			}
+		}
+	case len(r.ByDay) > 0:
+		for _, wd := range r.ByDay {
+			var matches []time.Time
+			for i := 0; i < length; i++ {
+				if d := first.AddDate(0, 0, i); d.Weekday() == wd.Weekday {
+					matches = append(matches, d)
+				}
+			}
+			switch {
+			case wd.N == 0:
+				days = append(days, matches...)
+			case wd.N > 0 && wd.N <= len(matches):
+				days = append(days, matches[wd.N-1])
+			case wd.N < 0 && -wd.N <= len(matches):
+				days = append(days, matches[len(matches)+wd.N])
+			}
+		}
+	case startDay <= length:
+		days = append(days, first.AddDate(0, 0, startDay-1))
+	}
+
+	sort.Slice(days, func(i, j int) bool { return days[i].Before(days[j]) })
+	return dedupe(days)
+}
+
+func (r *Rule) matchesWeekday(d time.Time) bool {
+	if len(r.ByDay) == 0 {
+		return true
+	}
+	for _, wd := range r.ByDay {
+		if wd.Weekday == d.Weekday() {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Rule) matchesMonthDay(d time.Time) bool {
+	if len(r.ByMonthDay) == 0 {
+		return true
+	}
+	length := d.AddDate(0, 1, -d.Day()).Day()
+	for _, n := range r.ByMonthDay {
+		if n == d.Day() || n < 0 && length+n+1 == d.Day() {
+			return true
+		}
+	}
+	return false
+}
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// civil returns the wall-clock time of the day in loc
+func civil(day time.Time, loc *time.Location, hour, minute, second int) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), hour, minute, second, 0, loc)
+}
+
+func dedupe(days []time.Time) []time.Time {
+	out := days[:0]
+	for i, d := range days {
+		if i == 0 || !d.Equal(days[i-1]) {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+// WallClockShift returns by how much the wall-clock time of to in loc differs
+// from that of from, ignoring daylight saving offsets
+func WallClockShift(from, to time.Time, loc *time.Location) time.Duration {
+	return wallClock(to.In(loc)).Sub(wallClock(from.In(loc)))
+}
+
+// ShiftWallClock moves the wall-clock time of t in loc by d
+func ShiftWallClock(t time.Time, d time.Duration, loc *time.Location) time.Time {
+	w := wallClock(t.In(loc)).Add(d)
+	return time.Date(w.Year(), w.Month(), w.Day(), w.Hour(), w.Minute(), w.Second(), w.Nanosecond(), loc)
+}
+
+func wallClock(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+}
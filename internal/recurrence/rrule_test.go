@@ -0,0 +1,329 @@
+package recurrence
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+const layout = "2006-01-02 15:04"
+
+func mustLoad(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loc
+}
+
+func at(t *testing.T, value string, loc *time.Location) time.Time {
+	t.Helper()
+	parsed, err := time.ParseInLocation(layout, value, loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed
+}
+
+func TestOccurrences(t *testing.T) {
+	berlin := mustLoad(t, "Europe/Berlin")
+	tests := []struct {
+		name  string
+		rule  string
+		loc   *time.Location
+		start string
+		limit string
+		want  []string
+	}{
+		{
+			name:  "daily",
+			rule:  "FREQ=DAILY;COUNT=3",
+			start: "2024-01-30 10:00",
+			want:  []string{"2024-01-30 10:00", "2024-01-31 10:00", "2024-02-01 10:00"},
+		},
+		{
+			name:  "daily with interval until",
+			rule:  "FREQ=DAILY;INTERVAL=2;UNTIL=20240105T100000Z",
+			start: "2024-01-01 10:00",
+			want:  []string{"2024-01-01 10:00", "2024-01-03 10:00", "2024-01-05 10:00"},
+		},
+		{
+			name:  "until date includes the whole day",
+			rule:  "FREQ=DAILY;UNTIL=20240103",
+			start: "2024-01-01 22:00",
+			want:  []string{"2024-01-01 22:00", "2024-01-02 22:00", "2024-01-03 22:00"},
+		},
+		{
+			name:  "count beyond the limit",
+			rule:  "FREQ=DAILY;COUNT=10",
+			start: "2024-01-01 10:00",
+			limit: "2024-01-02 10:00",
+			want:  []string{"2024-01-01 10:00", "2024-01-02 10:00"},
+		},
+		{
+			name:  "until before the count",
+			rule:  "FREQ=WEEKLY;UNTIL=20240110T000000Z",
+			start: "2024-01-01 10:00",
+			want:  []string{"2024-01-01 10:00", "2024-01-08 10:00"},
+		},
+		{
+			name:  "unbounded up to the limit",
+			rule:  "FREQ=DAILY",
+			start: "2024-01-01 10:00",
+			limit: "2024-01-03 12:00",
+			want:  []string{"2024-01-01 10:00", "2024-01-02 10:00", "2024-01-03 10:00"},
+		},
+		{
+			name:  "weekly",
+			rule:  "FREQ=WEEKLY;INTERVAL=2;COUNT=3",
+			start: "2024-01-01 10:00",
+			want:  []string{"2024-01-01 10:00", "2024-01-15 10:00", "2024-01-29 10:00"},
+		},
+		{
+			name:  "weekly by day",
+			rule:  "FREQ=WEEKLY;BYDAY=MO,WE;COUNT=4",
+			start: "2024-01-01 10:00",
+			want:  []string{"2024-01-01 10:00", "2024-01-03 10:00", "2024-01-08 10:00", "2024-01-10 10:00"},
+		},
+		{
+			name:  "weekly by day starting mid week",
+			rule:  "FREQ=WEEKLY;BYDAY=MO,FR;COUNT=3",
+			start: "2024-01-03 10:00",
+			want:  []string{"2024-01-03 10:00", "2024-01-05 10:00", "2024-01-08 10:00"},
+		},
+		{
+			name:  "monthly",
+			rule:  "FREQ=MONTHLY;COUNT=3",
+			start: "2024-01-15 10:00",
+			want:  []string{"2024-01-15 10:00", "2024-02-15 10:00", "2024-03-15 10:00"},
+		},
+		{
+			name:  "monthly on the 31st skips short months",
+			rule:  "FREQ=MONTHLY;COUNT=4",
+			start: "2024-01-31 10:00",
+			want:  []string{"2024-01-31 10:00", "2024-03-31 10:00", "2024-05-31 10:00", "2024-07-31 10:00"},
+		},
+		{
+			name:  "monthly on the last day",
+			rule:  "FREQ=MONTHLY;BYMONTHDAY=-1;COUNT=4",
+			start: "2024-01-31 10:00",
+			want:  []string{"2024-01-31 10:00", "2024-02-29 10:00", "2024-03-31 10:00", "2024-04-30 10:00"},
+		},
+		{
+			name:  "monthly by month day 31",
+			rule:  "FREQ=MONTHLY;BYMONTHDAY=31;UNTIL=20240601T000000Z",
+			start: "2024-01-31 10:00",
+			want:  []string{"2024-01-31 10:00", "2024-03-31 10:00", "2024-05-31 10:00"},
+		},
+		{
+			name:  "monthly on the last friday",
+			rule:  "FREQ=MONTHLY;BYDAY=-1FR;COUNT=3",
+			start: "2024-01-26 18:00",
+			want:  []string{"2024-01-26 18:00", "2024-02-23 18:00", "2024-03-29 18:00"},
+		},
+		{
+			name:  "monthly on the second tuesday",
+			rule:  "FREQ=MONTHLY;BYDAY=2TU;COUNT=3",
+			start: "2024-01-09 18:00",
+			want:  []string{"2024-01-09 18:00", "2024-02-13 18:00", "2024-03-12 18:00"},
+		},
+		{
+			name:  "monthly on the fifth monday skips months without one",
+			rule:  "FREQ=MONTHLY;BYDAY=5MO;COUNT=3",
+			start: "2024-01-29 18:00",
+			want:  []string{"2024-01-29 18:00", "2024-04-29 18:00", "2024-07-29 18:00"},
+		},
+		{
+			name:  "monthly on the second to last weekday",
+			rule:  "FREQ=MONTHLY;BYDAY=-2WE;COUNT=2",
+			start: "2024-02-21 18:00",
+			want:  []string{"2024-02-21 18:00", "2024-03-20 18:00"},
+		},
+		{
+			name:  "yearly",
+			rule:  "FREQ=YEARLY;COUNT=3",
+			start: "2024-06-01 10:00",
+			want:  []string{"2024-06-01 10:00", "2025-06-01 10:00", "2026-06-01 10:00"},
+		},
+		{
+			name:  "yearly on february 29",
+			rule:  "FREQ=YEARLY;COUNT=3",
+			start: "2024-02-29 10:00",
+			want:  []string{"2024-02-29 10:00", "2028-02-29 10:00", "2032-02-29 10:00"},
+		},
+		{
+			name:  "weekly across the start of daylight saving time",
+			rule:  "FREQ=WEEKLY;COUNT=3",
+			loc:   berlin,
+			start: "2024-03-24 18:00",
+			want:  []string{"2024-03-24 18:00", "2024-03-31 18:00", "2024-04-07 18:00"},
+		},
+		{
+			name:  "daily across the end of daylight saving time",
+			rule:  "FREQ=DAILY;COUNT=3",
+			loc:   berlin,
+			start: "2024-10-26 07:30",
+			want:  []string{"2024-10-26 07:30", "2024-10-27 07:30", "2024-10-28 07:30"},
+		},
+		{
+			name:  "until in utc across daylight saving time",
+			rule:  "FREQ=WEEKLY;UNTIL=20240407T160000Z",
+			loc:   berlin,
+			start: "2024-03-24 18:00",
+			want:  []string{"2024-03-24 18:00", "2024-03-31 18:00", "2024-04-07 18:00"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loc := tt.loc
+			if loc == nil {
+				loc = time.UTC
+			}
+			rule, err := Parse(tt.rule, loc)
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			start := at(t, tt.start, loc)
+			limit := start.AddDate(20, 0, 0)
+			if tt.limit != "" {
+				limit = at(t, tt.limit, loc)
+			}
+
+			got := rule.Occurrences(start, limit)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d occurrences %v, want %v", len(got), got, tt.want)
+			}
+			for i, occurrence := range got {
+				if want := at(t, tt.want[i], loc); !occurrence.Equal(want) || occurrence.Location() != loc {
+					t.Errorf("occurrence %d is %v, want %v", i, occurrence, want)
+				}
+			}
+		})
+	}
+}
+
+func TestOccurrencesAcrossDaylightSavingTimeKeepWallClock(t *testing.T) {
+	berlin := mustLoad(t, "Europe/Berlin")
+	rule, err := Parse("FREQ=WEEKLY;COUNT=2", berlin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := rule.Occurrences(at(t, "2024-03-24 18:00", berlin), at(t, "2025-01-01 00:00", berlin))
+	if len(got) != 2 {
+		t.Fatalf("got %v", got)
+	}
+	// The class stays at 18:00, which is an hour earlier in UTC in summer time
+	if gap := got[1].Sub(got[0]); gap != 7*24*time.Hour-time.Hour {
+		t.Errorf("gap %v, want a week less an hour", gap)
+	}
+}
+
+func TestOccurrencesLimits(t *testing.T) {
+	rule, err := Parse("FREQ=DAILY", time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := at(t, "2024-01-01 10:00", time.UTC)
+	if got := rule.Occurrences(start, start.AddDate(10, 0, 0)); len(got) != MaxOccurrences {
+		t.Errorf("unbounded rule expanded to %d occurrences, want %d", len(got), MaxOccurrences)
+	}
+	if got := rule.Occurrences(start, start.Add(-time.Hour)); got != nil {
+		t.Errorf("start after the limit: %v", got)
+	}
+
+	rule, err = Parse("FREQ=DAILY;UNTIL=20231231T000000Z", time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := rule.Occurrences(start, start.AddDate(1, 0, 0)); got != nil {
+		t.Errorf("start after UNTIL: %v", got)
+	}
+}
+
+func TestEnds(t *testing.T) {
+	start := at(t, "2024-01-01 10:00", time.UTC)
+	limit := start.AddDate(1, 0, 0)
+	tests := []struct {
+		rule string
+		want bool
+	}{
+		{"FREQ=WEEKLY", false},
+		{"FREQ=WEEKLY;COUNT=52", true},
+		{"FREQ=WEEKLY;COUNT=54", false},
+		{"FREQ=WEEKLY;UNTIL=20241231T000000Z", true},
+		{"FREQ=WEEKLY;UNTIL=20250107T000000Z", false},
+		// UNTIL is after the limit but there is no occurrence between them
+		{"FREQ=MONTHLY;UNTIL=20250115T000000Z", true},
+	}
+	for _, tt := range tests {
+		rule, err := Parse(tt.rule, time.UTC)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := rule.Ends(start, limit); got != tt.want {
+			t.Errorf("%s: Ends = %v, want %v", tt.rule, got, tt.want)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		rule string
+		// want is the formatted rule, empty when parsing fails
+		want string
+	}{
+		{"RRULE:FREQ=WEEKLY;BYDAY=MO,WE", "FREQ=WEEKLY;BYDAY=MO,WE"},
+		{"freq=monthly;byday=-1fr", "FREQ=MONTHLY;BYDAY=-1FR"},
+		{"FREQ=DAILY;INTERVAL=1;COUNT=5", "FREQ=DAILY;COUNT=5"},
+		{"FREQ=WEEKLY;WKST=SU;INTERVAL=2", "FREQ=WEEKLY;INTERVAL=2;WKST=SU"},
+		{"FREQ=MONTHLY;BYMONTHDAY=1,-1", "FREQ=MONTHLY;BYMONTHDAY=1,-1"},
+		{"FREQ=DAILY;UNTIL=20240105T100000Z", "FREQ=DAILY;UNTIL=20240105T100000Z"},
+		{"FREQ=DAILY;UNTIL=20240105T100000", "FREQ=DAILY;UNTIL=20240105T100000Z"},
+		{"FREQ=DAILY;UNTIL=20240105", "FREQ=DAILY;UNTIL=20240105T235959Z"},
+		{"", ""},
+		{"COUNT=3", ""},
+		{"FREQ=HOURLY", ""},
+		{"FREQ=DAILY;FREQ=WEEKLY", ""},
+		{"FREQ=DAILY;COUNT=0", ""},
+		{"FREQ=DAILY;INTERVAL=-1", ""},
+		{"FREQ=DAILY;COUNT=3;UNTIL=20240105", ""},
+		{"FREQ=DAILY;UNTIL=tomorrow", ""},
+		{"FREQ=DAILY;BYSETPOS=1", ""},
+		{"FREQ=WEEKLY;BYMONTHDAY=1", ""},
+		{"FREQ=YEARLY;BYDAY=MO", ""},
+		{"FREQ=WEEKLY;BYDAY=1MO", ""},
+		{"FREQ=MONTHLY;BYDAY=6MO", ""},
+		{"FREQ=MONTHLY;BYDAY=0MO", ""},
+		{"FREQ=MONTHLY;BYDAY=XX", ""},
+		{"FREQ=MONTHLY;BYMONTHDAY=0", ""},
+		{"FREQ=MONTHLY;BYMONTHDAY=32", ""},
+		{"FREQ=MONTHLY;BYMONTHDAY=1;BYDAY=1MO", ""},
+		{"FREQ=WEEKLY;WKST=XX", ""},
+	}
+	for _, tt := range tests {
+		rule, err := Parse(tt.rule, time.UTC)
+		switch {
+		case tt.want == "" && err == nil:
+			t.Errorf("Parse(%q) = %s, want an error", tt.rule, rule)
+		case tt.want != "" && err != nil:
+			t.Errorf("Parse(%q): %v", tt.rule, err)
+		case tt.want != "" && rule.String() != tt.want:
+			t.Errorf("Parse(%q) = %s, want %s", tt.rule, rule, tt.want)
+		}
+	}
+}
+
+func TestShiftWallClock(t *testing.T) {
+	berlin := mustLoad(t, "Europe/Berlin")
+	from := at(t, "2024-03-24 18:00", berlin)
+	to := at(t, "2024-03-31 19:30", berlin)
+
+	shift := WallClockShift(from, to, berlin)
+	if want := 7*24*time.Hour + 90*time.Minute; shift != want {
+		t.Errorf("WallClockShift = %v, want %v", shift, want)
+	}
+	if got := ShiftWallClock(from, shift, berlin); !got.Equal(to) {
+		t.Errorf("ShiftWallClock = %v, want %v", got, to)
+	}
+}
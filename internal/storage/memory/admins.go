@@ -0,0 +1,72 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/folklinoff/fitness-app/internal/domain"
+	"github.com/folklinoff/fitness-app/internal/storage"
+)
+
+// AdminRepository keeps admins in memory
+type AdminRepository struct {
+	s *store
+}
+
+// nameTaken reports whether another admin has the name, mu must be held
+func (r *AdminRepository) nameTaken(name string, id int) bool {
+	for _, admin := range r.s.admins {
+		if admin.Name == name && admin.ID != id {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *AdminRepository) Create(ctx context.Context, admin *domain.Admin) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if r.nameTaken(admin.Name, 0) {
+		return storage.ErrAlreadyExists
+	}
+	admin.ID = nextID(&r.s.adminID)
+	r.s.admins[admin.ID] = *admin
+	return nil
+}
+
+func (r *AdminRepository) GetByID(ctx context.Context, id int) (*domain.Admin, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	admin, ok := r.s.admins[id]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	return &admin, nil
+}
+
+func (r *AdminRepository) GetByName(ctx context.Context, name string) (*domain.Admin, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	for _, admin := range r.s.admins {
+		if admin.Name == name {
+			return &admin, nil
+		}
+	}
+	return nil, storage.ErrNotFound
+}
+
+func (r *AdminRepository) Update(ctx context.Context, admin *domain.Admin) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.admins[admin.ID]; !ok {
+		return storage.ErrNotFound
+	}
+	if r.nameTaken(admin.Name, admin.ID) {
+		return storage.ErrAlreadyExists
+	}
+	r.s.admins[admin.ID] = *admin
+	return nil
+}
@@ -0,0 +1,131 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/folklinoff/fitness-app/internal/domain"
+	"github.com/folklinoff/fitness-app/internal/storage"
+)
+
+// AttendanceRepository keeps attendance and check-in codes in memory
+type AttendanceRepository struct {
+	s *store
+}
+
+func (r *AttendanceRepository) Mark(ctx context.Context, attendance *domain.Attendance) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if !r.s.isRegistered(attendance.TrainingID, attendance.UserID) {
+		return storage.ErrNotFound
+	}
+	if _, ok := r.s.attendance[attendance.TrainingID][attendance.UserID]; ok {
+		return storage.ErrAlreadyExists
+	}
+	if r.s.attendance[attendance.TrainingID] == nil {
+		r.s.attendance[attendance.TrainingID] = make(map[int]domain.Attendance)
+	}
+	r.s.attendance[attendance.TrainingID][attendance.UserID] = *attendance
+
+	for id, incident := range r.s.incidents {
+		if incident.UserID == attendance.UserID && incident.TrainingID == attendance.TrainingID && incident.Kind == domain.IncidentNoShow {
+			delete(r.s.incidents, id)
+		}
+	}
+	return nil
+}
+
+func (r *AttendanceRepository) Unmark(ctx context.Context, trainingID, userID int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.attendance[trainingID][userID]; !ok {
+		return storage.ErrNotFound
+	}
+	delete(r.s.attendance[trainingID], userID)
+	return nil
+}
+
+func (r *AttendanceRepository) Get(ctx context.Context, trainingID, userID int) (*domain.Attendance, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	attendance, ok := r.s.attendance[trainingID][userID]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	return &attendance, nil
+}
+
+func (r *AttendanceRepository) ListByTraining(ctx context.Context, trainingID int) ([]domain.Attendance, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	return sortedValues(r.s.attendance[trainingID], nil), nil
+}
+
+func (r *AttendanceRepository) ReplaceCheckInCode(ctx context.Context, code *domain.CheckInCode) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.trainings[code.TrainingID]; !ok {
+		return storage.ErrNotFound
+	}
+	r.s.checkInCodes[code.TrainingID] = *code
+	return nil
+}
+
+func (r *AttendanceRepository) GetCheckInCode(ctx context.Context, trainingID int) (*domain.CheckInCode, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	code, ok := r.s.checkInCodes[trainingID]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	return &code, nil
+}
+
+func (r *AttendanceRepository) AddCheckInAttempt(ctx context.Context, trainingID, userID int) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.trainings[trainingID]; !ok {
+		return 0, storage.ErrNotFound
+	}
+	if _, ok := r.s.users[userID]; !ok {
+		return 0, storage.ErrNotFound
+	}
+	key := registrationKey{trainingID: trainingID, userID: userID}
+	r.s.checkInAttempts[key]++
+	return r.s.checkInAttempts[key], nil
+}
+
+func (r *AttendanceRepository) RecordNoShows(ctx context.Context, endedBy time.Time) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	ended := sortedValues(r.s.trainings, func(training domain.Training) bool {
+		return !training.EndTime.After(endedBy) && !r.s.noShowsRecorded[training.ID]
+	})
+	var recorded int
+	for _, training := range ended {
+		r.s.noShowsRecorded[training.ID] = true
+		for _, userID := range r.s.registrations[training.ID] {
+			if _, ok := r.s.attendance[training.ID][userID]; ok || r.s.hasIncident(userID, training.ID, domain.IncidentNoShow) {
+				continue
+			}
+			id := nextID(&r.s.incidentID)
+			r.s.incidents[id] = domain.Incident{
+				ID:         id,
+				UserID:     userID,
+				TrainingID: training.ID,
+				Kind:       domain.IncidentNoShow,
+				At:         training.EndTime,
+			}
+			recorded++
+		}
+	}
+	return recorded, nil
+}
@@ -0,0 +1,32 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/folklinoff/fitness-app/internal/domain"
+)
+
+// AuditRepository keeps audit events in memory
+type AuditRepository struct {
+	s *store
+}
+
+func (r *AuditRepository) Record(ctx context.Context, event *domain.AuditEvent) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	event.ID = nextID(&r.s.auditID)
+	r.s.auditEvents = append(r.s.auditEvents, *event)
+	return nil
+}
+
+func (r *AuditRepository) List(ctx context.Context, limit int) ([]domain.AuditEvent, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	events := make([]domain.AuditEvent, 0, min(limit, len(r.s.auditEvents)))
+	for i := len(r.s.auditEvents) - 1; i >= 0 && len(events) < limit; i-- {
+		events = append(events, r.s.auditEvents[i])
+	}
+	return events, nil
+}
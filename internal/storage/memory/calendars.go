@@ -0,0 +1,55 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/folklinoff/fitness-app/internal/domain"
+	"github.com/folklinoff/fitness-app/internal/storage"
+)
+
+// CalendarFeedRepository keeps calendar feeds in memory
+type CalendarFeedRepository struct {
+	s *store
+}
+
+func (r *CalendarFeedRepository) Replace(ctx context.Context, feed *domain.CalendarFeed) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	r.s.deleteCalendarFeed(account{feed.AccountType, feed.AccountID})
+	r.s.calendarFeeds[feed.TokenHash] = *feed
+	return nil
+}
+
+func (r *CalendarFeedRepository) GetByTokenHash(ctx context.Context, hash string) (*domain.CalendarFeed, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	feed, ok := r.s.calendarFeeds[hash]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	return &feed, nil
+}
+
+func (r *CalendarFeedRepository) Delete(ctx context.Context, accountType string, accountID int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if !r.s.deleteCalendarFeed(account{accountType, accountID}) {
+		return storage.ErrNotFound
+	}
+	return nil
+}
+
+// deleteCalendarFeed removes the feed of the account and reports whether it had
+// one, mu must be held
+func (s *store) deleteCalendarFeed(a account) bool {
+	for hash, feed := range s.calendarFeeds {
+		if feed.AccountType == a.accountType && feed.AccountID == a.accountID {
+			delete(s.calendarFeeds, hash)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,57 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/folklinoff/fitness-app/internal/domain"
+	"github.com/folklinoff/fitness-app/internal/storage"
+)
+
+// IncidentRepository keeps late cancellations and no-shows in memory
+type IncidentRepository struct {
+	s *store
+}
+
+func (r *IncidentRepository) Record(ctx context.Context, incident *domain.Incident) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.users[incident.UserID]; !ok {
+		return storage.ErrNotFound
+	}
+	if r.s.hasIncident(incident.UserID, incident.TrainingID, incident.Kind) {
+		return storage.ErrAlreadyExists
+	}
+	incident.ID = nextID(&r.s.incidentID)
+	r.s.incidents[incident.ID] = *incident
+	return nil
+}
+
+func (r *IncidentRepository) ListByUser(ctx context.Context, userID int) ([]domain.Incident, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	return sortedValues(r.s.incidents, func(incident domain.Incident) bool {
+		return incident.UserID == userID
+	}), nil
+}
+
+func (r *IncidentRepository) ListByTraining(ctx context.Context, trainingID int) ([]domain.Incident, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	return sortedValues(r.s.incidents, func(incident domain.Incident) bool {
+		return incident.TrainingID == trainingID
+	}), nil
+}
+
+// hasIncident reports whether the user has an incident of the kind for the
+// training, mu must be held
+func (s *store) hasIncident(userID, trainingID int, kind domain.IncidentKind) bool {
+	for _, incident := range s.incidents {
+		if incident.UserID == userID && incident.TrainingID == trainingID && incident.Kind == kind {
+			return true
+		}
+	}
+	return false
+}
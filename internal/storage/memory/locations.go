@@ -0,0 +1,190 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/folklinoff/fitness-app/internal/domain"
+	"github.com/folklinoff/fitness-app/internal/storage"
+)
+
+// LocationRepository keeps locations in memory
+type LocationRepository struct {
+	s *store
+}
+
+func (r *LocationRepository) Create(ctx context.Context, location *domain.Location) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if r.s.locationNameTaken(location.Name, 0) {
+		return storage.ErrAlreadyExists
+	}
+	location.ID = nextID(&r.s.locationID)
+	r.s.locations[location.ID] = cloneLocation(*location)
+	return nil
+}
+
+func (r *LocationRepository) GetByID(ctx context.Context, id int) (*domain.Location, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	location, ok := r.s.locations[id]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	location = cloneLocation(location)
+	return &location, nil
+}
+
+func (r *LocationRepository) List(ctx context.Context) ([]domain.Location, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	locations := sortedValues(r.s.locations, nil)
+	for i := range locations {
+		locations[i] = cloneLocation(locations[i])
+	}
+	return locations, nil
+}
+
+func (r *LocationRepository) Update(ctx context.Context, location *domain.Location) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.locations[location.ID]; !ok {
+		return storage.ErrNotFound
+	}
+	if r.s.locationNameTaken(location.Name, location.ID) {
+		return storage.ErrAlreadyExists
+	}
+	r.s.locations[location.ID] = cloneLocation(*location)
+	return nil
+}
+
+func (r *LocationRepository) Delete(ctx context.Context, id int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.locations[id]; !ok {
+		return storage.ErrNotFound
+	}
+	delete(r.s.locations, id)
+	for roomID, room := range r.s.rooms {
+		if room.LocationID == id {
+			r.s.deleteRoom(roomID)
+		}
+	}
+	return nil
+}
+
+// locationNameTaken reports whether a location other than id has the name, mu must be held
+func (s *store) locationNameTaken(name string, id int) bool {
+	for _, location := range s.locations {
+		if location.Name == name && location.ID != id {
+			return true
+		}
+	}
+	return false
+}
+
+func cloneLocation(location domain.Location) domain.Location {
+	location.OpeningHours = append([]domain.OpeningHours(nil), location.OpeningHours...)
+	return location
+}
+
+// RoomRepository keeps rooms in memory
+type RoomRepository struct {
+	s *store
+}
+
+func (r *RoomRepository) Create(ctx context.Context, room *domain.Room) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.locations[room.LocationID]; !ok {
+		return storage.ErrNotFound
+	}
+	if r.s.roomNameTaken(room.LocationID, room.Name, 0) {
+		return storage.ErrAlreadyExists
+	}
+	room.ID = nextID(&r.s.roomID)
+	r.s.rooms[room.ID] = *room
+	return nil
+}
+
+func (r *RoomRepository) GetByID(ctx context.Context, id int) (*domain.Room, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	room, ok := r.s.rooms[id]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	return &room, nil
+}
+
+func (r *RoomRepository) ListByLocation(ctx context.Context, locationID int) ([]domain.Room, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	return sortedValues(r.s.rooms, func(room domain.Room) bool {
+		return room.LocationID == locationID
+	}), nil
+}
+
+func (r *RoomRepository) Update(ctx context.Context, room *domain.Room) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	stored, ok := r.s.rooms[room.ID]
+	if !ok {
+		return storage.ErrNotFound
+	}
+	if r.s.roomNameTaken(stored.LocationID, room.Name, room.ID) {
+		return storage.ErrAlreadyExists
+	}
+	// Rooms cannot move to another location
+	room.LocationID = stored.LocationID
+	r.s.rooms[room.ID] = *room
+	return nil
+}
+
+func (r *RoomRepository) Delete(ctx context.Context, id int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.rooms[id]; !ok {
+		return storage.ErrNotFound
+	}
+	r.s.deleteRoom(id)
+	return nil
+}
+
+// roomNameTaken reports whether a room of the location other than id has the
+// name, mu must be held
+func (s *store) roomNameTaken(locationID int, name string, id int) bool {
+	for _, room := range s.rooms {
+		if room.LocationID == locationID && room.Name == name && room.ID != id {
+			return true
+		}
+	}
+	return false
+}
+
+// deleteRoom removes the room and clears it from the trainings and series in
+// it, mu must be held
+func (s *store) deleteRoom(id int) {
+	delete(s.rooms, id)
+	for trainingID, training := range s.trainings {
+		if training.RoomID != nil && *training.RoomID == id {
+			training.RoomID = nil
+			s.trainings[trainingID] = training
+		}
+	}
+	for seriesID, series := range s.series {
+		if series.RoomID != nil && *series.RoomID == id {
+			series.RoomID = nil
+			s.series[seriesID] = series
+		}
+	}
+}
@@ -0,0 +1,264 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/folklinoff/fitness-app/internal/domain"
+	"github.com/folklinoff/fitness-app/internal/storage"
+)
+
+// registrationKey identifies the registration or waitlist entry of a user
+type registrationKey struct {
+	trainingID int
+	userID     int
+}
+
+// charge is the payment of a registration from a membership
+type charge struct {
+	membershipID int
+	credits      int
+}
+
+// PlanRepository keeps membership plans in memory
+type PlanRepository struct {
+	s *store
+}
+
+func (r *PlanRepository) Create(ctx context.Context, plan *domain.Plan) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if r.nameTaken(plan.Name, 0) {
+		return storage.ErrAlreadyExists
+	}
+	plan.ID = nextID(&r.s.planID)
+	r.s.plans[plan.ID] = *plan
+	return nil
+}
+
+func (r *PlanRepository) GetByID(ctx context.Context, id int) (*domain.Plan, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	plan, ok := r.s.plans[id]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	return &plan, nil
+}
+
+func (r *PlanRepository) List(ctx context.Context, activeOnly bool) ([]domain.Plan, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	return sortedValues(r.s.plans, func(plan domain.Plan) bool {
+		return plan.Active || !activeOnly
+	}), nil
+}
+
+func (r *PlanRepository) Update(ctx context.Context, plan *domain.Plan) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.plans[plan.ID]; !ok {
+		return storage.ErrNotFound
+	}
+	if r.nameTaken(plan.Name, plan.ID) {
+		return storage.ErrAlreadyExists
+	}
+	r.s.plans[plan.ID] = *plan
+	return nil
+}
+
+// nameTaken reports whether a plan other than exceptID has the name, mu must be held
+func (r *PlanRepository) nameTaken(name string, exceptID int) bool {
+	for _, plan := range r.s.plans {
+		if plan.Name == name && plan.ID != exceptID {
+			return true
+		}
+	}
+	return false
+}
+
+// MembershipRepository keeps memberships and their ledger in memory
+type MembershipRepository struct {
+	s *store
+}
+
+func (r *MembershipRepository) Grant(ctx context.Context, membership *domain.Membership) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.users[membership.UserID]; !ok {
+		return storage.ErrNotFound
+	}
+	if _, ok := r.s.plans[membership.PlanID]; !ok {
+		return storage.ErrNotFound
+	}
+	r.s.grant(membership)
+	return nil
+}
+
+func (r *MembershipRepository) ListByUser(ctx context.Context, userID int) ([]domain.Membership, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	return sortedValues(r.s.memberships, func(membership domain.Membership) bool {
+		return membership.UserID == userID
+	}), nil
+}
+
+func (r *MembershipRepository) Ledger(ctx context.Context, userID int) ([]domain.LedgerEntry, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	var entries []domain.LedgerEntry
+	for _, entry := range r.s.ledger {
+		if entry.UserID == userID {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+// payRegistration pays the cost of the training from a membership of the user
+// covering it. Without one, the seat of a paid training is held pending payment;
+// waitlisted users pay once promoted. mu must be held.
+func (s *store) payRegistration(training domain.Training, registration *domain.Registration, at time.Time) error {
+	if training.Credits == 0 && training.Price == 0 {
+		return nil
+	}
+	if training.Credits > 0 {
+		if s.payCredits(training, registration, at) {
+			return nil
+		}
+		if training.Price == 0 {
+			return storage.ErrNoCredits
+		}
+	}
+	if registration.Status == domain.RegistrationConfirmed {
+		registration.Status, registration.HeldAt = domain.RegistrationPending, at
+	}
+	return nil
+}
+
+// payCredits pays the cost from the membership of the user covering the
+// training, unlimited ones first and then the one expiring first, and reports
+// whether there was one. mu must be held.
+func (s *store) payCredits(training domain.Training, registration *domain.Registration, at time.Time) bool {
+	candidates := sortedValues(s.memberships, func(membership domain.Membership) bool {
+		return membership.UserID == registration.UserID && membership.Covers(training.StartTime, training.Credits)
+	})
+	if len(candidates) == 0 {
+		return false
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if (a.Kind == domain.PlanUnlimited) != (b.Kind == domain.PlanUnlimited) {
+			return a.Kind == domain.PlanUnlimited
+		}
+		return a.ExpiresAt.Before(b.ExpiresAt)
+	})
+
+	membership := candidates[0]
+	registration.MembershipID = &membership.ID
+	if membership.Kind != domain.PlanUnlimited {
+		registration.Credits = training.Credits
+		s.changeCredits(membership.ID, &registration.TrainingID, domain.LedgerConsume, -training.Credits, at)
+	}
+	s.charges[registrationKey{registration.TrainingID, registration.UserID}] = charge{membership.ID, registration.Credits}
+	return true
+}
+
+// registration returns the registration of the user with its charge and
+// payment, mu must be held
+func (s *store) registration(trainingID, userID int) domain.Registration {
+	registration := domain.Registration{TrainingID: trainingID, UserID: userID}
+	key := registrationKey{trainingID, userID}
+	if c, ok := s.charges[key]; ok {
+		registration.MembershipID, registration.Credits = &c.membershipID, c.credits
+	}
+	if paymentID, ok := s.seatPayments[key]; ok {
+		registration.PaymentID = &paymentID
+	}
+	return registration
+}
+
+// refund gives the credits paid for the registration back to its membership,
+// mu must be held
+func (s *store) refund(registration domain.Registration, at time.Time) {
+	if registration.MembershipID == nil || registration.Credits == 0 {
+		return
+	}
+	if _, ok := s.memberships[*registration.MembershipID]; !ok {
+		return
+	}
+	s.changeCredits(*registration.MembershipID, &registration.TrainingID, domain.LedgerRefund, registration.Credits, at)
+}
+
+// refundTraining removes the charges and payments of the registrations of the
+// training and refunds them if it has not started, mu must be held
+func (s *store) refundTraining(trainingID int, now time.Time) {
+	started := !s.trainings[trainingID].StartTime.After(now)
+	for key, paymentID := range s.seatPayments {
+		if key.trainingID != trainingID {
+			continue
+		}
+		if !started {
+			s.refundPayment(paymentID, now)
+		}
+		delete(s.seatPayments, key)
+	}
+
+	var keys []registrationKey
+	for key := range s.charges {
+		if key.trainingID == trainingID {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].userID < keys[j].userID })
+
+	for _, key := range keys {
+		c := s.charges[key]
+		if !started {
+			s.refund(domain.Registration{TrainingID: trainingID, UserID: key.userID, MembershipID: &c.membershipID, Credits: c.credits}, now)
+		}
+		delete(s.charges, key)
+	}
+}
+
+// grant stores the membership with its grant ledger entry, mu must be held
+func (s *store) grant(membership *domain.Membership) {
+	membership.ID = nextID(&s.membershipID)
+	credits := membership.Credits
+	membership.Credits = 0
+	s.memberships[membership.ID] = *membership
+	membership.Credits = s.changeCredits(membership.ID, nil, domain.LedgerGrant, credits, membership.CreatedAt)
+}
+
+// changeCredits adds amount to the credits of the membership, records the
+// change in the ledger and returns the new balance. mu must be held.
+func (s *store) changeCredits(membershipID int, trainingID *int, kind domain.LedgerEntryKind, amount int, at time.Time) int {
+	membership := s.memberships[membershipID]
+	membership.Credits += amount
+	s.memberships[membershipID] = membership
+
+	var training *int
+	if trainingID != nil {
+		id := *trainingID
+		training = &id
+	}
+	s.ledger = append(s.ledger, domain.LedgerEntry{
+		ID:           nextID(&s.ledgerID),
+		UserID:       membership.UserID,
+		MembershipID: membershipID,
+		TrainingID:   training,
+		Kind:         kind,
+		Amount:       amount,
+		Balance:      membership.Credits,
+		At:           at,
+	})
+	return membership.Credits
+}
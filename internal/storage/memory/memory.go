@@ -0,0 +1,343 @@
+package memory
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/folklinoff/fitness-app/internal/domain"
+	"github.com/folklinoff/fitness-app/internal/storage"
+)
+
+// store holds the data of all repositories. Every repository method takes mu
+// for the whole operation, so compound updates such as registering a user or
+// cascading a delete are atomic. IDs are allocated with atomic counters and are
+// never reused.
+type store struct {
+	mu      sync.RWMutex
+	options storage.Options
+
+	users     map[int]domain.User
+	trainers  map[int]domain.Trainer
+	admins    map[int]domain.Admin
+	trainings map[int]domain.Training
+	series    map[int]domain.TrainingSeries
+	locations map[int]domain.Location
+	rooms     map[int]domain.Room
+	// registrations maps a training ID to the IDs of its users with a seat in
+	// registration order, waitlists to the IDs of the users waiting for a seat
+	// and holds to the time the seats pending payment have been held since by
+	// user ID
+	registrations map[int][]int
+	waitlists     map[int][]int
+	holds         map[int]map[int]time.Time
+	// attendance maps a training ID to the attendance of its users by user ID,
+	// noShowsRecorded holds the ended trainings whose no-shows are recorded
+	attendance      map[int]map[int]domain.Attendance
+	checkInCodes    map[int]domain.CheckInCode
+	checkInAttempts map[registrationKey]int
+	noShowsRecorded map[int]bool
+	// charges are the credits paid for registrations and waitlist entries,
+	// seatPayments the payments of the seats in paid trainings
+	charges      map[registrationKey]charge
+	seatPayments map[registrationKey]int
+
+	plans       map[int]domain.Plan
+	memberships map[int]domain.Membership
+	// ledger is kept in the order the entries were recorded
+	ledger []domain.LedgerEntry
+	// paymentEvents holds the IDs of the gateway events already applied
+	payments      map[int]domain.Payment
+	paymentEvents map[string]bool
+	// notifications is the outbox, reminders holds the seats already reminded
+	// of the times of their training
+	notifications map[int]domain.Notification
+	reminders     map[reminderKey]bool
+
+	refreshTokens map[string]domain.RefreshToken
+	// calendarFeeds are keyed by token hash
+	calendarFeeds       map[string]domain.CalendarFeed
+	revokedAccessTokens map[string]time.Time
+	accountRevocations  map[account]time.Time
+
+	// auditEvents are kept in the order they were recorded
+	auditEvents []domain.AuditEvent
+	incidents   map[int]domain.Incident
+
+	userID       atomic.Int64
+	trainerID    atomic.Int64
+	adminID      atomic.Int64
+	trainingID   atomic.Int64
+	seriesID     atomic.Int64
+	locationID   atomic.Int64
+	roomID       atomic.Int64
+	auditID      atomic.Int64
+	incidentID   atomic.Int64
+	planID       atomic.Int64
+	membershipID atomic.Int64
+	ledgerID     atomic.Int64
+	paymentID    atomic.Int64
+	notifyID     atomic.Int64
+}
+
+// New returns a storage which keeps all data in process memory
+func New(options storage.Options) storage.Storage {
+	s := &store{
+		options:       options,
+		users:         make(map[int]domain.User),
+		trainers:      make(map[int]domain.Trainer),
+		admins:        make(map[int]domain.Admin),
+		trainings:     make(map[int]domain.Training),
+		series:        make(map[int]domain.TrainingSeries),
+		locations:     make(map[int]domain.Location),
+		rooms:         make(map[int]domain.Room),
+		registrations: make(map[int][]int),
+		waitlists:     make(map[int][]int),
+		holds:         make(map[int]map[int]time.Time),
+
+		attendance:      make(map[int]map[int]domain.Attendance),
+		checkInCodes:    make(map[int]domain.CheckInCode),
+		checkInAttempts: make(map[registrationKey]int),
+		noShowsRecorded: make(map[int]bool),
+		charges:         make(map[registrationKey]charge),
+		seatPayments:    make(map[registrationKey]int),
+
+		plans:       make(map[int]domain.Plan),
+		memberships: make(map[int]domain.Membership),
+
+		payments:      make(map[int]domain.Payment),
+		paymentEvents: make(map[string]bool),
+		notifications: make(map[int]domain.Notification),
+		reminders:     make(map[reminderKey]bool),
+
+		refreshTokens:       make(map[string]domain.RefreshToken),
+		calendarFeeds:       make(map[string]domain.CalendarFeed),
+		revokedAccessTokens: make(map[string]time.Time),
+		accountRevocations:  make(map[account]time.Time),
+
+		incidents: make(map[int]domain.Incident),
+	}
+	return storage.Storage{
+		Users:         &UserRepository{s},
+		Trainers:      &TrainerRepository{s},
+		Admins:        &AdminRepository{s},
+		Trainings:     &TrainingRepository{s},
+		Series:        &SeriesRepository{s},
+		Locations:     &LocationRepository{s},
+		Rooms:         &RoomRepository{s},
+		Sessions:      &SessionRepository{s},
+		Audit:         &AuditRepository{s},
+		Incidents:     &IncidentRepository{s},
+		Plans:         &PlanRepository{s},
+		Memberships:   &MembershipRepository{s},
+		Payments:      &PaymentRepository{s},
+		Notifications: &NotificationRepository{s},
+		Attendance:    &AttendanceRepository{s},
+		Calendars:     &CalendarFeedRepository{s},
+	}
+}
+
+func nextID(counter *atomic.Int64) int {
+	return int(counter.Add(1))
+}
+
+// sortedValues returns the map values ordered by ID
+func sortedValues[T any](m map[int]T, keep func(T) bool) []T {
+	ids := make([]int, 0, len(m))
+	for id, v := range m {
+		if keep == nil || keep(v) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+
+	values := make([]T, 0, len(ids))
+	for _, id := range ids {
+		values = append(values, m[id])
+	}
+	return values
+}
+
+// sortByStart orders trainings sorted by ID by their start time
+func sortByStart(trainings []domain.Training) {
+	sort.SliceStable(trainings, func(i, j int) bool {
+		return trainings[i].StartTime.Before(trainings[j].StartTime)
+	})
+}
+
+// nextSequence returns the sequence of the stored training after the update,
+// it is raised when the times change
+func nextSequence(stored, updated domain.Training) int {
+	if stored.StartTime.Equal(updated.StartTime) && stored.EndTime.Equal(updated.EndTime) {
+		return stored.Sequence
+	}
+	return stored.Sequence + 1
+}
+
+// deleteTraining removes the training with its registrations and attendance,
+// the credits and payments paid for a training which has not started are
+// refunded. mu must be held.
+func (s *store) deleteTraining(id int) {
+	s.refundTraining(id, time.Now())
+	delete(s.trainings, id)
+	delete(s.registrations, id)
+	delete(s.waitlists, id)
+	delete(s.holds, id)
+	delete(s.attendance, id)
+	delete(s.checkInCodes, id)
+	for key := range s.checkInAttempts {
+		if key.trainingID == id {
+			delete(s.checkInAttempts, key)
+		}
+	}
+	delete(s.noShowsRecorded, id)
+}
+
+// isRegistered reports whether the user has a seat in the training, mu must be held
+func (s *store) isRegistered(trainingID, userID int) bool {
+	return indexOf(s.registrations[trainingID], userID) >= 0
+}
+
+// seats counts the confirmed seats of the training and those held pending
+// payment, mu must be held
+func (s *store) seats(trainingID int) int {
+	return len(s.registrations[trainingID]) + len(s.holds[trainingID])
+}
+
+// hold holds a seat of the training for the user pending payment, mu must be held
+func (s *store) hold(trainingID, userID int, at time.Time) {
+	if s.holds[trainingID] == nil {
+		s.holds[trainingID] = make(map[int]time.Time)
+	}
+	s.holds[trainingID][userID] = at
+}
+
+// promote moves the first waitlisted users into the free seats of the training
+// and returns their IDs, seats of a paid training are held for users who have
+// not paid it yet. Users with a seat in a conflicting training are passed over.
+// mu must be held.
+func (s *store) promote(trainingID int) []int {
+	training := s.trainings[trainingID]
+	waitlist := s.waitlists[trainingID]
+
+	n := len(waitlist)
+	if training.Capacity > 0 {
+		n = min(n, training.Capacity-s.seats(trainingID))
+	}
+	if n <= 0 {
+		return nil
+	}
+
+	var promoted, waiting []int
+	for _, userID := range waitlist {
+		if len(promoted) == n || len(s.conflicts(training, userID, false)) > 0 {
+			waiting = append(waiting, userID)
+			continue
+		}
+		promoted = append(promoted, userID)
+	}
+	now := time.Now()
+	for _, userID := range promoted {
+		if _, paid := s.charges[registrationKey{trainingID, userID}]; training.Price > 0 && !paid {
+			s.hold(trainingID, userID, now)
+			continue
+		}
+		s.registrations[trainingID] = append(s.registrations[trainingID], userID)
+	}
+	s.waitlists[trainingID] = waiting
+	for _, userID := range promoted {
+		s.enqueue(training.Notification(domain.NotificationPromoted, userID, now))
+	}
+	return promoted
+}
+
+// conflicts returns the IDs of the trainings overlapping the training, or closer
+// to it than the registration buffer, which the user has a seat or a held seat
+// in, or with waitlisted also a waitlist place. mu must be held.
+func (s *store) conflicts(training domain.Training, userID int, waitlisted bool) []int {
+	buffer := s.options.RegistrationBuffer
+	var ids []int
+	for id, other := range s.trainings {
+		if id == training.ID || !training.StartTime.Before(other.EndTime.Add(buffer)) || !other.StartTime.Before(training.EndTime.Add(buffer)) {
+			continue
+		}
+		_, held := s.holds[id][userID]
+		if held || s.isRegistered(id, userID) || waitlisted && indexOf(s.waitlists[id], userID) >= 0 {
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// checkOverlaps returns an OverlapError if any of the trainings overlaps other
+// trainings of its trainer or in its room. Stored trainings keeping their
+// trainer, room and times are not checked, the stored trainings with the IDs of
+// the trainings or in removed are being replaced and do not count. mu must be
+// held.
+func (s *store) checkOverlaps(trainings []domain.Training, removed []int) error {
+	ignore := make(map[int]bool, len(trainings)+len(removed))
+	var moving []domain.Training
+	for _, training := range trainings {
+		ignore[training.ID] = true
+		if stored, ok := s.trainings[training.ID]; !ok || !sameSlot(stored, training) {
+			moving = append(moving, training)
+		}
+	}
+	for _, id := range removed {
+		ignore[id] = true
+	}
+
+	var overlap storage.OverlapError
+	for id, other := range s.trainings {
+		if ignore[id] {
+			continue
+		}
+		var trainer, room bool
+		for _, training := range moving {
+			if !training.StartTime.Before(other.EndTime) || !other.StartTime.Before(training.EndTime) {
+				continue
+			}
+			trainer = trainer || training.TrainerID == other.TrainerID
+			room = room || training.RoomID != nil && other.RoomID != nil && *training.RoomID == *other.RoomID
+		}
+		if trainer {
+			overlap.TrainerTrainingIDs = append(overlap.TrainerTrainingIDs, id)
+		}
+		if room {
+			overlap.RoomTrainingIDs = append(overlap.RoomTrainingIDs, id)
+		}
+	}
+	if len(overlap.TrainerTrainingIDs) == 0 && len(overlap.RoomTrainingIDs) == 0 {
+		return nil
+	}
+	sort.Ints(overlap.TrainerTrainingIDs)
+	sort.Ints(overlap.RoomTrainingIDs)
+	return &overlap
+}
+
+// sameSlot reports whether the trainings have the same trainer, room and times
+func sameSlot(a, b domain.Training) bool {
+	return a.TrainerID == b.TrainerID && sameRoom(a.RoomID, b.RoomID) &&
+		a.StartTime.Equal(b.StartTime) && a.EndTime.Equal(b.EndTime)
+}
+
+func sameRoom(a, b *int) bool {
+	return a == nil && b == nil || a != nil && b != nil && *a == *b
+}
+
+// indexOf returns the index of id in ids or -1
+func indexOf(ids []int, id int) int {
+	for i, v := range ids {
+		if v == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// without returns ids without the element at index i
+func without(ids []int, i int) []int {
+	return append(ids[:i:i], ids[i+1:]...)
+}
@@ -0,0 +1,15 @@
+package memory_test
+
+import (
+	"testing"
+
+	"github.com/folklinoff/fitness-app/internal/storage"
+	"github.com/folklinoff/fitness-app/internal/storage/memory"
+	"github.com/folklinoff/fitness-app/internal/storage/storagetest"
+)
+
+func TestStorage(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T, options storage.Options) storage.Storage {
+		return memory.New(options)
+	})
+}
@@ -0,0 +1,111 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/folklinoff/fitness-app/internal/domain"
+	"github.com/folklinoff/fitness-app/internal/storage"
+)
+
+// reminderKey is a seat in a training at the times of the given sequence
+type reminderKey struct {
+	trainingID int
+	userID     int
+	sequence   int
+}
+
+// NotificationRepository keeps the notification outbox in memory
+type NotificationRepository struct {
+	s *store
+}
+
+func (r *NotificationRepository) Enqueue(ctx context.Context, notifications []domain.Notification) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for _, notification := range notifications {
+		if _, ok := r.s.users[notification.UserID]; !ok {
+			return storage.ErrNotFound
+		}
+	}
+	for _, notification := range notifications {
+		r.s.enqueue(notification)
+	}
+	return nil
+}
+
+func (r *NotificationRepository) EnqueueReminders(ctx context.Context, from, to time.Time) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	trainings := sortedValues(r.s.trainings, func(training domain.Training) bool {
+		return !training.StartTime.Before(from) && training.StartTime.Before(to)
+	})
+	var reminded int
+	now := time.Now()
+	for _, training := range trainings {
+		userIDs := append([]int(nil), r.s.registrations[training.ID]...)
+		sort.Ints(userIDs)
+		for _, userID := range userIDs {
+			key := reminderKey{training.ID, userID, training.Sequence}
+			if r.s.reminders[key] {
+				continue
+			}
+			r.s.reminders[key] = true
+			r.s.enqueue(training.Notification(domain.NotificationReminder, userID, now))
+			reminded++
+		}
+	}
+	return reminded, nil
+}
+
+func (r *NotificationRepository) ListPending(ctx context.Context, limit int) ([]domain.Notification, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	pending := sortedValues(r.s.notifications, func(notification domain.Notification) bool {
+		return notification.Status == domain.NotificationPending
+	})
+	if len(pending) > limit {
+		pending = pending[:limit]
+	}
+	return pending, nil
+}
+
+func (r *NotificationRepository) Finish(ctx context.Context, id int, status domain.NotificationStatus, reason string, at time.Time) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	notification, ok := r.s.notifications[id]
+	if !ok || notification.Status != domain.NotificationPending {
+		return storage.ErrNotFound
+	}
+	notification.Status, notification.Error, notification.SentAt = status, reason, &at
+	r.s.notifications[id] = notification
+	return nil
+}
+
+func (r *NotificationRepository) Retry(ctx context.Context, id int, reason string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	notification, ok := r.s.notifications[id]
+	if !ok || notification.Status != domain.NotificationPending {
+		return storage.ErrNotFound
+	}
+	notification.Attempts++
+	notification.Error = reason
+	r.s.notifications[id] = notification
+	return nil
+}
+
+// enqueue queues the notification on every channel, mu must be held
+func (s *store) enqueue(notification domain.Notification) {
+	for _, channel := range domain.NotificationChannels {
+		notification.ID = nextID(&s.notifyID)
+		notification.Channel = channel
+		s.notifications[notification.ID] = notification
+	}
+}
@@ -0,0 +1,214 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/folklinoff/fitness-app/internal/domain"
+	"github.com/folklinoff/fitness-app/internal/storage"
+)
+
+// PaymentRepository keeps payments in memory
+type PaymentRepository struct {
+	s *store
+}
+
+func (r *PaymentRepository) Create(ctx context.Context, payment *domain.Payment) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	payment.ID = nextID(&r.s.paymentID)
+	payment.UpdatedAt = payment.CreatedAt
+	r.s.payments[payment.ID] = *payment
+	return nil
+}
+
+func (r *PaymentRepository) SetSession(ctx context.Context, id int, sessionID string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	payment, ok := r.s.payments[id]
+	if !ok {
+		return storage.ErrNotFound
+	}
+	for _, other := range r.s.payments {
+		if other.SessionID == sessionID && other.ID != id {
+			return storage.ErrAlreadyExists
+		}
+	}
+	payment.SessionID = sessionID
+	r.s.payments[id] = payment
+	return nil
+}
+
+func (r *PaymentRepository) GetByID(ctx context.Context, id int) (*domain.Payment, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	payment, ok := r.s.payments[id]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	return &payment, nil
+}
+
+func (r *PaymentRepository) GetBySession(ctx context.Context, sessionID string) (*domain.Payment, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	for _, payment := range r.s.payments {
+		if payment.SessionID == sessionID {
+			return &payment, nil
+		}
+	}
+	return nil, storage.ErrNotFound
+}
+
+func (r *PaymentRepository) ListByUser(ctx context.Context, userID int) ([]domain.Payment, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	return sortedValues(r.s.payments, func(payment domain.Payment) bool {
+		return payment.UserID == userID
+	}), nil
+}
+
+func (r *PaymentRepository) ListRefundsDue(ctx context.Context) ([]domain.Payment, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	return sortedValues(r.s.payments, func(payment domain.Payment) bool {
+		return payment.Status == domain.PaymentRefundDue
+	}), nil
+}
+
+func (r *PaymentRepository) Settle(ctx context.Context, eventID string, paymentID int, reference string, membership *domain.Membership) (*domain.Payment, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	payment, err := r.s.processEvent(eventID, paymentID)
+	if err != nil {
+		return nil, err
+	}
+	if payment.Status != domain.PaymentPending && payment.Status != domain.PaymentFailed {
+		return &payment, nil
+	}
+
+	payment.Status, payment.Reference, payment.UpdatedAt = domain.PaymentPaid, reference, time.Now()
+	switch {
+	case payment.TrainingID != nil:
+		trainingID := *payment.TrainingID
+		if _, ok := r.s.holds[trainingID][payment.UserID]; !ok {
+			payment.Status = domain.PaymentRefundDue
+			break
+		}
+		delete(r.s.holds[trainingID], payment.UserID)
+		r.s.registrations[trainingID] = append(r.s.registrations[trainingID], payment.UserID)
+		r.s.seatPayments[registrationKey{trainingID, payment.UserID}] = payment.ID
+	case membership != nil:
+		// The membership of a deleted user cannot be granted
+		if _, ok := r.s.users[payment.UserID]; !ok {
+			payment.Status = domain.PaymentRefundDue
+			break
+		}
+		r.s.grant(membership)
+	}
+	r.s.payments[payment.ID] = payment
+	return &payment, nil
+}
+
+func (r *PaymentRepository) Fail(ctx context.Context, eventID string, paymentID int) (*domain.Payment, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	payment, err := r.s.processEvent(eventID, paymentID)
+	if err != nil {
+		return nil, err
+	}
+	if payment.Status != domain.PaymentPending {
+		return &payment, nil
+	}
+
+	payment.Status, payment.UpdatedAt = domain.PaymentFailed, time.Now()
+	r.s.payments[payment.ID] = payment
+	return &payment, nil
+}
+
+func (r *PaymentRepository) ReleaseHolds(ctx context.Context, heldBefore time.Time) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	trainingIDs := make([]int, 0, len(r.s.holds))
+	for trainingID := range r.s.holds {
+		trainingIDs = append(trainingIDs, trainingID)
+	}
+	sort.Ints(trainingIDs)
+
+	var released int
+	for _, trainingID := range trainingIDs {
+		n := 0
+		for userID, heldAt := range r.s.holds[trainingID] {
+			if heldAt.Before(heldBefore) {
+				delete(r.s.holds[trainingID], userID)
+				n++
+			}
+		}
+		if n > 0 {
+			released += n
+			r.s.promote(trainingID)
+		}
+	}
+	return released, nil
+}
+
+func (r *PaymentRepository) StartRefund(ctx context.Context, id int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	payment, ok := r.s.payments[id]
+	if !ok || payment.Status != domain.PaymentRefundDue {
+		return storage.ErrNotFound
+	}
+	now := time.Now()
+	payment.RefundStartedAt, payment.UpdatedAt = &now, now
+	r.s.payments[id] = payment
+	return nil
+}
+
+func (r *PaymentRepository) MarkRefunded(ctx context.Context, id int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	payment, ok := r.s.payments[id]
+	if !ok || payment.Status != domain.PaymentRefundDue {
+		return storage.ErrNotFound
+	}
+	payment.Status, payment.UpdatedAt = domain.PaymentRefunded, time.Now()
+	r.s.payments[id] = payment
+	return nil
+}
+
+// processEvent records the gateway event and returns the payment it is about,
+// ErrAlreadyExists is returned if the event was processed before. mu must be held.
+func (s *store) processEvent(eventID string, paymentID int) (domain.Payment, error) {
+	payment, ok := s.payments[paymentID]
+	if !ok {
+		return domain.Payment{}, storage.ErrNotFound
+	}
+	if s.paymentEvents[eventID] {
+		return domain.Payment{}, storage.ErrAlreadyExists
+	}
+	s.paymentEvents[eventID] = true
+	return payment, nil
+}
+
+// refundPayment marks the payment of a cancelled seat due for refund, mu must be held
+func (s *store) refundPayment(paymentID int, at time.Time) {
+	payment, ok := s.payments[paymentID]
+	if !ok || payment.Status != domain.PaymentPaid {
+		return
+	}
+	payment.Status, payment.UpdatedAt = domain.PaymentRefundDue, at
+	s.payments[paymentID] = payment
+}
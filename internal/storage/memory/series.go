@@ -0,0 +1,162 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/folklinoff/fitness-app/internal/domain"
+	"github.com/folklinoff/fitness-app/internal/storage"
+)
+
+// SeriesRepository keeps recurring trainings in memory
+type SeriesRepository struct {
+	s *store
+}
+
+func (r *SeriesRepository) Create(ctx context.Context, series *domain.TrainingSeries, occurrences []domain.Training, allowOverlap bool) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.trainers[series.TrainerID]; !ok {
+		return storage.ErrNotFound
+	}
+	if !allowOverlap {
+		if err := r.s.checkOverlaps(occurrences, nil); err != nil {
+			return err
+		}
+	}
+	series.ID = nextID(&r.s.seriesID)
+	r.s.series[series.ID] = cloneSeries(*series)
+	for i := range occurrences {
+		occurrences[i].ID = nextID(&r.s.trainingID)
+		occurrences[i].SeriesID = &series.ID
+		r.s.trainings[occurrences[i].ID] = occurrences[i]
+	}
+	return nil
+}
+
+func (r *SeriesRepository) GetByID(ctx context.Context, id int) (*domain.TrainingSeries, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	series, ok := r.s.series[id]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	series = cloneSeries(series)
+	return &series, nil
+}
+
+func (r *SeriesRepository) ListOccurrences(ctx context.Context, seriesID int) ([]domain.Training, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	occurrences := sortedValues(r.s.trainings, func(training domain.Training) bool {
+		return training.SeriesID != nil && *training.SeriesID == seriesID
+	})
+	sortByStart(occurrences)
+	return occurrences, nil
+}
+
+func (r *SeriesRepository) Update(ctx context.Context, series *domain.TrainingSeries, changed []domain.Training, removed []int, allowOverlap bool) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if err := r.s.checkOccurrences(series.ID, changed, removed); err != nil {
+		return err
+	}
+	if !allowOverlap {
+		if err := r.s.checkOverlaps(changed, removed); err != nil {
+			return err
+		}
+	}
+	r.s.series[series.ID] = cloneSeries(*series)
+	for _, id := range removed {
+		r.s.deleteTraining(id)
+	}
+	r.s.storeOccurrences(changed)
+	return nil
+}
+
+func (r *SeriesRepository) Split(ctx context.Context, series, next *domain.TrainingSeries, moved []domain.Training, allowOverlap bool) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if err := r.s.checkOccurrences(series.ID, moved, nil); err != nil {
+		return err
+	}
+	if !allowOverlap {
+		if err := r.s.checkOverlaps(moved, nil); err != nil {
+			return err
+		}
+	}
+	r.s.series[series.ID] = cloneSeries(*series)
+	next.ID = nextID(&r.s.seriesID)
+	r.s.series[next.ID] = cloneSeries(*next)
+	for i := range moved {
+		moved[i].SeriesID = &next.ID
+	}
+	r.s.storeOccurrences(moved)
+	return nil
+}
+
+func (r *SeriesRepository) Delete(ctx context.Context, id int, removed []int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.series[id]; !ok {
+		return storage.ErrNotFound
+	}
+	r.s.deleteSeries(id)
+	for _, trainingID := range removed {
+		r.s.deleteTraining(trainingID)
+	}
+	return nil
+}
+
+// checkOccurrences verifies that the series and the trainings exist and that
+// the trainings keep enough seats for the taken ones, mu must be held
+func (s *store) checkOccurrences(seriesID int, trainings []domain.Training, ids []int) error {
+	if _, ok := s.series[seriesID]; !ok {
+		return storage.ErrNotFound
+	}
+	for _, training := range trainings {
+		if training.Capacity > 0 && s.seats(training.ID) > training.Capacity {
+			return storage.ErrCapacityBelowSeats
+		}
+		ids = append(ids, training.ID)
+	}
+	for _, id := range ids {
+		if _, ok := s.trainings[id]; !ok {
+			return storage.ErrNotFound
+		}
+	}
+	return nil
+}
+
+// storeOccurrences saves the trainings and fills seats added by a raised
+// capacity, mu must be held
+func (s *store) storeOccurrences(trainings []domain.Training) {
+	for i, training := range trainings {
+		trainings[i].Sequence = nextSequence(s.trainings[training.ID], training)
+		s.trainings[training.ID] = trainings[i]
+		s.promote(training.ID)
+	}
+}
+
+// deleteSeries removes the series and detaches its occurrences, mu must be held
+func (s *store) deleteSeries(id int) {
+	delete(s.series, id)
+	for trainingID, training := range s.trainings {
+		if training.SeriesID != nil && *training.SeriesID == id {
+			training.SeriesID = nil
+			s.trainings[trainingID] = training
+		}
+	}
+}
+
+// cloneSeries copies the series so callers cannot change the stored exceptions
+func cloneSeries(series domain.TrainingSeries) domain.TrainingSeries {
+	series.Exceptions = append([]time.Time(nil), series.Exceptions...)
+	return series
+}
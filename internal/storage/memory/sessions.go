@@ -0,0 +1,107 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/folklinoff/fitness-app/internal/domain"
+	"github.com/folklinoff/fitness-app/internal/storage"
+)
+
+type account struct {
+	accountType string
+	accountID   int
+}
+
+// SessionRepository keeps refresh tokens and revoked access tokens in memory
+type SessionRepository struct {
+	s *store
+}
+
+func (r *SessionRepository) CreateRefreshToken(ctx context.Context, token *domain.RefreshToken) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.refreshTokens[token.Hash]; ok {
+		return storage.ErrAlreadyExists
+	}
+	r.s.refreshTokens[token.Hash] = *token
+	return nil
+}
+
+func (r *SessionRepository) GetRefreshToken(ctx context.Context, hash string) (*domain.RefreshToken, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	token, ok := r.s.refreshTokens[hash]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	return &token, nil
+}
+
+func (r *SessionRepository) UseRefreshToken(ctx context.Context, hash string, at time.Time) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	token, ok := r.s.refreshTokens[hash]
+	if !ok || !token.UsedAt.IsZero() || !token.RevokedAt.IsZero() {
+		return storage.ErrNotFound
+	}
+	token.UsedAt = at
+	r.s.refreshTokens[hash] = token
+	return nil
+}
+
+func (r *SessionRepository) RevokeRefreshTokenFamily(ctx context.Context, familyID string, at time.Time) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for hash, token := range r.s.refreshTokens {
+		if token.FamilyID == familyID && token.RevokedAt.IsZero() {
+			token.RevokedAt = at
+			r.s.refreshTokens[hash] = token
+		}
+	}
+	return nil
+}
+
+func (r *SessionRepository) RevokeAccount(ctx context.Context, accountType string, accountID int, at time.Time) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for hash, token := range r.s.refreshTokens {
+		if token.AccountType == accountType && token.AccountID == accountID && token.RevokedAt.IsZero() {
+			token.RevokedAt = at
+			r.s.refreshTokens[hash] = token
+		}
+	}
+	r.s.accountRevocations[account{accountType, accountID}] = at.Truncate(time.Microsecond)
+	return nil
+}
+
+func (r *SessionRepository) RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	// Expired tokens are rejected anyway, so their entries are dropped here
+	now := time.Now()
+	for id, exp := range r.s.revokedAccessTokens {
+		if exp.Before(now) {
+			delete(r.s.revokedAccessTokens, id)
+		}
+	}
+	r.s.revokedAccessTokens[jti] = expiresAt
+	return nil
+}
+
+func (r *SessionRepository) IsAccessTokenRevoked(ctx context.Context, jti, accountType string, accountID int, issuedAt time.Time) (bool, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	if _, ok := r.s.revokedAccessTokens[jti]; ok {
+		return true, nil
+	}
+	revokedBefore, ok := r.s.accountRevocations[account{accountType, accountID}]
+	return ok && !issuedAt.After(revokedBefore), nil
+}
@@ -0,0 +1,94 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/folklinoff/fitness-app/internal/domain"
+	"github.com/folklinoff/fitness-app/internal/storage"
+)
+
+// TrainerRepository keeps trainers in memory
+type TrainerRepository struct {
+	s *store
+}
+
+// nameTaken reports whether another trainer has the name, mu must be held
+func (r *TrainerRepository) nameTaken(name string, id int) bool {
+	for _, trainer := range r.s.trainers {
+		if trainer.Name == name && trainer.ID != id {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *TrainerRepository) Create(ctx context.Context, trainer *domain.Trainer) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if r.nameTaken(trainer.Name, 0) {
+		return storage.ErrAlreadyExists
+	}
+	trainer.ID = nextID(&r.s.trainerID)
+	r.s.trainers[trainer.ID] = *trainer
+	return nil
+}
+
+func (r *TrainerRepository) GetByID(ctx context.Context, id int) (*domain.Trainer, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	trainer, ok := r.s.trainers[id]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	return &trainer, nil
+}
+
+func (r *TrainerRepository) GetByName(ctx context.Context, name string) (*domain.Trainer, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	for _, trainer := range r.s.trainers {
+		if trainer.Name == name {
+			return &trainer, nil
+		}
+	}
+	return nil, storage.ErrNotFound
+}
+
+func (r *TrainerRepository) Update(ctx context.Context, trainer *domain.Trainer) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.trainers[trainer.ID]; !ok {
+		return storage.ErrNotFound
+	}
+	if r.nameTaken(trainer.Name, trainer.ID) {
+		return storage.ErrAlreadyExists
+	}
+	r.s.trainers[trainer.ID] = *trainer
+	return nil
+}
+
+func (r *TrainerRepository) Delete(ctx context.Context, id int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.trainers[id]; !ok {
+		return storage.ErrNotFound
+	}
+	delete(r.s.trainers, id)
+	for seriesID, series := range r.s.series {
+		if series.TrainerID == id {
+			delete(r.s.series, seriesID)
+		}
+	}
+	trainings := sortedValues(r.s.trainings, func(training domain.Training) bool {
+		return training.TrainerID == id
+	})
+	for _, training := range trainings {
+		r.s.deleteTraining(training.ID)
+	}
+	return nil
+}
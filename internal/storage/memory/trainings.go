@@ -0,0 +1,288 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/folklinoff/fitness-app/internal/domain"
+	"github.com/folklinoff/fitness-app/internal/storage"
+)
+
+// TrainingRepository keeps trainings and registrations in memory
+type TrainingRepository struct {
+	s *store
+}
+
+func (r *TrainingRepository) Create(ctx context.Context, training *domain.Training, allowOverlap bool) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.trainers[training.TrainerID]; !ok {
+		return storage.ErrNotFound
+	}
+	if !allowOverlap {
+		if err := r.s.checkOverlaps([]domain.Training{*training}, nil); err != nil {
+			return err
+		}
+	}
+	training.ID = nextID(&r.s.trainingID)
+	r.s.trainings[training.ID] = *training
+	return nil
+}
+
+func (r *TrainingRepository) GetByID(ctx context.Context, id int) (*domain.Training, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	training, ok := r.s.trainings[id]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	return &training, nil
+}
+
+func (r *TrainingRepository) Update(ctx context.Context, training *domain.Training, allowOverlap bool) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	stored, ok := r.s.trainings[training.ID]
+	if !ok {
+		return storage.ErrNotFound
+	}
+	if _, ok := r.s.trainers[training.TrainerID]; !ok {
+		return storage.ErrNotFound
+	}
+	if training.Capacity > 0 && r.s.seats(training.ID) > training.Capacity {
+		return storage.ErrCapacityBelowSeats
+	}
+	if !allowOverlap {
+		if err := r.s.checkOverlaps([]domain.Training{*training}, nil); err != nil {
+			return err
+		}
+	}
+	// The series reference is changed only by the series repository
+	training.SeriesID, training.RecurrenceID = stored.SeriesID, stored.RecurrenceID
+	training.Sequence = nextSequence(stored, *training)
+	r.s.trainings[training.ID] = *training
+	r.s.promote(training.ID)
+	return nil
+}
+
+func (r *TrainingRepository) Delete(ctx context.Context, id int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.trainings[id]; !ok {
+		return storage.ErrNotFound
+	}
+	r.s.deleteTraining(id)
+	return nil
+}
+
+func (r *TrainingRepository) List(ctx context.Context) ([]domain.Training, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	return sortedValues(r.s.trainings, nil), nil
+}
+
+func (r *TrainingRepository) ListByTrainer(ctx context.Context, trainerID int) ([]domain.Training, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	return sortedValues(r.s.trainings, func(training domain.Training) bool {
+		return training.TrainerID == trainerID
+	}), nil
+}
+
+func (r *TrainingRepository) Search(ctx context.Context, filter storage.TrainingFilter, after *storage.TrainingCursor, limit int) (storage.Page[domain.Training], error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	search := strings.ToLower(filter.Search)
+	trainings := sortedValues(r.s.trainings, func(training domain.Training) bool {
+		switch {
+		case filter.Type != "" && training.Type != filter.Type,
+			filter.Level != "" && training.Level != filter.Level,
+			filter.TrainerID != 0 && training.TrainerID != filter.TrainerID,
+			filter.UserID != 0 && !r.s.isRegistered(training.ID, filter.UserID),
+			filter.LocationID != 0 && (training.RoomID == nil || r.s.rooms[*training.RoomID].LocationID != filter.LocationID),
+			!filter.From.IsZero() && training.StartTime.Before(filter.From),
+			!filter.To.IsZero() && !training.StartTime.Before(filter.To),
+			filter.FreeSpots && training.Capacity > 0 && r.s.seats(training.ID) >= training.Capacity,
+			!strings.Contains(strings.ToLower(training.Name), search):
+			return false
+		}
+		return true
+	})
+
+	page := storage.Page[domain.Training]{Total: len(trainings)}
+	less := trainingOrder(filter.Sort)
+	sort.Slice(trainings, func(i, j int) bool {
+		return less(trainings[i], trainings[j])
+	})
+	if after != nil {
+		cursor := domain.Training{ID: after.ID, Name: after.Name, StartTime: after.StartTime}
+		trainings = trainings[sort.Search(len(trainings), func(i int) bool {
+			return less(cursor, trainings[i])
+		}):]
+	}
+	page.Items, page.More = trainings[:min(limit, len(trainings))], len(trainings) > limit
+	return page, nil
+}
+
+// trainingOrder returns the less function of the sort order, ties are broken by ID
+func trainingOrder(order storage.TrainingSort) func(a, b domain.Training) bool {
+	switch order {
+	case storage.SortByStartTimeDesc:
+		return func(a, b domain.Training) bool {
+			if !a.StartTime.Equal(b.StartTime) {
+				return a.StartTime.After(b.StartTime)
+			}
+			return a.ID > b.ID
+		}
+	case storage.SortByName:
+		return func(a, b domain.Training) bool {
+			if a.Name != b.Name {
+				return a.Name < b.Name
+			}
+			return a.ID < b.ID
+		}
+	case storage.SortByNameDesc:
+		return func(a, b domain.Training) bool {
+			if a.Name != b.Name {
+				return a.Name > b.Name
+			}
+			return a.ID > b.ID
+		}
+	default:
+		return func(a, b domain.Training) bool {
+			if !a.StartTime.Equal(b.StartTime) {
+				return a.StartTime.Before(b.StartTime)
+			}
+			return a.ID < b.ID
+		}
+	}
+}
+
+func (r *TrainingRepository) ListByUser(ctx context.Context, userID int) ([]domain.Training, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	return sortedValues(r.s.trainings, func(training domain.Training) bool {
+		return r.s.isRegistered(training.ID, userID)
+	}), nil
+}
+
+func (r *TrainingRepository) RegisterUser(ctx context.Context, trainingID, userID int) (*domain.Registration, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	training, ok := r.s.trainings[trainingID]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	if _, ok := r.s.users[userID]; !ok {
+		return nil, storage.ErrNotFound
+	}
+	_, held := r.s.holds[trainingID][userID]
+	if held || r.s.isRegistered(trainingID, userID) || indexOf(r.s.waitlists[trainingID], userID) >= 0 {
+		return nil, storage.ErrAlreadyExists
+	}
+	if conflicts := r.s.conflicts(training, userID, true); len(conflicts) > 0 {
+		return nil, &storage.ScheduleConflictError{TrainingIDs: conflicts}
+	}
+
+	registration := domain.Registration{TrainingID: trainingID, UserID: userID, Status: domain.RegistrationConfirmed}
+	if training.Capacity > 0 && r.s.seats(trainingID) >= training.Capacity {
+		registration.Status = domain.RegistrationWaitlisted
+	}
+	if err := r.s.payRegistration(training, &registration, time.Now()); err != nil {
+		return nil, err
+	}
+	switch registration.Status {
+	case domain.RegistrationWaitlisted:
+		r.s.waitlists[trainingID] = append(r.s.waitlists[trainingID], userID)
+		registration.Position = len(r.s.waitlists[trainingID])
+	case domain.RegistrationPending:
+		r.s.hold(trainingID, userID, registration.HeldAt)
+	default:
+		r.s.registrations[trainingID] = append(r.s.registrations[trainingID], userID)
+	}
+	return &registration, nil
+}
+
+func (r *TrainingRepository) ListRegistrants(ctx context.Context, trainingID int) ([]int, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	var userIDs []int
+	userIDs = append(userIDs, r.s.registrations[trainingID]...)
+	userIDs = append(userIDs, r.s.waitlists[trainingID]...)
+	for userID := range r.s.holds[trainingID] {
+		userIDs = append(userIDs, userID)
+	}
+	sort.Ints(userIDs)
+	return userIDs, nil
+}
+
+func (r *TrainingRepository) GetRegistration(ctx context.Context, trainingID, userID int) (*domain.Registration, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	registration := r.s.registration(trainingID, userID)
+	if r.s.isRegistered(trainingID, userID) {
+		registration.Status = domain.RegistrationConfirmed
+		return &registration, nil
+	}
+	if heldAt, ok := r.s.holds[trainingID][userID]; ok {
+		registration.Status, registration.HeldAt = domain.RegistrationPending, heldAt
+		return &registration, nil
+	}
+	if i := indexOf(r.s.waitlists[trainingID], userID); i >= 0 {
+		registration.Status = domain.RegistrationWaitlisted
+		registration.Position = i + 1
+		return &registration, nil
+	}
+	return nil, storage.ErrNotFound
+}
+
+func (r *TrainingRepository) CancelRegistration(ctx context.Context, trainingID, userID int, refundSeat bool) (*domain.Registration, []int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	registration := r.s.registration(trainingID, userID)
+	key := registrationKey{trainingID, userID}
+	if heldAt, ok := r.s.holds[trainingID][userID]; ok {
+		delete(r.s.holds[trainingID], userID)
+		registration.Status, registration.HeldAt = domain.RegistrationPending, heldAt
+		delete(r.s.charges, key)
+		return &registration, r.s.promote(trainingID), nil
+	}
+	if i := indexOf(r.s.waitlists[trainingID], userID); i >= 0 {
+		r.s.waitlists[trainingID] = without(r.s.waitlists[trainingID], i)
+		registration.Status = domain.RegistrationWaitlisted
+		registration.Position = i + 1
+		r.s.refund(registration, time.Now())
+		delete(r.s.charges, key)
+		return &registration, nil, nil
+	}
+	i := indexOf(r.s.registrations[trainingID], userID)
+	if i < 0 {
+		return nil, nil, storage.ErrNotFound
+	}
+	r.s.registrations[trainingID] = without(r.s.registrations[trainingID], i)
+	delete(r.s.attendance[trainingID], userID)
+	registration.Status = domain.RegistrationConfirmed
+	if refundSeat {
+		r.s.refund(registration, time.Now())
+		if registration.PaymentID != nil {
+			r.s.refundPayment(*registration.PaymentID, time.Now())
+		}
+	}
+	delete(r.s.charges, key)
+	delete(r.s.seatPayments, key)
+	return &registration, r.s.promote(trainingID), nil
+}
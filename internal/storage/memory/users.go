@@ -0,0 +1,164 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"sort"
+
+	"github.com/folklinoff/fitness-app/internal/domain"
+	"github.com/folklinoff/fitness-app/internal/storage"
+)
+
+// UserRepository keeps users in memory
+type UserRepository struct {
+	s *store
+}
+
+// nameTaken reports whether another user has the name, mu must be held
+func (r *UserRepository) nameTaken(name string, id int) bool {
+	for _, user := range r.s.users {
+		if user.Name == name && user.ID != id {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *UserRepository) Create(ctx context.Context, user *domain.User) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if r.nameTaken(user.Name, 0) {
+		return storage.ErrAlreadyExists
+	}
+	user.ID = nextID(&r.s.userID)
+	r.s.users[user.ID] = *user
+	return nil
+}
+
+func (r *UserRepository) GetByID(ctx context.Context, id int) (*domain.User, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	user, ok := r.s.users[id]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	return &user, nil
+}
+
+func (r *UserRepository) GetByName(ctx context.Context, name string) (*domain.User, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	for _, user := range r.s.users {
+		if user.Name == name {
+			return &user, nil
+		}
+	}
+	return nil, storage.ErrNotFound
+}
+
+func (r *UserRepository) Update(ctx context.Context, user *domain.User) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.users[user.ID]; !ok {
+		return storage.ErrNotFound
+	}
+	if r.nameTaken(user.Name, user.ID) {
+		return storage.ErrAlreadyExists
+	}
+	r.s.users[user.ID] = *user
+	return nil
+}
+
+func (r *UserRepository) Delete(ctx context.Context, id int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.users[id]; !ok {
+		return storage.ErrNotFound
+	}
+	delete(r.s.users, id)
+	for incidentID, incident := range r.s.incidents {
+		if incident.UserID == id {
+			delete(r.s.incidents, incidentID)
+		}
+	}
+	for notificationID, notification := range r.s.notifications {
+		if notification.UserID == id {
+			delete(r.s.notifications, notificationID)
+		}
+	}
+	for key := range r.s.reminders {
+		if key.userID == id {
+			delete(r.s.reminders, key)
+		}
+	}
+	for trainingID, waitlist := range r.s.waitlists {
+		if i := indexOf(waitlist, id); i >= 0 {
+			r.s.waitlists[trainingID] = without(waitlist, i)
+		}
+	}
+	for _, attendance := range r.s.attendance {
+		delete(attendance, id)
+	}
+	for key := range r.s.checkInAttempts {
+		if key.userID == id {
+			delete(r.s.checkInAttempts, key)
+		}
+	}
+	for key := range r.s.charges {
+		if key.userID == id {
+			delete(r.s.charges, key)
+		}
+	}
+	for key := range r.s.seatPayments {
+		if key.userID == id {
+			delete(r.s.seatPayments, key)
+		}
+	}
+	for membershipID, membership := range r.s.memberships {
+		if membership.UserID == id {
+			delete(r.s.memberships, membershipID)
+		}
+	}
+	r.s.ledger = slices.DeleteFunc(r.s.ledger, func(entry domain.LedgerEntry) bool {
+		return entry.UserID == id
+	})
+	for trainingID, userIDs := range r.s.registrations {
+		if i := indexOf(userIDs, id); i >= 0 {
+			r.s.registrations[trainingID] = without(userIDs, i)
+			r.s.promote(trainingID)
+		}
+	}
+	for trainingID, holds := range r.s.holds {
+		if _, ok := holds[id]; ok {
+			delete(holds, id)
+			r.s.promote(trainingID)
+		}
+	}
+	return nil
+}
+
+func (r *UserRepository) ListByTraining(ctx context.Context, trainingID, afterID, limit int) (storage.Page[domain.User], error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	var page storage.Page[domain.User]
+	if _, ok := r.s.trainings[trainingID]; !ok {
+		return page, storage.ErrNotFound
+	}
+
+	users := sortedValues(r.s.users, func(user domain.User) bool {
+		return r.s.isRegistered(trainingID, user.ID)
+	})
+	page.Total = len(users)
+	i := sort.Search(len(users), func(i int) bool {
+		return users[i].ID > afterID
+	})
+	users = users[i:]
+	page.Items, page.More = users[:min(limit, len(users))], len(users) > limit
+	return page, nil
+}
@@ -0,0 +1,37 @@
+CREATE TABLE users (
+    id                 SERIAL PRIMARY KEY,
+    name               TEXT NOT NULL UNIQUE,
+    password           TEXT NOT NULL,
+    mail               TEXT NOT NULL DEFAULT '',
+    phone              TEXT NOT NULL DEFAULT '',
+    health_description TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE trainers (
+    id       SERIAL PRIMARY KEY,
+    name     TEXT NOT NULL UNIQUE,
+    password TEXT NOT NULL,
+    mail     TEXT NOT NULL DEFAULT '',
+    phone    TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE trainings (
+    id         SERIAL PRIMARY KEY,
+    name       TEXT NOT NULL,
+    type       TEXT NOT NULL DEFAULT '',
+    level      TEXT NOT NULL DEFAULT '',
+    trainer_id INTEGER NOT NULL REFERENCES trainers (id) ON DELETE CASCADE,
+    start_time TIMESTAMPTZ NOT NULL,
+    end_time   TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX trainings_trainer_id_idx ON trainings (trainer_id);
+
+CREATE TABLE training_registrations (
+    training_id   INTEGER NOT NULL REFERENCES trainings (id) ON DELETE CASCADE,
+    user_id       INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    registered_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (training_id, user_id)
+);
+
+CREATE INDEX training_registrations_user_id_idx ON training_registrations (user_id);
@@ -0,0 +1,5 @@
+CREATE TABLE admins (
+    id       SERIAL PRIMARY KEY,
+    name     TEXT NOT NULL UNIQUE,
+    password TEXT NOT NULL
+);
@@ -0,0 +1,9 @@
+CREATE TABLE audit_events (
+    id         SERIAL PRIMARY KEY,
+    at         TIMESTAMPTZ NOT NULL,
+    actor_type TEXT NOT NULL,
+    actor_id   INTEGER NOT NULL,
+    action     TEXT NOT NULL,
+    resource   TEXT NOT NULL DEFAULT '',
+    reason     TEXT NOT NULL DEFAULT ''
+);
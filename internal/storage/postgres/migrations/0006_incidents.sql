@@ -0,0 +1,9 @@
+-- training_id has no foreign key, incidents are kept after the training is deleted
+CREATE TABLE incidents (
+    id          SERIAL PRIMARY KEY,
+    user_id     INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    training_id INTEGER NOT NULL,
+    kind        TEXT NOT NULL CHECK (kind IN ('late_cancellation', 'no_show')),
+    at          TIMESTAMPTZ NOT NULL,
+    UNIQUE (user_id, training_id, kind)
+);
@@ -0,0 +1,30 @@
+CREATE TABLE locations (
+    id        SERIAL PRIMARY KEY,
+    name      TEXT NOT NULL UNIQUE,
+    address   TEXT NOT NULL DEFAULT '',
+    time_zone TEXT NOT NULL
+);
+
+-- opens and closes are minutes after midnight in the time zone of the location
+CREATE TABLE location_opening_hours (
+    location_id INTEGER NOT NULL REFERENCES locations (id) ON DELETE CASCADE,
+    weekday     INTEGER NOT NULL CHECK (weekday BETWEEN 0 AND 6),
+    opens       INTEGER NOT NULL CHECK (opens >= 0),
+    closes      INTEGER NOT NULL CHECK (closes <= 1440),
+    CHECK (opens < closes)
+);
+
+CREATE INDEX location_opening_hours_location_id_idx ON location_opening_hours (location_id);
+
+CREATE TABLE rooms (
+    id          SERIAL PRIMARY KEY,
+    location_id INTEGER NOT NULL REFERENCES locations (id) ON DELETE CASCADE,
+    name        TEXT NOT NULL,
+    capacity    INTEGER NOT NULL CHECK (capacity > 0),
+    UNIQUE (location_id, name)
+);
+
+ALTER TABLE trainings ADD COLUMN room_id INTEGER REFERENCES rooms (id) ON DELETE SET NULL;
+ALTER TABLE training_series ADD COLUMN room_id INTEGER REFERENCES rooms (id) ON DELETE SET NULL;
+
+CREATE INDEX trainings_room_id_idx ON trainings (room_id, start_time);
@@ -0,0 +1,21 @@
+-- no_shows_recorded is set once the no-shows of an ended training are recorded
+ALTER TABLE trainings ADD COLUMN no_shows_recorded BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX trainings_no_shows_idx ON trainings (end_time) WHERE NOT no_shows_recorded;
+
+-- Attendance is removed together with the registration it belongs to
+CREATE TABLE attendance (
+    training_id   INTEGER NOT NULL,
+    user_id       INTEGER NOT NULL,
+    method        TEXT NOT NULL CHECK (method IN ('trainer', 'self')),
+    checked_in_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (training_id, user_id),
+    FOREIGN KEY (training_id, user_id) REFERENCES training_registrations (training_id, user_id) ON DELETE CASCADE
+);
+
+-- A training has at most one check-in code
+CREATE TABLE check_in_codes (
+    training_id INTEGER PRIMARY KEY REFERENCES trainings (id) ON DELETE CASCADE,
+    code        TEXT NOT NULL,
+    expires_at  TIMESTAMPTZ NOT NULL
+);
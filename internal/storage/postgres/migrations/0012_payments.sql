@@ -0,0 +1,40 @@
+-- price is the price of a registration or plan paid through the payment
+-- gateway, in the minor unit of the currency
+ALTER TABLE trainings ADD COLUMN price INTEGER NOT NULL DEFAULT 0 CHECK (price >= 0);
+ALTER TABLE training_series ADD COLUMN price INTEGER NOT NULL DEFAULT 0 CHECK (price >= 0);
+ALTER TABLE plans ADD COLUMN price INTEGER NOT NULL DEFAULT 0 CHECK (price >= 0);
+
+-- user_id and training_id have no foreign keys, payments are kept after the
+-- user or training is deleted
+CREATE TABLE payments (
+    id          SERIAL PRIMARY KEY,
+    user_id     INTEGER NOT NULL,
+    training_id INTEGER,
+    plan_id     INTEGER REFERENCES plans (id),
+    amount      INTEGER NOT NULL CHECK (amount > 0),
+    currency    TEXT NOT NULL,
+    status      TEXT NOT NULL CHECK (status IN ('pending', 'paid', 'failed', 'refund_due', 'refunded')),
+    session_id  TEXT UNIQUE,
+    reference   TEXT NOT NULL DEFAULT '',
+    created_at  TIMESTAMPTZ NOT NULL,
+    updated_at  TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX payments_user_id_idx ON payments (user_id);
+CREATE INDEX payments_refund_due_idx ON payments (id) WHERE status = 'refund_due';
+
+-- Gateway events already applied, webhooks are delivered at least once
+CREATE TABLE payment_events (
+    id           TEXT PRIMARY KEY,
+    payment_id   INTEGER NOT NULL REFERENCES payments (id),
+    processed_at TIMESTAMPTZ NOT NULL
+);
+
+-- Seats of paid trainings are held from held_at until the payment succeeds
+ALTER TABLE training_registrations DROP CONSTRAINT training_registrations_status_check;
+ALTER TABLE training_registrations ADD CONSTRAINT training_registrations_status_check
+    CHECK (status IN ('confirmed', 'waitlisted', 'pending_payment'));
+ALTER TABLE training_registrations ADD COLUMN held_at TIMESTAMPTZ;
+ALTER TABLE training_registrations ADD COLUMN payment_id INTEGER REFERENCES payments (id);
+
+CREATE INDEX training_registrations_held_at_idx ON training_registrations (held_at) WHERE status = 'pending_payment';
@@ -0,0 +1,23 @@
+-- Outbox of the notifications sent to users. training_id has no foreign key and
+-- the training is copied, cancellations are sent after the training is deleted.
+CREATE TABLE notifications (
+    id            SERIAL PRIMARY KEY,
+    user_id       INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    channel       TEXT NOT NULL CHECK (channel IN ('email', 'sms')),
+    kind          TEXT NOT NULL CHECK (kind IN ('registration_confirmed', 'training_updated', 'training_cancelled', 'waitlist_promoted', 'training_reminder')),
+    training_id   INTEGER NOT NULL,
+    training_name TEXT NOT NULL,
+    start_time    TIMESTAMPTZ NOT NULL,
+    end_time      TIMESTAMPTZ NOT NULL,
+    sequence      INTEGER NOT NULL,
+    status        TEXT NOT NULL CHECK (status IN ('pending', 'sent', 'skipped', 'failed')),
+    attempts      INTEGER NOT NULL DEFAULT 0,
+    error         TEXT NOT NULL DEFAULT '',
+    created_at    TIMESTAMPTZ NOT NULL,
+    sent_at       TIMESTAMPTZ
+);
+
+CREATE INDEX notifications_pending_idx ON notifications (id) WHERE status = 'pending';
+-- A seat is reminded of a training once for each of its times
+CREATE UNIQUE INDEX notifications_reminder_idx ON notifications (training_id, user_id, sequence, channel)
+    WHERE kind = 'training_reminder';
@@ -0,0 +1,7 @@
+-- Attempts of users to check in with a code, check-in is locked after a few
+CREATE TABLE check_in_attempts (
+    training_id INTEGER NOT NULL REFERENCES trainings (id) ON DELETE CASCADE,
+    user_id     INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    attempts    INTEGER NOT NULL,
+    PRIMARY KEY (training_id, user_id)
+);
@@ -0,0 +1,82 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"io/fs"
+
+	"github.com/folklinoff/fitness-app/internal/storage"
+	"github.com/folklinoff/fitness-app/internal/storage/sqlstore"
+	"github.com/jackc/pgx/v5/pgconn"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"golang.org/x/xerrors"
+)
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// migrationLockID is the advisory lock key which serializes migrations of
+// concurrently starting instances
+const migrationLockID = 7261001
+
+// Dialect is the PostgreSQL flavour of the SQL storage
+var Dialect = sqlstore.Dialect{
+	Migrations:     mustSub(migrations, "migrations"),
+	MapError:       mapError,
+	LockMigrations: lockMigrations,
+	ForUpdate:      " FOR UPDATE",
+	Lower:          "LOWER",
+}
+
+// Open connects to the database described by dsn and applies pending migrations
+func Open(ctx context.Context, dsn string) (*sql.DB, error) {
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		return nil, xerrors.Errorf("open postgres: %w", err)
+	}
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, xerrors.Errorf("ping postgres: %w", err)
+	}
+	if err := sqlstore.Migrate(ctx, db, Dialect); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// New returns a storage backed by the given database
+func New(db *sql.DB, options storage.Options) storage.Storage {
+	return sqlstore.New(db, Dialect, options)
+}
+
+func lockMigrations(ctx context.Context, conn *sql.Conn) (func(), error) {
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLockID); err != nil {
+		return nil, err
+	}
+	return func() {
+		conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLockID)
+	}, nil
+}
+
+func mapError(err error) error {
+	var pgErr *pgconn.PgError
+	if xerrors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case "23505": // unique_violation
+			return storage.ErrAlreadyExists
+		case "23503": // foreign_key_violation
+			return storage.ErrNotFound
+		}
+	}
+	return err
+}
+
+func mustSub(fsys fs.FS, dir string) fs.FS {
+	sub, err := fs.Sub(fsys, dir)
+	if err != nil {
+		panic(err)
+	}
+	return sub
+}
@@ -0,0 +1,55 @@
+package postgres_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/folklinoff/fitness-app/internal/storage"
+	"github.com/folklinoff/fitness-app/internal/storage/postgres"
+	"github.com/folklinoff/fitness-app/internal/storage/storagetest"
+)
+
+// TestStorage runs against the database of the TEST_DATABASE_URL URL, each
+// subtest in a schema of its own which is dropped afterwards
+func TestStorage(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	admin, err := sql.Open("pgx", dsn)
+	if err != nil {
+		t.Fatalf("open postgres: %v", err)
+	}
+	defer admin.Close()
+
+	n := 0
+	storagetest.Run(t, func(t *testing.T, options storage.Options) storage.Storage {
+		ctx := context.Background()
+		n++
+		schema := fmt.Sprintf("storagetest_%d_%d", time.Now().UnixNano(), n)
+		if _, err := admin.ExecContext(ctx, `CREATE SCHEMA `+schema); err != nil {
+			t.Fatalf("create schema: %v", err)
+		}
+		t.Cleanup(func() { admin.ExecContext(context.Background(), `DROP SCHEMA `+schema+` CASCADE`) })
+
+		u, err := url.Parse(dsn)
+		if err != nil {
+			t.Fatalf("TEST_DATABASE_URL: %v", err)
+		}
+		query := u.Query()
+		query.Set("search_path", schema)
+		u.RawQuery = query.Encode()
+
+		db, err := postgres.Open(ctx, u.String())
+		if err != nil {
+			t.Fatalf("open postgres: %v", err)
+		}
+		t.Cleanup(func() { db.Close() })
+		return postgres.New(db, options)
+	})
+}
@@ -0,0 +1,25 @@
+CREATE TABLE refresh_tokens (
+    token_hash   TEXT PRIMARY KEY,
+    family_id    TEXT NOT NULL,
+    account_type TEXT NOT NULL,
+    account_id   INTEGER NOT NULL,
+    issued_at    TIMESTAMP NOT NULL,
+    expires_at   TIMESTAMP NOT NULL,
+    used_at      TIMESTAMP,
+    revoked_at   TIMESTAMP
+);
+
+CREATE INDEX refresh_tokens_family_id_idx ON refresh_tokens (family_id);
+CREATE INDEX refresh_tokens_account_idx ON refresh_tokens (account_type, account_id);
+
+CREATE TABLE revoked_access_tokens (
+    jti        TEXT PRIMARY KEY,
+    expires_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE account_revocations (
+    account_type   TEXT NOT NULL,
+    account_id     INTEGER NOT NULL,
+    revoked_before TIMESTAMP NOT NULL,
+    PRIMARY KEY (account_type, account_id)
+);
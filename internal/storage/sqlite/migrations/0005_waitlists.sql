@@ -0,0 +1,6 @@
+ALTER TABLE trainings ADD COLUMN capacity INTEGER NOT NULL DEFAULT 0 CHECK (capacity >= 0);
+
+ALTER TABLE training_registrations
+    ADD COLUMN status TEXT NOT NULL DEFAULT 'confirmed' CHECK (status IN ('confirmed', 'waitlisted'));
+
+CREATE INDEX training_registrations_waitlist_idx ON training_registrations (training_id, status, registered_at);
@@ -0,0 +1,21 @@
+-- exceptions holds the excluded occurrence start times as comma separated RFC 3339 UTC times
+CREATE TABLE training_series (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    name       TEXT NOT NULL,
+    type       TEXT NOT NULL DEFAULT '',
+    level      TEXT NOT NULL DEFAULT '',
+    trainer_id INTEGER NOT NULL REFERENCES trainers (id) ON DELETE CASCADE,
+    capacity   INTEGER NOT NULL DEFAULT 0 CHECK (capacity >= 0),
+    start_time TIMESTAMP NOT NULL,
+    end_time   TIMESTAMP NOT NULL,
+    time_zone  TEXT NOT NULL,
+    rrule      TEXT NOT NULL,
+    exceptions TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX training_series_trainer_id_idx ON training_series (trainer_id);
+
+ALTER TABLE trainings ADD COLUMN series_id INTEGER REFERENCES training_series (id) ON DELETE SET NULL;
+ALTER TABLE trainings ADD COLUMN recurrence_id TIMESTAMP;
+
+CREATE INDEX trainings_series_id_idx ON trainings (series_id, start_time);
@@ -0,0 +1,12 @@
+-- sequence counts the changes of the times of a training, it is the SEQUENCE of
+-- its calendar event
+ALTER TABLE trainings ADD COLUMN sequence INTEGER NOT NULL DEFAULT 0;
+
+-- Only the hash of a feed token is stored, an account has at most one feed
+CREATE TABLE calendar_feeds (
+    token_hash   TEXT PRIMARY KEY,
+    account_type TEXT NOT NULL,
+    account_id   INTEGER NOT NULL,
+    created_at   TIMESTAMP NOT NULL,
+    UNIQUE (account_type, account_id)
+);
@@ -0,0 +1,43 @@
+-- credits is the cost of a registration in membership credits
+ALTER TABLE trainings ADD COLUMN credits INTEGER NOT NULL DEFAULT 0 CHECK (credits >= 0);
+ALTER TABLE training_series ADD COLUMN credits INTEGER NOT NULL DEFAULT 0 CHECK (credits >= 0);
+
+CREATE TABLE plans (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    name       TEXT NOT NULL UNIQUE,
+    kind       TEXT NOT NULL CHECK (kind IN ('unlimited', 'pass', 'drop_in')),
+    sessions   INTEGER NOT NULL CHECK (sessions >= 0),
+    valid_days INTEGER NOT NULL CHECK (valid_days > 0),
+    active     BOOLEAN NOT NULL DEFAULT TRUE
+);
+
+CREATE TABLE memberships (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id    INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    plan_id    INTEGER NOT NULL REFERENCES plans (id),
+    kind       TEXT NOT NULL,
+    credits    INTEGER NOT NULL CHECK (credits >= 0),
+    starts_at  TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX memberships_user_id_idx ON memberships (user_id, expires_at);
+
+-- A registration remembers the membership it was paid from and its cost for refunds
+ALTER TABLE training_registrations ADD COLUMN membership_id INTEGER REFERENCES memberships (id) ON DELETE SET NULL;
+ALTER TABLE training_registrations ADD COLUMN credits INTEGER NOT NULL DEFAULT 0;
+
+-- training_id has no foreign key, the ledger is kept after the training is deleted
+CREATE TABLE ledger_entries (
+    id            INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id       INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    membership_id INTEGER NOT NULL REFERENCES memberships (id) ON DELETE CASCADE,
+    training_id   INTEGER,
+    kind          TEXT NOT NULL CHECK (kind IN ('grant', 'consume', 'refund')),
+    amount        INTEGER NOT NULL,
+    balance       INTEGER NOT NULL,
+    at            TIMESTAMP NOT NULL
+);
+
+CREATE INDEX ledger_entries_user_id_idx ON ledger_entries (user_id);
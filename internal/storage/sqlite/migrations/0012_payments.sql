@@ -0,0 +1,71 @@
+-- price is the price of a registration or plan paid through the payment
+-- gateway, in the minor unit of the currency
+ALTER TABLE trainings ADD COLUMN price INTEGER NOT NULL DEFAULT 0 CHECK (price >= 0);
+ALTER TABLE training_series ADD COLUMN price INTEGER NOT NULL DEFAULT 0 CHECK (price >= 0);
+ALTER TABLE plans ADD COLUMN price INTEGER NOT NULL DEFAULT 0 CHECK (price >= 0);
+
+-- user_id and training_id have no foreign keys, payments are kept after the
+-- user or training is deleted
+CREATE TABLE payments (
+    id          INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id     INTEGER NOT NULL,
+    training_id INTEGER,
+    plan_id     INTEGER REFERENCES plans (id),
+    amount      INTEGER NOT NULL CHECK (amount > 0),
+    currency    TEXT NOT NULL,
+    status      TEXT NOT NULL CHECK (status IN ('pending', 'paid', 'failed', 'refund_due', 'refunded')),
+    session_id  TEXT UNIQUE,
+    reference   TEXT NOT NULL DEFAULT '',
+    created_at  TIMESTAMP NOT NULL,
+    updated_at  TIMESTAMP NOT NULL
+);
+
+CREATE INDEX payments_user_id_idx ON payments (user_id);
+CREATE INDEX payments_refund_due_idx ON payments (id) WHERE status = 'refund_due';
+
+-- Gateway events already applied, webhooks are delivered at least once
+CREATE TABLE payment_events (
+    id           TEXT PRIMARY KEY,
+    payment_id   INTEGER NOT NULL REFERENCES payments (id),
+    processed_at TIMESTAMP NOT NULL
+);
+
+-- Seats of paid trainings are held from held_at until the payment succeeds.
+-- SQLite cannot change the status check, so the table is rebuilt; attendance
+-- references it and is moved aside first so the rebuild does not cascade to it.
+CREATE TABLE attendance_old AS SELECT * FROM attendance;
+DROP TABLE attendance;
+
+CREATE TABLE training_registrations_new (
+    training_id   INTEGER NOT NULL REFERENCES trainings (id) ON DELETE CASCADE,
+    user_id       INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    registered_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    status        TEXT NOT NULL DEFAULT 'confirmed' CHECK (status IN ('confirmed', 'waitlisted', 'pending_payment')),
+    membership_id INTEGER REFERENCES memberships (id) ON DELETE SET NULL,
+    credits       INTEGER NOT NULL DEFAULT 0,
+    held_at       TIMESTAMP,
+    payment_id    INTEGER REFERENCES payments (id),
+    PRIMARY KEY (training_id, user_id)
+);
+
+INSERT INTO training_registrations_new (training_id, user_id, registered_at, status, membership_id, credits)
+SELECT training_id, user_id, registered_at, status, membership_id, credits FROM training_registrations;
+
+DROP TABLE training_registrations;
+ALTER TABLE training_registrations_new RENAME TO training_registrations;
+
+CREATE INDEX training_registrations_user_id_idx ON training_registrations (user_id);
+CREATE INDEX training_registrations_waitlist_idx ON training_registrations (training_id, status, registered_at);
+CREATE INDEX training_registrations_held_at_idx ON training_registrations (held_at) WHERE status = 'pending_payment';
+
+CREATE TABLE attendance (
+    training_id   INTEGER NOT NULL,
+    user_id       INTEGER NOT NULL,
+    method        TEXT NOT NULL CHECK (method IN ('trainer', 'self')),
+    checked_in_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (training_id, user_id),
+    FOREIGN KEY (training_id, user_id) REFERENCES training_registrations (training_id, user_id) ON DELETE CASCADE
+);
+
+INSERT INTO attendance SELECT * FROM attendance_old;
+DROP TABLE attendance_old;
@@ -0,0 +1,3 @@
+-- refund_started_at is set when the refund of a payment due for refund is sent
+-- to the gateway, the refund may have been issued when it is set
+ALTER TABLE payments ADD COLUMN refund_started_at TIMESTAMP;
@@ -0,0 +1,92 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"embed"
+	"io/fs"
+	"net/url"
+	"strings"
+
+	"github.com/folklinoff/fitness-app/internal/storage"
+	"github.com/folklinoff/fitness-app/internal/storage/sqlstore"
+	"golang.org/x/xerrors"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// Dialect is the SQLite flavour of the SQL storage. Its migrations create the
+// same tables, columns and constraints as the PostgreSQL ones.
+var Dialect = sqlstore.Dialect{
+	Migrations: mustSub(migrations, "migrations"),
+	MapError:   mapError,
+	Lower:      "unicode_lower",
+}
+
+// The built-in LOWER of SQLite only folds ASCII letters
+func init() {
+	err := sqlite.RegisterDeterministicScalarFunction("unicode_lower", 1, func(ctx *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		s, ok := args[0].(string)
+		if !ok {
+			return args[0], nil
+		}
+		return strings.ToLower(s), nil
+	})
+	if err != nil {
+		panic(err)
+	}
+}
+
+// Open opens or creates the database file at path and applies pending migrations
+func Open(ctx context.Context, path string) (*sql.DB, error) {
+	params := url.Values{}
+	params.Add("_pragma", "foreign_keys(1)")
+	params.Add("_pragma", "busy_timeout(5000)")
+	params.Add("_pragma", "journal_mode(WAL)")
+	// Take the write lock when a transaction starts instead of failing on upgrade
+	params.Add("_txlock", "immediate")
+
+	db, err := sql.Open("sqlite", "file:"+path+"?"+params.Encode())
+	if err != nil {
+		return nil, xerrors.Errorf("open sqlite: %w", err)
+	}
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, xerrors.Errorf("ping sqlite: %w", err)
+	}
+	if err := sqlstore.Migrate(ctx, db, Dialect); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// New returns a storage backed by the given database
+func New(db *sql.DB, options storage.Options) storage.Storage {
+	return sqlstore.New(db, Dialect, options)
+}
+
+func mapError(err error) error {
+	var sqliteErr *sqlite.Error
+	if xerrors.As(err, &sqliteErr) {
+		switch sqliteErr.Code() {
+		case sqlite3.SQLITE_CONSTRAINT_UNIQUE, sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY:
+			return storage.ErrAlreadyExists
+		case sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY:
+			return storage.ErrNotFound
+		}
+	}
+	return err
+}
+
+func mustSub(fsys fs.FS, dir string) fs.FS {
+	sub, err := fs.Sub(fsys, dir)
+	if err != nil {
+		panic(err)
+	}
+	return sub
+}
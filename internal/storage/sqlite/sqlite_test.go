@@ -0,0 +1,22 @@
+package sqlite_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/folklinoff/fitness-app/internal/storage"
+	"github.com/folklinoff/fitness-app/internal/storage/sqlite"
+	"github.com/folklinoff/fitness-app/internal/storage/storagetest"
+)
+
+func TestStorage(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T, options storage.Options) storage.Storage {
+		db, err := sqlite.Open(context.Background(), filepath.Join(t.TempDir(), "fitness.db"))
+		if err != nil {
+			t.Fatalf("open sqlite: %v", err)
+		}
+		t.Cleanup(func() { db.Close() })
+		return sqlite.New(db, options)
+	})
+}
@@ -0,0 +1,50 @@
+package sqlstore
+
+import (
+	"context"
+
+	"github.com/folklinoff/fitness-app/internal/domain"
+)
+
+const adminColumns = `id, name, password`
+
+// AdminRepository stores admins in a SQL database
+type AdminRepository struct {
+	s *store
+}
+
+func (s *store) scanAdmin(row interface{ Scan(...any) error }) (*domain.Admin, error) {
+	var admin domain.Admin
+	err := row.Scan(&admin.ID, &admin.Name, &admin.Password)
+	if err != nil {
+		return nil, s.mapError(err)
+	}
+	return &admin, nil
+}
+
+func (r *AdminRepository) Create(ctx context.Context, admin *domain.Admin) error {
+	err := r.s.db.QueryRowContext(ctx,
+		`INSERT INTO admins (name, password) VALUES ($1, $2) RETURNING id`,
+		admin.Name, admin.Password,
+	).Scan(&admin.ID)
+	return r.s.mapError(err)
+}
+
+func (r *AdminRepository) GetByID(ctx context.Context, id int) (*domain.Admin, error) {
+	return r.s.scanAdmin(r.s.db.QueryRowContext(ctx, `SELECT `+adminColumns+` FROM admins WHERE id = $1`, id))
+}
+
+func (r *AdminRepository) GetByName(ctx context.Context, name string) (*domain.Admin, error) {
+	return r.s.scanAdmin(r.s.db.QueryRowContext(ctx, `SELECT `+adminColumns+` FROM admins WHERE name = $1`, name))
+}
+
+func (r *AdminRepository) Update(ctx context.Context, admin *domain.Admin) error {
+	res, err := r.s.db.ExecContext(ctx,
+		`UPDATE admins SET name = $2, password = $3 WHERE id = $1`,
+		admin.ID, admin.Name, admin.Password,
+	)
+	if err != nil {
+		return r.s.mapError(err)
+	}
+	return expectAffected(res)
+}
@@ -0,0 +1,179 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/folklinoff/fitness-app/internal/domain"
+	"github.com/folklinoff/fitness-app/internal/storage"
+)
+
+// AttendanceRepository stores attendance and check-in codes in a SQL database
+type AttendanceRepository struct {
+	s *store
+}
+
+func (r *AttendanceRepository) Mark(ctx context.Context, attendance *domain.Attendance) error {
+	return r.s.inTx(ctx, func(tx *sql.Tx) error {
+		// Locking the training orders marking against recording its no-shows
+		if _, err := r.s.lockTraining(ctx, tx, attendance.TrainingID); err != nil {
+			return err
+		}
+
+		var status domain.RegistrationStatus
+		err := tx.QueryRowContext(ctx,
+			`SELECT status FROM training_registrations WHERE training_id = $1 AND user_id = $2`,
+			attendance.TrainingID, attendance.UserID,
+		).Scan(&status)
+		if err != nil {
+			return r.s.mapError(err)
+		}
+		if status != domain.RegistrationConfirmed {
+			return storage.ErrNotFound
+		}
+
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO attendance (training_id, user_id, method, checked_in_at) VALUES ($1, $2, $3, $4)`,
+			attendance.TrainingID, attendance.UserID, attendance.Method, attendance.CheckedInAt.UTC(),
+		)
+		if err != nil {
+			return r.s.mapError(err)
+		}
+		_, err = tx.ExecContext(ctx,
+			`DELETE FROM incidents WHERE user_id = $1 AND training_id = $2 AND kind = $3`,
+			attendance.UserID, attendance.TrainingID, domain.IncidentNoShow,
+		)
+		return r.s.mapError(err)
+	})
+}
+
+func (r *AttendanceRepository) Unmark(ctx context.Context, trainingID, userID int) error {
+	res, err := r.s.db.ExecContext(ctx,
+		`DELETE FROM attendance WHERE training_id = $1 AND user_id = $2`,
+		trainingID, userID,
+	)
+	if err != nil {
+		return r.s.mapError(err)
+	}
+	return expectAffected(res)
+}
+
+func (r *AttendanceRepository) Get(ctx context.Context, trainingID, userID int) (*domain.Attendance, error) {
+	var attendance domain.Attendance
+	err := r.s.db.QueryRowContext(ctx,
+		`SELECT training_id, user_id, method, checked_in_at FROM attendance WHERE training_id = $1 AND user_id = $2`,
+		trainingID, userID,
+	).Scan(&attendance.TrainingID, &attendance.UserID, &attendance.Method, &attendance.CheckedInAt)
+	if err != nil {
+		return nil, r.s.mapError(err)
+	}
+	return &attendance, nil
+}
+
+func (r *AttendanceRepository) ListByTraining(ctx context.Context, trainingID int) ([]domain.Attendance, error) {
+	rows, err := r.s.db.QueryContext(ctx,
+		`SELECT training_id, user_id, method, checked_in_at FROM attendance WHERE training_id = $1 ORDER BY user_id`,
+		trainingID,
+	)
+	if err != nil {
+		return nil, r.s.mapError(err)
+	}
+	defer rows.Close()
+
+	var list []domain.Attendance
+	for rows.Next() {
+		var attendance domain.Attendance
+		if err := rows.Scan(&attendance.TrainingID, &attendance.UserID, &attendance.Method, &attendance.CheckedInAt); err != nil {
+			return nil, r.s.mapError(err)
+		}
+		list = append(list, attendance)
+	}
+	return list, rows.Err()
+}
+
+func (r *AttendanceRepository) ReplaceCheckInCode(ctx context.Context, code *domain.CheckInCode) error {
+	_, err := r.s.db.ExecContext(ctx,
+		`INSERT INTO check_in_codes (training_id, code, expires_at) VALUES ($1, $2, $3)
+		ON CONFLICT (training_id) DO UPDATE SET code = excluded.code, expires_at = excluded.expires_at`,
+		code.TrainingID, code.Code, code.ExpiresAt.UTC(),
+	)
+	return r.s.mapError(err)
+}
+
+func (r *AttendanceRepository) GetCheckInCode(ctx context.Context, trainingID int) (*domain.CheckInCode, error) {
+	var code domain.CheckInCode
+	err := r.s.db.QueryRowContext(ctx,
+		`SELECT training_id, code, expires_at FROM check_in_codes WHERE training_id = $1`,
+		trainingID,
+	).Scan(&code.TrainingID, &code.Code, &code.ExpiresAt)
+	if err != nil {
+		return nil, r.s.mapError(err)
+	}
+	return &code, nil
+}
+
+func (r *AttendanceRepository) AddCheckInAttempt(ctx context.Context, trainingID, userID int) (int, error) {
+	var attempts int
+	err := r.s.db.QueryRowContext(ctx,
+		`INSERT INTO check_in_attempts (training_id, user_id, attempts) VALUES ($1, $2, 1)
+		ON CONFLICT (training_id, user_id) DO UPDATE SET attempts = check_in_attempts.attempts + 1
+		RETURNING attempts`,
+		trainingID, userID,
+	).Scan(&attempts)
+	if err != nil {
+		return 0, r.s.mapError(err)
+	}
+	return attempts, nil
+}
+
+func (r *AttendanceRepository) RecordNoShows(ctx context.Context, endedBy time.Time) (int, error) {
+	var recorded int
+	err := r.s.inTx(ctx, func(tx *sql.Tx) error {
+		// Flagging the trainings first locks them against concurrent marking
+		rows, err := tx.QueryContext(ctx,
+			`UPDATE trainings SET no_shows_recorded = TRUE
+			WHERE end_time <= $1 AND NOT no_shows_recorded
+			RETURNING id, end_time`,
+			endedBy.UTC(),
+		)
+		if err != nil {
+			return r.s.mapError(err)
+		}
+		var ended []domain.Training
+		for rows.Next() {
+			var training domain.Training
+			if err := rows.Scan(&training.ID, &training.EndTime); err != nil {
+				rows.Close()
+				return r.s.mapError(err)
+			}
+			ended = append(ended, training)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return r.s.mapError(err)
+		}
+
+		for _, training := range ended {
+			res, err := tx.ExecContext(ctx,
+				`INSERT INTO incidents (user_id, training_id, kind, at)
+				SELECT r.user_id, r.training_id, $2, $3 FROM training_registrations r
+				WHERE r.training_id = $1 AND r.status = 'confirmed' AND NOT EXISTS (
+					SELECT 1 FROM attendance a WHERE a.training_id = r.training_id AND a.user_id = r.user_id
+				)
+				ON CONFLICT (user_id, training_id, kind) DO NOTHING`,
+				training.ID, domain.IncidentNoShow, training.EndTime.UTC(),
+			)
+			if err != nil {
+				return r.s.mapError(err)
+			}
+			n, err := res.RowsAffected()
+			if err != nil {
+				return err
+			}
+			recorded += int(n)
+		}
+		return nil
+	})
+	return recorded, err
+}
@@ -0,0 +1,44 @@
+package sqlstore
+
+import (
+	"context"
+
+	"github.com/folklinoff/fitness-app/internal/domain"
+)
+
+// AuditRepository stores audit events in a SQL database
+type AuditRepository struct {
+	s *store
+}
+
+func (r *AuditRepository) Record(ctx context.Context, event *domain.AuditEvent) error {
+	err := r.s.db.QueryRowContext(ctx,
+		`INSERT INTO audit_events (at, actor_type, actor_id, action, resource, reason)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`,
+		event.At.UTC(), event.ActorType, event.ActorID, event.Action, event.Resource, event.Reason,
+	).Scan(&event.ID)
+	return r.s.mapError(err)
+}
+
+func (r *AuditRepository) List(ctx context.Context, limit int) ([]domain.AuditEvent, error) {
+	rows, err := r.s.db.QueryContext(ctx,
+		`SELECT id, at, actor_type, actor_id, action, resource, reason
+		FROM audit_events ORDER BY id DESC LIMIT $1`,
+		limit,
+	)
+	if err != nil {
+		return nil, r.s.mapError(err)
+	}
+	defer rows.Close()
+
+	var events []domain.AuditEvent
+	for rows.Next() {
+		var event domain.AuditEvent
+		err := rows.Scan(&event.ID, &event.At, &event.ActorType, &event.ActorID, &event.Action, &event.Resource, &event.Reason)
+		if err != nil {
+			return nil, r.s.mapError(err)
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
@@ -0,0 +1,53 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/folklinoff/fitness-app/internal/domain"
+)
+
+// CalendarFeedRepository stores calendar feeds in a SQL database
+type CalendarFeedRepository struct {
+	s *store
+}
+
+func (r *CalendarFeedRepository) Replace(ctx context.Context, feed *domain.CalendarFeed) error {
+	return r.s.inTx(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx,
+			`DELETE FROM calendar_feeds WHERE account_type = $1 AND account_id = $2`,
+			feed.AccountType, feed.AccountID,
+		)
+		if err != nil {
+			return r.s.mapError(err)
+		}
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO calendar_feeds (token_hash, account_type, account_id, created_at) VALUES ($1, $2, $3, $4)`,
+			feed.TokenHash, feed.AccountType, feed.AccountID, feed.CreatedAt.UTC(),
+		)
+		return r.s.mapError(err)
+	})
+}
+
+func (r *CalendarFeedRepository) GetByTokenHash(ctx context.Context, hash string) (*domain.CalendarFeed, error) {
+	var feed domain.CalendarFeed
+	err := r.s.db.QueryRowContext(ctx,
+		`SELECT token_hash, account_type, account_id, created_at FROM calendar_feeds WHERE token_hash = $1`,
+		hash,
+	).Scan(&feed.TokenHash, &feed.AccountType, &feed.AccountID, &feed.CreatedAt)
+	if err != nil {
+		return nil, r.s.mapError(err)
+	}
+	return &feed, nil
+}
+
+func (r *CalendarFeedRepository) Delete(ctx context.Context, accountType string, accountID int) error {
+	res, err := r.s.db.ExecContext(ctx,
+		`DELETE FROM calendar_feeds WHERE account_type = $1 AND account_id = $2`,
+		accountType, accountID,
+	)
+	if err != nil {
+		return r.s.mapError(err)
+	}
+	return expectAffected(res)
+}
@@ -0,0 +1,46 @@
+package sqlstore
+
+import (
+	"context"
+
+	"github.com/folklinoff/fitness-app/internal/domain"
+)
+
+// IncidentRepository stores late cancellations and no-shows in a SQL database
+type IncidentRepository struct {
+	s *store
+}
+
+func (r *IncidentRepository) Record(ctx context.Context, incident *domain.Incident) error {
+	err := r.s.db.QueryRowContext(ctx,
+		`INSERT INTO incidents (user_id, training_id, kind, at) VALUES ($1, $2, $3, $4) RETURNING id`,
+		incident.UserID, incident.TrainingID, incident.Kind, incident.At.UTC(),
+	).Scan(&incident.ID)
+	return r.s.mapError(err)
+}
+
+func (r *IncidentRepository) ListByUser(ctx context.Context, userID int) ([]domain.Incident, error) {
+	return r.query(ctx, `SELECT id, user_id, training_id, kind, at FROM incidents WHERE user_id = $1 ORDER BY id`, userID)
+}
+
+func (r *IncidentRepository) ListByTraining(ctx context.Context, trainingID int) ([]domain.Incident, error) {
+	return r.query(ctx, `SELECT id, user_id, training_id, kind, at FROM incidents WHERE training_id = $1 ORDER BY id`, trainingID)
+}
+
+func (r *IncidentRepository) query(ctx context.Context, query string, args ...any) ([]domain.Incident, error) {
+	rows, err := r.s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, r.s.mapError(err)
+	}
+	defer rows.Close()
+
+	var incidents []domain.Incident
+	for rows.Next() {
+		var incident domain.Incident
+		if err := rows.Scan(&incident.ID, &incident.UserID, &incident.TrainingID, &incident.Kind, &incident.At); err != nil {
+			return nil, r.s.mapError(err)
+		}
+		incidents = append(incidents, incident)
+	}
+	return incidents, rows.Err()
+}
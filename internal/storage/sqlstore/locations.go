@@ -0,0 +1,199 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/folklinoff/fitness-app/internal/domain"
+)
+
+// LocationRepository stores locations in a SQL database
+type LocationRepository struct {
+	s *store
+}
+
+func (r *LocationRepository) Create(ctx context.Context, location *domain.Location) error {
+	return r.s.inTx(ctx, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(ctx,
+			`INSERT INTO locations (name, address, time_zone) VALUES ($1, $2, $3) RETURNING id`,
+			location.Name, location.Address, location.TimeZone,
+		).Scan(&location.ID)
+		if err != nil {
+			return r.s.mapError(err)
+		}
+		return r.insertOpeningHours(ctx, tx, location)
+	})
+}
+
+func (r *LocationRepository) GetByID(ctx context.Context, id int) (*domain.Location, error) {
+	var location domain.Location
+	err := r.s.db.QueryRowContext(ctx,
+		`SELECT id, name, address, time_zone FROM locations WHERE id = $1`, id,
+	).Scan(&location.ID, &location.Name, &location.Address, &location.TimeZone)
+	if err != nil {
+		return nil, r.s.mapError(err)
+	}
+
+	hours, err := r.openingHours(ctx, `WHERE location_id = $1`, id)
+	if err != nil {
+		return nil, err
+	}
+	location.OpeningHours = hours[id]
+	return &location, nil
+}
+
+func (r *LocationRepository) List(ctx context.Context) ([]domain.Location, error) {
+	rows, err := r.s.db.QueryContext(ctx, `SELECT id, name, address, time_zone FROM locations ORDER BY id`)
+	if err != nil {
+		return nil, r.s.mapError(err)
+	}
+	defer rows.Close()
+
+	var locations []domain.Location
+	for rows.Next() {
+		var location domain.Location
+		if err := rows.Scan(&location.ID, &location.Name, &location.Address, &location.TimeZone); err != nil {
+			return nil, r.s.mapError(err)
+		}
+		locations = append(locations, location)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	hours, err := r.openingHours(ctx, ``)
+	if err != nil {
+		return nil, err
+	}
+	for i := range locations {
+		locations[i].OpeningHours = hours[locations[i].ID]
+	}
+	return locations, nil
+}
+
+func (r *LocationRepository) Update(ctx context.Context, location *domain.Location) error {
+	return r.s.inTx(ctx, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx,
+			`UPDATE locations SET name = $2, address = $3, time_zone = $4 WHERE id = $1`,
+			location.ID, location.Name, location.Address, location.TimeZone,
+		)
+		if err != nil {
+			return r.s.mapError(err)
+		}
+		if err := expectAffected(res); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM location_opening_hours WHERE location_id = $1`, location.ID); err != nil {
+			return r.s.mapError(err)
+		}
+		return r.insertOpeningHours(ctx, tx, location)
+	})
+}
+
+func (r *LocationRepository) Delete(ctx context.Context, id int) error {
+	res, err := r.s.db.ExecContext(ctx, `DELETE FROM locations WHERE id = $1`, id)
+	if err != nil {
+		return r.s.mapError(err)
+	}
+	return expectAffected(res)
+}
+
+func (r *LocationRepository) insertOpeningHours(ctx context.Context, tx *sql.Tx, location *domain.Location) error {
+	for _, hours := range location.OpeningHours {
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO location_opening_hours (location_id, weekday, opens, closes) VALUES ($1, $2, $3, $4)`,
+			location.ID, int(hours.Weekday), hours.Opens, hours.Closes,
+		)
+		if err != nil {
+			return r.s.mapError(err)
+		}
+	}
+	return nil
+}
+
+// openingHours returns the opening hours selected by the condition by location ID
+func (r *LocationRepository) openingHours(ctx context.Context, where string, args ...any) (map[int][]domain.OpeningHours, error) {
+	rows, err := r.s.db.QueryContext(ctx,
+		`SELECT location_id, weekday, opens, closes FROM location_opening_hours `+where+` ORDER BY location_id, weekday, opens`,
+		args...,
+	)
+	if err != nil {
+		return nil, r.s.mapError(err)
+	}
+	defer rows.Close()
+
+	hours := make(map[int][]domain.OpeningHours)
+	for rows.Next() {
+		var locationID int
+		var h domain.OpeningHours
+		if err := rows.Scan(&locationID, &h.Weekday, &h.Opens, &h.Closes); err != nil {
+			return nil, r.s.mapError(err)
+		}
+		hours[locationID] = append(hours[locationID], h)
+	}
+	return hours, rows.Err()
+}
+
+// RoomRepository stores rooms in a SQL database
+type RoomRepository struct {
+	s *store
+}
+
+const roomColumns = `id, location_id, name, capacity`
+
+func (s *store) scanRoom(row interface{ Scan(...any) error }) (*domain.Room, error) {
+	var room domain.Room
+	if err := row.Scan(&room.ID, &room.LocationID, &room.Name, &room.Capacity); err != nil {
+		return nil, s.mapError(err)
+	}
+	return &room, nil
+}
+
+func (r *RoomRepository) Create(ctx context.Context, room *domain.Room) error {
+	err := r.s.db.QueryRowContext(ctx,
+		`INSERT INTO rooms (location_id, name, capacity) VALUES ($1, $2, $3) RETURNING id`,
+		room.LocationID, room.Name, room.Capacity,
+	).Scan(&room.ID)
+	return r.s.mapError(err)
+}
+
+func (r *RoomRepository) GetByID(ctx context.Context, id int) (*domain.Room, error) {
+	return r.s.scanRoom(r.s.db.QueryRowContext(ctx, `SELECT `+roomColumns+` FROM rooms WHERE id = $1`, id))
+}
+
+func (r *RoomRepository) ListByLocation(ctx context.Context, locationID int) ([]domain.Room, error) {
+	rows, err := r.s.db.QueryContext(ctx, `SELECT `+roomColumns+` FROM rooms WHERE location_id = $1 ORDER BY id`, locationID)
+	if err != nil {
+		return nil, r.s.mapError(err)
+	}
+	defer rows.Close()
+
+	var rooms []domain.Room
+	for rows.Next() {
+		room, err := r.s.scanRoom(rows)
+		if err != nil {
+			return nil, err
+		}
+		rooms = append(rooms, *room)
+	}
+	return rooms, rows.Err()
+}
+
+func (r *RoomRepository) Update(ctx context.Context, room *domain.Room) error {
+	res, err := r.s.db.ExecContext(ctx,
+		`UPDATE rooms SET name = $2, capacity = $3 WHERE id = $1`,
+		room.ID, room.Name, room.Capacity,
+	)
+	if err != nil {
+		return r.s.mapError(err)
+	}
+	return expectAffected(res)
+}
+
+func (r *RoomRepository) Delete(ctx context.Context, id int) error {
+	res, err := r.s.db.ExecContext(ctx, `DELETE FROM rooms WHERE id = $1`, id)
+	if err != nil {
+		return r.s.mapError(err)
+	}
+	return expectAffected(res)
+}
@@ -0,0 +1,297 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/folklinoff/fitness-app/internal/domain"
+	"github.com/folklinoff/fitness-app/internal/storage"
+	"golang.org/x/xerrors"
+)
+
+const planColumns = `id, name, kind, sessions, valid_days, price, active`
+
+// PlanRepository stores membership plans in a SQL database
+type PlanRepository struct {
+	s *store
+}
+
+func (r *PlanRepository) Create(ctx context.Context, plan *domain.Plan) error {
+	err := r.s.db.QueryRowContext(ctx,
+		`INSERT INTO plans (name, kind, sessions, valid_days, price, active) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`,
+		plan.Name, plan.Kind, plan.Sessions, plan.ValidDays, plan.Price, plan.Active,
+	).Scan(&plan.ID)
+	return r.s.mapError(err)
+}
+
+func (r *PlanRepository) GetByID(ctx context.Context, id int) (*domain.Plan, error) {
+	var plan domain.Plan
+	err := r.s.db.QueryRowContext(ctx, `SELECT `+planColumns+` FROM plans WHERE id = $1`, id).
+		Scan(&plan.ID, &plan.Name, &plan.Kind, &plan.Sessions, &plan.ValidDays, &plan.Price, &plan.Active)
+	if err != nil {
+		return nil, r.s.mapError(err)
+	}
+	return &plan, nil
+}
+
+func (r *PlanRepository) List(ctx context.Context, activeOnly bool) ([]domain.Plan, error) {
+	query := `SELECT ` + planColumns + ` FROM plans ORDER BY id`
+	if activeOnly {
+		query = `SELECT ` + planColumns + ` FROM plans WHERE active ORDER BY id`
+	}
+	rows, err := r.s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, r.s.mapError(err)
+	}
+	defer rows.Close()
+
+	var plans []domain.Plan
+	for rows.Next() {
+		var plan domain.Plan
+		if err := rows.Scan(&plan.ID, &plan.Name, &plan.Kind, &plan.Sessions, &plan.ValidDays, &plan.Price, &plan.Active); err != nil {
+			return nil, r.s.mapError(err)
+		}
+		plans = append(plans, plan)
+	}
+	return plans, rows.Err()
+}
+
+func (r *PlanRepository) Update(ctx context.Context, plan *domain.Plan) error {
+	res, err := r.s.db.ExecContext(ctx,
+		`UPDATE plans SET name = $2, kind = $3, sessions = $4, valid_days = $5, price = $6, active = $7 WHERE id = $1`,
+		plan.ID, plan.Name, plan.Kind, plan.Sessions, plan.ValidDays, plan.Price, plan.Active,
+	)
+	if err != nil {
+		return r.s.mapError(err)
+	}
+	return expectAffected(res)
+}
+
+// MembershipRepository stores memberships and their ledger in a SQL database
+type MembershipRepository struct {
+	s *store
+}
+
+func (r *MembershipRepository) Grant(ctx context.Context, membership *domain.Membership) error {
+	return r.s.inTx(ctx, func(tx *sql.Tx) error {
+		return r.s.grant(ctx, tx, membership)
+	})
+}
+
+func (r *MembershipRepository) ListByUser(ctx context.Context, userID int) ([]domain.Membership, error) {
+	rows, err := r.s.db.QueryContext(ctx,
+		`SELECT id, user_id, plan_id, kind, credits, starts_at, expires_at, created_at
+		FROM memberships WHERE user_id = $1 ORDER BY id`,
+		userID,
+	)
+	if err != nil {
+		return nil, r.s.mapError(err)
+	}
+	defer rows.Close()
+
+	var memberships []domain.Membership
+	for rows.Next() {
+		var m domain.Membership
+		if err := rows.Scan(&m.ID, &m.UserID, &m.PlanID, &m.Kind, &m.Credits, &m.StartsAt, &m.ExpiresAt, &m.CreatedAt); err != nil {
+			return nil, r.s.mapError(err)
+		}
+		memberships = append(memberships, m)
+	}
+	return memberships, rows.Err()
+}
+
+func (r *MembershipRepository) Ledger(ctx context.Context, userID int) ([]domain.LedgerEntry, error) {
+	rows, err := r.s.db.QueryContext(ctx,
+		`SELECT id, user_id, membership_id, training_id, kind, amount, balance, at
+		FROM ledger_entries WHERE user_id = $1 ORDER BY id`,
+		userID,
+	)
+	if err != nil {
+		return nil, r.s.mapError(err)
+	}
+	defer rows.Close()
+
+	var entries []domain.LedgerEntry
+	for rows.Next() {
+		var e domain.LedgerEntry
+		if err := rows.Scan(&e.ID, &e.UserID, &e.MembershipID, &e.TrainingID, &e.Kind, &e.Amount, &e.Balance, &e.At); err != nil {
+			return nil, r.s.mapError(err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// payRegistration pays the cost of the training of the stored registration
+// from a membership of the user covering the training. Without one, the seat of
+// a paid training is held pending payment; waitlisted users pay once promoted.
+func (s *store) payRegistration(ctx context.Context, tx *sql.Tx, registration *domain.Registration, at time.Time) error {
+	var start time.Time
+	var cost, price int
+	err := tx.QueryRowContext(ctx, `SELECT start_time, credits, price FROM trainings WHERE id = $1`, registration.TrainingID).
+		Scan(&start, &cost, &price)
+	if err != nil {
+		return s.mapError(err)
+	}
+	if cost == 0 && price == 0 {
+		return nil
+	}
+
+	if cost > 0 {
+		paid, err := s.payCredits(ctx, tx, registration, start, cost, at)
+		if err != nil || paid {
+			return err
+		}
+		if price == 0 {
+			return storage.ErrNoCredits
+		}
+	}
+	if registration.Status != domain.RegistrationConfirmed {
+		return nil
+	}
+
+	registration.Status, registration.HeldAt = domain.RegistrationPending, at
+	_, err = tx.ExecContext(ctx,
+		`UPDATE training_registrations SET status = $3, held_at = $4 WHERE training_id = $1 AND user_id = $2`,
+		registration.TrainingID, registration.UserID, registration.Status, at.UTC(),
+	)
+	return s.mapError(err)
+}
+
+// payCredits pays the cost from the membership of the user covering the
+// training, unlimited ones first and then the one expiring first, and reports
+// whether there was one
+func (s *store) payCredits(ctx context.Context, tx *sql.Tx, registration *domain.Registration, start time.Time, cost int, at time.Time) (bool, error) {
+	var membershipID int
+	var kind domain.PlanKind
+	err := tx.QueryRowContext(ctx,
+		`SELECT id, kind FROM memberships
+		WHERE user_id = $1 AND starts_at <= $2 AND expires_at > $2 AND (kind = $3 OR credits >= $4)
+		ORDER BY CASE WHEN kind = $3 THEN 0 ELSE 1 END, expires_at, id
+		LIMIT 1`+s.dialect.ForUpdate,
+		registration.UserID, start.UTC(), domain.PlanUnlimited, cost,
+	).Scan(&membershipID, &kind)
+	if xerrors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, s.mapError(err)
+	}
+
+	registration.MembershipID = &membershipID
+	if kind != domain.PlanUnlimited {
+		registration.Credits = cost
+	}
+	_, err = tx.ExecContext(ctx,
+		`UPDATE training_registrations SET membership_id = $3, credits = $4 WHERE training_id = $1 AND user_id = $2`,
+		registration.TrainingID, registration.UserID, membershipID, registration.Credits,
+	)
+	if err != nil {
+		return false, s.mapError(err)
+	}
+	if registration.Credits == 0 {
+		return true, nil
+	}
+	return true, s.changeCredits(ctx, tx, registration.UserID, membershipID, &registration.TrainingID, domain.LedgerConsume, -cost, at)
+}
+
+// refund gives the credits paid for the removed registration back to its membership
+func (s *store) refund(ctx context.Context, tx *sql.Tx, registration domain.Registration, at time.Time) error {
+	if registration.MembershipID == nil || registration.Credits == 0 {
+		return nil
+	}
+	return s.changeCredits(ctx, tx, registration.UserID, *registration.MembershipID, &registration.TrainingID,
+		domain.LedgerRefund, registration.Credits, at)
+}
+
+// refundPayment marks the payment of a cancelled seat due for refund
+func (s *store) refundPayment(ctx context.Context, tx *sql.Tx, paymentID int, at time.Time) error {
+	_, err := tx.ExecContext(ctx,
+		`UPDATE payments SET status = 'refund_due', updated_at = $2 WHERE id = $1 AND status = 'paid'`,
+		paymentID, at.UTC(),
+	)
+	return s.mapError(err)
+}
+
+// refundTrainings refunds the credits and payments of the registrations of the
+// trainings which have not started, before the trainings are deleted
+func (s *store) refundTrainings(ctx context.Context, tx *sql.Tx, trainingIDs []int) error {
+	now := time.Now()
+	for _, trainingID := range trainingIDs {
+		rows, err := tx.QueryContext(ctx,
+			`SELECT r.user_id, r.membership_id, r.credits FROM training_registrations r
+			JOIN trainings t ON t.id = r.training_id
+			WHERE r.training_id = $1 AND t.start_time > $2 AND r.membership_id IS NOT NULL AND r.credits > 0
+			ORDER BY r.user_id`,
+			trainingID, now.UTC(),
+		)
+		if err != nil {
+			return s.mapError(err)
+		}
+		var registrations []domain.Registration
+		for rows.Next() {
+			registration := domain.Registration{TrainingID: trainingID}
+			if err := rows.Scan(&registration.UserID, &registration.MembershipID, &registration.Credits); err != nil {
+				rows.Close()
+				return s.mapError(err)
+			}
+			registrations = append(registrations, registration)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return s.mapError(err)
+		}
+
+		for _, registration := range registrations {
+			if err := s.refund(ctx, tx, registration, now); err != nil {
+				return err
+			}
+		}
+
+		_, err = tx.ExecContext(ctx,
+			`UPDATE payments SET status = 'refund_due', updated_at = $2 WHERE status = 'paid' AND id IN (
+				SELECT r.payment_id FROM training_registrations r JOIN trainings t ON t.id = r.training_id
+				WHERE r.training_id = $1 AND t.start_time > $2
+			)`,
+			trainingID, now.UTC(),
+		)
+		if err != nil {
+			return s.mapError(err)
+		}
+	}
+	return nil
+}
+
+// grant stores the membership with its grant ledger entry
+func (s *store) grant(ctx context.Context, tx *sql.Tx, membership *domain.Membership) error {
+	err := tx.QueryRowContext(ctx,
+		`INSERT INTO memberships (user_id, plan_id, kind, credits, starts_at, expires_at, created_at)
+		VALUES ($1, $2, $3, 0, $4, $5, $6) RETURNING id`,
+		membership.UserID, membership.PlanID, membership.Kind,
+		membership.StartsAt.UTC(), membership.ExpiresAt.UTC(), membership.CreatedAt.UTC(),
+	).Scan(&membership.ID)
+	if err != nil {
+		return s.mapError(err)
+	}
+	return s.changeCredits(ctx, tx, membership.UserID, membership.ID, nil, domain.LedgerGrant, membership.Credits, membership.CreatedAt)
+}
+
+// changeCredits adds amount to the credits of the membership and records the
+// change in the ledger
+func (s *store) changeCredits(ctx context.Context, tx *sql.Tx, userID, membershipID int, trainingID *int, kind domain.LedgerEntryKind, amount int, at time.Time) error {
+	var balance int
+	err := tx.QueryRowContext(ctx,
+		`UPDATE memberships SET credits = credits + $2 WHERE id = $1 RETURNING credits`,
+		membershipID, amount,
+	).Scan(&balance)
+	if err != nil {
+		return s.mapError(err)
+	}
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO ledger_entries (user_id, membership_id, training_id, kind, amount, balance, at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		userID, membershipID, trainingID, kind, amount, balance, at.UTC(),
+	)
+	return s.mapError(err)
+}
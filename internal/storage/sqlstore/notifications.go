@@ -0,0 +1,137 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/folklinoff/fitness-app/internal/domain"
+)
+
+const notificationColumns = `id, user_id, channel, kind, training_id, training_name, start_time, end_time, sequence, status, attempts, error, created_at, sent_at`
+
+// NotificationRepository stores the notification outbox in a SQL database
+type NotificationRepository struct {
+	s *store
+}
+
+func (r *NotificationRepository) Enqueue(ctx context.Context, notifications []domain.Notification) error {
+	return r.s.inTx(ctx, func(tx *sql.Tx) error {
+		for _, notification := range notifications {
+			if err := r.s.enqueue(ctx, tx, notification); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *NotificationRepository) EnqueueReminders(ctx context.Context, from, to time.Time) (int, error) {
+	var reminded int
+	err := r.s.inTx(ctx, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx,
+			`SELECT r.user_id, t.id, t.name, t.start_time, t.end_time, t.sequence
+			FROM training_registrations r JOIN trainings t ON t.id = r.training_id
+			WHERE r.status = 'confirmed' AND t.start_time >= $1 AND t.start_time < $2
+			AND NOT EXISTS (
+				SELECT 1 FROM notifications n
+				WHERE n.kind = 'training_reminder' AND n.training_id = t.id
+				AND n.user_id = r.user_id AND n.sequence = t.sequence
+			)
+			ORDER BY t.id, r.user_id`,
+			from.UTC(), to.UTC(),
+		)
+		if err != nil {
+			return r.s.mapError(err)
+		}
+		var reminders []domain.Notification
+		for rows.Next() {
+			var userID int
+			var training domain.Training
+			if err := rows.Scan(&userID, &training.ID, &training.Name, &training.StartTime, &training.EndTime, &training.Sequence); err != nil {
+				rows.Close()
+				return err
+			}
+			reminders = append(reminders, training.Notification(domain.NotificationReminder, userID, time.Now()))
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for _, reminder := range reminders {
+			if err := r.s.enqueue(ctx, tx, reminder); err != nil {
+				return err
+			}
+		}
+		reminded = len(reminders)
+		return nil
+	})
+	return reminded, err
+}
+
+func (r *NotificationRepository) ListPending(ctx context.Context, limit int) ([]domain.Notification, error) {
+	rows, err := r.s.db.QueryContext(ctx,
+		`SELECT `+notificationColumns+` FROM notifications WHERE status = 'pending' ORDER BY id LIMIT $1`, limit,
+	)
+	if err != nil {
+		return nil, r.s.mapError(err)
+	}
+	defer rows.Close()
+
+	var notifications []domain.Notification
+	for rows.Next() {
+		var notification domain.Notification
+		var sentAt sql.NullTime
+		err := rows.Scan(&notification.ID, &notification.UserID, &notification.Channel, &notification.Kind,
+			&notification.TrainingID, &notification.TrainingName, &notification.StartTime, &notification.EndTime,
+			&notification.Sequence, &notification.Status, &notification.Attempts, &notification.Error,
+			&notification.CreatedAt, &sentAt)
+		if err != nil {
+			return nil, r.s.mapError(err)
+		}
+		if sentAt.Valid {
+			notification.SentAt = &sentAt.Time
+		}
+		notifications = append(notifications, notification)
+	}
+	return notifications, rows.Err()
+}
+
+func (r *NotificationRepository) Finish(ctx context.Context, id int, status domain.NotificationStatus, reason string, at time.Time) error {
+	res, err := r.s.db.ExecContext(ctx,
+		`UPDATE notifications SET status = $2, error = $3, sent_at = $4 WHERE id = $1 AND status = 'pending'`,
+		id, status, reason, at.UTC(),
+	)
+	if err != nil {
+		return r.s.mapError(err)
+	}
+	return expectAffected(res)
+}
+
+func (r *NotificationRepository) Retry(ctx context.Context, id int, reason string) error {
+	res, err := r.s.db.ExecContext(ctx,
+		`UPDATE notifications SET attempts = attempts + 1, error = $2 WHERE id = $1 AND status = 'pending'`,
+		id, reason,
+	)
+	if err != nil {
+		return r.s.mapError(err)
+	}
+	return expectAffected(res)
+}
+
+// enqueue queues the notification on every channel
+func (s *store) enqueue(ctx context.Context, tx *sql.Tx, notification domain.Notification) error {
+	for _, channel := range domain.NotificationChannels {
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO notifications (user_id, channel, kind, training_id, training_name, start_time, end_time, sequence, status, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, 'pending', $9)`,
+			notification.UserID, channel, notification.Kind, notification.TrainingID, notification.TrainingName,
+			notification.StartTime.UTC(), notification.EndTime.UTC(), notification.Sequence, notification.CreatedAt.UTC(),
+		)
+		if err != nil {
+			return s.mapError(err)
+		}
+	}
+	return nil
+}
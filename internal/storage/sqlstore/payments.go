@@ -0,0 +1,260 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/folklinoff/fitness-app/internal/domain"
+	"github.com/folklinoff/fitness-app/internal/storage"
+	"golang.org/x/xerrors"
+)
+
+const paymentColumns = `id, user_id, training_id, plan_id, amount, currency, status, session_id, reference, created_at, updated_at, refund_started_at`
+
+// PaymentRepository stores payments in a SQL database
+type PaymentRepository struct {
+	s *store
+}
+
+func scanPayment(row interface{ Scan(...any) error }) (*domain.Payment, error) {
+	var payment domain.Payment
+	var sessionID sql.NullString
+	err := row.Scan(&payment.ID, &payment.UserID, &payment.TrainingID, &payment.PlanID, &payment.Amount, &payment.Currency,
+		&payment.Status, &sessionID, &payment.Reference, &payment.CreatedAt, &payment.UpdatedAt, &payment.RefundStartedAt)
+	if err != nil {
+		return nil, err
+	}
+	payment.SessionID = sessionID.String
+	return &payment, nil
+}
+
+func (r *PaymentRepository) Create(ctx context.Context, payment *domain.Payment) error {
+	err := r.s.db.QueryRowContext(ctx,
+		`INSERT INTO payments (user_id, training_id, plan_id, amount, currency, status, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $7) RETURNING id`,
+		payment.UserID, payment.TrainingID, payment.PlanID, payment.Amount, payment.Currency, payment.Status,
+		payment.CreatedAt.UTC(),
+	).Scan(&payment.ID)
+	return r.s.mapError(err)
+}
+
+func (r *PaymentRepository) SetSession(ctx context.Context, id int, sessionID string) error {
+	res, err := r.s.db.ExecContext(ctx, `UPDATE payments SET session_id = $2 WHERE id = $1`, id, sessionID)
+	if err != nil {
+		return r.s.mapError(err)
+	}
+	return expectAffected(res)
+}
+
+func (r *PaymentRepository) GetByID(ctx context.Context, id int) (*domain.Payment, error) {
+	payment, err := scanPayment(r.s.db.QueryRowContext(ctx, `SELECT `+paymentColumns+` FROM payments WHERE id = $1`, id))
+	return payment, r.s.mapError(err)
+}
+
+func (r *PaymentRepository) GetBySession(ctx context.Context, sessionID string) (*domain.Payment, error) {
+	payment, err := scanPayment(r.s.db.QueryRowContext(ctx, `SELECT `+paymentColumns+` FROM payments WHERE session_id = $1`, sessionID))
+	return payment, r.s.mapError(err)
+}
+
+func (r *PaymentRepository) ListByUser(ctx context.Context, userID int) ([]domain.Payment, error) {
+	return r.s.queryPayments(ctx, `SELECT `+paymentColumns+` FROM payments WHERE user_id = $1 ORDER BY id`, userID)
+}
+
+func (r *PaymentRepository) ListRefundsDue(ctx context.Context) ([]domain.Payment, error) {
+	return r.s.queryPayments(ctx, `SELECT `+paymentColumns+` FROM payments WHERE status = 'refund_due' ORDER BY id`)
+}
+
+func (r *PaymentRepository) Settle(ctx context.Context, eventID string, paymentID int, reference string, membership *domain.Membership) (*domain.Payment, error) {
+	var payment *domain.Payment
+	err := r.s.inTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		if payment, err = r.s.processEvent(ctx, tx, eventID, paymentID); err != nil {
+			return err
+		}
+		if payment.Status != domain.PaymentPending && payment.Status != domain.PaymentFailed {
+			return nil
+		}
+
+		now := time.Now()
+		payment.Status, payment.Reference, payment.UpdatedAt = domain.PaymentPaid, reference, now
+		switch {
+		case payment.TrainingID != nil:
+			// Locked like every other change of the seats of the training
+			if _, err := r.s.lockTraining(ctx, tx, *payment.TrainingID); err != nil && !xerrors.Is(err, storage.ErrNotFound) {
+				return err
+			}
+			res, err := tx.ExecContext(ctx,
+				`UPDATE training_registrations SET status = 'confirmed', held_at = NULL, payment_id = $3
+				WHERE training_id = $1 AND user_id = $2 AND status = 'pending_payment'`,
+				*payment.TrainingID, payment.UserID, payment.ID,
+			)
+			if err != nil {
+				return r.s.mapError(err)
+			}
+			if n, err := res.RowsAffected(); err != nil {
+				return err
+			} else if n == 0 {
+				payment.Status = domain.PaymentRefundDue
+			}
+		case membership != nil:
+			// The membership of a deleted user cannot be granted
+			var exists bool
+			err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM users WHERE id = $1)`, payment.UserID).Scan(&exists)
+			if err != nil {
+				return r.s.mapError(err)
+			}
+			if !exists {
+				payment.Status = domain.PaymentRefundDue
+				break
+			}
+			if err := r.s.grant(ctx, tx, membership); err != nil {
+				return err
+			}
+		}
+
+		_, err = tx.ExecContext(ctx,
+			`UPDATE payments SET status = $2, reference = $3, updated_at = $4 WHERE id = $1`,
+			payment.ID, payment.Status, payment.Reference, now.UTC(),
+		)
+		return r.s.mapError(err)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return payment, nil
+}
+
+func (r *PaymentRepository) Fail(ctx context.Context, eventID string, paymentID int) (*domain.Payment, error) {
+	var payment *domain.Payment
+	err := r.s.inTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		if payment, err = r.s.processEvent(ctx, tx, eventID, paymentID); err != nil {
+			return err
+		}
+		if payment.Status != domain.PaymentPending {
+			return nil
+		}
+
+		payment.Status, payment.UpdatedAt = domain.PaymentFailed, time.Now()
+		_, err = tx.ExecContext(ctx,
+			`UPDATE payments SET status = $2, updated_at = $3 WHERE id = $1`,
+			payment.ID, payment.Status, payment.UpdatedAt.UTC(),
+		)
+		return r.s.mapError(err)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return payment, nil
+}
+
+func (r *PaymentRepository) ReleaseHolds(ctx context.Context, heldBefore time.Time) (int, error) {
+	var released int
+	err := r.s.inTx(ctx, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx,
+			`SELECT training_id, user_id FROM training_registrations
+			WHERE status = 'pending_payment' AND held_at < $1
+			ORDER BY training_id, user_id`,
+			heldBefore.UTC(),
+		)
+		if err != nil {
+			return r.s.mapError(err)
+		}
+		var expired []domain.Registration
+		for rows.Next() {
+			var registration domain.Registration
+			if err := rows.Scan(&registration.TrainingID, &registration.UserID); err != nil {
+				rows.Close()
+				return err
+			}
+			expired = append(expired, registration)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for i, registration := range expired {
+			if i > 0 && expired[i-1].TrainingID == registration.TrainingID {
+				continue
+			}
+			capacity, err := r.s.lockTraining(ctx, tx, registration.TrainingID)
+			if err != nil {
+				return err
+			}
+			res, err := tx.ExecContext(ctx,
+				`DELETE FROM training_registrations WHERE training_id = $1 AND status = 'pending_payment' AND held_at < $2`,
+				registration.TrainingID, heldBefore.UTC(),
+			)
+			if err != nil {
+				return r.s.mapError(err)
+			}
+			n, err := res.RowsAffected()
+			if err != nil {
+				return err
+			}
+			released += int(n)
+			if _, err := r.s.promote(ctx, tx, registration.TrainingID, capacity); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return released, err
+}
+
+func (r *PaymentRepository) StartRefund(ctx context.Context, id int) error {
+	now := time.Now().UTC()
+	res, err := r.s.db.ExecContext(ctx,
+		`UPDATE payments SET refund_started_at = $2, updated_at = $2 WHERE id = $1 AND status = 'refund_due'`,
+		id, now,
+	)
+	if err != nil {
+		return r.s.mapError(err)
+	}
+	return expectAffected(res)
+}
+
+func (r *PaymentRepository) MarkRefunded(ctx context.Context, id int) error {
+	res, err := r.s.db.ExecContext(ctx,
+		`UPDATE payments SET status = 'refunded', updated_at = $2 WHERE id = $1 AND status = 'refund_due'`,
+		id, time.Now().UTC(),
+	)
+	if err != nil {
+		return r.s.mapError(err)
+	}
+	return expectAffected(res)
+}
+
+// processEvent records the gateway event and returns the locked payment it is
+// about, ErrAlreadyExists is returned if the event was processed before
+func (s *store) processEvent(ctx context.Context, tx *sql.Tx, eventID string, paymentID int) (*domain.Payment, error) {
+	_, err := tx.ExecContext(ctx,
+		`INSERT INTO payment_events (id, payment_id, processed_at) VALUES ($1, $2, $3)`,
+		eventID, paymentID, time.Now().UTC(),
+	)
+	if err != nil {
+		return nil, s.mapError(err)
+	}
+	payment, err := scanPayment(tx.QueryRowContext(ctx, `SELECT `+paymentColumns+` FROM payments WHERE id = $1`+s.dialect.ForUpdate, paymentID))
+	return payment, s.mapError(err)
+}
+
+func (s *store) queryPayments(ctx context.Context, query string, args ...any) ([]domain.Payment, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, s.mapError(err)
+	}
+	defer rows.Close()
+
+	var payments []domain.Payment
+	for rows.Next() {
+		payment, err := scanPayment(rows)
+		if err != nil {
+			return nil, s.mapError(err)
+		}
+		payments = append(payments, *payment)
+	}
+	return payments, rows.Err()
+}
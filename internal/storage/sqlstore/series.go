@@ -0,0 +1,193 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/folklinoff/fitness-app/internal/domain"
+	"golang.org/x/xerrors"
+)
+
+// SeriesRepository stores recurring trainings in a SQL database
+type SeriesRepository struct {
+	s *store
+}
+
+func (r *SeriesRepository) Create(ctx context.Context, series *domain.TrainingSeries, occurrences []domain.Training, allowOverlap bool) error {
+	return r.s.inTx(ctx, func(tx *sql.Tx) error {
+		if !allowOverlap {
+			if err := r.s.checkOverlaps(ctx, tx, occurrences, nil); err != nil {
+				return err
+			}
+		}
+		if err := r.insertSeries(ctx, tx, series); err != nil {
+			return err
+		}
+		for i := range occurrences {
+			occurrences[i].SeriesID = &series.ID
+			if err := r.s.insertTraining(ctx, tx, &occurrences[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *SeriesRepository) GetByID(ctx context.Context, id int) (*domain.TrainingSeries, error) {
+	var series domain.TrainingSeries
+	var exceptions string
+	err := r.s.db.QueryRowContext(ctx,
+		`SELECT id, name, type, level, trainer_id, capacity, room_id, start_time, end_time, time_zone, rrule, exceptions, credits, price
+		FROM training_series WHERE id = $1`,
+		id,
+	).Scan(&series.ID, &series.Name, &series.Type, &series.Level, &series.TrainerID, &series.Capacity, &series.RoomID,
+		&series.StartTime, &series.EndTime, &series.TimeZone, &series.RRule, &exceptions, &series.Credits, &series.Price)
+	if err != nil {
+		return nil, r.s.mapError(err)
+	}
+	if series.Exceptions, err = decodeExceptions(exceptions); err != nil {
+		return nil, xerrors.Errorf("decode exceptions of series %d: %w", id, err)
+	}
+	return &series, nil
+}
+
+func (r *SeriesRepository) ListOccurrences(ctx context.Context, seriesID int) ([]domain.Training, error) {
+	return r.s.queryTrainings(ctx,
+		`SELECT `+trainingColumns+` FROM trainings t WHERE t.series_id = $1 ORDER BY t.start_time, t.id`,
+		seriesID,
+	)
+}
+
+func (r *SeriesRepository) Update(ctx context.Context, series *domain.TrainingSeries, changed []domain.Training, removed []int, allowOverlap bool) error {
+	return r.s.inTx(ctx, func(tx *sql.Tx) error {
+		if !allowOverlap {
+			if err := r.s.checkOverlaps(ctx, tx, changed, removed); err != nil {
+				return err
+			}
+		}
+		if err := r.updateSeries(ctx, tx, series); err != nil {
+			return err
+		}
+		if err := r.deleteTrainings(ctx, tx, removed); err != nil {
+			return err
+		}
+		for i := range changed {
+			if err := r.updateOccurrence(ctx, tx, &changed[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *SeriesRepository) Split(ctx context.Context, series, next *domain.TrainingSeries, moved []domain.Training, allowOverlap bool) error {
+	return r.s.inTx(ctx, func(tx *sql.Tx) error {
+		if !allowOverlap {
+			if err := r.s.checkOverlaps(ctx, tx, moved, nil); err != nil {
+				return err
+			}
+		}
+		if err := r.updateSeries(ctx, tx, series); err != nil {
+			return err
+		}
+		if err := r.insertSeries(ctx, tx, next); err != nil {
+			return err
+		}
+		for i := range moved {
+			moved[i].SeriesID = &next.ID
+			if err := r.updateOccurrence(ctx, tx, &moved[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *SeriesRepository) Delete(ctx context.Context, id int, removed []int) error {
+	return r.s.inTx(ctx, func(tx *sql.Tx) error {
+		if err := r.deleteTrainings(ctx, tx, removed); err != nil {
+			return err
+		}
+		// The kept occurrences are detached by ON DELETE SET NULL
+		res, err := tx.ExecContext(ctx, `DELETE FROM training_series WHERE id = $1`, id)
+		if err != nil {
+			return r.s.mapError(err)
+		}
+		return expectAffected(res)
+	})
+}
+
+func (r *SeriesRepository) insertSeries(ctx context.Context, tx *sql.Tx, series *domain.TrainingSeries) error {
+	err := tx.QueryRowContext(ctx,
+		`INSERT INTO training_series (name, type, level, trainer_id, capacity, room_id, start_time, end_time, time_zone, rrule, exceptions, credits, price)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13) RETURNING id`,
+		series.Name, series.Type, series.Level, series.TrainerID, series.Capacity, series.RoomID,
+		series.StartTime.UTC(), series.EndTime.UTC(), series.TimeZone, series.RRule, encodeExceptions(series.Exceptions),
+		series.Credits, series.Price,
+	).Scan(&series.ID)
+	return r.s.mapError(err)
+}
+
+func (r *SeriesRepository) updateSeries(ctx context.Context, tx *sql.Tx, series *domain.TrainingSeries) error {
+	res, err := tx.ExecContext(ctx,
+		`UPDATE training_series SET name = $2, type = $3, level = $4, capacity = $5, start_time = $6, end_time = $7,
+		time_zone = $8, rrule = $9, exceptions = $10, room_id = $11, credits = $12, price = $13
+		WHERE id = $1`,
+		series.ID, series.Name, series.Type, series.Level, series.Capacity,
+		series.StartTime.UTC(), series.EndTime.UTC(), series.TimeZone, series.RRule, encodeExceptions(series.Exceptions),
+		series.RoomID, series.Credits, series.Price,
+	)
+	if err != nil {
+		return r.s.mapError(err)
+	}
+	return expectAffected(res)
+}
+
+// updateOccurrence stores the training together with its series reference
+func (r *SeriesRepository) updateOccurrence(ctx context.Context, tx *sql.Tx, training *domain.Training) error {
+	if err := r.s.updateTraining(ctx, tx, training); err != nil {
+		return err
+	}
+	_, err := tx.ExecContext(ctx,
+		`UPDATE trainings SET series_id = $2, recurrence_id = $3 WHERE id = $1`,
+		training.ID, training.SeriesID, utcOrNil(training.RecurrenceID),
+	)
+	return r.s.mapError(err)
+}
+
+func (r *SeriesRepository) deleteTrainings(ctx context.Context, tx *sql.Tx, ids []int) error {
+	if err := r.s.refundTrainings(ctx, tx, ids); err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM trainings WHERE id = $1`, id); err != nil {
+			return r.s.mapError(err)
+		}
+	}
+	return nil
+}
+
+func encodeExceptions(exceptions []time.Time) string {
+	values := make([]string, 0, len(exceptions))
+	for _, t := range exceptions {
+		values = append(values, t.UTC().Format(time.RFC3339))
+	}
+	return strings.Join(values, ",")
+}
+
+func decodeExceptions(s string) ([]time.Time, error) {
+	if s == "" {
+		return nil, nil
+	}
+	var exceptions []time.Time
+	for _, value := range strings.Split(s, ",") {
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return nil, err
+		}
+		exceptions = append(exceptions, t)
+	}
+	return exceptions, nil
+}
@@ -0,0 +1,114 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/folklinoff/fitness-app/internal/domain"
+)
+
+// SessionRepository stores refresh tokens and revoked access tokens in a SQL database
+type SessionRepository struct {
+	s *store
+}
+
+func (r *SessionRepository) CreateRefreshToken(ctx context.Context, token *domain.RefreshToken) error {
+	_, err := r.s.db.ExecContext(ctx,
+		`INSERT INTO refresh_tokens (token_hash, family_id, account_type, account_id, issued_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+		token.Hash, token.FamilyID, token.AccountType, token.AccountID, token.IssuedAt.UTC(), token.ExpiresAt.UTC(),
+	)
+	return r.s.mapError(err)
+}
+
+func (r *SessionRepository) GetRefreshToken(ctx context.Context, hash string) (*domain.RefreshToken, error) {
+	var token domain.RefreshToken
+	var usedAt, revokedAt sql.NullTime
+	err := r.s.db.QueryRowContext(ctx,
+		`SELECT token_hash, family_id, account_type, account_id, issued_at, expires_at, used_at, revoked_at
+		FROM refresh_tokens WHERE token_hash = $1`,
+		hash,
+	).Scan(&token.Hash, &token.FamilyID, &token.AccountType, &token.AccountID,
+		&token.IssuedAt, &token.ExpiresAt, &usedAt, &revokedAt)
+	if err != nil {
+		return nil, r.s.mapError(err)
+	}
+	token.UsedAt = usedAt.Time
+	token.RevokedAt = revokedAt.Time
+	return &token, nil
+}
+
+func (r *SessionRepository) UseRefreshToken(ctx context.Context, hash string, at time.Time) error {
+	res, err := r.s.db.ExecContext(ctx,
+		`UPDATE refresh_tokens SET used_at = $2
+		WHERE token_hash = $1 AND used_at IS NULL AND revoked_at IS NULL`,
+		hash, at.UTC(),
+	)
+	if err != nil {
+		return r.s.mapError(err)
+	}
+	return expectAffected(res)
+}
+
+func (r *SessionRepository) RevokeRefreshTokenFamily(ctx context.Context, familyID string, at time.Time) error {
+	_, err := r.s.db.ExecContext(ctx,
+		`UPDATE refresh_tokens SET revoked_at = $2 WHERE family_id = $1 AND revoked_at IS NULL`,
+		familyID, at.UTC(),
+	)
+	return r.s.mapError(err)
+}
+
+func (r *SessionRepository) RevokeAccount(ctx context.Context, accountType string, accountID int, at time.Time) error {
+	tx, err := r.s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx,
+		`UPDATE refresh_tokens SET revoked_at = $3
+		WHERE account_type = $1 AND account_id = $2 AND revoked_at IS NULL`,
+		accountType, accountID, at.UTC(),
+	)
+	if err != nil {
+		return r.s.mapError(err)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO account_revocations (account_type, account_id, revoked_before) VALUES ($1, $2, $3)
+		ON CONFLICT (account_type, account_id) DO UPDATE SET revoked_before = excluded.revoked_before`,
+		accountType, accountID, at.Truncate(time.Microsecond).UTC(),
+	)
+	if err != nil {
+		return r.s.mapError(err)
+	}
+
+	return tx.Commit()
+}
+
+func (r *SessionRepository) RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	// Expired tokens are rejected anyway, so their entries are dropped here
+	if _, err := r.s.db.ExecContext(ctx, `DELETE FROM revoked_access_tokens WHERE expires_at < $1`, time.Now().UTC()); err != nil {
+		return r.s.mapError(err)
+	}
+
+	_, err := r.s.db.ExecContext(ctx,
+		`INSERT INTO revoked_access_tokens (jti, expires_at) VALUES ($1, $2) ON CONFLICT (jti) DO NOTHING`,
+		jti, expiresAt.UTC(),
+	)
+	return r.s.mapError(err)
+}
+
+func (r *SessionRepository) IsAccessTokenRevoked(ctx context.Context, jti, accountType string, accountID int, issuedAt time.Time) (bool, error) {
+	var revoked bool
+	err := r.s.db.QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM revoked_access_tokens WHERE jti = $1)
+		OR EXISTS (
+			SELECT 1 FROM account_revocations
+			WHERE account_type = $2 AND account_id = $3 AND revoked_before >= $4
+		)`,
+		jti, accountType, accountID, issuedAt.UTC(),
+	).Scan(&revoked)
+	return revoked, err
+}
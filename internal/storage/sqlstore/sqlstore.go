@@ -0,0 +1,223 @@
+// Package sqlstore implements the storage repositories on top of database/sql.
+// The queries are shared by the SQL backends, which differ only in their Dialect.
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/folklinoff/fitness-app/internal/storage"
+	"golang.org/x/xerrors"
+)
+
+// Dialect describes the differences between the supported SQL databases
+type Dialect struct {
+	// Migrations holds the <version>_<description>.sql schema migrations at its root
+	Migrations fs.FS
+	// MapError translates driver specific constraint violations into storage errors,
+	// it is called only for errors which are not sql.ErrNoRows
+	MapError func(err error) error
+	// LockMigrations serializes migrations of concurrently starting instances,
+	// it is optional and returns a function releasing the lock
+	LockMigrations func(ctx context.Context, conn *sql.Conn) (func(), error)
+	// ForUpdate is appended to SELECTs locking the rows they read until the end of
+	// the transaction, it is empty for databases whose write transactions lock
+	// the whole database
+	ForUpdate string
+	// Lower is the SQL function lowercasing text, including non-ASCII letters
+	Lower string
+}
+
+type store struct {
+	db      *sql.DB
+	dialect Dialect
+	options storage.Options
+}
+
+// New returns a storage backed by the given database
+func New(db *sql.DB, dialect Dialect, options storage.Options) storage.Storage {
+	s := &store{db: db, dialect: dialect, options: options}
+	return storage.Storage{
+		Users:         &UserRepository{s},
+		Trainers:      &TrainerRepository{s},
+		Admins:        &AdminRepository{s},
+		Trainings:     &TrainingRepository{s},
+		Series:        &SeriesRepository{s},
+		Locations:     &LocationRepository{s},
+		Rooms:         &RoomRepository{s},
+		Sessions:      &SessionRepository{s},
+		Audit:         &AuditRepository{s},
+		Incidents:     &IncidentRepository{s},
+		Plans:         &PlanRepository{s},
+		Memberships:   &MembershipRepository{s},
+		Payments:      &PaymentRepository{s},
+		Notifications: &NotificationRepository{s},
+		Attendance:    &AttendanceRepository{s},
+		Calendars:     &CalendarFeedRepository{s},
+	}
+}
+
+type migration struct {
+	version int
+	name    string
+}
+
+// Migrate applies the dialect migrations which are not recorded in schema_migrations yet
+func Migrate(ctx context.Context, db *sql.DB, dialect Dialect) error {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return xerrors.Errorf("acquire connection: %w", err)
+	}
+	defer conn.Close()
+
+	if dialect.LockMigrations != nil {
+		unlock, err := dialect.LockMigrations(ctx, conn)
+		if err != nil {
+			return xerrors.Errorf("lock migrations: %w", err)
+		}
+		defer unlock()
+	}
+
+	_, err = conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`)
+	if err != nil {
+		return xerrors.Errorf("create schema_migrations: %w", err)
+	}
+
+	pending, err := listMigrations(dialect.Migrations)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range pending {
+		var applied bool
+		err := conn.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = $1)`, m.version).Scan(&applied)
+		if err != nil {
+			return xerrors.Errorf("check migration %s: %w", m.name, err)
+		}
+		if applied {
+			continue
+		}
+
+		query, err := fs.ReadFile(dialect.Migrations, m.name)
+		if err != nil {
+			return xerrors.Errorf("read migration %s: %w", m.name, err)
+		}
+
+		tx, err := conn.BeginTx(ctx, nil)
+		if err != nil {
+			return xerrors.Errorf("begin migration %s: %w", m.name, err)
+		}
+		if _, err := tx.ExecContext(ctx, string(query)); err != nil {
+			tx.Rollback()
+			return xerrors.Errorf("apply migration %s: %w", m.name, err)
+		}
+		if _, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version) VALUES ($1)`, m.version); err != nil {
+			tx.Rollback()
+			return xerrors.Errorf("record migration %s: %w", m.name, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return xerrors.Errorf("commit migration %s: %w", m.name, err)
+		}
+	}
+
+	return nil
+}
+
+func listMigrations(migrations fs.FS) ([]migration, error) {
+	entries, err := fs.ReadDir(migrations, ".")
+	if err != nil {
+		return nil, xerrors.Errorf("read migrations: %w", err)
+	}
+
+	var list []migration
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sql") {
+			continue
+		}
+		prefix, _, ok := strings.Cut(entry.Name(), "_")
+		if !ok {
+			return nil, xerrors.Errorf("migration %s: missing version prefix", entry.Name())
+		}
+		version, err := strconv.Atoi(prefix)
+		if err != nil {
+			return nil, xerrors.Errorf("migration %s: invalid version: %w", entry.Name(), err)
+		}
+		list = append(list, migration{version: version, name: entry.Name()})
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].version < list[j].version
+	})
+	return list, nil
+}
+
+// inTx runs fn in a transaction, which is committed if fn succeeds
+func (s *store) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// mapError translates sql.ErrNoRows and constraint violations into storage errors
+func (s *store) mapError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if xerrors.Is(err, sql.ErrNoRows) {
+		return storage.ErrNotFound
+	}
+	return s.dialect.MapError(err)
+}
+
+// where collects the conditions of a query together with their numbered arguments
+type where struct {
+	conds []string
+	args  []any
+}
+
+// arg adds an argument and returns its placeholder
+func (w *where) arg(v any) string {
+	w.args = append(w.args, v)
+	return "$" + strconv.Itoa(len(w.args))
+}
+
+func (w *where) add(cond string) {
+	w.conds = append(w.conds, cond)
+}
+
+// String returns the WHERE clause, it is empty without conditions
+func (w *where) String() string {
+	if len(w.conds) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(w.conds, " AND ")
+}
+
+// likeEscaper escapes the wildcards of a LIKE pattern for ESCAPE '\'
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// expectAffected returns storage.ErrNotFound when the statement changed no rows
+func expectAffected(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return storage.ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/folklinoff/fitness-app/internal/domain"
+)
+
+const trainerColumns = `id, name, password, mail, phone`
+
+// TrainerRepository stores trainers in a SQL database
+type TrainerRepository struct {
+	s *store
+}
+
+func (s *store) scanTrainer(row interface{ Scan(...any) error }) (*domain.Trainer, error) {
+	var trainer domain.Trainer
+	err := row.Scan(&trainer.ID, &trainer.Name, &trainer.Password, &trainer.Mail, &trainer.Phone)
+	if err != nil {
+		return nil, s.mapError(err)
+	}
+	return &trainer, nil
+}
+
+func (r *TrainerRepository) Create(ctx context.Context, trainer *domain.Trainer) error {
+	err := r.s.db.QueryRowContext(ctx,
+		`INSERT INTO trainers (name, password, mail, phone) VALUES ($1, $2, $3, $4) RETURNING id`,
+		trainer.Name, trainer.Password, trainer.Mail, trainer.Phone,
+	).Scan(&trainer.ID)
+	return r.s.mapError(err)
+}
+
+func (r *TrainerRepository) GetByID(ctx context.Context, id int) (*domain.Trainer, error) {
+	return r.s.scanTrainer(r.s.db.QueryRowContext(ctx, `SELECT `+trainerColumns+` FROM trainers WHERE id = $1`, id))
+}
+
+func (r *TrainerRepository) GetByName(ctx context.Context, name string) (*domain.Trainer, error) {
+	return r.s.scanTrainer(r.s.db.QueryRowContext(ctx, `SELECT `+trainerColumns+` FROM trainers WHERE name = $1`, name))
+}
+
+func (r *TrainerRepository) Update(ctx context.Context, trainer *domain.Trainer) error {
+	res, err := r.s.db.ExecContext(ctx,
+		`UPDATE trainers SET name = $2, password = $3, mail = $4, phone = $5 WHERE id = $1`,
+		trainer.ID, trainer.Name, trainer.Password, trainer.Mail, trainer.Phone,
+	)
+	if err != nil {
+		return r.s.mapError(err)
+	}
+	return expectAffected(res)
+}
+
+func (r *TrainerRepository) Delete(ctx context.Context, id int) error {
+	return r.s.inTx(ctx, func(tx *sql.Tx) error {
+		var trainingIDs []int
+		rows, err := tx.QueryContext(ctx, `SELECT id FROM trainings WHERE trainer_id = $1 ORDER BY id`, id)
+		if err != nil {
+			return r.s.mapError(err)
+		}
+		for rows.Next() {
+			var trainingID int
+			if err := rows.Scan(&trainingID); err != nil {
+				rows.Close()
+				return r.s.mapError(err)
+			}
+			trainingIDs = append(trainingIDs, trainingID)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return r.s.mapError(err)
+		}
+		if err := r.s.refundTrainings(ctx, tx, trainingIDs); err != nil {
+			return err
+		}
+
+		// The trainings are deleted by ON DELETE CASCADE
+		res, err := tx.ExecContext(ctx, `DELETE FROM trainers WHERE id = $1`, id)
+		if err != nil {
+			return r.s.mapError(err)
+		}
+		return expectAffected(res)
+	})
+}
@@ -0,0 +1,646 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/folklinoff/fitness-app/internal/domain"
+	"github.com/folklinoff/fitness-app/internal/storage"
+)
+
+const trainingColumns = `t.id, t.name, t.type, t.level, t.trainer_id, t.capacity, t.start_time, t.end_time, t.room_id, t.series_id, t.recurrence_id, t.sequence, t.credits, t.price`
+
+// TrainingRepository stores trainings and registrations in a SQL database
+type TrainingRepository struct {
+	s *store
+}
+
+func (s *store) scanTraining(row interface{ Scan(...any) error }) (*domain.Training, error) {
+	var training domain.Training
+	err := row.Scan(&training.ID, &training.Name, &training.Type, &training.Level,
+		&training.TrainerID, &training.Capacity, &training.StartTime, &training.EndTime,
+		&training.RoomID, &training.SeriesID, &training.RecurrenceID, &training.Sequence, &training.Credits, &training.Price)
+	if err != nil {
+		return nil, s.mapError(err)
+	}
+	return &training, nil
+}
+
+func (s *store) queryTrainings(ctx context.Context, query string, args ...any) ([]domain.Training, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var trainings []domain.Training
+	for rows.Next() {
+		training, err := s.scanTraining(rows)
+		if err != nil {
+			return nil, err
+		}
+		trainings = append(trainings, *training)
+	}
+	return trainings, rows.Err()
+}
+
+func (r *TrainingRepository) Create(ctx context.Context, training *domain.Training, allowOverlap bool) error {
+	return r.s.inTx(ctx, func(tx *sql.Tx) error {
+		if !allowOverlap {
+			if err := r.s.checkOverlaps(ctx, tx, []domain.Training{*training}, nil); err != nil {
+				return err
+			}
+		}
+		return r.s.insertTraining(ctx, tx, training)
+	})
+}
+
+func (r *TrainingRepository) GetByID(ctx context.Context, id int) (*domain.Training, error) {
+	return r.s.scanTraining(r.s.db.QueryRowContext(ctx, `SELECT `+trainingColumns+` FROM trainings t WHERE t.id = $1`, id))
+}
+
+func (r *TrainingRepository) Update(ctx context.Context, training *domain.Training, allowOverlap bool) error {
+	return r.s.inTx(ctx, func(tx *sql.Tx) error {
+		if !allowOverlap {
+			if err := r.s.checkOverlaps(ctx, tx, []domain.Training{*training}, nil); err != nil {
+				return err
+			}
+		}
+		return r.s.updateTraining(ctx, tx, training)
+	})
+}
+
+func (r *TrainingRepository) Delete(ctx context.Context, id int) error {
+	return r.s.inTx(ctx, func(tx *sql.Tx) error {
+		if err := r.s.refundTrainings(ctx, tx, []int{id}); err != nil {
+			return err
+		}
+		res, err := tx.ExecContext(ctx, `DELETE FROM trainings WHERE id = $1`, id)
+		if err != nil {
+			return r.s.mapError(err)
+		}
+		return expectAffected(res)
+	})
+}
+
+func (r *TrainingRepository) List(ctx context.Context) ([]domain.Training, error) {
+	return r.s.queryTrainings(ctx, `SELECT `+trainingColumns+` FROM trainings t ORDER BY t.id`)
+}
+
+func (r *TrainingRepository) ListByTrainer(ctx context.Context, trainerID int) ([]domain.Training, error) {
+	return r.s.queryTrainings(ctx, `SELECT `+trainingColumns+` FROM trainings t WHERE t.trainer_id = $1 ORDER BY t.id`, trainerID)
+}
+
+func (r *TrainingRepository) Search(ctx context.Context, filter storage.TrainingFilter, after *storage.TrainingCursor, limit int) (storage.Page[domain.Training], error) {
+	var page storage.Page[domain.Training]
+	var w where
+	if filter.Type != "" {
+		w.add(`t.type = ` + w.arg(filter.Type))
+	}
+	if filter.Level != "" {
+		w.add(`t.level = ` + w.arg(filter.Level))
+	}
+	if filter.TrainerID != 0 {
+		w.add(`t.trainer_id = ` + w.arg(filter.TrainerID))
+	}
+	if filter.UserID != 0 {
+		w.add(`EXISTS (SELECT 1 FROM training_registrations r
+			WHERE r.training_id = t.id AND r.user_id = ` + w.arg(filter.UserID) + ` AND r.status = 'confirmed')`)
+	}
+	if filter.LocationID != 0 {
+		w.add(`t.room_id IN (SELECT id FROM rooms WHERE location_id = ` + w.arg(filter.LocationID) + `)`)
+	}
+	if !filter.From.IsZero() {
+		w.add(`t.start_time >= ` + w.arg(filter.From.UTC()))
+	}
+	if !filter.To.IsZero() {
+		w.add(`t.start_time < ` + w.arg(filter.To.UTC()))
+	}
+	if filter.FreeSpots {
+		w.add(`(t.capacity = 0 OR t.capacity > (SELECT COUNT(*) FROM training_registrations r
+			WHERE r.training_id = t.id AND r.status <> 'waitlisted'))`)
+	}
+	if filter.Search != "" {
+		pattern := "%" + likeEscaper.Replace(strings.ToLower(filter.Search)) + "%"
+		w.add(r.s.dialect.Lower + `(t.name) LIKE ` + w.arg(pattern) + ` ESCAPE '\'`)
+	}
+
+	err := r.s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM trainings t`+w.String(), w.args...).Scan(&page.Total)
+	if err != nil {
+		return page, err
+	}
+
+	column, direction, cmp := "t.start_time", "ASC", ">"
+	switch filter.Sort {
+	case storage.SortByStartTimeDesc:
+		direction, cmp = "DESC", "<"
+	case storage.SortByName:
+		column = "t.name"
+	case storage.SortByNameDesc:
+		column, direction, cmp = "t.name", "DESC", "<"
+	}
+	if after != nil {
+		var key any = after.StartTime.UTC()
+		if column == "t.name" {
+			key = after.Name
+		}
+		k, id := w.arg(key), w.arg(after.ID)
+		w.add(`(` + column + ` ` + cmp + ` ` + k + ` OR (` + column + ` = ` + k + ` AND t.id ` + cmp + ` ` + id + `))`)
+	}
+
+	// One more row than asked for tells whether another page follows
+	page.Items, err = r.s.queryTrainings(ctx,
+		`SELECT `+trainingColumns+` FROM trainings t`+w.String()+
+			` ORDER BY `+column+` `+direction+`, t.id `+direction+` LIMIT `+w.arg(limit+1),
+		w.args...,
+	)
+	if err != nil {
+		return page, err
+	}
+	if len(page.Items) > limit {
+		page.Items, page.More = page.Items[:limit], true
+	}
+	return page, nil
+}
+
+func (r *TrainingRepository) ListByUser(ctx context.Context, userID int) ([]domain.Training, error) {
+	return r.s.queryTrainings(ctx,
+		`SELECT `+trainingColumns+` FROM trainings t
+		JOIN training_registrations r ON r.training_id = t.id
+		WHERE r.user_id = $1 AND r.status = 'confirmed' ORDER BY t.id`,
+		userID,
+	)
+}
+
+func (r *TrainingRepository) RegisterUser(ctx context.Context, trainingID, userID int) (*domain.Registration, error) {
+	registration := domain.Registration{TrainingID: trainingID, UserID: userID}
+	err := r.s.inTx(ctx, func(tx *sql.Tx) error {
+		capacity, err := r.s.lockTraining(ctx, tx, trainingID)
+		if err != nil {
+			return err
+		}
+		seats, err := countSeats(ctx, tx, trainingID)
+		if err != nil {
+			return err
+		}
+		if err := r.s.lockUser(ctx, tx, userID); err != nil {
+			return err
+		}
+		conflicts, err := r.s.conflicts(ctx, tx, trainingID, userID, true)
+		if err != nil {
+			return err
+		}
+		if len(conflicts) > 0 {
+			return &storage.ScheduleConflictError{TrainingIDs: conflicts}
+		}
+
+		registration.Status = domain.RegistrationConfirmed
+		if capacity > 0 && seats >= capacity {
+			registration.Status = domain.RegistrationWaitlisted
+		}
+		now := time.Now()
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO training_registrations (training_id, user_id, status, registered_at) VALUES ($1, $2, $3, $4)`,
+			trainingID, userID, registration.Status, now.UTC(),
+		)
+		if err != nil {
+			return r.s.mapError(err)
+		}
+		if err := r.s.payRegistration(ctx, tx, &registration, now); err != nil {
+			return err
+		}
+
+		if registration.Status == domain.RegistrationWaitlisted {
+			registration.Position, err = waitlistPosition(ctx, tx, trainingID, userID)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &registration, nil
+}
+
+func (r *TrainingRepository) GetRegistration(ctx context.Context, trainingID, userID int) (*domain.Registration, error) {
+	registration := domain.Registration{TrainingID: trainingID, UserID: userID}
+	err := scanRegistration(r.s.db.QueryRowContext(ctx,
+		`SELECT `+registrationColumns+` FROM training_registrations WHERE training_id = $1 AND user_id = $2`,
+		trainingID, userID,
+	), &registration)
+	if err != nil {
+		return nil, r.s.mapError(err)
+	}
+
+	if registration.Status == domain.RegistrationWaitlisted {
+		if registration.Position, err = waitlistPosition(ctx, r.s.db, trainingID, userID); err != nil {
+			return nil, err
+		}
+	}
+	return &registration, nil
+}
+
+func (r *TrainingRepository) ListRegistrants(ctx context.Context, trainingID int) ([]int, error) {
+	rows, err := r.s.db.QueryContext(ctx,
+		`SELECT user_id FROM training_registrations WHERE training_id = $1 ORDER BY user_id`, trainingID,
+	)
+	if err != nil {
+		return nil, r.s.mapError(err)
+	}
+	defer rows.Close()
+
+	var userIDs []int
+	for rows.Next() {
+		var userID int
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, userID)
+	}
+	return userIDs, rows.Err()
+}
+
+func (r *TrainingRepository) CancelRegistration(ctx context.Context, trainingID, userID int, refundSeat bool) (*domain.Registration, []int, error) {
+	registration := domain.Registration{TrainingID: trainingID, UserID: userID}
+	var promoted []int
+	err := r.s.inTx(ctx, func(tx *sql.Tx) error {
+		capacity, err := r.s.lockTraining(ctx, tx, trainingID)
+		if err != nil {
+			return err
+		}
+
+		err = scanRegistration(tx.QueryRowContext(ctx,
+			`SELECT `+registrationColumns+` FROM training_registrations WHERE training_id = $1 AND user_id = $2`,
+			trainingID, userID,
+		), &registration)
+		if err != nil {
+			return r.s.mapError(err)
+		}
+		if registration.Status == domain.RegistrationWaitlisted {
+			if registration.Position, err = waitlistPosition(ctx, tx, trainingID, userID); err != nil {
+				return err
+			}
+		}
+
+		_, err = tx.ExecContext(ctx,
+			`DELETE FROM training_registrations WHERE training_id = $1 AND user_id = $2`,
+			trainingID, userID,
+		)
+		if err != nil {
+			return r.s.mapError(err)
+		}
+
+		now := time.Now()
+		if registration.Status == domain.RegistrationWaitlisted || refundSeat {
+			if err := r.s.refund(ctx, tx, registration, now); err != nil {
+				return err
+			}
+		}
+		if registration.Status == domain.RegistrationConfirmed && refundSeat && registration.PaymentID != nil {
+			if err := r.s.refundPayment(ctx, tx, *registration.PaymentID, now); err != nil {
+				return err
+			}
+		}
+		if registration.Status != domain.RegistrationWaitlisted {
+			promoted, err = r.s.promote(ctx, tx, trainingID, capacity)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return &registration, promoted, nil
+}
+
+const registrationColumns = `status, membership_id, credits, payment_id, held_at`
+
+func scanRegistration(row interface{ Scan(...any) error }, registration *domain.Registration) error {
+	var heldAt *time.Time
+	err := row.Scan(&registration.Status, &registration.MembershipID, &registration.Credits, &registration.PaymentID, &heldAt)
+	if heldAt != nil {
+		registration.HeldAt = *heldAt
+	}
+	return err
+}
+
+type querier interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// insertTraining stores a new training, an occurrence of a series keeps its
+// series reference
+func (s *store) insertTraining(ctx context.Context, q querier, training *domain.Training) error {
+	err := q.QueryRowContext(ctx,
+		`INSERT INTO trainings (name, type, level, trainer_id, capacity, start_time, end_time, room_id, series_id, recurrence_id, credits, price)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12) RETURNING id`,
+		training.Name, training.Type, training.Level, training.TrainerID, training.Capacity,
+		training.StartTime.UTC(), training.EndTime.UTC(), training.RoomID, training.SeriesID, utcOrNil(training.RecurrenceID),
+		training.Credits, training.Price,
+	).Scan(&training.ID)
+	return s.mapError(err)
+}
+
+// updateTraining stores the training, raising its sequence when the times
+// change, and promotes waitlisted users into seats added by a raised capacity.
+// The series reference is left to the series repository.
+func (s *store) updateTraining(ctx context.Context, tx *sql.Tx, training *domain.Training) error {
+	if _, err := s.lockTraining(ctx, tx, training.ID); err != nil {
+		return err
+	}
+	seats, err := countSeats(ctx, tx, training.ID)
+	if err != nil {
+		return err
+	}
+	if training.Capacity > 0 && seats > training.Capacity {
+		return storage.ErrCapacityBelowSeats
+	}
+
+	err = tx.QueryRowContext(ctx,
+		`UPDATE trainings SET name = $2, type = $3, level = $4, trainer_id = $5, capacity = $6, start_time = $7, end_time = $8,
+		room_id = $9, credits = $10, price = $11,
+		sequence = CASE WHEN start_time <> $7 OR end_time <> $8 THEN sequence + 1 ELSE sequence END
+		WHERE id = $1 RETURNING sequence`,
+		training.ID, training.Name, training.Type, training.Level, training.TrainerID, training.Capacity,
+		training.StartTime.UTC(), training.EndTime.UTC(), training.RoomID, training.Credits, training.Price,
+	).Scan(&training.Sequence)
+	if err != nil {
+		return s.mapError(err)
+	}
+	_, err = s.promote(ctx, tx, training.ID, training.Capacity)
+	return err
+}
+
+func utcOrNil(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return t.UTC()
+}
+
+// lockTraining locks the training against concurrent registration changes and
+// returns its capacity
+func (s *store) lockTraining(ctx context.Context, tx *sql.Tx, trainingID int) (int, error) {
+	var capacity int
+	err := tx.QueryRowContext(ctx, `SELECT capacity FROM trainings WHERE id = $1`+s.dialect.ForUpdate, trainingID).Scan(&capacity)
+	return capacity, s.mapError(err)
+}
+
+// lockUser locks the user row until the end of the transaction, which
+// serializes the registrations of the user
+func (s *store) lockUser(ctx context.Context, tx *sql.Tx, userID int) error {
+	var id int
+	err := tx.QueryRowContext(ctx, `SELECT id FROM users WHERE id = $1`+s.dialect.ForUpdate, userID).Scan(&id)
+	return s.mapError(err)
+}
+
+// lockTrainer locks the trainer row until the end of the transaction, which
+// serializes the changes to the schedule of the trainer
+func (s *store) lockTrainer(ctx context.Context, tx *sql.Tx, trainerID int) error {
+	var id int
+	err := tx.QueryRowContext(ctx, `SELECT id FROM trainers WHERE id = $1`+s.dialect.ForUpdate, trainerID).Scan(&id)
+	return s.mapError(err)
+}
+
+// lockRoom locks the room row until the end of the transaction, which
+// serializes the changes to the schedule of the room
+func (s *store) lockRoom(ctx context.Context, tx *sql.Tx, roomID int) error {
+	var id int
+	err := tx.QueryRowContext(ctx, `SELECT id FROM rooms WHERE id = $1`+s.dialect.ForUpdate, roomID).Scan(&id)
+	return s.mapError(err)
+}
+
+// checkOverlaps returns an OverlapError if any of the trainings overlaps other
+// trainings of its trainer or in its room. Stored trainings keeping their
+// trainer, room and times are not checked, the stored trainings with the IDs of
+// the trainings or in removed are being replaced and do not count. The trainers
+// and rooms are locked until the end of the transaction.
+func (s *store) checkOverlaps(ctx context.Context, tx *sql.Tx, trainings []domain.Training, removed []int) error {
+	// Locked in ID order, trainers before rooms, so concurrent changes cannot deadlock
+	var trainerIDs, roomIDs []int
+	for _, training := range trainings {
+		if !containsID(trainerIDs, training.TrainerID) {
+			trainerIDs = append(trainerIDs, training.TrainerID)
+		}
+		if training.RoomID != nil && !containsID(roomIDs, *training.RoomID) {
+			roomIDs = append(roomIDs, *training.RoomID)
+		}
+	}
+	sort.Ints(trainerIDs)
+	sort.Ints(roomIDs)
+	for _, id := range trainerIDs {
+		if err := s.lockTrainer(ctx, tx, id); err != nil {
+			return err
+		}
+	}
+	for _, id := range roomIDs {
+		if err := s.lockRoom(ctx, tx, id); err != nil {
+			return err
+		}
+	}
+
+	ignore := make(map[int]bool, len(trainings)+len(removed))
+	for _, training := range trainings {
+		ignore[training.ID] = true
+	}
+	for _, id := range removed {
+		ignore[id] = true
+	}
+	var overlap storage.OverlapError
+	add := func(ids *[]int, query string, args ...any) error {
+		found, err := queryIDs(ctx, tx, query, args...)
+		for _, id := range found {
+			if !ignore[id] && !containsID(*ids, id) {
+				*ids = append(*ids, id)
+			}
+		}
+		return err
+	}
+	for _, training := range trainings {
+		if training.ID != 0 {
+			var stored domain.Training
+			err := tx.QueryRowContext(ctx, `SELECT trainer_id, room_id, start_time, end_time FROM trainings WHERE id = $1`, training.ID).
+				Scan(&stored.TrainerID, &stored.RoomID, &stored.StartTime, &stored.EndTime)
+			if err != nil {
+				return s.mapError(err)
+			}
+			if stored.TrainerID == training.TrainerID && sameRoom(stored.RoomID, training.RoomID) &&
+				stored.StartTime.Equal(training.StartTime) && stored.EndTime.Equal(training.EndTime) {
+				continue
+			}
+		}
+		start, end := training.StartTime.UTC(), training.EndTime.UTC()
+		err := add(&overlap.TrainerTrainingIDs,
+			`SELECT id FROM trainings WHERE trainer_id = $1 AND start_time < $3 AND end_time > $2`,
+			training.TrainerID, start, end,
+		)
+		if err != nil {
+			return err
+		}
+		if training.RoomID == nil {
+			continue
+		}
+		err = add(&overlap.RoomTrainingIDs,
+			`SELECT id FROM trainings WHERE room_id = $1 AND start_time < $3 AND end_time > $2`,
+			*training.RoomID, start, end,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	if len(overlap.TrainerTrainingIDs) == 0 && len(overlap.RoomTrainingIDs) == 0 {
+		return nil
+	}
+	sort.Ints(overlap.TrainerTrainingIDs)
+	sort.Ints(overlap.RoomTrainingIDs)
+	return &overlap
+}
+
+func sameRoom(a, b *int) bool {
+	return a == nil && b == nil || a != nil && b != nil && *a == *b
+}
+
+func containsID(ids []int, id int) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+
+// conflicts returns the IDs of the trainings overlapping the training, or closer
+// to it than the registration buffer, which the user has a seat or a held seat
+// in, or with waitlisted also a waitlist place. The user must be locked by the
+// transaction.
+func (s *store) conflicts(ctx context.Context, tx *sql.Tx, trainingID, userID int, waitlisted bool) ([]int, error) {
+	var start, end time.Time
+	err := tx.QueryRowContext(ctx, `SELECT start_time, end_time FROM trainings WHERE id = $1`, trainingID).Scan(&start, &end)
+	if err != nil {
+		return nil, s.mapError(err)
+	}
+	buffer := s.options.RegistrationBuffer
+	return queryIDs(ctx, tx,
+		`SELECT t.id FROM trainings t JOIN training_registrations r ON r.training_id = t.id
+		WHERE r.user_id = $1 AND t.id <> $2 AND (r.status <> 'waitlisted' OR $3)
+			AND t.end_time > $4 AND t.start_time < $5
+		ORDER BY t.id`,
+		userID, trainingID, waitlisted, start.Add(-buffer).UTC(), end.Add(buffer).UTC(),
+	)
+}
+
+// queryIDs returns the IDs selected by the query, the rows are closed before it
+// returns so the transaction can go on
+func queryIDs(ctx context.Context, tx *sql.Tx, query string, args ...any) ([]int, error) {
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// countSeats counts the confirmed seats of the training and those held pending payment
+func countSeats(ctx context.Context, q querier, trainingID int) (int, error) {
+	var n int
+	err := q.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM training_registrations WHERE training_id = $1 AND status <> 'waitlisted'`,
+		trainingID,
+	).Scan(&n)
+	return n, err
+}
+
+// waitlistPosition returns the place of the user on the waitlist, which is
+// ordered by registration time
+func waitlistPosition(ctx context.Context, q querier, trainingID, userID int) (int, error) {
+	var position int
+	err := q.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM training_registrations w
+		JOIN training_registrations me ON me.training_id = w.training_id AND me.user_id = $2
+		WHERE w.training_id = $1 AND w.status = 'waitlisted'
+		AND (w.registered_at < me.registered_at OR (w.registered_at = me.registered_at AND w.user_id <= me.user_id))`,
+		trainingID, userID,
+	).Scan(&position)
+	return position, err
+}
+
+// promote moves the first waitlisted users into the free seats of the training,
+// seats of a paid training are held for users who have not paid it yet. Users
+// with a seat in a conflicting training are passed over. The training must be
+// locked by the transaction.
+func (s *store) promote(ctx context.Context, tx *sql.Tx, trainingID, capacity int) ([]int, error) {
+	seats, err := countSeats(ctx, tx, trainingID)
+	if err != nil {
+		return nil, err
+	}
+	training := domain.Training{ID: trainingID}
+	err = tx.QueryRowContext(ctx,
+		`SELECT name, start_time, end_time, sequence, price FROM trainings WHERE id = $1`, trainingID,
+	).Scan(&training.Name, &training.StartTime, &training.EndTime, &training.Sequence, &training.Price)
+	if err != nil {
+		return nil, s.mapError(err)
+	}
+
+	waitlist, err := queryIDs(ctx, tx,
+		`SELECT user_id FROM training_registrations
+		WHERE training_id = $1 AND status = 'waitlisted'
+		ORDER BY registered_at, user_id`,
+		trainingID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var promoted []int
+	for _, userID := range waitlist {
+		if capacity > 0 && seats >= capacity {
+			break
+		}
+		if err := s.lockUser(ctx, tx, userID); err != nil {
+			return nil, err
+		}
+		conflicts, err := s.conflicts(ctx, tx, trainingID, userID, false)
+		if err != nil {
+			return nil, err
+		}
+		if len(conflicts) > 0 {
+			continue
+		}
+		_, err = tx.ExecContext(ctx,
+			`UPDATE training_registrations SET status = 'confirmed' WHERE training_id = $1 AND user_id = $2`,
+			trainingID, userID,
+		)
+		if err != nil {
+			return nil, s.mapError(err)
+		}
+		if training.Price > 0 {
+			_, err = tx.ExecContext(ctx,
+				`UPDATE training_registrations SET status = 'pending_payment', held_at = $3
+				WHERE training_id = $1 AND user_id = $2 AND membership_id IS NULL`,
+				trainingID, userID, now.UTC(),
+			)
+			if err != nil {
+				return nil, s.mapError(err)
+			}
+		}
+		promoted = append(promoted, userID)
+		seats++
+	}
+	for _, userID := range promoted {
+		if err := s.enqueue(ctx, tx, training.Notification(domain.NotificationPromoted, userID, now)); err != nil {
+			return nil, err
+		}
+	}
+	return promoted, nil
+}
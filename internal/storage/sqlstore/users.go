@@ -0,0 +1,147 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/folklinoff/fitness-app/internal/domain"
+	"github.com/folklinoff/fitness-app/internal/storage"
+)
+
+const userColumns = `id, name, password, mail, phone, health_description`
+
+// UserRepository stores users in a SQL database
+type UserRepository struct {
+	s *store
+}
+
+func (s *store) scanUser(row interface{ Scan(...any) error }) (*domain.User, error) {
+	var user domain.User
+	err := row.Scan(&user.ID, &user.Name, &user.Password, &user.Mail, &user.Phone, &user.HealthDescription)
+	if err != nil {
+		return nil, s.mapError(err)
+	}
+	return &user, nil
+}
+
+func (r *UserRepository) Create(ctx context.Context, user *domain.User) error {
+	err := r.s.db.QueryRowContext(ctx,
+		`INSERT INTO users (name, password, mail, phone, health_description)
+		VALUES ($1, $2, $3, $4, $5) RETURNING id`,
+		user.Name, user.Password, user.Mail, user.Phone, user.HealthDescription,
+	).Scan(&user.ID)
+	return r.s.mapError(err)
+}
+
+func (r *UserRepository) GetByID(ctx context.Context, id int) (*domain.User, error) {
+	return r.s.scanUser(r.s.db.QueryRowContext(ctx, `SELECT `+userColumns+` FROM users WHERE id = $1`, id))
+}
+
+func (r *UserRepository) GetByName(ctx context.Context, name string) (*domain.User, error) {
+	return r.s.scanUser(r.s.db.QueryRowContext(ctx, `SELECT `+userColumns+` FROM users WHERE name = $1`, name))
+}
+
+func (r *UserRepository) Update(ctx context.Context, user *domain.User) error {
+	res, err := r.s.db.ExecContext(ctx,
+		`UPDATE users SET name = $2, password = $3, mail = $4, phone = $5, health_description = $6
+		WHERE id = $1`,
+		user.ID, user.Name, user.Password, user.Mail, user.Phone, user.HealthDescription,
+	)
+	if err != nil {
+		return r.s.mapError(err)
+	}
+	return expectAffected(res)
+}
+
+func (r *UserRepository) Delete(ctx context.Context, id int) error {
+	return r.s.inTx(ctx, func(tx *sql.Tx) error {
+		// The seats of the user are freed by the cascade, so the trainings are
+		// collected and locked, in ID order, before the user row is deleted
+		rows, err := tx.QueryContext(ctx,
+			`SELECT training_id FROM training_registrations WHERE user_id = $1 AND status <> 'waitlisted' ORDER BY training_id`,
+			id,
+		)
+		if err != nil {
+			return r.s.mapError(err)
+		}
+		var trainingIDs []int
+		for rows.Next() {
+			var trainingID int
+			if err := rows.Scan(&trainingID); err != nil {
+				rows.Close()
+				return err
+			}
+			trainingIDs = append(trainingIDs, trainingID)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		capacities := make([]int, len(trainingIDs))
+		for i, trainingID := range trainingIDs {
+			if capacities[i], err = r.s.lockTraining(ctx, tx, trainingID); err != nil {
+				return err
+			}
+		}
+
+		res, err := tx.ExecContext(ctx, `DELETE FROM users WHERE id = $1`, id)
+		if err != nil {
+			return r.s.mapError(err)
+		}
+		if err := expectAffected(res); err != nil {
+			return err
+		}
+
+		for i, trainingID := range trainingIDs {
+			if _, err := r.s.promote(ctx, tx, trainingID, capacities[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *UserRepository) ListByTraining(ctx context.Context, trainingID, afterID, limit int) (storage.Page[domain.User], error) {
+	var page storage.Page[domain.User]
+	var exists bool
+	if err := r.s.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM trainings WHERE id = $1)`, trainingID).Scan(&exists); err != nil {
+		return page, err
+	}
+	if !exists {
+		return page, storage.ErrNotFound
+	}
+
+	err := r.s.db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM training_registrations WHERE training_id = $1 AND status = 'confirmed'`,
+		trainingID,
+	).Scan(&page.Total)
+	if err != nil {
+		return page, err
+	}
+
+	// One more row than asked for tells whether another page follows
+	rows, err := r.s.db.QueryContext(ctx,
+		`SELECT u.id, u.name, u.password, u.mail, u.phone, u.health_description
+		FROM users u JOIN training_registrations r ON r.user_id = u.id
+		WHERE r.training_id = $1 AND r.status = 'confirmed' AND u.id > $2
+		ORDER BY u.id LIMIT $3`,
+		trainingID, afterID, limit+1,
+	)
+	if err != nil {
+		return page, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		user, err := r.s.scanUser(rows)
+		if err != nil {
+			return page, err
+		}
+		page.Items = append(page.Items, *user)
+	}
+	if len(page.Items) > limit {
+		page.Items, page.More = page.Items[:limit], true
+	}
+	return page, rows.Err()
+}
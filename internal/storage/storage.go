@@ -0,0 +1,400 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/folklinoff/fitness-app/internal/domain"
+	"golang.org/x/xerrors"
+)
+
+var (
+	// ErrNotFound is returned when the requested entity does not exist
+	ErrNotFound = xerrors.New("not found")
+	// ErrAlreadyExists is returned when the entity or relation is already stored
+	ErrAlreadyExists = xerrors.New("already exists")
+	// ErrNoCredits is returned when no membership of the user can pay a registration
+	ErrNoCredits = xerrors.New("no membership credits")
+	// ErrCapacityBelowSeats is returned when a training is given fewer seats than
+	// are confirmed or held in it
+	ErrCapacityBelowSeats = xerrors.New("capacity below the taken seats")
+	// ErrScheduleConflict is matched by a ScheduleConflictError
+	ErrScheduleConflict = xerrors.New("schedule conflict")
+	// ErrOverlap is matched by an OverlapError
+	ErrOverlap = xerrors.New("overlapping trainings")
+)
+
+// ScheduleConflictError is returned when registering a user for a training which
+// overlaps, or is closer than the registration buffer to, trainings the user
+// has a seat, a held seat or a waitlist place in
+type ScheduleConflictError struct {
+	TrainingIDs []int
+}
+
+func (e *ScheduleConflictError) Error() string {
+	return fmt.Sprintf("schedule conflict with trainings %v", e.TrainingIDs)
+}
+
+func (e *ScheduleConflictError) Is(target error) bool {
+	return target == ErrScheduleConflict
+}
+
+// OverlapError is returned when stored trainings would overlap other trainings
+// of their trainer or other trainings in their room and overlapping is not
+// allowed
+type OverlapError struct {
+	// TrainerTrainingIDs are the overlapped trainings of the trainer
+	TrainerTrainingIDs []int
+	// RoomTrainingIDs are the overlapped trainings in the room
+	RoomTrainingIDs []int
+}
+
+func (e *OverlapError) Error() string {
+	return fmt.Sprintf("overlapping trainings %v of the trainer and %v in the room", e.TrainerTrainingIDs, e.RoomTrainingIDs)
+}
+
+func (e *OverlapError) Is(target error) bool {
+	return target == ErrOverlap
+}
+
+// Options are the booking rules enforced by a backend
+type Options struct {
+	// RegistrationBuffer is the minimum time between two trainings a user is
+	// registered for, zero only keeps them from overlapping
+	RegistrationBuffer time.Duration
+}
+
+// UserRepository stores users
+type UserRepository interface {
+	Create(ctx context.Context, user *domain.User) error
+	GetByID(ctx context.Context, id int) (*domain.User, error)
+	GetByName(ctx context.Context, name string) (*domain.User, error)
+	Update(ctx context.Context, user *domain.User) error
+	Delete(ctx context.Context, id int) error
+	// ListByTraining returns up to limit of the users with a confirmed seat in the
+	// training ordered by ID, starting after the user with ID afterID
+	ListByTraining(ctx context.Context, trainingID, afterID, limit int) (Page[domain.User], error)
+}
+
+// TrainerRepository stores trainers
+type TrainerRepository interface {
+	Create(ctx context.Context, trainer *domain.Trainer) error
+	GetByID(ctx context.Context, id int) (*domain.Trainer, error)
+	GetByName(ctx context.Context, name string) (*domain.Trainer, error)
+	Update(ctx context.Context, trainer *domain.Trainer) error
+	// Delete removes the trainer with their trainings, which are refunded like
+	// deleted trainings
+	Delete(ctx context.Context, id int) error
+}
+
+// AdminRepository stores admins, they cannot register themselves so there is no
+// public way to create one
+type AdminRepository interface {
+	Create(ctx context.Context, admin *domain.Admin) error
+	GetByID(ctx context.Context, id int) (*domain.Admin, error)
+	GetByName(ctx context.Context, name string) (*domain.Admin, error)
+	Update(ctx context.Context, admin *domain.Admin) error
+}
+
+// TrainingRepository stores trainings and user registrations for them.
+// Registrations beyond the capacity of a training go to its waitlist; whenever a
+// seat becomes free, because of a cancellation, a deleted user or a raised
+// capacity, the first waitlisted users are promoted. Waitlisted users with a
+// seat or a held seat in a conflicting training, for example because one of the
+// trainings was moved, keep their place and are passed over. Users promoted
+// into a seat of a paid training they have not paid get it held pending
+// payment, promoted users are notified. Deleting a training that has not started refunds the
+// credits and payments made for its registrations.
+type TrainingRepository interface {
+	// Create stores the training. Unless allowOverlap is set an OverlapError is
+	// returned if it overlaps other trainings of its trainer or in its room,
+	// Update and the series repository do the same.
+	Create(ctx context.Context, training *domain.Training, allowOverlap bool) error
+	GetByID(ctx context.Context, id int) (*domain.Training, error)
+	// Update stores the training and promotes waitlisted users into seats added
+	// by a raised capacity. The sequence is raised when the times change.
+	// ErrCapacityBelowSeats is returned if the capacity is lowered below the
+	// seats taken.
+	Update(ctx context.Context, training *domain.Training, allowOverlap bool) error
+	Delete(ctx context.Context, id int) error
+	List(ctx context.Context) ([]domain.Training, error)
+	ListByTrainer(ctx context.Context, trainerID int) ([]domain.Training, error)
+	// Search returns up to limit of the trainings selected by the filter in its
+	// sort order, starting after the cursor if one is given
+	Search(ctx context.Context, filter TrainingFilter, after *TrainingCursor, limit int) (Page[domain.Training], error)
+	// ListByUser returns the trainings the user has a confirmed seat in
+	ListByUser(ctx context.Context, userID int) ([]domain.Training, error)
+	// RegisterUser gives the user a seat in the training or, when it is full, puts
+	// the user on its waitlist. ErrAlreadyExists is returned if the user is
+	// already registered or waitlisted, a ScheduleConflictError if the user is
+	// registered or waitlisted for conflicting trainings. The cost of the training is paid from a
+	// membership of the user covering it, unlimited ones first and then the one
+	// expiring first. Without one the seat of a paid training is held pending
+	// payment, ErrNoCredits is returned for other trainings.
+	RegisterUser(ctx context.Context, trainingID, userID int) (*domain.Registration, error)
+	// GetRegistration returns the registration of the user with the waitlist position
+	GetRegistration(ctx context.Context, trainingID, userID int) (*domain.Registration, error)
+	// ListRegistrants returns the IDs of the users with a seat, a held seat or a
+	// waitlist place in the training, ordered by ID
+	ListRegistrants(ctx context.Context, trainingID int) ([]int, error)
+	// CancelRegistration removes the registration or waitlist entry of the user.
+	// It returns the removed registration and the IDs of the users promoted into
+	// the freed seat. The credits paid for a waitlist entry are refunded, those
+	// paid for a seat, and its payment, only with refundSeat.
+	CancelRegistration(ctx context.Context, trainingID, userID int, refundSeat bool) (*domain.Registration, []int, error)
+}
+
+// TrainingSort orders searched trainings, ties are broken by ID in the same direction
+type TrainingSort string
+
+const (
+	SortByStartTime     TrainingSort = "start_time"
+	SortByStartTimeDesc TrainingSort = "-start_time"
+	SortByName          TrainingSort = "name"
+	SortByNameDesc      TrainingSort = "-name"
+)
+
+// TrainingFilter selects and orders trainings, zero fields do not restrict the
+// result
+type TrainingFilter struct {
+	Type      string
+	Level     string
+	TrainerID int
+	// UserID selects the trainings the user has a confirmed seat in
+	UserID     int
+	LocationID int
+	// From and To bound the start time to [From, To)
+	From time.Time
+	To   time.Time
+	// FreeSpots selects the trainings with unlimited capacity or free seats
+	FreeSpots bool
+	// Search selects the trainings whose name contains it, ignoring case
+	Search string
+	// Sort defaults to SortByStartTime
+	Sort TrainingSort
+}
+
+// TrainingCursor is the position of the last training of a page, only the fields
+// of the sort key and the ID are used
+type TrainingCursor struct {
+	StartTime time.Time
+	Name      string
+	ID        int
+}
+
+// Page is a part of a listing, Total counts the items of the whole listing and
+// More reports whether items follow the page
+type Page[T any] struct {
+	Items []T
+	Total int
+	More  bool
+}
+
+// LocationRepository stores locations with their opening hours
+type LocationRepository interface {
+	// Create stores the location, ErrAlreadyExists is returned if the name is taken
+	Create(ctx context.Context, location *domain.Location) error
+	GetByID(ctx context.Context, id int) (*domain.Location, error)
+	List(ctx context.Context) ([]domain.Location, error)
+	// Update stores the location and replaces its opening hours
+	Update(ctx context.Context, location *domain.Location) error
+	// Delete removes the location with its rooms, trainings in them are kept
+	// without a room
+	Delete(ctx context.Context, id int) error
+}
+
+// RoomRepository stores the rooms of locations
+type RoomRepository interface {
+	// Create stores the room, ErrNotFound is returned if the location does not
+	// exist and ErrAlreadyExists if it has a room with the same name
+	Create(ctx context.Context, room *domain.Room) error
+	GetByID(ctx context.Context, id int) (*domain.Room, error)
+	ListByLocation(ctx context.Context, locationID int) ([]domain.Room, error)
+	Update(ctx context.Context, room *domain.Room) error
+	// Delete removes the room, trainings in it are kept without a room
+	Delete(ctx context.Context, id int) error
+}
+
+// SeriesRepository stores recurring trainings. Occurrences are changed together
+// with their series in one transaction, seats added to an occurrence by a raised
+// capacity are given to its waitlisted users and its sequence is raised when its
+// times change. Removed occurrences are deleted like trainings.
+type SeriesRepository interface {
+	// Create stores the series with its occurrences and sets their IDs
+	Create(ctx context.Context, series *domain.TrainingSeries, occurrences []domain.Training, allowOverlap bool) error
+	GetByID(ctx context.Context, id int) (*domain.TrainingSeries, error)
+	// ListOccurrences returns the trainings of the series ordered by start time
+	ListOccurrences(ctx context.Context, seriesID int) ([]domain.Training, error)
+	// Update stores the series, the changed occurrences and deletes the removed
+	// ones. ErrCapacityBelowSeats is returned if the capacity of an occurrence is
+	// lowered below its seats taken, Split does the same.
+	Update(ctx context.Context, series *domain.TrainingSeries, changed []domain.Training, removed []int, allowOverlap bool) error
+	// Split stores the truncated series and creates next, which takes over the
+	// moved occurrences
+	Split(ctx context.Context, series, next *domain.TrainingSeries, moved []domain.Training, allowOverlap bool) error
+	// Delete removes the series with the removed occurrences, its other
+	// occurrences are kept as standalone trainings
+	Delete(ctx context.Context, id int, removed []int) error
+}
+
+// IncidentRepository stores late cancellations and no-shows of users
+type IncidentRepository interface {
+	// Record stores the incident, ErrAlreadyExists is returned if the user already
+	// has an incident of the kind for the training
+	Record(ctx context.Context, incident *domain.Incident) error
+	ListByUser(ctx context.Context, userID int) ([]domain.Incident, error)
+	ListByTraining(ctx context.Context, trainingID int) ([]domain.Incident, error)
+}
+
+// PlanRepository stores membership plans
+type PlanRepository interface {
+	// Create stores the plan, ErrAlreadyExists is returned if the name is taken
+	Create(ctx context.Context, plan *domain.Plan) error
+	GetByID(ctx context.Context, id int) (*domain.Plan, error)
+	// List returns the plans ordered by ID, only the active ones with activeOnly
+	List(ctx context.Context, activeOnly bool) ([]domain.Plan, error)
+	Update(ctx context.Context, plan *domain.Plan) error
+}
+
+// MembershipRepository stores the memberships of users and the ledger of their
+// credits. Credits are consumed and refunded by the training repository.
+type MembershipRepository interface {
+	// Grant stores the membership together with its grant ledger entry
+	Grant(ctx context.Context, membership *domain.Membership) error
+	// ListByUser returns the memberships of the user ordered by ID
+	ListByUser(ctx context.Context, userID int) ([]domain.Membership, error)
+	// Ledger returns the ledger entries of the user ordered by ID
+	Ledger(ctx context.Context, userID int) ([]domain.LedgerEntry, error)
+}
+
+// PaymentRepository stores the payments made through the payment gateway.
+// Gateway events are applied at most once: Settle and Fail return
+// ErrAlreadyExists for an event ID they have already seen.
+type PaymentRepository interface {
+	// Create stores a pending payment
+	Create(ctx context.Context, payment *domain.Payment) error
+	// SetSession stores the checkout session of the payment
+	SetSession(ctx context.Context, id int, sessionID string) error
+	GetByID(ctx context.Context, id int) (*domain.Payment, error)
+	GetBySession(ctx context.Context, sessionID string) (*domain.Payment, error)
+	// ListByUser returns the payments of the user ordered by ID
+	ListByUser(ctx context.Context, userID int) ([]domain.Payment, error)
+	// Settle records the captured payment with the given reference. The seat
+	// held for the user in the paid training is confirmed, or the membership
+	// is granted for a plan. A payment whose seat is no longer held is due for
+	// refund. Payments which are already settled are left unchanged.
+	Settle(ctx context.Context, eventID string, paymentID int, reference string, membership *domain.Membership) (*domain.Payment, error)
+	// Fail records that the pending payment expired or could not be captured,
+	// the seat it was for stays held until ReleaseHolds
+	Fail(ctx context.Context, eventID string, paymentID int) (*domain.Payment, error)
+	// ReleaseHolds removes the registrations pending payment since before
+	// heldBefore, promoting waitlisted users into their seats, and returns how
+	// many were removed
+	ReleaseHolds(ctx context.Context, heldBefore time.Time) (int, error)
+	// ListRefundsDue returns the payments due for refund ordered by ID
+	ListRefundsDue(ctx context.Context) ([]domain.Payment, error)
+	// StartRefund records that the refund of the payment due for refund is sent
+	// to the gateway, so a refund whose result was not recorded is not repeated
+	StartRefund(ctx context.Context, id int) error
+	// MarkRefunded records that the payment due for refund was refunded
+	MarkRefunded(ctx context.Context, id int) error
+}
+
+// NotificationRepository is the outbox of the notifications sent to users, every
+// notification is queued once for each of domain.NotificationChannels. Users
+// promoted from a waitlist are queued a notification by the training repository
+// together with their seat.
+type NotificationRepository interface {
+	// Enqueue queues the pending notifications, their channel is ignored
+	Enqueue(ctx context.Context, notifications []domain.Notification) error
+	// EnqueueReminders queues a reminder for every confirmed seat in the trainings
+	// starting in [from, to) which was not reminded of the current times of its
+	// training yet, and returns the number of seats reminded
+	EnqueueReminders(ctx context.Context, from, to time.Time) (int, error)
+	// ListPending returns up to limit of the pending notifications, oldest first
+	ListPending(ctx context.Context, limit int) ([]domain.Notification, error)
+	// Finish records that the pending notification was sent, skipped or failed
+	Finish(ctx context.Context, id int, status domain.NotificationStatus, reason string, at time.Time) error
+	// Retry records a failed attempt to send the pending notification
+	Retry(ctx context.Context, id int, reason string) error
+}
+
+// AttendanceRepository stores the attendance of trainings and their check-in codes
+type AttendanceRepository interface {
+	// Mark stores the attendance and removes a no-show of the user recorded for
+	// the training. ErrNotFound is returned if the user has no seat in the
+	// training and ErrAlreadyExists if the attendance is already taken.
+	Mark(ctx context.Context, attendance *domain.Attendance) error
+	// Unmark removes the attendance, ErrNotFound is returned if there is none
+	Unmark(ctx context.Context, trainingID, userID int) error
+	Get(ctx context.Context, trainingID, userID int) (*domain.Attendance, error)
+	// ListByTraining returns the attendance of the training ordered by user ID
+	ListByTraining(ctx context.Context, trainingID int) ([]domain.Attendance, error)
+	// ReplaceCheckInCode stores the code in place of the current code of its training
+	ReplaceCheckInCode(ctx context.Context, code *domain.CheckInCode) error
+	GetCheckInCode(ctx context.Context, trainingID int) (*domain.CheckInCode, error)
+	// AddCheckInAttempt counts an attempt of the user to check in to the training
+	// with a code and returns the number of attempts including this one
+	AddCheckInAttempt(ctx context.Context, trainingID, userID int) (int, error)
+	// RecordNoShows records a no-show, at the end of the training, for every user
+	// with a seat but no attendance in the trainings which ended by the given
+	// time. Every training is processed once, it returns the number of recorded
+	// no-shows.
+	RecordNoShows(ctx context.Context, endedBy time.Time) (int, error)
+}
+
+// SessionRepository stores refresh tokens and revoked access tokens
+type SessionRepository interface {
+	CreateRefreshToken(ctx context.Context, token *domain.RefreshToken) error
+	GetRefreshToken(ctx context.Context, hash string) (*domain.RefreshToken, error)
+	// UseRefreshToken marks an unused and unrevoked token as used, ErrNotFound is
+	// returned if there is no such token, so a token can be used only once
+	UseRefreshToken(ctx context.Context, hash string, at time.Time) error
+	RevokeRefreshTokenFamily(ctx context.Context, familyID string, at time.Time) error
+	// RevokeAccount revokes all refresh tokens of the account and every access
+	// token issued to it up to the given time. Times are kept to the microsecond,
+	// like the issue times of access tokens.
+	RevokeAccount(ctx context.Context, accountType string, accountID int, at time.Time) error
+	// RevokeAccessToken denies the access token until it expires
+	RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) error
+	// IsAccessTokenRevoked reports whether the token was revoked by its jti or by
+	// revoking its account when or after it was issued
+	IsAccessTokenRevoked(ctx context.Context, jti, accountType string, accountID int, issuedAt time.Time) (bool, error)
+}
+
+// CalendarFeedRepository stores the calendar feeds of accounts
+type CalendarFeedRepository interface {
+	// Replace stores the feed in place of the current feed of its account
+	Replace(ctx context.Context, feed *domain.CalendarFeed) error
+	GetByTokenHash(ctx context.Context, hash string) (*domain.CalendarFeed, error)
+	// Delete removes the feed of the account, ErrNotFound is returned if it has none
+	Delete(ctx context.Context, accountType string, accountID int) error
+}
+
+// AuditRepository stores audit events
+type AuditRepository interface {
+	Record(ctx context.Context, event *domain.AuditEvent) error
+	// List returns up to limit of the most recent events, newest first
+	List(ctx context.Context, limit int) ([]domain.AuditEvent, error)
+}
+
+// Storage groups the repositories of a single backend
+type Storage struct {
+	Users         UserRepository
+	Trainers      TrainerRepository
+	Admins        AdminRepository
+	Trainings     TrainingRepository
+	Series        SeriesRepository
+	Locations     LocationRepository
+	Rooms         RoomRepository
+	Sessions      SessionRepository
+	Audit         AuditRepository
+	Incidents     IncidentRepository
+	Plans         PlanRepository
+	Memberships   MembershipRepository
+	Payments      PaymentRepository
+	Notifications NotificationRepository
+	Attendance    AttendanceRepository
+	Calendars     CalendarFeedRepository
+}
@@ -0,0 +1,821 @@
+// Package storagetest is the contract every storage backend has to satisfy, run
+// by the tests of the memory, SQLite and PostgreSQL backends.
+package storagetest
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/folklinoff/fitness-app/internal/domain"
+	"github.com/folklinoff/fitness-app/internal/storage"
+	"golang.org/x/xerrors"
+)
+
+// Open returns an empty storage with the options which is released when the
+// test ends
+type Open func(t *testing.T, options storage.Options) storage.Storage
+
+// buffer is the registration buffer of the storages under test
+const buffer = 30 * time.Minute
+
+// Run runs the contract against storages returned by open, each subtest gets a
+// new one
+func Run(t *testing.T, open Open) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, store storage.Storage)
+	}{
+		{"Users", testUsers},
+		{"Trainings", testTrainings},
+		{"Registrations", testRegistrations},
+		{"RegisterWithoutCredits", testRegisterWithoutCredits},
+		{"CapacityBelowSeats", testCapacityBelowSeats},
+		{"ConcurrentCreateTraining", testConcurrentCreateTraining},
+		{"ConcurrentRegisterUser", testConcurrentRegisterUser},
+		{"ScheduleConflicts", testScheduleConflicts},
+		{"PromotionConflicts", testPromotionConflicts},
+		{"ConcurrentConflictingRegistrations", testConcurrentConflictingRegistrations},
+		{"TrainerOverlaps", testTrainerOverlaps},
+		{"ConcurrentOverlappingTrainings", testConcurrentOverlappingTrainings},
+		{"RoomOverlaps", testRoomOverlaps},
+		{"ConcurrentRoomBookings", testConcurrentRoomBookings},
+		{"RevokeAccount", testRevokeAccount},
+		{"Refunds", testRefunds},
+		{"CheckInAttempts", testCheckInAttempts},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, open(t, storage.Options{RegistrationBuffer: buffer}))
+		})
+	}
+}
+
+func testUsers(t *testing.T, store storage.Storage) {
+	ctx := context.Background()
+	user := domain.User{Name: "alice", Password: "hash", Mail: "alice@example.com", Phone: "+100"}
+	if err := store.Users.Create(ctx, &user); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	if user.ID == 0 {
+		t.Fatal("created user has no ID")
+	}
+
+	got, err := store.Users.GetByID(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("get user: %v", err)
+	}
+	if *got != user {
+		t.Errorf("got user %+v, want %+v", *got, user)
+	}
+	if got, err = store.Users.GetByName(ctx, "alice"); err != nil || got.ID != user.ID {
+		t.Errorf("get user by name: %+v, %v", got, err)
+	}
+
+	duplicate := domain.User{Name: "alice", Password: "hash"}
+	if err := store.Users.Create(ctx, &duplicate); !xerrors.Is(err, storage.ErrAlreadyExists) {
+		t.Errorf("create user with a taken name: got %v, want ErrAlreadyExists", err)
+	}
+
+	user.Phone = "+200"
+	if err := store.Users.Update(ctx, &user); err != nil {
+		t.Fatalf("update user: %v", err)
+	}
+	if got, err = store.Users.GetByID(ctx, user.ID); err != nil || got.Phone != "+200" {
+		t.Errorf("updated user: %+v, %v", got, err)
+	}
+
+	if err := store.Users.Delete(ctx, user.ID); err != nil {
+		t.Fatalf("delete user: %v", err)
+	}
+	if _, err := store.Users.GetByID(ctx, user.ID); !xerrors.Is(err, storage.ErrNotFound) {
+		t.Errorf("get deleted user: got %v, want ErrNotFound", err)
+	}
+}
+
+func testTrainings(t *testing.T, store storage.Storage) {
+	ctx := context.Background()
+	training := newTraining(t, store, createTrainer(t, store), 10)
+
+	got, err := store.Trainings.GetByID(ctx, training.ID)
+	if err != nil {
+		t.Fatalf("get training: %v", err)
+	}
+	if got.Name != training.Name || got.Capacity != 10 || !got.StartTime.Equal(training.StartTime) || !got.EndTime.Equal(training.EndTime) {
+		t.Errorf("got training %+v, want %+v", *got, training)
+	}
+
+	training.Name = "Evening yoga"
+	training.StartTime = training.StartTime.Add(time.Hour)
+	training.EndTime = training.EndTime.Add(time.Hour)
+	if err := store.Trainings.Update(ctx, &training, false); err != nil {
+		t.Fatalf("update training: %v", err)
+	}
+	if got, err = store.Trainings.GetByID(ctx, training.ID); err != nil {
+		t.Fatalf("get updated training: %v", err)
+	}
+	if got.Name != "Evening yoga" || !got.StartTime.Equal(training.StartTime) || got.Sequence != 1 {
+		t.Errorf("updated training %+v, want the new name and start time and sequence 1", *got)
+	}
+
+	if err := store.Trainings.Delete(ctx, training.ID); err != nil {
+		t.Fatalf("delete training: %v", err)
+	}
+	if _, err := store.Trainings.GetByID(ctx, training.ID); !xerrors.Is(err, storage.ErrNotFound) {
+		t.Errorf("get deleted training: got %v, want ErrNotFound", err)
+	}
+}
+
+func testRegistrations(t *testing.T, store storage.Storage) {
+	ctx := context.Background()
+	training := newTraining(t, store, createTrainer(t, store), 2)
+	users := createUsers(t, store, 4)
+
+	want := []domain.RegistrationStatus{domain.RegistrationConfirmed, domain.RegistrationConfirmed, domain.RegistrationWaitlisted, domain.RegistrationWaitlisted}
+	for i, userID := range users {
+		registration, err := store.Trainings.RegisterUser(ctx, training.ID, userID)
+		if err != nil {
+			t.Fatalf("register user %d: %v", userID, err)
+		}
+		if registration.Status != want[i] {
+			t.Errorf("user %d got status %s, want %s", userID, registration.Status, want[i])
+		}
+	}
+	if _, err := store.Trainings.RegisterUser(ctx, training.ID, users[0]); !xerrors.Is(err, storage.ErrAlreadyExists) {
+		t.Errorf("register twice: got %v, want ErrAlreadyExists", err)
+	}
+	if _, err := store.Trainings.RegisterUser(ctx, training.ID, users[3]); !xerrors.Is(err, storage.ErrAlreadyExists) {
+		t.Errorf("register twice on the waitlist: got %v, want ErrAlreadyExists", err)
+	}
+	for i, userID := range users[2:] {
+		registration, err := store.Trainings.GetRegistration(ctx, training.ID, userID)
+		if err != nil {
+			t.Fatalf("get registration of user %d: %v", userID, err)
+		}
+		if registration.Position != i+1 {
+			t.Errorf("user %d is at position %d, want %d", userID, registration.Position, i+1)
+		}
+	}
+
+	registrants, err := store.Trainings.ListRegistrants(ctx, training.ID)
+	if err != nil {
+		t.Fatalf("list registrants: %v", err)
+	}
+	if fmt.Sprint(registrants) != fmt.Sprint(users) {
+		t.Errorf("registrants %v, want %v", registrants, users)
+	}
+
+	_, promoted, err := store.Trainings.CancelRegistration(ctx, training.ID, users[0], false)
+	if err != nil {
+		t.Fatalf("cancel registration: %v", err)
+	}
+	if fmt.Sprint(promoted) != fmt.Sprint(users[2:3]) {
+		t.Errorf("promoted %v, want %v", promoted, users[2:3])
+	}
+	registration, err := store.Trainings.GetRegistration(ctx, training.ID, users[3])
+	if err != nil || registration.Position != 1 {
+		t.Errorf("last waitlisted user after a promotion: %+v, %v", registration, err)
+	}
+	if _, err := store.Trainings.GetRegistration(ctx, training.ID, users[0]); !xerrors.Is(err, storage.ErrNotFound) {
+		t.Errorf("get cancelled registration: got %v, want ErrNotFound", err)
+	}
+}
+
+func testRegisterWithoutCredits(t *testing.T, store storage.Storage) {
+	ctx := context.Background()
+	training := domain.Training{
+		Name:      "Pilates",
+		TrainerID: createTrainer(t, store),
+		Capacity:  5,
+		Credits:   1,
+		StartTime: start(),
+		EndTime:   start().Add(time.Hour),
+	}
+	if err := store.Trainings.Create(ctx, &training, false); err != nil {
+		t.Fatalf("create training: %v", err)
+	}
+	userID := createUsers(t, store, 1)[0]
+
+	if _, err := store.Trainings.RegisterUser(ctx, training.ID, userID); !xerrors.Is(err, storage.ErrNoCredits) {
+		t.Errorf("register without a membership: got %v, want ErrNoCredits", err)
+	}
+	if _, err := store.Trainings.GetRegistration(ctx, training.ID, userID); !xerrors.Is(err, storage.ErrNotFound) {
+		t.Errorf("get rejected registration: got %v, want ErrNotFound", err)
+	}
+}
+
+func testCapacityBelowSeats(t *testing.T, store storage.Storage) {
+	ctx := context.Background()
+	training := newTraining(t, store, createTrainer(t, store), 3)
+	for _, userID := range createUsers(t, store, 3) {
+		if _, err := store.Trainings.RegisterUser(ctx, training.ID, userID); err != nil {
+			t.Fatalf("register user %d: %v", userID, err)
+		}
+	}
+
+	training.Capacity = 2
+	if err := store.Trainings.Update(ctx, &training, false); !xerrors.Is(err, storage.ErrCapacityBelowSeats) {
+		t.Errorf("lower the capacity below the seats: got %v, want ErrCapacityBelowSeats", err)
+	}
+	if got, err := store.Trainings.GetByID(ctx, training.ID); err != nil || got.Capacity != 3 {
+		t.Errorf("training after the rejected update: %+v, %v", got, err)
+	}
+	for _, capacity := range []int{3, 0} {
+		training.Capacity = capacity
+		if err := store.Trainings.Update(ctx, &training, false); err != nil {
+			t.Errorf("set the capacity to %d: %v", capacity, err)
+		}
+	}
+}
+
+func testConcurrentCreateTraining(t *testing.T, store storage.Storage) {
+	const n = 50
+	ctx := context.Background()
+	trainerID := createTrainer(t, store)
+
+	ids := make([]int, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := range ids {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			training := domain.Training{
+				Name:      fmt.Sprintf("Training %d", i),
+				TrainerID: trainerID,
+				StartTime: start(),
+				EndTime:   start().Add(time.Hour),
+			}
+			errs[i] = store.Trainings.Create(ctx, &training, true)
+			ids[i] = training.ID
+		}(i)
+	}
+	wg.Wait()
+
+	seen := make(map[int]bool)
+	for i, id := range ids {
+		if errs[i] != nil {
+			t.Fatalf("create training %d: %v", i, errs[i])
+		}
+		if id == 0 || seen[id] {
+			t.Errorf("training %d got ID %d, which is zero or taken", i, id)
+		}
+		seen[id] = true
+	}
+	trainings, err := store.Trainings.ListByTrainer(ctx, trainerID)
+	if err != nil {
+		t.Fatalf("list trainings: %v", err)
+	}
+	if len(trainings) != n {
+		t.Errorf("%d trainings stored, want %d", len(trainings), n)
+	}
+}
+
+func testConcurrentRegisterUser(t *testing.T, store storage.Storage) {
+	const capacity, n = 5, 30
+	ctx := context.Background()
+	training := newTraining(t, store, createTrainer(t, store), capacity)
+	users := createUsers(t, store, n)
+
+	registrations := make([]*domain.Registration, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i, userID := range users {
+		wg.Add(1)
+		go func(i, userID int) {
+			defer wg.Done()
+			registrations[i], errs[i] = store.Trainings.RegisterUser(ctx, training.ID, userID)
+		}(i, userID)
+	}
+	wg.Wait()
+
+	var confirmed []int
+	waitlist := make([]int, n-capacity)
+	for i, registration := range registrations {
+		if errs[i] != nil {
+			t.Fatalf("register user %d: %v", users[i], errs[i])
+		}
+		switch registration.Status {
+		case domain.RegistrationConfirmed:
+			confirmed = append(confirmed, users[i])
+		case domain.RegistrationWaitlisted:
+			if registration.Position < 1 || registration.Position > len(waitlist) || waitlist[registration.Position-1] != 0 {
+				t.Fatalf("user %d got waitlist position %d, which is out of range or taken", users[i], registration.Position)
+			}
+			waitlist[registration.Position-1] = users[i]
+		default:
+			t.Fatalf("user %d got status %s", users[i], registration.Status)
+		}
+	}
+	if len(confirmed) != capacity {
+		t.Fatalf("%d seats confirmed, want the capacity %d", len(confirmed), capacity)
+	}
+	page, err := store.Users.ListByTraining(ctx, training.ID, 0, n)
+	if err != nil {
+		t.Fatalf("list users of the training: %v", err)
+	}
+	if page.Total != capacity {
+		t.Errorf("%d users listed with a seat, want %d", page.Total, capacity)
+	}
+
+	// Every freed seat goes to the first waitlisted user, in the order the
+	// registrations got their positions
+	for i, userID := range confirmed {
+		_, promoted, err := store.Trainings.CancelRegistration(ctx, training.ID, userID, false)
+		if err != nil {
+			t.Fatalf("cancel registration of user %d: %v", userID, err)
+		}
+		if len(promoted) != 1 || promoted[0] != waitlist[i] {
+			t.Errorf("cancelling seat %d promoted %v, want [%d]", i, promoted, waitlist[i])
+		}
+	}
+	for i, userID := range waitlist[capacity:] {
+		registration, err := store.Trainings.GetRegistration(ctx, training.ID, userID)
+		if err != nil {
+			t.Fatalf("get registration of user %d: %v", userID, err)
+		}
+		if registration.Status != domain.RegistrationWaitlisted || registration.Position != i+1 {
+			t.Errorf("user %d is %s at position %d, want waitlisted at %d", userID, registration.Status, registration.Position, i+1)
+		}
+	}
+}
+
+func testScheduleConflicts(t *testing.T, store storage.Storage) {
+	ctx := context.Background()
+	trainerID := createTrainer(t, store)
+	full := newTraining(t, store, trainerID, 1)
+	overlapping := newTrainingAt(t, store, trainerID, full.StartTime.Add(30*time.Minute))
+	near := newTrainingAt(t, store, trainerID, full.EndTime.Add(buffer/2))
+	later := newTrainingAt(t, store, trainerID, full.EndTime.Add(buffer))
+	users := createUsers(t, store, 2)
+
+	for _, userID := range users {
+		if _, err := store.Trainings.RegisterUser(ctx, full.ID, userID); err != nil {
+			t.Fatalf("register user %d: %v", userID, err)
+		}
+	}
+	// Both the seat and the waitlist place conflict
+	for _, userID := range users {
+		for _, training := range []domain.Training{overlapping, near} {
+			_, err := store.Trainings.RegisterUser(ctx, training.ID, userID)
+			var conflict *storage.ScheduleConflictError
+			if !xerrors.As(err, &conflict) || !xerrors.Is(err, storage.ErrScheduleConflict) {
+				t.Fatalf("register user %d for training %d: got %v, want a ScheduleConflictError", userID, training.ID, err)
+			}
+			if fmt.Sprint(conflict.TrainingIDs) != fmt.Sprint([]int{full.ID}) {
+				t.Errorf("user %d conflicts with %v, want [%d]", userID, conflict.TrainingIDs, full.ID)
+			}
+		}
+		if _, err := store.Trainings.RegisterUser(ctx, later.ID, userID); err != nil {
+			t.Errorf("register user %d for a training a buffer later: %v", userID, err)
+		}
+	}
+	if _, err := store.Trainings.GetRegistration(ctx, overlapping.ID, users[0]); !xerrors.Is(err, storage.ErrNotFound) {
+		t.Errorf("get rejected registration: got %v, want ErrNotFound", err)
+	}
+}
+
+func testPromotionConflicts(t *testing.T, store storage.Storage) {
+	ctx := context.Background()
+	trainerID := createTrainer(t, store)
+	morning := newTraining(t, store, trainerID, 10)
+	evening := newTrainingAt(t, store, trainerID, morning.StartTime.Add(10*time.Hour))
+	evening.Capacity = 1
+	if err := store.Trainings.Update(ctx, &evening, false); err != nil {
+		t.Fatalf("update training: %v", err)
+	}
+	users := createUsers(t, store, 3)
+
+	if _, err := store.Trainings.RegisterUser(ctx, morning.ID, users[1]); err != nil {
+		t.Fatalf("register user %d: %v", users[1], err)
+	}
+	for _, userID := range users {
+		if _, err := store.Trainings.RegisterUser(ctx, evening.ID, userID); err != nil {
+			t.Fatalf("register user %d: %v", userID, err)
+		}
+	}
+	// Moving the evening training onto the morning one makes the seat of the
+	// first waitlisted user in the morning conflict
+	evening.StartTime, evening.EndTime = morning.StartTime, morning.EndTime
+	if err := store.Trainings.Update(ctx, &evening, true); err != nil {
+		t.Fatalf("move training: %v", err)
+	}
+
+	_, promoted, err := store.Trainings.CancelRegistration(ctx, evening.ID, users[0], false)
+	if err != nil {
+		t.Fatalf("cancel registration: %v", err)
+	}
+	if fmt.Sprint(promoted) != fmt.Sprint(users[2:]) {
+		t.Errorf("promoted %v, want %v", promoted, users[2:])
+	}
+	registration, err := store.Trainings.GetRegistration(ctx, evening.ID, users[1])
+	if err != nil {
+		t.Fatalf("get registration of the passed over user: %v", err)
+	}
+	if registration.Status != domain.RegistrationWaitlisted || registration.Position != 1 {
+		t.Errorf("passed over user is %s at position %d, want waitlisted at 1", registration.Status, registration.Position)
+	}
+}
+
+func testConcurrentConflictingRegistrations(t *testing.T, store storage.Storage) {
+	const n = 10
+	ctx := context.Background()
+	trainerID := createTrainer(t, store)
+	trainings := make([]domain.Training, n)
+	for i := range trainings {
+		trainings[i] = newTrainingAt(t, store, trainerID, start().Add(time.Duration(i)*time.Minute))
+	}
+	userID := createUsers(t, store, 1)[0]
+
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i, training := range trainings {
+		wg.Add(1)
+		go func(i, trainingID int) {
+			defer wg.Done()
+			_, errs[i] = store.Trainings.RegisterUser(ctx, trainingID, userID)
+		}(i, training.ID)
+	}
+	wg.Wait()
+
+	registered := 0
+	for i, err := range errs {
+		switch {
+		case err == nil:
+			registered++
+		case !xerrors.Is(err, storage.ErrScheduleConflict):
+			t.Fatalf("register for training %d: %v", trainings[i].ID, err)
+		}
+	}
+	if registered != 1 {
+		t.Errorf("user registered for %d of the overlapping trainings, want 1", registered)
+	}
+}
+
+func testTrainerOverlaps(t *testing.T, store storage.Storage) {
+	ctx := context.Background()
+	trainerID := createTrainer(t, store)
+	morning := newTrainingAt(t, store, trainerID, start())
+	evening := newTrainingAt(t, store, trainerID, start().Add(10*time.Hour))
+	// Trainings of other trainers do not count
+	other := domain.Trainer{Name: "other trainer", Password: "hash"}
+	if err := store.Trainers.Create(ctx, &other); err != nil {
+		t.Fatalf("create trainer: %v", err)
+	}
+	newTrainingAt(t, store, other.ID, start())
+
+	overlapping := domain.Training{
+		Name:      "Boxing",
+		TrainerID: trainerID,
+		StartTime: morning.StartTime.Add(30 * time.Minute),
+		EndTime:   morning.EndTime.Add(30 * time.Minute),
+	}
+	err := store.Trainings.Create(ctx, &overlapping, false)
+	var overlap *storage.OverlapError
+	if !xerrors.As(err, &overlap) || !xerrors.Is(err, storage.ErrOverlap) {
+		t.Fatalf("create overlapping training: got %v, want an OverlapError", err)
+	}
+	if fmt.Sprint(overlap.TrainerTrainingIDs) != fmt.Sprint([]int{morning.ID}) {
+		t.Errorf("overlapped trainings %v, want [%d]", overlap.TrainerTrainingIDs, morning.ID)
+	}
+	if err := store.Trainings.Create(ctx, &overlapping, true); err != nil {
+		t.Fatalf("create overlapping training when allowed: %v", err)
+	}
+
+	evening.StartTime, evening.EndTime = morning.StartTime, morning.EndTime
+	if err := store.Trainings.Update(ctx, &evening, false); !xerrors.Is(err, storage.ErrOverlap) {
+		t.Errorf("move training onto others: got %v, want ErrOverlap", err)
+	}
+	// Trainings keeping their times keep the overlaps they were created with
+	overlapping.Name = "Late boxing"
+	if err := store.Trainings.Update(ctx, &overlapping, false); err != nil {
+		t.Errorf("rename overlapping training: %v", err)
+	}
+
+	// The training itself does not count
+	if err := store.Trainings.Delete(ctx, overlapping.ID); err != nil {
+		t.Fatalf("delete training: %v", err)
+	}
+	morning.StartTime, morning.EndTime = morning.StartTime.Add(30*time.Minute), morning.EndTime.Add(30*time.Minute)
+	if err := store.Trainings.Update(ctx, &morning, false); err != nil {
+		t.Errorf("move training within its own time: %v", err)
+	}
+}
+
+func testConcurrentOverlappingTrainings(t *testing.T, store storage.Storage) {
+	const n = 10
+	ctx := context.Background()
+	trainerID := createTrainer(t, store)
+
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			training := domain.Training{
+				Name:      fmt.Sprintf("Training %d", i),
+				TrainerID: trainerID,
+				StartTime: start().Add(time.Duration(i) * time.Minute),
+				EndTime:   start().Add(time.Hour),
+			}
+			errs[i] = store.Trainings.Create(ctx, &training, false)
+		}(i)
+	}
+	wg.Wait()
+
+	created := 0
+	for i, err := range errs {
+		switch {
+		case err == nil:
+			created++
+		case !xerrors.Is(err, storage.ErrOverlap):
+			t.Fatalf("create training %d: %v", i, err)
+		}
+	}
+	if created != 1 {
+		t.Errorf("created %d of the overlapping trainings of the trainer, want 1", created)
+	}
+}
+
+func testRoomOverlaps(t *testing.T, store storage.Storage) {
+	ctx := context.Background()
+	roomID := createRoom(t, store)
+	trainerIDs := createTrainers(t, store, 2)
+	booked := domain.Training{
+		Name:      "Yoga",
+		TrainerID: trainerIDs[0],
+		RoomID:    &roomID,
+		StartTime: start(),
+		EndTime:   start().Add(time.Hour),
+	}
+	if err := store.Trainings.Create(ctx, &booked, false); err != nil {
+		t.Fatalf("create training: %v", err)
+	}
+
+	training := domain.Training{
+		Name:      "Boxing",
+		TrainerID: trainerIDs[1],
+		StartTime: booked.StartTime.Add(30 * time.Minute),
+		EndTime:   booked.EndTime.Add(30 * time.Minute),
+	}
+	if err := store.Trainings.Create(ctx, &training, false); err != nil {
+		t.Fatalf("create training outside the room: %v", err)
+	}
+	// Moving the training into the room keeps its times but makes it overlap
+	training.RoomID = &roomID
+	err := store.Trainings.Update(ctx, &training, false)
+	var overlap *storage.OverlapError
+	if !xerrors.As(err, &overlap) {
+		t.Fatalf("move training into the booked room: got %v, want an OverlapError", err)
+	}
+	if len(overlap.TrainerTrainingIDs) != 0 || fmt.Sprint(overlap.RoomTrainingIDs) != fmt.Sprint([]int{booked.ID}) {
+		t.Errorf("overlapped trainings %v of the trainer and %v in the room, want none and [%d]",
+			overlap.TrainerTrainingIDs, overlap.RoomTrainingIDs, booked.ID)
+	}
+	if err := store.Trainings.Update(ctx, &training, true); err != nil {
+		t.Fatalf("move training into the booked room when allowed: %v", err)
+	}
+}
+
+func testConcurrentRoomBookings(t *testing.T, store storage.Storage) {
+	const n = 10
+	ctx := context.Background()
+	roomID := createRoom(t, store)
+	trainerIDs := createTrainers(t, store, n)
+
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i, trainerID := range trainerIDs {
+		wg.Add(1)
+		go func(i, trainerID int) {
+			defer wg.Done()
+			training := domain.Training{
+				Name:      fmt.Sprintf("Training %d", i),
+				TrainerID: trainerID,
+				RoomID:    &roomID,
+				StartTime: start().Add(time.Duration(i) * time.Minute),
+				EndTime:   start().Add(time.Hour),
+			}
+			errs[i] = store.Trainings.Create(ctx, &training, false)
+		}(i, trainerID)
+	}
+	wg.Wait()
+
+	created := 0
+	for i, err := range errs {
+		switch {
+		case err == nil:
+			created++
+		case !xerrors.Is(err, storage.ErrOverlap):
+			t.Fatalf("create training %d: %v", i, err)
+		}
+	}
+	if created != 1 {
+		t.Errorf("booked the room for %d of the overlapping trainings, want 1", created)
+	}
+}
+
+func testRevokeAccount(t *testing.T, store storage.Storage) {
+	ctx := context.Background()
+	// Within a second, so tokens issued in the same second come before and after it
+	revokedAt := time.Now().Truncate(time.Second).Add(500 * time.Millisecond)
+	if err := store.Sessions.RevokeAccount(ctx, "user", 1, revokedAt); err != nil {
+		t.Fatalf("revoke account: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		issuedAt time.Time
+		want     bool
+	}{
+		{"issued before", revokedAt.Add(-time.Minute), true},
+		{"issued earlier in the same second", revokedAt.Add(-time.Microsecond), true},
+		{"issued at the revocation", revokedAt, true},
+		{"issued later in the same second", revokedAt.Add(time.Microsecond), false},
+		{"issued after", revokedAt.Add(time.Minute), false},
+	}
+	for _, tt := range tests {
+		revoked, err := store.Sessions.IsAccessTokenRevoked(ctx, "jti", "user", 1, tt.issuedAt)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if revoked != tt.want {
+			t.Errorf("%s: revoked %v, want %v", tt.name, revoked, tt.want)
+		}
+	}
+
+	revoked, err := store.Sessions.IsAccessTokenRevoked(ctx, "jti", "user", 2, revokedAt.Add(-time.Minute))
+	if err != nil || revoked {
+		t.Errorf("token of another account: revoked %v, %v", revoked, err)
+	}
+}
+
+func testRefunds(t *testing.T, store storage.Storage) {
+	ctx := context.Background()
+	userID := createUsers(t, store, 1)[0]
+	paid := domain.Payment{UserID: userID, Amount: 1500, Currency: "eur", Status: domain.PaymentPaid, CreatedAt: time.Now()}
+	due := paid
+	due.Status = domain.PaymentRefundDue
+	for _, payment := range []*domain.Payment{&paid, &due} {
+		if err := store.Payments.Create(ctx, payment); err != nil {
+			t.Fatalf("create payment: %v", err)
+		}
+	}
+
+	if err := store.Payments.StartRefund(ctx, paid.ID); !xerrors.Is(err, storage.ErrNotFound) {
+		t.Errorf("start the refund of a paid payment: got %v, want ErrNotFound", err)
+	}
+	if err := store.Payments.StartRefund(ctx, due.ID); err != nil {
+		t.Fatalf("start refund: %v", err)
+	}
+	refunds, err := store.Payments.ListRefundsDue(ctx)
+	if err != nil || len(refunds) != 1 || refunds[0].ID != due.ID || refunds[0].RefundStartedAt == nil {
+		t.Fatalf("refunds due after starting the refund: %+v, %v", refunds, err)
+	}
+
+	if err := store.Payments.MarkRefunded(ctx, due.ID); err != nil {
+		t.Fatalf("mark refunded: %v", err)
+	}
+	if refunds, err := store.Payments.ListRefundsDue(ctx); err != nil || len(refunds) != 0 {
+		t.Errorf("refunds due after the refund: %+v, %v", refunds, err)
+	}
+	if err := store.Payments.MarkRefunded(ctx, due.ID); !xerrors.Is(err, storage.ErrNotFound) {
+		t.Errorf("mark a refunded payment refunded: got %v, want ErrNotFound", err)
+	}
+}
+
+func testCheckInAttempts(t *testing.T, store storage.Storage) {
+	ctx := context.Background()
+	training := newTraining(t, store, createTrainer(t, store), 10)
+	users := createUsers(t, store, 2)
+
+	for want := 1; want <= 3; want++ {
+		if got, err := store.Attendance.AddCheckInAttempt(ctx, training.ID, users[0]); err != nil || got != want {
+			t.Fatalf("attempt %d: got %d, %v", want, got, err)
+		}
+	}
+	// Attempts are counted per user
+	if got, err := store.Attendance.AddCheckInAttempt(ctx, training.ID, users[1]); err != nil || got != 1 {
+		t.Errorf("attempt of another user: got %d, %v, want 1", got, err)
+	}
+
+	const concurrent = 10
+	var wg sync.WaitGroup
+	for i := 0; i < concurrent; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := store.Attendance.AddCheckInAttempt(ctx, training.ID, users[1]); err != nil {
+				t.Errorf("concurrent attempt: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if got, err := store.Attendance.AddCheckInAttempt(ctx, training.ID, users[1]); err != nil || got != concurrent+2 {
+		t.Errorf("attempt after concurrent ones: got %d, %v, want %d", got, err, concurrent+2)
+	}
+
+	if _, err := store.Attendance.AddCheckInAttempt(ctx, training.ID+1000, users[0]); !xerrors.Is(err, storage.ErrNotFound) {
+		t.Errorf("attempt at an unknown training: got %v, want ErrNotFound", err)
+	}
+	if _, err := store.Attendance.AddCheckInAttempt(ctx, training.ID, users[1]+1000); !xerrors.Is(err, storage.ErrNotFound) {
+		t.Errorf("attempt of an unknown user: got %v, want ErrNotFound", err)
+	}
+}
+
+func createTrainer(t *testing.T, store storage.Storage) int {
+	t.Helper()
+	trainer := domain.Trainer{Name: "trainer", Password: "hash"}
+	if err := store.Trainers.Create(context.Background(), &trainer); err != nil {
+		t.Fatalf("create trainer: %v", err)
+	}
+	return trainer.ID
+}
+
+// createUsers creates n users and returns their IDs in ascending order
+func createUsers(t *testing.T, store storage.Storage, n int) []int {
+	t.Helper()
+	ids := make([]int, n)
+	for i := range ids {
+		user := domain.User{Name: fmt.Sprintf("user%d", i), Password: "hash"}
+		if err := store.Users.Create(context.Background(), &user); err != nil {
+			t.Fatalf("create user: %v", err)
+		}
+		ids[i] = user.ID
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// createTrainers creates n trainers and returns their IDs
+func createTrainers(t *testing.T, store storage.Storage, n int) []int {
+	t.Helper()
+	ids := make([]int, n)
+	for i := range ids {
+		trainer := domain.Trainer{Name: fmt.Sprintf("trainer %d", i), Password: "hash"}
+		if err := store.Trainers.Create(context.Background(), &trainer); err != nil {
+			t.Fatalf("create trainer: %v", err)
+		}
+		ids[i] = trainer.ID
+	}
+	return ids
+}
+
+// createRoom creates a location which is always open with a room and returns
+// the ID of the room
+func createRoom(t *testing.T, store storage.Storage) int {
+	t.Helper()
+	ctx := context.Background()
+	location := domain.Location{Name: "Studio", TimeZone: "UTC"}
+	if err := store.Locations.Create(ctx, &location); err != nil {
+		t.Fatalf("create location: %v", err)
+	}
+	room := domain.Room{LocationID: location.ID, Name: "Hall", Capacity: 20}
+	if err := store.Rooms.Create(ctx, &room); err != nil {
+		t.Fatalf("create room: %v", err)
+	}
+	return room.ID
+}
+
+// newTrainingAt creates a free training of the trainer lasting an hour, it may
+// overlap other trainings of the trainer
+func newTrainingAt(t *testing.T, store storage.Storage, trainerID int, startTime time.Time) domain.Training {
+	t.Helper()
+	training := domain.Training{
+		Name:      "Boxing",
+		Type:      "boxing",
+		TrainerID: trainerID,
+		StartTime: startTime,
+		EndTime:   startTime.Add(time.Hour),
+	}
+	if err := store.Trainings.Create(context.Background(), &training, true); err != nil {
+		t.Fatalf("create training: %v", err)
+	}
+	return training
+}
+
+// newTraining creates a free training of the trainer tomorrow
+func newTraining(t *testing.T, store storage.Storage, trainerID, capacity int) domain.Training {
+	t.Helper()
+	training := domain.Training{
+		Name:      "Morning yoga",
+		Type:      "yoga",
+		TrainerID: trainerID,
+		Capacity:  capacity,
+		StartTime: start(),
+		EndTime:   start().Add(time.Hour),
+	}
+	if err := store.Trainings.Create(context.Background(), &training, true); err != nil {
+		t.Fatalf("create training: %v", err)
+	}
+	return training
+}
+
+// start is a start time in the future, in whole seconds as the databases store it
+func start() time.Time {
+	return time.Now().Add(24 * time.Hour).Truncate(time.Hour).UTC()
+}